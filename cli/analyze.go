@@ -2,9 +2,15 @@ package cli
 
 import (
 	"context"
+	"io"
+	"log"
+	"os"
+	"strings"
 
 	"github.com/kvesta/vesta/config"
 	"github.com/kvesta/vesta/internal"
+	"github.com/kvesta/vesta/internal/analyzer"
+	"github.com/kvesta/vesta/internal/report"
 	"github.com/spf13/cobra"
 )
 
@@ -23,47 +29,332 @@ Examples:
   # analyze by specifying config
   $ vesta analyze k8s --kubeconfig config
 
+  # analyze by specifying config and context
+  $ vesta analyze k8s --kubeconfig config --context production
+
   # analyze all the namespace
   $ vesta analyze k8s -n all
 
   # analyze a special namespace
   $ vesta analyze k8s -n namespace 
 
+  # analyze the namespaces matching the patterns
+  $ vesta analyze k8s -n 'team-*,production'
+
+  # analyze the manifests before deploying, such as the output of helm template
+  $ vesta analyze k8s -f deploy/ -f rendered.yaml
+
   # analyze in a pod
   $ vesta analyze k8s --inside
+
+  # analyze the specific kinds of workload
+  $ vesta analyze k8s --kinds pod,daemonset
+
+  # match the images with a list of known-malicious images
+  $ vesta analyze docker --blocklist https://example.com/malicious-images.txt
+
+  # explain which checks would run without analyzing
+  $ vesta analyze k8s --explain --k8s-version 1.23
+
+  # save the findings as CSV
+  $ vesta analyze docker -o report.csv
+
+  # weight the compliance score by severity
+  $ vesta analyze k8s --weights critical=20,high=8
+
+  # skip the vendor images in the image analyzing
+  $ vesta analyze docker --exclude-image 'vendor/*,registry.example.com/base:*'
+
+  # analyze the saved output of docker inspect without a docker daemon
+  $ vesta analyze docker --inspect containers.json --server-version 20.10.17
+
+  # analyze the services of a compose file before deploying
+  $ vesta analyze docker --compose docker-compose.yml
+
+  # analyze the containers of containerd or CRI-O by the CRI socket on the node, detected if no socket is given
+  $ vesta analyze docker --cri=unix:///run/containerd/containerd.sock
+
+  # analyze the containers on the OpenShift node of CRI-O
+  $ vesta analyze docker --cri=unix:///var/run/crio/crio.sock
+
+  # analyze the containers of Podman, the socket of rootless Podman is detected first
+  $ vesta analyze docker --podman
+
+  # analyze the containers of the rootful Podman
+  $ vesta analyze docker --podman=/run/podman/podman.sock
+
+  # analyze the remote docker daemon by TLS, the certificates are of $DOCKER_CERT_PATH or ~/.docker
+  $ vesta analyze docker --host tcp://node1.example.com:2376 --tlsverify
+
+  # analyze the remote docker daemon over ssh
+  $ vesta analyze docker --host ssh://root@node1.example.com
+
+  # treat the findings of a check as critical
+  $ vesta analyze docker --severity checkEnvPassword=critical
+
+  # mask the values and references of the findings for sharing the report
+  $ vesta analyze k8s --redact
+
+  # mask the specific fields of the findings, the fields are given after '='
+  $ vesta analyze docker --redact=Value,Param,Describe
+
+  # write the findings as JSON lines once they are discovered
+  $ vesta analyze k8s -n all --stream findings.jsonl
+
+  # list the top 5 findings to fix first
+  $ vesta analyze docker --top 5
+
+  # analyze the clusters of the contexts and merge the results
+  $ vesta analyze k8s --context prod,staging
+
+  # analyze the clusters of all the contexts in the kubeconfig, the results are keyed by the context
+  $ vesta analyze k8s --kubeconfig clusters.yaml --all-contexts
+
+  # analyze by the options saved in a file
+  $ vesta analyze k8s --config vesta.yaml
+
+  # print only the critical findings without the logs, exit 1 if any is found
+  $ vesta analyze k8s --quiet=critical
+
+  # exit with 2 if any high or critical finding is found, as the gate of CI
+  $ vesta analyze docker --exit-code 2 --severity-threshold high
+
+  # report only the findings not in the baseline generated by 'vesta baseline export'
+  $ vesta analyze k8s --baseline .vesta-baseline.yaml --exit-code 1
+
+  # run the custom rules defined in the YAML files of directory alongside the built-in checks
+  $ vesta analyze k8s --rules ./rules
+
+  # evaluate the Rego v1 policies of the package vesta by the opa binary in PATH, the violations of data.vesta.deny are reported
+  $ vesta analyze docker --policy ./policies
+
+  # run the plugin executable reading the target as JSON on stdin and writing the findings on stdout
+  $ vesta analyze k8s --plugin /usr/local/lib/vesta/checkIngressClass
+
+  # flag the images not pulled from the allowed registries
+  $ vesta analyze k8s --registries registry.example.com,docker.io/library
+
+  # match the aliases of product names such as docker-ce in the vulnerability database
+  $ vesta analyze docker --fuzzy-match
+
+  # flag the containers whose writable layer has grown over 500MB
+  $ vesta analyze docker --layer-size-limit 500MB
+
+  # flag the containers whose main process is one of the commands
+  $ vesta analyze docker --debug-commands bash,sh,socat
+
+  # save the findings as JSON of the documented schema for CI pipelines
+  $ vesta analyze docker -o json
+
+  # save the findings as an HTML report with the executive summary
+  $ vesta analyze k8s -o report.html
+
+  # save the findings as JUnit XML for the test reports of Jenkins and GitLab
+  $ vesta analyze docker -o vesta-junit.xml
+
+  # save the findings as SARIF for GitHub Code Scanning
+  $ vesta analyze k8s --format sarif -o vesta.sarif
+
+  # send the findings as CEF events to the syslog collector of SIEM
+  $ vesta analyze k8s --syslog tcp://siem.example.com:514
+
+  # keep the findings in the local history, list them by 'vesta history'
+  $ vesta analyze k8s --history
+
+  # keep the findings in a specific history file, the file is given after '='
+  $ vesta analyze docker --history=scans.db
+
+  # reuse the findings of the pods unchanged since the previous scan
+  $ vesta analyze k8s --incremental
+
+  # analyze without some checks
+  $ vesta analyze docker --disable checkEnvPassword,checkPid
 `}
 
 	dockerAnalyze := &cobra.Command{
 		Use:   "docker",
 		Short: "analyze docker container",
+		// Values of the flags with the optional value are given after '=',
+		// the value after a space is a stray argument and rejected
+		Args: NoArgs,
 		Run: func(cmd *cobra.Command, args []string) {
+			applyScanFile(cmd)
+			registerRules()
+			registerPolicy()
+			registerPlugins()
+
 			ctx := config.Ctx
 			ctx = context.WithValue(ctx, "output", outfile)
+			ctx = context.WithValue(ctx, "format", checkFormat())
+			ctx = context.WithValue(ctx, "disable", disableChecks)
+			ctx = context.WithValue(ctx, "scoreWeights", scoreWeights)
+			ctx = context.WithValue(ctx, "severity", severities)
+			ctx = context.WithValue(ctx, "concurrency", concurrency)
+			ctx = context.WithValue(ctx, "explain", explain)
+			ctx = context.WithValue(ctx, "blocklist", blocklist)
+			ctx = context.WithValue(ctx, "excludeImages", excludeImages)
+			ctx = context.WithValue(ctx, "inspect", inspectFile)
+			ctx = context.WithValue(ctx, "compose", composeFile)
+			ctx = context.WithValue(ctx, "cri", criEndpoint)
+			ctx = context.WithValue(ctx, "podman", podmanSocket)
+			ctx = context.WithValue(ctx, "host", dockerHost)
+			ctx = context.WithValue(ctx, "tlsverify", tlsVerify)
+			ctx = context.WithValue(ctx, "certPath", certPath)
+			ctx = context.WithValue(ctx, "engineVersion", engineVersion)
+			ctx = context.WithValue(ctx, "serverVersion", serverVersion)
+			ctx = context.WithValue(ctx, "usernsRemap", usernsRemap)
+			ctx = context.WithValue(ctx, "layerSizeLimit", layerLimit)
+			ctx = context.WithValue(ctx, "debugCommands", debugCommands)
+			ctx = context.WithValue(ctx, "redact", redactFields)
+			ctx = context.WithValue(ctx, "stream", streamFile)
+			ctx = context.WithValue(ctx, "top", topFindings)
+			ctx = context.WithValue(ctx, "baseline", baselineFile)
+			ctx = context.WithValue(ctx, "fuzzyMatch", fuzzyMatch)
+			ctx = context.WithValue(ctx, "history", historyFile)
+			ctx = context.WithValue(ctx, "syslog", syslogAddr)
 
-			internal.DoInspectInDocker(ctx)
+			ctx, q := withQuiet(ctx)
+			ctx, g := withGate(ctx)
+			err := internal.DoInspectInDocker(ctx)
+			exitAnalysis(err, g, q)
 		},
 	}
 
 	kubernetesAnalyze := &cobra.Command{
 		Use:   "k8s",
 		Short: "analyze configure of kubernetes",
+		Args:  NoArgs,
 		Run: func(cmd *cobra.Command, args []string) {
+			applyScanFile(cmd)
+			registerRules()
+			registerPolicy()
+			registerPlugins()
+
 			ctx := config.Ctx
 			ctx = context.WithValue(ctx, "nameSpace", nameSpace)
 			ctx = context.WithValue(ctx, "kubeconfig", kubeconfig)
+			ctx = context.WithValue(ctx, "kubeContext", kubeContext)
+			ctx = context.WithValue(ctx, "allContexts", allContexts)
+			ctx = context.WithValue(ctx, "manifests", manifests)
 			ctx = context.WithValue(ctx, "output", outfile)
+			ctx = context.WithValue(ctx, "format", checkFormat())
 			ctx = context.WithValue(ctx, "inside", inside)
+			ctx = context.WithValue(ctx, "kinds", kinds)
+			ctx = context.WithValue(ctx, "explain", explain)
+			ctx = context.WithValue(ctx, "blocklist", blocklist)
+			ctx = context.WithValue(ctx, "k8sVersion", k8sVersion)
+			ctx = context.WithValue(ctx, "disable", disableChecks)
+			ctx = context.WithValue(ctx, "scoreWeights", scoreWeights)
+			ctx = context.WithValue(ctx, "severity", severities)
+			ctx = context.WithValue(ctx, "redact", redactFields)
+			ctx = context.WithValue(ctx, "stream", streamFile)
+			ctx = context.WithValue(ctx, "top", topFindings)
+			ctx = context.WithValue(ctx, "baseline", baselineFile)
+			ctx = context.WithValue(ctx, "fuzzyMatch", fuzzyMatch)
+			ctx = context.WithValue(ctx, "history", historyFile)
+			ctx = context.WithValue(ctx, "syslog", syslogAddr)
+			ctx = context.WithValue(ctx, "incremental", incremental)
 
-			internal.DoInspectInKubernetes(ctx)
+			ctx, q := withQuiet(ctx)
+			ctx, g := withGate(ctx)
+			err := internal.DoInspectInKubernetes(ctx)
+			exitAnalysis(err, g, q)
 		},
 	}
 
-	kubernetesAnalyze.Flags().StringVarP(&nameSpace, "ns", "n", "standard", "specific namespace")
+	kubernetesAnalyze.Flags().StringVarP(&nameSpace, "ns", "n", "standard", "specific namespace, comma-separated names or glob patterns")
 	kubernetesAnalyze.Flags().StringVar(&kubeconfig, "kubeconfig", "default", "specific configure file")
+	kubernetesAnalyze.Flags().StringVar(&kubeContext, "context", "",
+		"specific context in the configure file, comma-separated contexts to analyze the clusters and merge the results")
+	kubernetesAnalyze.Flags().BoolVar(&allContexts, "all-contexts", false, "analyze the clusters of all the contexts in the configure file")
+	kubernetesAnalyze.Flags().StringSliceVarP(&manifests, "manifest", "f", []string{},
+		"manifest files or directories to analyze statically without a cluster")
 	kubernetesAnalyze.Flags().BoolVar(&inside, "inside", false, "running analyze in a pod by using service account token")
-	kubernetesAnalyze.Flags().StringVarP(&outfile, "output", "o", "output", "output file location")
+	kubernetesAnalyze.Flags().StringVarP(&outfile, "output", "o", "output",
+		"output file location, or json, csv, sarif, html or junit to save in the format at the default location")
+	kubernetesAnalyze.Flags().StringVar(&outFormat, "format", "",
+		"format of the output file: json, csv, sarif, html or junit, by the extension of output file if no format is given")
+	kubernetesAnalyze.Flags().StringSliceVar(&kinds, "kinds", []string{},
+		"kinds of workload to analyze: pod, daemonset, job, cronjob, rolebinding, configmap, secret, service, pdb")
+	kubernetesAnalyze.Flags().StringVar(&blocklist, "blocklist", "", "file or URL of the known-malicious images")
+	kubernetesAnalyze.Flags().BoolVar(&explain, "explain", false, "list the checks which would run without analyzing")
+	kubernetesAnalyze.Flags().StringVar(&k8sVersion, "k8s-version", "", "version of kubernetes for the explain mode")
+	kubernetesAnalyze.Flags().StringSliceVar(&disableChecks, "disable", []string{}, "names of the checks to disable")
+	kubernetesAnalyze.Flags().StringToIntVar(&scoreWeights, "weights", map[string]int{}, "weights of severity for the compliance score")
+	kubernetesAnalyze.Flags().StringToStringVar(&severities, "severity", map[string]string{}, "remap the severity of the findings by the name of check")
+	kubernetesAnalyze.Flags().StringSliceVar(&redactFields, "redact", []string{},
+		"mask the fields of the findings given by --redact=<fields>: Param, Value, Reference, Describe, Remediation")
+	kubernetesAnalyze.Flags().Lookup("redact").NoOptDefVal = "Value,Reference"
+	kubernetesAnalyze.Flags().IntVar(&topFindings, "top", 0, "number of the prioritized findings to fix first, none are listed by default")
+	kubernetesAnalyze.Flags().StringVar(&streamFile, "stream", "", "file to write the findings as JSON lines once they are discovered, - for stdout")
+	kubernetesAnalyze.Flags().StringVarP(&quiet, "quiet", "q", "",
+		"print only the findings at or above the severity and the errors without the other logs, high if no severity is given")
+	kubernetesAnalyze.Flags().Lookup("quiet").NoOptDefVal = "high"
+	kubernetesAnalyze.Flags().IntVar(&exitCode, "exit-code", 0, "exit code when any finding is at or above the severity threshold, 0 to disable")
+	kubernetesAnalyze.Flags().StringVar(&threshold, "severity-threshold", "warning", "severity of the findings failing the scan with the exit code")
+	kubernetesAnalyze.Flags().StringVar(&baselineFile, "baseline", "", "baseline file of the known findings which are not reported")
+	kubernetesAnalyze.Flags().StringSliceVar(&ruleFiles, "rules", []string{}, "YAML files or directories of the custom rules")
+	kubernetesAnalyze.Flags().StringSliceVar(&registries, "registries", []string{}, "registries which the images are allowed to be pulled from, matched as the prefix of image")
+	kubernetesAnalyze.Flags().StringSliceVar(&policyFiles, "policy", []string{}, "Rego v1 files or directories of the policies evaluated by the opa binary in PATH")
+	kubernetesAnalyze.Flags().StringSliceVar(&plugins, "plugin", []string{}, "executables of the third-party checks, named after the file")
+	kubernetesAnalyze.Flags().BoolVar(&fuzzyMatch, "fuzzy-match", false, "match the aliases of product names in the vulnerability database")
+	kubernetesAnalyze.Flags().StringVarP(&configFile, "config", "c", "", "YAML file of the options, the flags given take precedence")
+	kubernetesAnalyze.Flags().StringVar(&historyFile, "history", "", "SQLite file given by --history=<file> to keep the findings of scans, ~/.vesta/history.db if no file is given")
+	kubernetesAnalyze.Flags().Lookup("history").NoOptDefVal = "default"
+	kubernetesAnalyze.Flags().StringVar(&syslogAddr, "syslog", "",
+		"syslog collector to send the findings as CEF events, udp://host:port or tcp://host:port")
+	kubernetesAnalyze.Flags().StringVar(&incremental, "incremental", "",
+		"file of the previous scan state to reuse the findings of unchanged pods, ~/.vesta/incremental.json if no file is given")
+	kubernetesAnalyze.Flags().Lookup("incremental").NoOptDefVal = "default"
 
-	dockerAnalyze.Flags().StringVarP(&outfile, "output", "o", "output", "output file location")
+	dockerAnalyze.Flags().StringVarP(&outfile, "output", "o", "output",
+		"output file location, or json, csv, sarif, html or junit to save in the format at the default location")
+	dockerAnalyze.Flags().StringVar(&outFormat, "format", "",
+		"format of the output file: json, csv, sarif, html or junit, by the extension of output file if no format is given")
+	dockerAnalyze.Flags().StringVar(&blocklist, "blocklist", "", "file or URL of the known-malicious images")
+	dockerAnalyze.Flags().BoolVar(&explain, "explain", false, "list the checks which would run without analyzing")
+	dockerAnalyze.Flags().StringSliceVar(&disableChecks, "disable", []string{}, "names of the checks to disable")
+	dockerAnalyze.Flags().IntVar(&concurrency, "concurrency", 4, "number of images analyzed concurrently")
+	dockerAnalyze.Flags().StringSliceVar(&excludeImages, "exclude-image", []string{},
+		"images skipped by the image analyzing, by name, wildcard pattern or digest")
+	dockerAnalyze.Flags().StringVar(&inspectFile, "inspect", "", "file of the saved output of docker inspect for the offline analysis")
+	dockerAnalyze.Flags().StringVar(&composeFile, "compose", "", "compose file to analyze the services statically")
+	dockerAnalyze.Flags().StringVar(&criEndpoint, "cri", "", "CRI socket given by --cri=<socket> to analyze the containers of containerd or CRI-O, detected if no socket is given")
+	dockerAnalyze.Flags().Lookup("cri").NoOptDefVal = "default"
+	dockerAnalyze.Flags().StringVar(&podmanSocket, "podman", "", "Podman socket given by --podman=<socket> to analyze the containers of Podman, the rootless socket is detected first if no socket is given")
+	dockerAnalyze.Flags().Lookup("podman").NoOptDefVal = "default"
+	dockerAnalyze.Flags().StringVarP(&dockerHost, "host", "H", "", "remote docker daemon to analyze, tcp://host:port or ssh://user@host")
+	dockerAnalyze.Flags().BoolVar(&tlsVerify, "tlsverify", false, "use TLS and verify the remote daemon by the certificates of cert path")
+	dockerAnalyze.Flags().StringVar(&certPath, "cert-path", "", "directory of ca.pem, cert.pem and key.pem, $DOCKER_CERT_PATH or ~/.docker if empty")
+	dockerAnalyze.Flags().StringVar(&engineVersion, "engine-version", "", "containerd version for the offline analysis")
+	dockerAnalyze.Flags().StringVar(&serverVersion, "server-version", "", "docker server version for the offline analysis")
+	dockerAnalyze.Flags().BoolVar(&usernsRemap, "userns-remap", false, "docker daemon is run with userns-remap, for the offline analysis")
+	dockerAnalyze.Flags().StringVar(&layerLimit, "layer-size-limit", "1GiB", "size of the writable layer of container flagged as unusual growth")
+	dockerAnalyze.Flags().StringSliceVar(&debugCommands, "debug-commands", []string{},
+		"commands flagged as the main process of container, shells, package managers, curl, wget and sleep by default")
+	dockerAnalyze.Flags().StringToIntVar(&scoreWeights, "weights", map[string]int{}, "weights of severity for the compliance score")
+	dockerAnalyze.Flags().StringToStringVar(&severities, "severity", map[string]string{}, "remap the severity of the findings by the name of check")
+	dockerAnalyze.Flags().StringSliceVar(&redactFields, "redact", []string{},
+		"mask the fields of the findings given by --redact=<fields>: Param, Value, Reference, Describe, Remediation")
+	dockerAnalyze.Flags().Lookup("redact").NoOptDefVal = "Value,Reference"
+	dockerAnalyze.Flags().IntVar(&topFindings, "top", 0, "number of the prioritized findings to fix first, none are listed by default")
+	dockerAnalyze.Flags().StringVar(&streamFile, "stream", "", "file to write the findings as JSON lines once they are discovered, - for stdout")
+	dockerAnalyze.Flags().StringVarP(&quiet, "quiet", "q", "",
+		"print only the findings at or above the severity and the errors without the other logs, high if no severity is given")
+	dockerAnalyze.Flags().Lookup("quiet").NoOptDefVal = "high"
+	dockerAnalyze.Flags().IntVar(&exitCode, "exit-code", 0, "exit code when any finding is at or above the severity threshold, 0 to disable")
+	dockerAnalyze.Flags().StringVar(&threshold, "severity-threshold", "warning", "severity of the findings failing the scan with the exit code")
+	dockerAnalyze.Flags().StringVar(&baselineFile, "baseline", "", "baseline file of the known findings which are not reported")
+	dockerAnalyze.Flags().StringSliceVar(&ruleFiles, "rules", []string{}, "YAML files or directories of the custom rules")
+	dockerAnalyze.Flags().StringSliceVar(&registries, "registries", []string{}, "registries which the images are allowed to be pulled from, matched as the prefix of image")
+	dockerAnalyze.Flags().StringSliceVar(&policyFiles, "policy", []string{}, "Rego v1 files or directories of the policies evaluated by the opa binary in PATH")
+	dockerAnalyze.Flags().StringSliceVar(&plugins, "plugin", []string{}, "executables of the third-party checks, named after the file")
+	dockerAnalyze.Flags().BoolVar(&fuzzyMatch, "fuzzy-match", false, "match the aliases of product names in the vulnerability database")
+	dockerAnalyze.Flags().StringVarP(&configFile, "config", "c", "", "YAML file of the options, the flags given take precedence")
+	dockerAnalyze.Flags().StringVar(&historyFile, "history", "", "SQLite file given by --history=<file> to keep the findings of scans, ~/.vesta/history.db if no file is given")
+	dockerAnalyze.Flags().Lookup("history").NoOptDefVal = "default"
+	dockerAnalyze.Flags().StringVar(&syslogAddr, "syslog", "",
+		"syslog collector to send the findings as CEF events, udp://host:port or tcp://host:port")
 
 	analyzeCmd.AddCommand(dockerAnalyze)
 	analyzeCmd.AddCommand(kubernetesAnalyze)
@@ -71,3 +362,127 @@ Examples:
 	rootCmd.AddCommand(analyzeCmd)
 
 }
+
+// checkFormat get the option `format`, exit if it is not one of the output formats
+func checkFormat() string {
+	if outFormat == "" {
+		return ""
+	}
+
+	for _, format := range config.OutputFormats {
+		if strings.EqualFold(outFormat, format) {
+			return format
+		}
+	}
+
+	config.ErrorLog.Printf("unknown output format '%s', available formats: %s",
+		outFormat, strings.Join(config.OutputFormats, ", "))
+	os.Exit(1)
+
+	return ""
+}
+
+// registerRules register the custom rules of the option `rules` as the checks,
+// the option `registries` is registered as the rule of the image prefixes
+func registerRules() {
+	if len(registries) > 0 {
+		err := analyzer.RegisterCheck(analyzer.RegistryRule(registries))
+		if err != nil {
+			config.ErrorLog.Printf("failed to register the registries, error: %v", err)
+			os.Exit(1)
+		}
+	}
+
+	if len(ruleFiles) < 1 {
+		return
+	}
+
+	n, err := analyzer.RegisterRules(ruleFiles)
+	if err != nil {
+		config.ErrorLog.Printf("failed to load the rules, error: %v", err)
+		os.Exit(1)
+	}
+
+	log.Printf("%d custom rules are loaded", n)
+}
+
+// registerPolicy register the Rego policies of the option `policy` as the check
+func registerPolicy() {
+	if len(policyFiles) < 1 {
+		return
+	}
+
+	err := analyzer.RegisterPolicy(policyFiles)
+	if err != nil {
+		config.ErrorLog.Printf("failed to load the policies, error: %v", err)
+		os.Exit(1)
+	}
+}
+
+// registerPlugins register the executables of the option `plugin` as the checks
+func registerPlugins() {
+	if len(plugins) < 1 {
+		return
+	}
+
+	err := analyzer.RegisterPlugins(plugins)
+	if err != nil {
+		config.ErrorLog.Printf("failed to load the plugins, error: %v", err)
+		os.Exit(1)
+	}
+}
+
+// withGate set the gate of the options `exit-code` and `severity-threshold`
+func withGate(ctx context.Context) (context.Context, *report.Gate) {
+	if exitCode == 0 {
+		return ctx, nil
+	}
+
+	if _, ok := config.SeverityMap[strings.ToLower(threshold)]; !ok {
+		config.ErrorLog.Printf("unknown severity threshold '%s'", threshold)
+		os.Exit(1)
+	}
+
+	g := &report.Gate{Severity: threshold, ExitCode: exitCode}
+
+	return context.WithValue(ctx, "gate", g), g
+}
+
+// withQuiet set the quiet mode by the option `quiet`, the progress logs are discarded
+// and only the findings at or above the severity and the failures logged by config.ErrorLog are printed
+func withQuiet(ctx context.Context) (context.Context, *report.Quiet) {
+	if quiet == "" {
+		return ctx, nil
+	}
+
+	if _, ok := config.SeverityMap[strings.ToLower(quiet)]; !ok {
+		config.ErrorLog.Printf("unknown severity '%s' of quiet mode", quiet)
+		os.Exit(1)
+	}
+
+	log.SetOutput(io.Discard)
+
+	q := &report.Quiet{Severity: quiet}
+
+	return context.WithValue(ctx, "quiet", q), q
+}
+
+// exitAnalysis exit by the result of the analysis or scan, in the order of
+//
+//  1. the analysis failed: exit with 1, the gate and quiet mode fail closed
+//  2. any finding reaches the severity threshold of gate: exit with the exit code of gate
+//  3. any finding is printed in quiet mode: exit with 1
+func exitAnalysis(err error, g *report.Gate, q *report.Quiet) {
+	if err != nil {
+		config.ErrorLog.Printf("%v", err)
+		os.Exit(1)
+	}
+
+	if g != nil && g.Found > 0 {
+		os.Exit(g.ExitCode)
+	}
+
+	if q != nil && q.Found > 0 {
+		os.Exit(1)
+	}
+}
@@ -1,3 +1,5 @@
 package cli
 
-const versions = "v1.0.6"
+import "github.com/kvesta/vesta/config"
+
+const versions = config.Version
@@ -0,0 +1,57 @@
+package cli
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/kvesta/vesta/config"
+	"github.com/kvesta/vesta/internal/analyzer"
+	"github.com/spf13/cobra"
+)
+
+func baseline() {
+	baselineCmd := &cobra.Command{
+		Use:   "baseline",
+		Short: "Manage the baseline of the known findings suppressed by the option --baseline",
+	}
+
+	exportCmd := &cobra.Command{
+		Use: "export",
+		Short: `Generate the baseline from the JSON output of vesta analyze
+
+Examples:
+  # accept the current findings until the end of June
+  $ vesta analyze k8s -o output.json
+  $ vesta baseline export -i output.json -o .vesta-baseline.yaml \
+      --justification "accepted in the review of 2023 Q2" --expires 2023-06-30
+
+  # only the new findings are reported and fail the pipeline
+  $ vesta analyze k8s --baseline .vesta-baseline.yaml --exit-code 1
+`,
+		Args: NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			b, err := analyzer.BaselineFromOutput(inputFile, justification, expires)
+			if err != nil {
+				log.Printf("failed to generate the baseline, error: %v", err)
+				return
+			}
+
+			err = b.Save(outfile)
+			if err != nil {
+				log.Printf("failed to save the baseline, error: %v", err)
+				return
+			}
+
+			log.Printf(config.Green(fmt.Sprintf("%d findings are saved in the baseline %s", len(b.Suppressions), outfile)))
+		},
+	}
+
+	exportCmd.Flags().StringVarP(&inputFile, "input", "i", "", "JSON output of vesta analyze without --redact, the findings of each cluster are kept apart")
+	exportCmd.Flags().StringVarP(&outfile, "output", "o", ".vesta-baseline.yaml", "baseline file location")
+	exportCmd.Flags().StringVar(&justification, "justification", "", "reason of accepting the findings")
+	exportCmd.Flags().StringVar(&expires, "expires", "", "last day of the suppressions as YYYY-MM-DD, never expire if empty")
+	_ = exportCmd.MarkFlagRequired("input")
+
+	baselineCmd.AddCommand(exportCmd)
+	rootCmd.AddCommand(baselineCmd)
+}
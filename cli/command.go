@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"os"
 
 	"github.com/kvesta/vesta/config"
 	"github.com/kvesta/vesta/pkg/vulnlib"
@@ -18,13 +19,67 @@ var (
                Tutorial is available at https://github.com/kvesta/vesta`,
 	}
 
-	tarFile    string
-	nameSpace  string
-	kubeconfig string
-	outfile    string
-	updateall  bool
-	skipUpdate bool
-	inside     bool
+	tarFile     string
+	nameSpace   string
+	kubeconfig  string
+	kubeContext string
+	allContexts bool
+	outfile     string
+	outFormat   string
+	sbomFormat  string
+	updateall   bool
+
+	snapshotURL      string
+	snapshotChecksum string
+	skipUpdate       bool
+	inside           bool
+
+	disableChecks []string
+	scoreWeights  map[string]int
+	severities    map[string]string
+	concurrency   int
+	kinds         []string
+	explain       bool
+	k8sVersion    string
+	blocklist     string
+	redactFields  []string
+	streamFile    string
+	topFindings   int
+	quiet         string
+	exitCode      int
+	threshold     string
+	baselineFile  string
+	inputFile     string
+	justification string
+	expires       string
+	syslogAddr    string
+	registries    []string
+	ruleFiles     []string
+	policyFiles   []string
+	plugins       []string
+	fuzzyMatch    bool
+	configFile    string
+	excludeImages []string
+	historyFile   string
+	historyLimit  int
+	incremental   string
+
+	inspectFile   string
+	composeFile   string
+	criEndpoint   string
+	podmanSocket  string
+	dockerHost    string
+	tlsVerify     bool
+	certPath      string
+	manifests     []string
+	engineVersion string
+	serverVersion string
+	usernsRemap   bool
+	layerLimit    string
+	debugCommands []string
+
+	listen string
+	token  string
 )
 
 func Execute() error {
@@ -40,29 +95,50 @@ func Execute() error {
 
 	// Upgrade vulnerability database
 	dataupgradeCmd := &cobra.Command{
-		Use:   "update",
-		Short: "Update vulnerability database",
-		Args:  NoArgs,
+		Use: "update",
+		Short: `Update vulnerability database
+
+Examples:
+  # fetch the vulnerability data from NVD
+  $ vesta update
+
+  # replace the database with a prebuilt snapshot, the checksum is fetched from <url>.sha256
+  $ vesta update --snapshot https://example.com/vesta.db
+
+  # verify the snapshot by the given checksum
+  $ vesta update --snapshot https://example.com/vesta.db --checksum <sha256>
+`,
+		Args: NoArgs,
 		Run: func(cmd *cobra.Command, args []string) {
 			ctx := config.Ctx
 			ctx = context.WithValue(ctx, "reset", updateall)
+			ctx = context.WithValue(ctx, "snapshot", snapshotURL)
+			ctx = context.WithValue(ctx, "checksum", snapshotChecksum)
 
-			err := vulnlib.Fetch(ctx)
+			err := vulnlib.Update(ctx)
 			if err != nil {
 				log.Printf("Updating vulnerability database failed, error: %v", err)
+				os.Exit(1)
 			}
 
 			log.Printf(config.Green("Updating vulnerability database success"))
+			vulnlib.LogFreshness()
 		},
 	}
 
 	dataupgradeCmd.Flags().BoolVarP(&updateall, "all", "a", false, "Reset the database")
+	dataupgradeCmd.Flags().StringVar(&snapshotURL, "snapshot", "", "URL of the prebuilt database snapshot")
+	dataupgradeCmd.Flags().StringVar(&snapshotChecksum, "checksum", "", "sha256 of the snapshot, fetched from <snapshot>.sha256 if empty")
 
 	rootCmd.AddCommand(dataupgradeCmd)
 	rootCmd.AddCommand(versionCmd)
 
 	analyze()
 	scan()
+	sbom()
+	serve()
+	history()
+	baseline()
 
 	return rootCmd.Execute()
 }
@@ -0,0 +1,48 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/kvesta/vesta/config"
+	"github.com/spf13/cobra"
+)
+
+// applyScanFile set the flags of command by the config file,
+// the flags given in the command line take precedence over the file
+func applyScanFile(cmd *cobra.Command) {
+	if configFile == "" {
+		return
+	}
+
+	sf, err := config.LoadScanFile(configFile)
+	if err != nil {
+		config.ErrorLog.Printf("failed to load the config file, error: %v", err)
+		os.Exit(1)
+	}
+
+	flags := sf.Flags()
+
+	names := []string{}
+	for name := range flags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		flag := cmd.Flags().Lookup(name)
+
+		// Options of the other commands are shared in the file
+		if flag == nil || flag.Changed {
+			continue
+		}
+
+		err = cmd.Flags().Set(name, flags[name])
+		if err != nil {
+			config.ErrorLog.Printf("failed to load the config file, error: %v",
+				fmt.Errorf("%s: field %s: %v", configFile, name, err))
+			os.Exit(1)
+		}
+	}
+}
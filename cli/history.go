@@ -0,0 +1,35 @@
+package cli
+
+import (
+	"log"
+
+	"github.com/kvesta/vesta/internal/report"
+	"github.com/spf13/cobra"
+)
+
+func history() {
+	historyCmd := &cobra.Command{
+		Use: "history",
+		Short: `List the past scans saved by the option --history
+
+Examples:
+  # list the latest 20 scans
+  $ vesta history
+
+  # list the scans of a specific history file
+  $ vesta history --file scans.db -n 50
+`,
+		Args: NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			err := report.ResolveHistory(historyFile, historyLimit)
+			if err != nil {
+				log.Printf("failed to list the history, error: %v", err)
+			}
+		},
+	}
+
+	historyCmd.Flags().StringVarP(&historyFile, "file", "f", "default", "SQLite file of the history, ~/.vesta/history.db by default")
+	historyCmd.Flags().IntVarP(&historyLimit, "number", "n", 20, "number of the latest scans to list")
+
+	rootCmd.AddCommand(historyCmd)
+}
@@ -0,0 +1,93 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/kvesta/vesta/config"
+	"github.com/kvesta/vesta/internal"
+	"github.com/kvesta/vesta/pkg/inspector"
+	"github.com/kvesta/vesta/pkg/packages"
+	"github.com/spf13/cobra"
+)
+
+func sbom() {
+	sbomCmd := &cobra.Command{
+		Use: "sbom [OPTIONS]",
+		Short: `Software bill of materials of image or container
+
+Examples:
+  # Save the packages of a container image as CycloneDX
+  $ vesta sbom image nginx:latest -o nginx.cdx.json
+
+  # Save the packages of an image from a tar archive to the standard output
+  $ vesta sbom image -f python.tar
+
+  # Save the packages as SPDX for the compliance tooling
+  $ vesta sbom image nginx:latest --format spdx -o nginx.spdx.json
+
+  # Save the packages of a running container
+  $ vesta sbom container nginx1 -o nginx1.cdx.json
+`}
+
+	newSbomCmd := func(tarType string) *cobra.Command {
+		cmd := &cobra.Command{
+			Use:   tarType,
+			Short: fmt.Sprintf("input from %s", tarType),
+			Run: func(cmd *cobra.Command, args []string) {
+				if len(args) < 1 && tarFile == "" {
+					fmt.Println("Require at least 1 argument.")
+					os.Exit(1)
+				}
+
+				if _, err := inspector.NewSBOM(sbomFormat, &packages.Packages{}, ""); err != nil {
+					log.Printf("%v", err)
+					os.Exit(1)
+				}
+
+				ctx := config.Ctx
+				ctx = context.WithValue(ctx, "tarType", tarType)
+				ctx = context.WithValue(ctx, "output", outfile)
+				ctx = context.WithValue(ctx, "format", sbomFormat)
+
+				target := tarFile
+				var tarIO []io.ReadCloser
+
+				if tarFile == "" {
+					target = args[0]
+
+					var err error
+					tarIO, err = inspector.GetTarFromID(ctx, args[0])
+					if err != nil {
+						os.Exit(1)
+					}
+
+					if len(tarIO) < 1 {
+						log.Printf("Can not get tarfile. "+
+							"Make sure that you have the right %s ID "+
+							"or use -f to get from tar file", tarType)
+						os.Exit(1)
+					}
+				}
+
+				internal.DoSBOM(ctx, target, tarFile, tarIO)
+			},
+		}
+
+		cmd.Flags().StringVarP(&tarFile, "file", "f", "", "path of tar file")
+		cmd.Flags().StringVarP(&outfile, "output", "o", "", "output file location, the standard output if no file is given")
+		cmd.Flags().StringVar(&sbomFormat, "format", inspector.SBOMFormats[0],
+			fmt.Sprintf("format of the SBOM: %s", strings.Join(inspector.SBOMFormats, ", ")))
+
+		return cmd
+	}
+
+	sbomCmd.AddCommand(newSbomCmd("image"))
+	sbomCmd.AddCommand(newSbomCmd("container"))
+
+	rootCmd.AddCommand(sbomCmd)
+}
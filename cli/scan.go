@@ -30,6 +30,15 @@ Examples:
 
   # Scan a exported container from a tar archive
   $ vesta scan container -f nginx.tar
+
+  # Scan a running container of containerd or CRI-O, by ID or <namespace>_<pod>_<container>
+  $ sudo vesta scan container --cri default_web_nginx
+
+  # Scan a running container of the runtime of the CRI socket
+  $ sudo vesta scan container --cri=unix:///var/run/crio/crio.sock default_web_nginx
+
+  # Exit with 1 if any critical vulnerability is found
+  $ vesta scan image nginx:latest --exit-code 1 --severity-threshold critical
 `}
 
 	imageCheck := &cobra.Command{
@@ -63,15 +72,19 @@ Examples:
 				log.Printf("Can not get tarfile. " +
 					"Make sure that you have the right image ID " +
 					"or use -f to get from tar file")
-				return
+				os.Exit(1)
 			}
-			internal.DoScan(ctx, tarFile, tarIO)
+			ctx, g := withGate(ctx)
+			err := internal.DoScan(ctx, tarFile, tarIO)
+			exitAnalysis(err, g, nil)
 		},
 	}
 
 	containerCheck := &cobra.Command{
 		Use:   "container",
 		Short: "input from inspector",
+		// The socket of --cri is given after '=', the one after a space is taken as the container
+		Args: cobra.MaximumNArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
 
 			if len(args) < 1 {
@@ -82,6 +95,7 @@ Examples:
 			ctx := config.Ctx
 			ctx = context.WithValue(ctx, "tarType", "container")
 			ctx = context.WithValue(ctx, "output", outfile)
+			ctx = context.WithValue(ctx, "cri", criEndpoint)
 			ctx = context.WithValue(ctx, "skip", skipUpdate)
 
 			var tarIO []io.ReadCloser
@@ -100,20 +114,28 @@ Examples:
 				log.Printf("Can not get tarfile. " +
 					"Make sure that you have the right container ID" +
 					"or use -f to get from tar file")
-				return
+				os.Exit(1)
 			}
 
-			internal.DoScan(ctx, tarFile, tarIO)
+			ctx, g := withGate(ctx)
+			err := internal.DoScan(ctx, tarFile, tarIO)
+			exitAnalysis(err, g, nil)
 		},
 	}
 
 	imageCheck.Flags().StringVarP(&tarFile, "file", "f", "", "path of tar file")
-	imageCheck.Flags().StringVarP(&outfile, "output", "o", "output", "output file location")
+	imageCheck.Flags().StringVarP(&outfile, "output", "o", "output", "output file location, the .html file is saved as an HTML report")
 	imageCheck.Flags().BoolVar(&skipUpdate, "skip", false, "skip the updating")
+	imageCheck.Flags().IntVar(&exitCode, "exit-code", 0, "exit code when any vulnerability is at or above the severity threshold, 0 to disable")
+	imageCheck.Flags().StringVar(&threshold, "severity-threshold", "warning", "severity of the vulnerabilities failing the scan with the exit code")
 
 	containerCheck.Flags().StringVarP(&tarFile, "file", "f", "", "path of tar file")
-	containerCheck.Flags().StringVarP(&outfile, "output", "o", "output", "output file location")
+	containerCheck.Flags().StringVarP(&outfile, "output", "o", "output", "output file location, the .html file is saved as an HTML report")
 	containerCheck.Flags().BoolVar(&skipUpdate, "skip", false, "skip the updating")
+	containerCheck.Flags().StringVar(&criEndpoint, "cri", "", "CRI socket given by --cri=<socket> to scan the container of containerd or CRI-O, detected if no socket is given")
+	containerCheck.Flags().Lookup("cri").NoOptDefVal = "default"
+	containerCheck.Flags().IntVar(&exitCode, "exit-code", 0, "exit code when any vulnerability is at or above the severity threshold, 0 to disable")
+	containerCheck.Flags().StringVar(&threshold, "severity-threshold", "warning", "severity of the vulnerabilities failing the scan with the exit code")
 
 	scanCmd.AddCommand(imageCheck)
 	scanCmd.AddCommand(containerCheck)
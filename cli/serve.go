@@ -0,0 +1,70 @@
+package cli
+
+import (
+	"context"
+	"os"
+
+	"github.com/kvesta/vesta/config"
+	"github.com/kvesta/vesta/internal"
+	"github.com/spf13/cobra"
+)
+
+func serve() {
+	serveCmd := &cobra.Command{
+		Use: "serve",
+		Short: `Serve the Kubernetes analyze on demand by HTTP
+
+Endpoints:
+  POST /scan     analyze Kubernetes and return the result in JSON, requires the bearer token
+  GET  /healthz  health check
+
+Examples:
+  # serve in a pod by using service account token
+  $ vesta serve --inside --token <token>
+
+  # analyze all the namespace on demand
+  $ curl -X POST -H "Authorization: Bearer <token>" http://localhost:8080/scan?ns=all
+`,
+		Args: NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if token == "" {
+				token = os.Getenv("VESTA_TOKEN")
+			}
+
+			applyScanFile(cmd)
+
+			ctx := config.Ctx
+			ctx = context.WithValue(ctx, "listen", listen)
+			ctx = context.WithValue(ctx, "token", token)
+			ctx = context.WithValue(ctx, "nameSpace", nameSpace)
+			ctx = context.WithValue(ctx, "kubeconfig", kubeconfig)
+			ctx = context.WithValue(ctx, "kubeContext", kubeContext)
+			ctx = context.WithValue(ctx, "inside", inside)
+			ctx = context.WithValue(ctx, "kinds", kinds)
+			ctx = context.WithValue(ctx, "blocklist", blocklist)
+			ctx = context.WithValue(ctx, "disable", disableChecks)
+			ctx = context.WithValue(ctx, "scoreWeights", scoreWeights)
+			ctx = context.WithValue(ctx, "severity", severities)
+			ctx = context.WithValue(ctx, "fuzzyMatch", fuzzyMatch)
+
+			internal.DoServe(ctx)
+		},
+	}
+
+	serveCmd.Flags().StringVar(&listen, "listen", ":8080", "address to listen on")
+	serveCmd.Flags().StringVar(&token, "token", "", "bearer token of the scan endpoint, VESTA_TOKEN is used if empty")
+	serveCmd.Flags().StringVarP(&nameSpace, "ns", "n", "standard", "specific namespace")
+	serveCmd.Flags().StringVar(&kubeconfig, "kubeconfig", "default", "specific configure file")
+	serveCmd.Flags().StringVar(&kubeContext, "context", "", "specific context in the configure file")
+	serveCmd.Flags().BoolVar(&inside, "inside", false, "running analyze in a pod by using service account token")
+	serveCmd.Flags().StringSliceVar(&kinds, "kinds", []string{},
+		"kinds of workload to analyze: pod, daemonset, job, cronjob, rolebinding, configmap, secret, service, pdb")
+	serveCmd.Flags().StringVar(&blocklist, "blocklist", "", "file or URL of the known-malicious images")
+	serveCmd.Flags().StringSliceVar(&disableChecks, "disable", []string{}, "names of the checks to disable")
+	serveCmd.Flags().StringToIntVar(&scoreWeights, "weights", map[string]int{}, "weights of severity for the compliance score")
+	serveCmd.Flags().StringToStringVar(&severities, "severity", map[string]string{}, "remap the severity of the findings by the name of check")
+	serveCmd.Flags().BoolVar(&fuzzyMatch, "fuzzy-match", false, "match the aliases of product names in the vulnerability database")
+	serveCmd.Flags().StringVarP(&configFile, "config", "c", "", "YAML file of the options, the flags given take precedence")
+
+	rootCmd.AddCommand(serveCmd)
+}
@@ -1,15 +1,15 @@
 package main
 
 import (
-	"log"
 	"os"
 
 	"github.com/kvesta/vesta/cli"
+	"github.com/kvesta/vesta/config"
 )
 
 func main() {
 	if err := cli.Execute(); err != nil {
-		log.Printf("%v", err)
+		config.ErrorLog.Printf("%v", err)
 		os.Exit(1)
 	}
 }
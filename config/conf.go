@@ -2,10 +2,15 @@ package config
 
 import (
 	"context"
+	"log"
+	"os"
 
 	"github.com/fatih/color"
 )
 
+// Version of vesta, it is reported in the structured outputs
+const Version = "v1.0.6"
+
 var (
 	Yellow = color.New(color.FgYellow).SprintFunc()
 	Red    = color.New(color.FgRed).SprintFunc()
@@ -14,6 +19,9 @@ var (
 
 	Ctx = context.Background()
 
+	// ErrorLog logs the failures, it is kept on stderr when the other logs are discarded in quiet mode
+	ErrorLog = log.New(os.Stderr, "", log.LstdFlags)
+
 	SeverityMap = map[string]int{
 		"critical": 5,
 		"high":     4,
@@ -21,4 +29,16 @@ var (
 		"low":      2,
 		"warning":  1,
 	}
+
+	// OutputFormats are the formats of the output file
+	OutputFormats = []string{"json", "csv", "sarif", "html", "junit"}
+
+	// ScoreWeights are the default penalties of each finding by severity
+	ScoreWeights = map[string]int{
+		"critical": 10,
+		"high":     5,
+		"medium":   2,
+		"low":      1,
+		"warning":  0,
+	}
 )
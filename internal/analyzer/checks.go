@@ -0,0 +1,605 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/kvesta/vesta/config"
+	_image "github.com/kvesta/vesta/pkg/inspector"
+	"github.com/kvesta/vesta/pkg/osrelease"
+	"github.com/kvesta/vesta/pkg/vulnlib"
+)
+
+// dockerContextCheck checks the environment of docker,
+// findings are reported under the name of target
+type dockerContextCheck struct {
+	name   string
+	target string
+
+	// host checks inspect the machine running the daemon, which is the one running vesta
+	// or the remote host of daemon, they are skipped in the offline analysis
+	host bool
+
+	// image checks analyze the content of images,
+	// the images excluded by the option `excludeImages` are skipped
+	image bool
+
+	fn func(s *Scanner, cli vulnlib.Client, images []*_image.ImageInfo) (bool, []*threat)
+}
+
+// dockerCheck checks the configuration of a container
+type dockerCheck struct {
+	name string
+	fn   func(s *Scanner, config *types.ContainerJSON) (bool, []*threat)
+
+	// custom check registered by RegisterCheck, fn is not used
+	custom Check
+}
+
+// clusterCheck checks the configuration of the whole cluster
+type clusterCheck struct {
+	name string
+	desc string
+
+	// early checks run before the namespace checks,
+	// results of RBAC are referenced by the pod checks
+	early bool
+
+	// applies reports whether the check is suitable for the cluster, nil means always,
+	// condition describes it for the explain mode
+	applies   func(ks *KScanner) bool
+	condition string
+
+	fn func(ks *KScanner, ctx context.Context) error
+}
+
+// namespaceCheck checks the configuration in a namespace
+type namespaceCheck struct {
+	name string
+	desc string
+
+	// kind of the resource checked, used for filtering the scan
+	kind string
+
+	// skipWhiteList skips the namespaces in the white list
+	skipWhiteList bool
+
+	fn func(ks *KScanner, ns string) error
+
+	// custom check registered by RegisterCheck, fn is not used
+	custom Check
+}
+
+var (
+	dockerContextChecks = []dockerContextCheck{
+		{name: "checkKernelVersion", target: "Kernel", host: true,
+			fn: func(s *Scanner, cli vulnlib.Client, images []*_image.ImageInfo) (bool, []*threat) {
+				kernelVersion := s.KernelVersion
+				if kernelVersion == "" {
+					var err error
+					kernelVersion, err = osrelease.GetKernelVersion(context.Background())
+					if err != nil {
+						config.ErrorLog.Printf("failed to get kernel version: %v", err)
+					}
+				}
+
+				s.netRawKernel = checkNetRawKernel(cli, kernelVersion)
+				s.cgroupKernel = checkCgroupKernel(cli, kernelVersion)
+
+				return checkKernelVersion(cli, kernelVersion)
+			}},
+		{name: "checkDockerVersion", target: "Server Version",
+			fn: func(s *Scanner, cli vulnlib.Client, images []*_image.ImageInfo) (bool, []*threat) {
+				if s.ServerVersion == "" {
+					return false, nil
+				}
+
+				return checkDockerVersion(cli, s.ServerVersion)
+			}},
+		{name: "checkRootless", target: "Rootless",
+			fn: func(s *Scanner, cli vulnlib.Client, images []*_image.ImageInfo) (bool, []*threat) {
+				// Rootless mode is checked for the containers of Podman only
+				if s.Daemon != "podman" {
+					return false, nil
+				}
+
+				return checkRootless(s.Rootless)
+			}},
+		{name: "checkDockerUnauthorized", target: "Docker 2375 port", host: true,
+			fn: func(s *Scanner, cli vulnlib.Client, images []*_image.ImageInfo) (bool, []*threat) {
+				address := "0.0.0.0:2375"
+				if s.Remote != "" {
+					address = net.JoinHostPort(s.Remote, "2375")
+				}
+
+				return checkDockerUnauthorized(address)
+			}},
+		{name: "checkImages", target: "Image Tag", image: true,
+			fn: func(s *Scanner, cli vulnlib.Client, images []*_image.ImageInfo) (bool, []*threat) {
+				return checkImages(images, s.Concurrency)
+			}},
+		{name: "checkImageBlocklist", target: "Image Blocklist",
+			fn: func(s *Scanner, cli vulnlib.Client, images []*_image.ImageInfo) (bool, []*threat) {
+				return checkImageBlocklist(images, s.blocklist)
+			}},
+		{name: "checkHistories", target: "Image Configuration", image: true,
+			fn: func(s *Scanner, cli vulnlib.Client, images []*_image.ImageInfo) (bool, []*threat) {
+				return checkHistories(images, s.Concurrency)
+			}},
+		{name: "checkImageLayers", target: "Image Layer", image: true,
+			fn: func(s *Scanner, cli vulnlib.Client, images []*_image.ImageInfo) (bool, []*threat) {
+				return checkImageLayers(images, s.Concurrency)
+			}},
+		{name: "checkImageUsers", target: "Image User", image: true,
+			fn: func(s *Scanner, cli vulnlib.Client, images []*_image.ImageInfo) (bool, []*threat) {
+				return checkImageUsers(images, s.Concurrency)
+			}},
+	}
+
+	dockerChecks = []dockerCheck{
+		{name: "checkPrivileged",
+			fn: func(s *Scanner, config *types.ContainerJSON) (bool, []*threat) {
+				return checkPrivileged(config)
+			}},
+		{name: "checkMount",
+			fn: func(s *Scanner, config *types.ContainerJSON) (bool, []*threat) {
+				return checkMount(config)
+			}},
+		{name: "checkMissingMounts",
+			fn: func(s *Scanner, config *types.ContainerJSON) (bool, []*threat) {
+				// Paths of host are not accessible in the offline and remote analysis
+				if s.Offline || s.Remote != "" {
+					return false, nil
+				}
+
+				return checkMissingMounts(config)
+			}},
+		{name: "checkHostTakeover",
+			fn: func(s *Scanner, config *types.ContainerJSON) (bool, []*threat) {
+				return checkHostTakeover(config)
+			}},
+		{name: "checkEnvPassword",
+			fn: func(s *Scanner, config *types.ContainerJSON) (bool, []*threat) {
+				return checkEnvPassword(config)
+			}},
+		{name: "checkLoaderEnv",
+			fn: func(s *Scanner, config *types.ContainerJSON) (bool, []*threat) {
+				return checkLoaderEnv(config)
+			}},
+		{name: "checkNetworkModel",
+			fn: func(s *Scanner, config *types.ContainerJSON) (bool, []*threat) {
+				return checkNetworkModel(config, s.EngineVersion)
+			}},
+		{name: "checkPid",
+			fn: func(s *Scanner, config *types.ContainerJSON) (bool, []*threat) {
+				return checkPid(config)
+			}},
+		{name: "checkIPC",
+			fn: func(s *Scanner, config *types.ContainerJSON) (bool, []*threat) {
+				return checkIPC(config)
+			}},
+		{name: "checkShm",
+			fn: func(s *Scanner, config *types.ContainerJSON) (bool, []*threat) {
+				return checkShm(config)
+			}},
+		{name: "checkRootUser",
+			fn: func(s *Scanner, config *types.ContainerJSON) (bool, []*threat) {
+				return checkRootUser(config)
+			}},
+		{name: "checkRootGroup",
+			fn: func(s *Scanner, config *types.ContainerJSON) (bool, []*threat) {
+				return checkRootGroup(config)
+			}},
+		{name: "checkNoNewPrivileges",
+			fn: func(s *Scanner, config *types.ContainerJSON) (bool, []*threat) {
+				return checkNoNewPrivileges(config)
+			}},
+		{name: "checkUsernsMode",
+			fn: func(s *Scanner, config *types.ContainerJSON) (bool, []*threat) {
+				return checkUsernsMode(config, s.UsernsRemap)
+			}},
+		{name: "checkPtrace",
+			fn: func(s *Scanner, config *types.ContainerJSON) (bool, []*threat) {
+				return checkPtrace(config)
+			}},
+		{name: "checkDACCapabilities",
+			fn: func(s *Scanner, config *types.ContainerJSON) (bool, []*threat) {
+				return checkDACCapabilities(config)
+			}},
+		{name: "checkPortBindings",
+			fn: func(s *Scanner, config *types.ContainerJSON) (bool, []*threat) {
+				return checkPortBindings(config)
+			}},
+		{name: "checkNetRaw",
+			fn: func(s *Scanner, config *types.ContainerJSON) (bool, []*threat) {
+				return checkNetRaw(config, s.netRawKernel)
+			}},
+		{name: "checkCgroupEscape",
+			fn: func(s *Scanner, config *types.ContainerJSON) (bool, []*threat) {
+				return checkCgroupEscape(config, s.cgroupKernel)
+			}},
+		{name: "checkDevices",
+			fn: func(s *Scanner, config *types.ContainerJSON) (bool, []*threat) {
+				return checkDevices(config)
+			}},
+		{name: "checkRuntimeFeatures",
+			fn: func(s *Scanner, config *types.ContainerJSON) (bool, []*threat) {
+				return checkRuntimeFeatures(config, s.EngineVersion, s.ServerVersion)
+			}},
+		{name: "checkRestarts",
+			fn: func(s *Scanner, config *types.ContainerJSON) (bool, []*threat) {
+				return checkRestarts(config)
+			}},
+		{name: "checkWritableLayer",
+			fn: func(s *Scanner, config *types.ContainerJSON) (bool, []*threat) {
+				return checkWritableLayer(config, s.writableLayerLimit)
+			}},
+		{name: "checkMainProcess",
+			fn: func(s *Scanner, config *types.ContainerJSON) (bool, []*threat) {
+				return checkMainProcess(config, s.debugCommands)
+			}},
+	}
+
+	clusterChecks = []clusterCheck{
+		// If k8s version less than v1.24, using the docker checking
+		{name: "dockershimCheck", desc: "use docker to check", early: true,
+			applies: func(ks *KScanner) bool {
+				return compareVersion(ks.Version, "1.24", "0.0")
+			},
+			condition: "Kubernetes version is less than v1.24",
+			fn:        (*KScanner).dockershimCheck},
+		{name: "kernelCheck", desc: "check kernel version", early: true,
+			applies: func(ks *KScanner) bool {
+				return !compareVersion(ks.Version, "1.24", "0.0")
+			},
+			condition: "Kubernetes version is v1.24 or later",
+			fn:        (*KScanner).kernelCheck},
+		{name: "checkClusterBinding", desc: "check RBAC", early: true,
+			fn: func(ks *KScanner, ctx context.Context) error {
+				return ks.checkClusterBinding()
+			}},
+		{name: "checkAggregatedRoles", desc: "check aggregated clusterrole", early: true,
+			fn: func(ks *KScanner, ctx context.Context) error {
+				return ks.checkAggregatedRoles()
+			}},
+		{name: "checkSecretAccess", desc: "check RBAC reading secrets", early: true,
+			fn: func(ks *KScanner, ctx context.Context) error {
+				return ks.checkSecretAccess()
+			}},
+		{name: "checkNodePosture", desc: "check node swap and kernel settings",
+			fn: (*KScanner).checkNodePosture},
+		{name: "checkWebhooks", desc: "check admission webhooks",
+			fn: func(ks *KScanner, ctx context.Context) error {
+				return ks.checkWebhooks()
+			}},
+		{name: "checkSecretReuse", desc: "check secret reuse",
+			fn: func(ks *KScanner, ctx context.Context) error {
+				return ks.checkSecretReuse()
+			}},
+		{name: "checkPullSecrets", desc: "check registry credentials of pull secrets",
+			fn: func(ks *KScanner, ctx context.Context) error {
+				return ks.checkPullSecrets()
+			}},
+		{name: "checkPersistentVolume", desc: "check pv and pvc",
+			fn: func(ks *KScanner, ctx context.Context) error {
+				return ks.checkPersistentVolume()
+			}},
+		{name: "checkCerts", desc: "check certification expiration",
+			fn: func(ks *KScanner, ctx context.Context) error {
+				return ks.checkCerts()
+			}},
+		{name: "checkTLS", desc: "check TLS configuration",
+			fn: func(ks *KScanner, ctx context.Context) error {
+				return ks.checkTLS()
+			}},
+		{name: "checkCNI", desc: "check CNI",
+			fn: func(ks *KScanner, ctx context.Context) error {
+				return ks.checkCNI()
+			}},
+		{name: "checkKubeconfigTLS", desc: "check kubeconfig TLS verification",
+			fn: func(ks *KScanner, ctx context.Context) error {
+				return ks.checkKubeconfigTLS()
+			}},
+	}
+
+	namespaceChecks = []namespaceCheck{
+		{name: "checkRoleBinding", desc: "check role binding", kind: "rolebinding", skipWhiteList: true,
+			fn: (*KScanner).checkRoleBinding},
+		// TODO: remove from the white list, add kube-system namespace checking
+		{name: "checkConfigMap", desc: "check config map", kind: "configmap", skipWhiteList: true,
+			fn: (*KScanner).checkConfigMap},
+		// TODO: remove from the white list, add kube-system namespace checking
+		{name: "checkSecret", desc: "check secret", kind: "secret", skipWhiteList: true,
+			fn: (*KScanner).checkSecret},
+		{name: "checkPod", desc: "check pod", kind: "pod", skipWhiteList: true,
+			fn: (*KScanner).checkPod},
+		{name: "checkSharedVolumes", desc: "check shared volume", kind: "pod", skipWhiteList: true,
+			fn: (*KScanner).checkSharedVolumes},
+		{name: "checkJobs", desc: "check job", kind: "job", skipWhiteList: true,
+			fn: (*KScanner).checkJobs},
+		{name: "checkCronJobs", desc: "check cronjob", kind: "cronjob", skipWhiteList: true,
+			fn: (*KScanner).checkCronJobs},
+		{name: "checkService", desc: "check service", kind: "service", skipWhiteList: true,
+			fn: (*KScanner).checkService},
+		{name: "checkDisruptionBudgets", desc: "check pod disruption budget", kind: "pdb", skipWhiteList: true,
+			fn: (*KScanner).checkDisruptionBudgets},
+		{name: "checkDaemonSet", desc: "check daemonset", kind: "daemonset",
+			fn: (*KScanner).checkDaemonSet},
+	}
+)
+
+// checkNames list the names of all the registered checks
+func checkNames() []string {
+	names := []string{}
+
+	for _, ch := range dockerContextChecks {
+		names = append(names, ch.name)
+	}
+
+	for _, ch := range dockerChecks {
+		names = append(names, ch.name)
+	}
+
+	for _, ch := range clusterChecks {
+		names = append(names, ch.name)
+	}
+
+	for _, ch := range namespaceChecks {
+		names = append(names, ch.name)
+	}
+
+	return names
+}
+
+// isCheckEnabled check whether the check is disabled by the option `disable`
+func isCheckEnabled(ctx context.Context, name string) bool {
+	disabled, ok := ctx.Value("disable").([]string)
+	if !ok {
+		return true
+	}
+
+	for _, d := range disabled {
+		if d == name {
+			return false
+		}
+	}
+
+	return true
+}
+
+// CheckEnabled check whether the check is not disabled by the option `disable`
+func CheckEnabled(ctx context.Context, name string) bool {
+	return isCheckEnabled(ctx, name)
+}
+
+// validateChecks warns the unknown names of check in the option `disable`
+func validateChecks(ctx context.Context) {
+	disabled, ok := ctx.Value("disable").([]string)
+	if !ok {
+		return
+	}
+
+	names := checkNames()
+
+	for _, d := range disabled {
+		isKnown := false
+		for _, n := range names {
+			if d == n {
+				isKnown = true
+				break
+			}
+		}
+
+		if !isKnown {
+			config.ErrorLog.Printf("unknown check name: %s, ignored", d)
+		}
+	}
+}
+
+// validateSeverities warns the unknown names of check and severities in the option `severity`
+func validateSeverities(ctx context.Context) {
+	remap, ok := ctx.Value("severity").(map[string]string)
+	if !ok {
+		return
+	}
+
+	names := checkNames()
+
+	for name, severity := range remap {
+		isKnown := false
+		for _, n := range names {
+			if name == n {
+				isKnown = true
+				break
+			}
+		}
+
+		if !isKnown {
+			config.ErrorLog.Printf("unknown check name in severity remap: %s, ignored", name)
+		}
+
+		if _, ok := config.SeverityMap[strings.ToLower(severity)]; !ok {
+			config.ErrorLog.Printf("unknown severity of %s: %s, ignored", name, severity)
+		}
+	}
+}
+
+// remapSeverity set the severity of the findings of the check by the option `severity`
+func remapSeverity(ctx context.Context, name string, threats []*threat) {
+	remap, ok := ctx.Value("severity").(map[string]string)
+	if !ok {
+		return
+	}
+
+	severity, ok := remap[name]
+	if !ok {
+		return
+	}
+
+	severity = strings.ToLower(severity)
+	if _, ok := config.SeverityMap[severity]; !ok {
+		return
+	}
+
+	for _, th := range threats {
+		th.Severity = severity
+	}
+}
+
+// tagCheck set the name of the check on the findings, the ones tagged by a nested check are kept
+func tagCheck(name string, threats []*threat) {
+	for _, th := range threats {
+		if th.Check == "" {
+			th.Check = name
+		}
+	}
+}
+
+// isKindSelected check whether the kind is selected by the option `kinds`,
+// all the kinds are selected by default
+func isKindSelected(ctx context.Context, kind string) bool {
+	kinds, ok := ctx.Value("kinds").([]string)
+	if !ok || len(kinds) < 1 {
+		return true
+	}
+
+	for _, k := range kinds {
+		if strings.EqualFold(k, kind) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// validateKinds check the names of kind in the option `kinds`
+func validateKinds(ctx context.Context) error {
+	kinds, ok := ctx.Value("kinds").([]string)
+	if !ok {
+		return nil
+	}
+
+	for _, k := range kinds {
+		isKnown := false
+		for _, ch := range namespaceChecks {
+			if strings.EqualFold(k, ch.kind) {
+				isKnown = true
+				break
+			}
+		}
+
+		if !isKnown {
+			return fmt.Errorf("unknown kind: %s", k)
+		}
+	}
+
+	return nil
+}
+
+// CheckPlan is a check which would run in a scan, Reason notes why it is skipped
+type CheckPlan struct {
+	Name   string
+	Scope  string
+	Run    bool
+	Reason string
+}
+
+// ExplainDocker list the ordered checks of docker analysis without querying anything
+func ExplainDocker(ctx context.Context) []*CheckPlan {
+	plans := []*CheckPlan{}
+
+	offline, _ := ctx.Value("inspect").(string)
+	if compose, _ := ctx.Value("compose").(string); compose != "" {
+		offline = compose
+	}
+
+	for _, ch := range dockerContextChecks {
+		plan := explainCheck(ctx, ch.name, "docker")
+		if plan.Run && ch.host && offline != "" {
+			plan.Run = false
+			plan.Reason = "skipped in the offline analysis"
+		}
+
+		plans = append(plans, plan)
+	}
+
+	for _, ch := range dockerChecks {
+		plans = append(plans, explainCheck(ctx, ch.name, "container"))
+	}
+
+	return plans
+}
+
+// ExplainKubernetes list the ordered checks of kubernetes analysis without querying anything,
+// the version gating is resolved only if the version of cluster is given
+func ExplainKubernetes(ctx context.Context, version string) []*CheckPlan {
+	plans := []*CheckPlan{}
+	ks := &KScanner{Version: version}
+
+	explainCluster := func(early bool) {
+		for _, ch := range clusterChecks {
+			if ch.early != early {
+				continue
+			}
+
+			plan := explainCheck(ctx, ch.name, "cluster")
+			if plan.Run && ch.applies != nil {
+				switch {
+				case version == "":
+					plan.Reason = fmt.Sprintf("only if %s", ch.condition)
+				case !ch.applies(ks):
+					plan.Run = false
+					plan.Reason = fmt.Sprintf("skipped, requires %s", ch.condition)
+				}
+			}
+
+			plans = append(plans, plan)
+		}
+	}
+
+	explainCluster(true)
+
+	nameSpace, _ := ctx.Value("nameSpace").(string)
+	for _, ch := range namespaceChecks {
+		var scope string
+
+		switch {
+		case nameSpace != "standard" && nameSpace != "all":
+			scope = fmt.Sprintf("namespace: %s", nameSpace)
+		case nameSpace == "standard" && ch.skipWhiteList:
+			scope = fmt.Sprintf("all namespaces except: %s", strings.Join(namespaceWhileList, ", "))
+		default:
+			scope = "all namespaces"
+		}
+
+		plan := explainCheck(ctx, ch.name, scope)
+		if plan.Run && !isKindSelected(ctx, ch.kind) {
+			plan.Run = false
+			plan.Reason = fmt.Sprintf("skipped, kind %s is not selected", ch.kind)
+		}
+
+		plans = append(plans, plan)
+	}
+
+	explainCluster(false)
+
+	return plans
+}
+
+func explainCheck(ctx context.Context, name, scope string) *CheckPlan {
+	plan := &CheckPlan{
+		Name:  name,
+		Scope: scope,
+		Run:   true,
+	}
+
+	if !isCheckEnabled(ctx, name) {
+		plan.Run = false
+		plan.Reason = "skipped, disabled by config"
+	}
+
+	return plan
+}
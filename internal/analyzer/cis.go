@@ -0,0 +1,74 @@
+package analyzer
+
+import (
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// cisControls maps the type of threat to the control IDs of CIS Kubernetes Benchmark v1.8.0,
+// types without an obvious control are left empty
+var cisControls = map[string][]string{
+	"Sidecar Privileged": {"5.2.2"},
+	"capabilities.add":   {"5.2.9"},
+	"capabilities.drop":  {"5.2.10"},
+	"PersistentVolume":   {"5.2.12"},
+
+	// CAP_NET_RAW granted by the container runtime by default
+	"capabilities.default": {"5.2.8"},
+
+	"Sidecar Confinement": {"5.7.2"},
+	"Sidecar Token":       {"5.1.6"},
+
+	"RoleBinding":        {"5.1.1", "5.1.3"},
+	"ClusterRoleBinding": {"5.1.1", "5.1.3"},
+
+	"Secret":      {"5.4.1"},
+	"ConfigMap":   {"5.4.1"},
+	"Sidecar Env": {"5.4.1"},
+
+	"Job":     {"5.7.2", "5.7.3"},
+	"CronJob": {"5.7.2", "5.7.3"},
+
+	"Kubelet": {"4.2.4"},
+	"Etcd":    {"2.2", "2.5"},
+
+	// The type of hostPath volume is used as the type of threat
+	string(v1.HostPathDirectoryOrCreate): {"5.2.12"},
+	string(v1.HostPathDirectory):         {"5.2.12"},
+	string(v1.HostPathFileOrCreate):      {"5.2.12"},
+	string(v1.HostPathFile):              {"5.2.12"},
+	string(v1.HostPathSocket):            {"5.2.12"},
+	string(v1.HostPathCharDev):           {"5.2.12"},
+	string(v1.HostPathBlockDev):          {"5.2.12"},
+}
+
+// cisControlPrefixes maps the types which are generated by names,
+// e.g. the volume name of service account token
+var cisControlPrefixes = map[string][]string{
+	"Sidecar Env ":     {"5.4.1"},
+	"kube-api-access-": {"5.1.6"},
+	"default-token-":   {"5.1.5", "5.1.6"},
+}
+
+// getCISControls get the CIS control IDs of the type of threat
+func getCISControls(tp string) []string {
+	if controls, ok := cisControls[tp]; ok {
+		return controls
+	}
+
+	for prefix, controls := range cisControlPrefixes {
+		if strings.HasPrefix(tp, prefix) {
+			return controls
+		}
+	}
+
+	return []string{}
+}
+
+// tagCISControls tags the threats with CIS control IDs
+func tagCISControls(threats []*threat) {
+	for _, th := range threats {
+		th.CISControls = getCISControls(th.Type)
+	}
+}
@@ -0,0 +1,33 @@
+package analyzer
+
+import "sync"
+
+// threatCollector collects the threats added by the concurrent checks,
+// the threats are drained in the order of adding
+type threatCollector struct {
+	mu      sync.Mutex
+	threats []*threat
+}
+
+// Add append the threats, it is safe to be called concurrently
+func (tc *threatCollector) Add(threats ...*threat) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+
+	tc.threats = append(tc.threats, threats...)
+}
+
+// Drain return the collected threats and empty the collector
+func (tc *threatCollector) Drain() []*threat {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+
+	threats := tc.threats
+	tc.threats = nil
+
+	if threats == nil {
+		return []*threat{}
+	}
+
+	return threats
+}
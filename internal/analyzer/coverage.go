@@ -0,0 +1,131 @@
+package analyzer
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// CoverageGap is a check which could not look at the resources
+// for the insufficient permission of the scanner
+type CoverageGap struct {
+	Check      string
+	Namespaces []string
+	Reason     string
+
+	// RBAC permission needed by the check, in the form of `kubectl auth can-i`
+	Permission string
+}
+
+// The forbidden message of API server, e.g.
+// `pods is forbidden: User "x" cannot list resource "pods" in API group "" in the namespace "default"`
+var forbiddenReg = regexp.MustCompile(`cannot (\w+) resource "([^"]*)" in API group "([^"]*)"`)
+
+// getNeededPermission get the RBAC permission from the forbidden error
+func getNeededPermission(err error) string {
+	m := forbiddenReg.FindStringSubmatch(err.Error())
+	if m == nil {
+		return "unknown"
+	}
+
+	verb, resource, group := m[1], m[2], m[3]
+	if group != "" {
+		resource = fmt.Sprintf("%s.%s", resource, group)
+	}
+
+	return fmt.Sprintf("%s %s", verb, resource)
+}
+
+// recordGap record the check failed by the insufficient permission,
+// it returns false if the error is not a forbidden one
+func (ks *KScanner) recordGap(check, ns string, err error) bool {
+	if err == nil || !apierrors.IsForbidden(err) {
+		return false
+	}
+
+	permission := getNeededPermission(err)
+
+	for _, gap := range ks.Coverage {
+		if gap.Check != check || gap.Permission != permission {
+			continue
+		}
+
+		// The check may swallow and return the same error in a namespace
+		for _, n := range gap.Namespaces {
+			if n == ns {
+				return true
+			}
+		}
+
+		if ns != "" {
+			gap.Namespaces = append(gap.Namespaces, ns)
+		}
+		return true
+	}
+
+	gap := &CoverageGap{
+		Check:      check,
+		Reason:     "forbidden",
+		Permission: permission,
+	}
+	if ns != "" {
+		gap.Namespaces = []string{ns}
+	}
+
+	ks.Coverage = append(ks.Coverage, gap)
+
+	return true
+}
+
+// checkRun keeps the forbidden errors swallowed by the running check,
+// the pointer is shared by the copies of the scanner in the checks of value receiver
+type checkRun struct {
+	forbidden []error
+}
+
+// swallow keep the error if it is a forbidden one, the check goes on without the resources
+func (r *checkRun) swallow(err error) {
+	if r == nil || err == nil || !apierrors.IsForbidden(err) {
+		return
+	}
+
+	r.forbidden = append(r.forbidden, err)
+}
+
+// startCheck reset the forbidden errors kept for the check going to run
+func (ks *KScanner) startCheck() {
+	ks.run = &checkRun{}
+}
+
+// recordSwallowed record the forbidden errors swallowed by the check which has run,
+// it returns false if the check looked at all the resources
+func (ks *KScanner) recordSwallowed(check, ns string) bool {
+	if ks.run == nil {
+		return false
+	}
+
+	recorded := false
+	for _, err := range ks.run.forbidden {
+		if ks.recordGap(check, ns, err) {
+			recorded = true
+		}
+	}
+	ks.run = nil
+
+	return recorded
+}
+
+// Scope describe where the check could not look
+func (gap *CoverageGap) Scope() string {
+	if len(gap.Namespaces) < 1 {
+		return "cluster"
+	}
+
+	namespaces := append([]string{}, gap.Namespaces...)
+	sort.Strings(namespaces)
+
+	return fmt.Sprintf("namespaces: %s", strings.Join(namespaces, ", "))
+}
@@ -7,88 +7,75 @@ import (
 	"io/ioutil"
 	"log"
 	"net/http"
+	"os"
+	"path"
 	"regexp"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/docker/docker/api/types"
 	imagev1 "github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/go-connections/nat"
+	"github.com/docker/go-units"
 	version2 "github.com/hashicorp/go-version"
 	_config "github.com/kvesta/vesta/config"
 	_image "github.com/kvesta/vesta/pkg/inspector"
-	"github.com/kvesta/vesta/pkg/osrelease"
 	"github.com/kvesta/vesta/pkg/vulnlib"
 	"github.com/tidwall/gjson"
 )
 
 func (s *Scanner) checkDockerContext(ctx context.Context, images []*_image.ImageInfo) error {
 
-	cli := vulnlib.Client{}
+	cli := vulnlib.Client{Fuzzy: s.fuzzyMatch}
 	err := cli.Init()
 
 	if err != nil {
-		log.Printf("failed to init database, error: %v", err)
+		_config.ErrorLog.Printf("failed to init database, error: %v", err)
 	} else {
 		defer cli.DB.Close()
 	}
 
-	// Checking kernel version
-	kernelVersion, err := osrelease.GetKernelVersion(context.Background())
-	if err != nil {
-		log.Printf("failed to get kernel version: %v", err)
-	}
-
-	if ok, tlist := checkKernelVersion(cli, kernelVersion); ok {
-		ct := &container{
-			ContainerID:   "None",
-			ContainerName: "Kernel",
-			Threats:       tlist,
-		}
-
-		s.VulnContainers = append(s.VulnContainers, ct)
+	included, excluded := splitExcludedImages(images, excludedPatterns(ctx))
+	for _, img := range excluded {
+		s.ExcludedImages = append(s.ExcludedImages, imageName(img))
 	}
 
-	// Check Docker server version
-	if ok, tlist := checkDockerVersion(cli, s.ServerVersion); ok {
-		ct := &container{
-			ContainerID:   "None",
-			ContainerName: "Server Version",
-			Threats:       tlist,
-		}
-
-		s.VulnContainers = append(s.VulnContainers, ct)
+	if len(excluded) > 0 {
+		log.Printf("%d images are excluded from image analyzing", len(excluded))
 	}
 
-	// Check 2375 unauthorized
-	if ok, tlist := checkDockerUnauthorized(); ok {
-		ct := &container{
-			ContainerID:   "None",
-			ContainerName: "Docker 2375 port",
-			Threats:       tlist,
+	for _, ch := range dockerContextChecks {
+		if !isCheckEnabled(ctx, ch.name) || (ch.host && s.Offline) {
+			continue
 		}
 
-		s.VulnContainers = append(s.VulnContainers, ct)
-	}
+		s.Checks = append(s.Checks, ch.name)
 
-	// Check the repo's tag
-	if ok, tlist := checkImages(images); ok {
-		ct := &container{
-			ContainerID:   "None",
-			ContainerName: "Image Tag",
-			Threats:       tlist,
+		checkImages := images
+		if ch.image {
+			checkImages = included
 		}
 
-		s.VulnContainers = append(s.VulnContainers, ct)
-	}
+		start := time.Now()
+		ok, tlist := ch.fn(s, cli, checkImages)
+		s.Timings = addTiming(s.Timings, ch.name, time.Since(start))
+		tagCheck(ch.name, tlist)
+		remapSeverity(ctx, ch.name, tlist)
+
+		if ok {
+			ct := &container{
+				ContainerID:   "None",
+				ContainerName: ch.target,
+				Threats:       tlist,
+			}
 
-	// Check image's history
-	if ok, tlist := checkHistories(images); ok {
-		ct := &container{
-			ContainerID:   "None",
-			ContainerName: "Image Configuration",
-			Threats:       tlist,
+			s.VulnContainers = append(s.VulnContainers, ct)
+			s.stream.emit(dockerTarget(ct), ch.name, tlist)
 		}
-
-		s.VulnContainers = append(s.VulnContainers, ct)
 	}
 
 	return nil
@@ -109,38 +96,54 @@ func checkPrivileged(config *types.ContainerJSON) (bool, []*threat) {
 			}
 		}
 
-		if capadd == "CAP_DAC_READ_SEARCH" {
+		if strings.TrimPrefix(strings.ToUpper(capadd), "CAP_") == "DAC_READ_SEARCH" {
 			th := &threat{
-				Param:    "CapAdd",
-				Value:    "CAP_DAC_READ_SEARCH",
-				Describe: "There has a potential arbitrary file leakage.",
-				Severity: "medium",
+				Param:       "CapAdd",
+				Value:       "CAP_DAC_READ_SEARCH",
+				Describe:    "There has a potential arbitrary file leakage.",
+				Remediation: "Remove CAP_DAC_READ_SEARCH from `--cap-add`.",
+				Severity:    "medium",
 			}
 			tlist = append(tlist, th)
 		}
 	}
-	if vuln {
+
+	// Privileged container is granted all the capabilities whatever CapAdd is
+	if config.HostConfig.Privileged {
+		th := &threat{
+			Param: "CapAdd",
+			Value: "ALL (privileged)",
+			Describe: "Privileged container is granted all the capabilities implicitly, " +
+				"there has a potential container escape.",
+			Remediation: "Run the container without `--privileged`, add the needed capabilities by `--cap-add` only.",
+			Severity:    "critical",
+		}
+		tlist = append(tlist, th)
+	} else if vuln {
 		th := &threat{
-			Param:    "CapAdd",
-			Value:    capList,
-			Describe: "There has a potential container escape in privileged module.",
-			Severity: "critical",
+			Param:       "CapAdd",
+			Value:       capList,
+			Describe:    "There has a potential container escape in privileged module.",
+			Remediation: "Remove the dangerous capabilities from `--cap-add`.",
+			Severity:    "critical",
 		}
 		tlist = append(tlist, th)
 	}
 
 	if config.HostConfig.Privileged {
 		th := &threat{
-			Param:    "Privileged",
-			Value:    "true",
-			Describe: "There has a potential container escape in privileged module.",
-			Severity: "critical",
+			Param:       "Privileged",
+			Value:       "true",
+			Describe:    "There has a potential container escape in privileged module.",
+			Remediation: "Run the container without `--privileged`.",
+			Severity:    "critical",
 		}
 		tlist = append(tlist, th)
 		vuln = true
 	}
 
-	return vuln, tlist
+	// File leakage of CAP_DAC_READ_SEARCH is reported without the dangerous capabilities
+	return vuln || len(tlist) > 0, tlist
 }
 
 func checkMount(config *types.ContainerJSON) (bool, []*threat) {
@@ -158,7 +161,8 @@ func checkMount(config *types.ContainerJSON) (bool, []*threat) {
 				Value: mount.Source,
 				Describe: fmt.Sprintf("Mount '%s' in '%s' is suffer vulnerable of "+
 					"container escape.", mount.Source, mount.Destination),
-				Severity: "critical",
+				Remediation: "Remove the sensitive host path from `--volume`, or mount it read-only by `:ro`.",
+				Severity:    "critical",
 			}
 			tlist = append(tlist, th)
 			vuln = true
@@ -168,6 +172,116 @@ func checkMount(config *types.ContainerJSON) (bool, []*threat) {
 	return vuln, tlist
 }
 
+// checkMissingMounts check the bind mounts whose source does not exist on the host,
+// the path is a typo or is removed after the container started, whatever created there later is exposed to the container
+func checkMissingMounts(config *types.ContainerJSON) (bool, []*threat) {
+	tlist := []*threat{}
+
+	for _, m := range config.Mounts {
+		if m.Type != mount.TypeBind || m.Source == "" {
+			continue
+		}
+
+		_, err := os.Stat(m.Source)
+		if !os.IsNotExist(err) {
+			continue
+		}
+
+		th := &threat{
+			Param: "Mount",
+			Value: fmt.Sprintf("missing source: %s | destination: %s", m.Source, m.Destination),
+			Describe: fmt.Sprintf("Source '%s' of the bind mount does not exist on the host, "+
+				"the files created there later are exposed to the container.", m.Source),
+			Remediation: "Fix the path of the bind mount, or remove the mount if it is not used.",
+			Severity:    "warning",
+		}
+
+		tlist = append(tlist, th)
+	}
+
+	return len(tlist) > 0, tlist
+}
+
+// checkHostTakeover check whether the privileged container mounts the root filesystem of host
+func checkHostTakeover(config *types.ContainerJSON) (bool, []*threat) {
+	if !config.HostConfig.Privileged {
+		return false, nil
+	}
+
+	for _, mount := range config.Mounts {
+		if mount.Source != "/" {
+			continue
+		}
+
+		th := &threat{
+			Param: "Host Takeover",
+			Value: fmt.Sprintf("privileged: true | mount: %s:%s", mount.Source, mount.Destination),
+			Describe: fmt.Sprintf("Privileged container mounts the host root filesystem to '%s', "+
+				"the host can be taken over trivially by `chroot %s`.", mount.Destination, mount.Destination),
+			Remediation: "Run the container without `--privileged` and remove the mount of `/` from `--volume`.",
+			Severity:    "critical",
+		}
+
+		return true, []*threat{th}
+	}
+
+	return false, nil
+}
+
+// foldHostTakeover remove the privileged and root mount findings
+// which are covered by the host takeover finding
+func foldHostTakeover(threats []*threat) []*threat {
+	takeover := false
+	for _, th := range threats {
+		if th.Param == "Host Takeover" {
+			takeover = true
+			break
+		}
+	}
+
+	if !takeover {
+		return threats
+	}
+
+	folded := []*threat{}
+	for _, th := range threats {
+		if th.Param == "Privileged" || (th.Param == "CapAdd" && th.Value == "ALL (privileged)") ||
+			(th.Param == "Mount" && th.Value == "/") {
+			continue
+		}
+		folded = append(folded, th)
+	}
+
+	return folded
+}
+
+// checkLoaderEnv check the variables of dynamic loader in the environment of container
+func checkLoaderEnv(config *types.ContainerJSON) (bool, []*threat) {
+	tlist := []*threat{}
+
+	if config.Config == nil {
+		return false, tlist
+	}
+
+	writable := []string{}
+	for _, m := range config.Mounts {
+		if m.RW {
+			writable = append(writable, m.Destination)
+		}
+	}
+
+	for _, e := range config.Config.Env {
+		name, value, _ := strings.Cut(e, "=")
+
+		if th := getLoaderEnvThreat(name, value, writable); th != nil {
+			th.Param = "env"
+			tlist = append(tlist, th)
+		}
+	}
+
+	return len(tlist) > 0, tlist
+}
+
 func checkEnvPassword(config *types.ContainerJSON) (bool, []*threat) {
 	var vuln = false
 	var password string
@@ -197,10 +311,11 @@ func checkEnvPassword(config *types.ContainerJSON) (bool, []*threat) {
 			switch checkWeakPassword(password) {
 			case "Weak":
 				th := &threat{
-					Param:    "Weak Password",
-					Value:    fmt.Sprintf("Password: '%s'", password),
-					Describe: fmt.Sprintf("%s has weak password: '%s'.", imageVersion, password),
-					Severity: "high",
+					Param:       "Weak Password",
+					Value:       fmt.Sprintf("Password: '%s'", password),
+					Describe:    fmt.Sprintf("%s has weak password: '%s'.", imageVersion, password),
+					Remediation: "Use a strong password of at least 8 characters mixing cases, digits and symbols.",
+					Severity:    "high",
 				}
 				tlist = append(tlist, th)
 				vuln = true
@@ -210,7 +325,8 @@ func checkEnvPassword(config *types.ContainerJSON) (bool, []*threat) {
 					Value: fmt.Sprintf("Password: '%s'", password),
 					Describe: fmt.Sprintf("%s password '%s' "+
 						"need to be reinforced.", imageVersion, password),
-					Severity: "low",
+					Remediation: "Use a strong password of at least 8 characters mixing cases, digits and symbols.",
+					Severity:    "low",
 				}
 				tlist = append(tlist, th)
 				vuln = true
@@ -232,10 +348,11 @@ func checkEnvPassword(config *types.ContainerJSON) (bool, []*threat) {
 				switch checkWeakPassword(password) {
 				case "Weak":
 					th := &threat{
-						Param:    "Weak Password",
-						Value:    fmt.Sprintf("Password: '%s'", password),
-						Describe: fmt.Sprintf("Redis has weak password: '%s'.", password),
-						Severity: "high",
+						Param:       "Weak Password",
+						Value:       fmt.Sprintf("Password: '%s'", password),
+						Describe:    fmt.Sprintf("Redis has weak password: '%s'.", password),
+						Remediation: "Use a strong password of at least 8 characters mixing cases, digits and symbols.",
+						Severity:    "high",
 					}
 					tlist = append(tlist, th)
 					vuln = true
@@ -245,7 +362,8 @@ func checkEnvPassword(config *types.ContainerJSON) (bool, []*threat) {
 						Value: fmt.Sprintf("Password: '%s'", password),
 						Describe: fmt.Sprintf("Redis password '%s' "+
 							"need to be reinforced.", password),
-						Severity: "medium",
+						Remediation: "Use a strong password of at least 8 characters mixing cases, digits and symbols.",
+						Severity:    "medium",
 					}
 					tlist = append(tlist, th)
 					vuln = true
@@ -259,24 +377,26 @@ func checkEnvPassword(config *types.ContainerJSON) (bool, []*threat) {
 }
 
 // checkNetworkModel check container network model
-//reference: https://github.com/containerd/containerd/security/advisories/GHSA-36xw-fx78-c5r4
+// reference: https://github.com/containerd/containerd/security/advisories/GHSA-36xw-fx78-c5r4
 func checkNetworkModel(config *types.ContainerJSON, version string) (bool, []*threat) {
 	var vuln = false
 
 	tlist := []*threat{}
 
 	if config.HostConfig.NetworkMode == "host" {
-		currentVersion, _ := version2.NewVersion(version)
+		currentVersion, err := version2.NewVersion(version)
 		maxVersion, _ := version2.NewVersion("1.3.7")
 
-		if currentVersion.Compare(maxVersion) <= 0 || version == "1.4.1" || version == "1.4.0" {
+		// version of containerd is unknown in the offline analysis without --engine-version
+		if err == nil && currentVersion.Compare(maxVersion) <= 0 || version == "1.4.1" || version == "1.4.0" {
 			th := &threat{
 				Param: "network",
 				Value: "host",
 				Describe: fmt.Sprintf("Containerd version is %s lower than 1.3.7 or 1.4.1"+
 					" is suffer vulnerable of CVE-2020-15257.", version),
-				Reference: "https://github.com/containerd/containerd/security/advisories/GHSA-36xw-fx78-c5r4",
-				Severity:  "critical",
+				Reference:   "https://github.com/containerd/containerd/security/advisories/GHSA-36xw-fx78-c5r4",
+				Remediation: "Upgrade containerd to 1.3.9, 1.4.3 or later, and run the container without `--net=host`.",
+				Severity:    "critical",
 			}
 			tlist = append(tlist, th)
 			vuln = true
@@ -288,7 +408,8 @@ func checkNetworkModel(config *types.ContainerJSON, version string) (bool, []*th
 				Value: "host",
 				Describe: "Docker container is run with `--net=host`, " +
 					"which will exposed the network of physical machine.",
-				Severity: "medium",
+				Remediation: "Run the container without `--net=host`, publish the needed ports by `--publish`.",
+				Severity:    "medium",
 			}
 
 			tlist = append(tlist, th)
@@ -311,7 +432,8 @@ func checkPid(config *types.ContainerJSON) (bool, []*threat) {
 			Describe: "Docker container is run with `--pid=host`, " +
 				"which attackers can see all the processes in physical machine" +
 				" and cause the potential container escape.",
-			Severity: "high",
+			Remediation: "Run the container without `--pid=host`.",
+			Severity:    "high",
 		}
 
 		tlist = append(tlist, th)
@@ -321,212 +443,1222 @@ func checkPid(config *types.ContainerJSON) (bool, []*threat) {
 	return vuln, tlist
 }
 
-func checkDockerUnauthorized() (bool, []*threat) {
-	log.Printf(_config.Yellow("Begin unauthorized analyzing"))
+// checkIPC check whether the container shares the IPC namespace of host or other container,
+// the shared memory and the message queues are exposed by the shared namespace
+func checkIPC(config *types.ContainerJSON) (bool, []*threat) {
+	tlist := []*threat{}
 
-	var vuln = false
+	ipcMode := config.HostConfig.IpcMode
+
+	switch {
+	case ipcMode.IsHost():
+		th := &threat{
+			Param: "ipc",
+			Value: string(ipcMode),
+			Describe: "Docker container is run with `--ipc=host`, " +
+				"the shared memory of host is exposed and the data can leak between the host and container.",
+			Remediation: "Run the container without `--ipc=host`.",
+			Severity:    "critical",
+		}
+		tlist = append(tlist, th)
+
+	case ipcMode.IsContainer():
+		th := &threat{
+			Param: "ipc",
+			Value: string(ipcMode),
+			Describe: fmt.Sprintf("Docker container shares the IPC namespace of container '%s', "+
+				"the shared memory is exposed between the containers.", ipcMode.Container()),
+			Remediation: "Run the container with the private IPC namespace unless the shared memory is required.",
+			Severity:    "high",
+		}
+		tlist = append(tlist, th)
+	}
 
+	return len(tlist) > 0, tlist
+}
+
+// shmSizeLimit is the size of `/dev/shm` regarded as oversized, docker defaults to 64MB
+const shmSizeLimit = 1 << 30
+
+// checkShm check the shared memory and the tmpfs mounts of container,
+// the shared memory of host or the unbounded tmpfs is a covert channel or a DoS vector between containers
+func checkShm(config *types.ContainerJSON) (bool, []*threat) {
 	tlist := []*threat{}
 
-	client := &http.Client{
-		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-		},
+	ipcMode := string(config.HostConfig.IpcMode)
+	if ipcMode == "" {
+		ipcMode = "private"
 	}
-	var request *http.Request
 
-	request, err := http.NewRequest("GET", "http://0.0.0.0:2375/info", nil)
-	if err != nil {
-		return vuln, tlist
+	for _, m := range config.Mounts {
+		if m.Type != mount.TypeBind || (m.Source != "/dev/shm" && !strings.HasPrefix(m.Source, "/dev/shm/")) {
+			continue
+		}
+
+		th := &threat{
+			Param: "shm",
+			Value: fmt.Sprintf("source: %s | mode: %s", m.Source, ipcMode),
+			Describe: fmt.Sprintf("Shared memory of host '%s' is mounted to '%s', "+
+				"the data can be exchanged with the processes of host and other containers.", m.Source, m.Destination),
+			Remediation: "Remove the bind mount of `/dev/shm`, use `--shm-size` for the private shared memory.",
+			Severity:    "medium",
+		}
+
+		if !m.RW {
+			th.Severity = "low"
+		}
+
+		tlist = append(tlist, th)
 	}
 
-	resp, err := client.Do(request)
+	if config.HostConfig.ShmSize > shmSizeLimit {
+		th := &threat{
+			Param: "shm",
+			Value: fmt.Sprintf("size: %s | mode: %s", units.BytesSize(float64(config.HostConfig.ShmSize)), ipcMode),
+			Describe: "Docker container is run with an oversized `/dev/shm`, " +
+				"the memory of host can be exhausted by filling it.",
+			Remediation: "Decrease `--shm-size` to the size required by the application.",
+			Severity:    "low",
+		}
 
-	if err != nil {
-		return vuln, tlist
+		tlist = append(tlist, th)
 	}
 
-	defer resp.Body.Close()
+	// The tmpfs without size is limited by the half of host memory only
+	unbounded := []string{}
+	for path, options := range config.HostConfig.Tmpfs {
+		if !strings.Contains(options, "size=") {
+			unbounded = append(unbounded, path)
+		}
+	}
 
-	content, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return vuln, tlist
+	for _, m := range config.HostConfig.Mounts {
+		if m.Type == mount.TypeTmpfs && (m.TmpfsOptions == nil || m.TmpfsOptions.SizeBytes < 1) {
+			unbounded = append(unbounded, m.Target)
+		}
 	}
 
-	value := gjson.Parse(string(content))
+	if len(unbounded) > 0 {
+		sort.Strings(unbounded)
 
-	if value.Get("Containers").Value() != nil {
 		th := &threat{
-			Param:     "Docker unauthorized",
-			Value:     "0.0.0.0:2375",
-			Describe:  "Exporting 2375 port is suffering the container escape.",
-			Reference: "Delete row which contained `tcp://0.0.0.0:2375`.",
-			Severity:  "critical",
+			Param: "tmpfs",
+			Value: strings.Join(unbounded, ", "),
+			Describe: "Tmpfs is mounted without the size limit, " +
+				"the memory of host can be exhausted by writing to it.",
+			Remediation: "Set the size of tmpfs, e.g. `--tmpfs /tmp:size=64m`.",
+			Severity:    "low",
 		}
 
 		tlist = append(tlist, th)
-		vuln = true
 	}
 
-	return vuln, tlist
+	return len(tlist) > 0, tlist
 }
 
-func checkImages(images []*_image.ImageInfo) (bool, []*threat) {
-	log.Printf(_config.Yellow("Begin image analyzing"))
-
-	var vuln = false
+// checkNoNewPrivileges check whether the container is run without `--security-opt no-new-privileges`,
+// the processes can gain privileges by the setuid binaries without it
+func checkNoNewPrivileges(config *types.ContainerJSON) (bool, []*threat) {
 	tlist := []*threat{}
 
-	for _, image := range images {
-		if len(image.Summary.RepoTags) < 1 {
-			sha := strings.Split(image.Summary.ID, ":")[1]
-			th := &threat{
-				Param:    "Image ID",
-				Value:    sha[:12],
-				Describe: fmt.Sprintf("Image Id %s is not tagged, suspectable image.", sha[:12]),
-				Severity: "low",
-			}
-			tlist = append(tlist, th)
-			vuln = true
-			continue
-		}
+	// Privileged container holds all the privileges already, which is reported by checkPrivileged
+	if config.HostConfig.Privileged {
+		return false, tlist
+	}
 
-		repoTag := strings.Split(image.Summary.RepoTags[0], ":")
-		if len(repoTag) > 1 && repoTag[1] == "latest" {
-			th := &threat{
-				Param:    "Image Name",
-				Value:    image.Summary.RepoTags[0],
-				Describe: "Using the latest tag will be suffered potential image hijack.",
-				Severity: "low",
-			}
-			tlist = append(tlist, th)
-			vuln = true
+	for _, opt := range config.HostConfig.SecurityOpt {
+		opt = strings.ReplaceAll(opt, "=", ":")
+		if opt == "no-new-privileges" || opt == "no-new-privileges:true" {
+			return false, tlist
 		}
+	}
 
+	th := &threat{
+		Param: "SecurityOpt",
+		Value: "no-new-privileges: false",
+		Describe: "Docker container is run without `no-new-privileges`, " +
+			"the processes can escalate the privileges by the setuid or setgid binaries.",
+		Remediation: "Run the container with `--security-opt no-new-privileges:true`.",
+		Severity:    "low",
 	}
 
-	return vuln, tlist
-}
+	// Root user in container can escalate to the dangerous capabilities directly
+	if config.Config == nil || isRootUser(config.Config.User) {
+		th.Severity = "medium"
+	}
 
-func checkHistories(images []*_image.ImageInfo) (bool, []*threat) {
-	log.Printf(_config.Yellow("Begin image histories analyzing"))
+	tlist = append(tlist, th)
 
-	var vuln = false
+	return true, tlist
+}
+
+// checkRootUser check whether the container runs as root because the image configures no user
+// or root, and the user is not overridden by `--user`
+func checkRootUser(config *types.ContainerJSON) (bool, []*threat) {
 	tlist := []*threat{}
 
-	echoReg := regexp.MustCompile(`echo ["|'](.*?)["|']`)
+	if config.Config == nil || !isRootUser(config.Config.User) {
+		return false, tlist
+	}
 
-	for _, img := range images {
-		env := getEnv(img.History)
-		for _, layer := range img.History {
-			pruneLayerAfter1 := strings.TrimPrefix(layer.CreatedBy, "/bin/sh -c ")
-			pruneLayerAfter2 := strings.TrimPrefix(pruneLayerAfter1, "#(nop)")
-			pruneLayer := strings.TrimSpace(pruneLayerAfter2)
+	user := config.Config.User
+	if user == "" {
+		user = "unset"
+	}
 
-			link := strings.Split(pruneLayer, " ")[0]
-			switch link {
-			case "CMD", "ADD", "ARG", "LABEL", "WORKDIR", "COPY", "EXPOSE", "ENTRYPOINT", "USER", "ENV":
-				continue
-			}
+	th := &threat{
+		Param: "User",
+		Value: fmt.Sprintf("image: %s | user: %s", config.Config.Image, user),
+		Describe: fmt.Sprintf("Container runs as root, the image '%s' is configured with user '%s' "+
+			"and the user is not overridden at runtime.", config.Config.Image, user),
+		Remediation: "Run the container with `--user` of a non-root user, or set `USER` in the image.",
+		Severity:    "low",
+	}
 
-			commands := strings.Split(pruneLayer, "&&")
-			for _, cmd := range commands {
-				echoMatch := echoReg.FindStringSubmatch(cmd)
-				if len(echoMatch) > 1 {
-					pass := echoPass(echoMatch[1], env)
-					if len(pass) < 1 {
-						continue
-					}
-					switch checkWeakPassword(pass) {
-					case "Weak":
-						th := &threat{
-							Param: "Image History",
-							Value: fmt.Sprintf("Image name: %s | "+
-								"Image ID: %s", img.Summary.RepoTags[0],
-								strings.TrimPrefix(img.Summary.ID, "sha256:")[:12]),
-							Describe: fmt.Sprintf("Weak password found in command: '%s' "+
-								"with the password '%s'.", cmd, pass),
-							Severity: "high",
-						}
-
-						tlist = append(tlist, th)
-						vuln = true
-
-					case "Medium":
-						th := &threat{
-							Param: "Image History",
-							Value: fmt.Sprintf("Image name: %s | "+
-								"Image ID: %s", img.Summary.RepoTags[0],
-								strings.TrimPrefix(img.Summary.ID, "sha256:")[:12]),
-							Describe: fmt.Sprintf("Password need need to be reinforeced, found in command: '%s'.", cmd),
-							Severity: "medium",
-						}
-
-						tlist = append(tlist, th)
-						vuln = true
-					}
-				}
-			}
+	tlist = append(tlist, th)
+
+	return true, tlist
+}
+
+// checkRootGroup check whether the container runs with the root group by `--user uid:0` or `--group-add 0`,
+// the files owned by the root group are accessible even for a non-root user
+func checkRootGroup(config *types.ContainerJSON) (bool, []*threat) {
+	tlist := []*threat{}
 
+	user, group := "", ""
+	if config.Config != nil {
+		parts := strings.SplitN(config.Config.User, ":", 2)
+		user = parts[0]
+		if len(parts) > 1 {
+			group = parts[1]
 		}
 	}
 
-	return vuln, tlist
+	isRootGroup := group == "0" || group == "root"
+
+	groupAdd := []string{}
+	for _, g := range config.HostConfig.GroupAdd {
+		groupAdd = append(groupAdd, g)
+		if g == "0" || g == "root" {
+			isRootGroup = true
+		}
+	}
+
+	if !isRootGroup {
+		return false, tlist
+	}
+
+	if user == "" {
+		user = "root"
+	}
+	if group == "" {
+		group = "default"
+	}
+
+	th := &threat{
+		Param: "User",
+		Value: fmt.Sprintf("user: %s | group: %s | group-add: %s", user, group, strings.Join(groupAdd, ",")),
+		Describe: "Docker container is run with the root group (GID 0), " +
+			"the files owned by the root group on the mounts and the image are accessible.",
+		Remediation: "Run the container with a non-zero group by `--user uid:gid`, and remove 0 from `--group-add`.",
+		Severity:    "low",
+	}
+
+	// Root group is easily missed when the user is not root
+	if user != "root" && user != "0" {
+		th.Describe = fmt.Sprintf("Docker container is run as the non-root user '%s' but with the root group (GID 0), "+
+			"the files owned by the root group on the mounts and the image are accessible.", user)
+		th.Severity = "medium"
+	}
+
+	tlist = append(tlist, th)
+
+	return true, tlist
 }
 
-func echoPass(cmd string, env map[string]string) string {
+// checkUsernsMode check whether the container opts out of the user namespace remapping by `--userns=host`
+func checkUsernsMode(config *types.ContainerJSON, usernsRemap bool) (bool, []*threat) {
+	tlist := []*threat{}
 
-	var pass string
-	match := false
-	for _, p := range passKey {
-		if p.MatchString(cmd) {
-			match = true
-			break
-		}
+	if config.HostConfig.UsernsMode != "host" {
+		return false, tlist
 	}
 
-	if !match {
-		return pass
+	th := &threat{
+		Param: "userns",
+		Value: fmt.Sprintf("userns: %s", config.HostConfig.UsernsMode),
+		Describe: "Docker container is run with `--userns=host`, " +
+			"root in container is mapped to root of host.",
+		Remediation: "Run the container without `--userns=host`.",
+		Severity:    "low",
 	}
 
-	prune := strings.TrimSpace(cmd)
+	// The container regains the root of host which is remapped for the other containers
+	if usernsRemap {
+		th.Value += " | daemon: userns-remap"
+		th.Describe = "Docker container is run with `--userns=host` to opt out of the `userns-remap` of daemon, " +
+			"root in container is mapped to root of host."
+		th.Severity = "high"
+	}
 
-	if len(strings.Split(prune, "=")) > 1 {
-		pass = strings.Split(prune, "=")[1]
-	} else if len(strings.Split(prune, ":")) > 1 {
-		pass = strings.Split(prune, ":")[1]
+	tlist = append(tlist, th)
+
+	return true, tlist
+}
+
+// checkPtrace check whether the container holding CAP_SYS_PTRACE can access the other processes,
+// which is possible when the PID namespace is shared with host or the other containers
+func checkPtrace(config *types.ContainerJSON) (bool, []*threat) {
+	tlist := []*threat{}
+
+	held, how := holdsCapability("SYS_PTRACE", config.HostConfig.CapAdd,
+		config.HostConfig.CapDrop, config.HostConfig.Privileged)
+
+	// SYS_PTRACE is not granted by default
+	if !held || how == "default" {
+		return false, tlist
 	}
 
-	pass = strings.TrimSpace(pass)
+	pidMode := string(config.HostConfig.PidMode)
+	th := &threat{
+		Param: "capabilities",
+		Value: fmt.Sprintf("SYS_PTRACE (%s) | pid: private", how),
+		Describe: "Docker container holds CAP_SYS_PTRACE, " +
+			"the processes in the container can be debugged and their memory can be read.",
+		Remediation: "Remove SYS_PTRACE from `--cap-add`.",
+		Severity:    "low",
+	}
 
-	// Get true value from format `${env}`
-	envReg := regexp.MustCompile(`\${(.*)}`)
-	envMatch := envReg.FindStringSubmatch(pass)
-	if len(envMatch) > 1 {
-		if value, ok := env[envMatch[1]]; ok {
-			pass = value
+	switch {
+	case pidMode == "host":
+		th.Value = fmt.Sprintf("SYS_PTRACE (%s) | pid: host", how)
+		th.Describe = "Docker container holds CAP_SYS_PTRACE with `--pid=host`, " +
+			"the memory of processes on host can be read and the code can be injected."
+		th.Remediation = "Remove SYS_PTRACE from `--cap-add` and run the container without `--pid=host`."
+		th.Severity = "high"
+	case strings.HasPrefix(pidMode, "container:"):
+		th.Value = fmt.Sprintf("SYS_PTRACE (%s) | pid: %s", how, pidMode)
+		th.Describe = "Docker container holds CAP_SYS_PTRACE with the PID namespace shared, " +
+			"the memory of processes in the other container can be read and the code can be injected."
+		th.Remediation = "Remove SYS_PTRACE from `--cap-add` and run the container without `--pid=container:`."
+		th.Severity = "high"
+	}
+
+	tlist = append(tlist, th)
+
+	return true, tlist
+}
+
+// checkDACCapabilities check whether the capabilities bypassing the file permissions are added,
+// any file of the bind mounts from host can be read or written whatever its owner and mode
+func checkDACCapabilities(config *types.ContainerJSON) (bool, []*threat) {
+	tlist := []*threat{}
+
+	// Privileged container is reported by checkPrivileged
+	if config.HostConfig.Privileged {
+		return false, tlist
+	}
+
+	mounts := []string{}
+	for _, m := range config.Mounts {
+		if m.Type != mount.TypeBind {
+			continue
+		}
+
+		if m.RW {
+			mounts = append(mounts, m.Source)
+		} else {
+			mounts = append(mounts, fmt.Sprintf("%s (ro)", m.Source))
 		}
 	}
 
-	return pass
+	caps := []string{}
+	for _, c := range dacCaps {
+		// DAC_READ_SEARCH without the host mounts is reported by checkPrivileged
+		if c == "DAC_READ_SEARCH" && len(mounts) < 1 {
+			continue
+		}
+
+		held, how := holdsCapability(c, config.HostConfig.CapAdd, config.HostConfig.CapDrop, false)
+		if held && how == "added" {
+			caps = append(caps, c)
+		}
+	}
+
+	if len(caps) < 1 {
+		return false, tlist
+	}
+
+	th := &threat{
+		Param: "capabilities",
+		Value: fmt.Sprintf("%s (added)", strings.Join(caps, ", ")),
+		Describe: fmt.Sprintf("Docker container holds %s, "+
+			"the permissions of files in the container are bypassed.", strings.Join(caps, ", ")),
+		Remediation: fmt.Sprintf("Remove %s from `--cap-add`.", strings.Join(caps, ", ")),
+		Severity:    "medium",
+	}
+
+	if len(mounts) > 0 {
+		th.Value = fmt.Sprintf("%s (added) | mounts: %s", strings.Join(caps, ", "), strings.Join(mounts, ", "))
+		th.Describe = fmt.Sprintf("Docker container holds %s with the host paths mounted, "+
+			"the files of host under the mounts are accessible whatever the owners and modes.", strings.Join(caps, ", "))
+		th.Severity = "high"
+	}
+
+	tlist = append(tlist, th)
+
+	return true, tlist
 }
 
-func getEnv(images []imagev1.HistoryResponseItem) map[string]string {
-	env := map[string]string{}
+// checkPortBindings check whether the sensitive ports of the container are published on all the interfaces
+func checkPortBindings(config *types.ContainerJSON) (bool, []*threat) {
+	var vuln = false
 
-	for _, layer := range images {
-		pruneLayerAfter1 := strings.TrimPrefix(layer.CreatedBy, "/bin/sh -c ")
-		pruneLayerAfter2 := strings.TrimPrefix(pruneLayerAfter1, "#(nop)")
-		pruneLayer := strings.TrimSpace(pruneLayerAfter2)
+	tlist := []*threat{}
 
-		link := strings.Split(pruneLayer, " ")[0]
-		if link != "ENV" {
+	ports := []string{}
+	for port := range config.HostConfig.PortBindings {
+		ports = append(ports, string(port))
+	}
+	sort.Strings(ports)
+
+	for _, port := range ports {
+		service, ok := sensitivePorts[strings.Split(port, "/")[0]]
+		if !ok {
 			continue
 		}
-		envLayer := strings.TrimPrefix(pruneLayer, "ENV ")
-		e := strings.Split(envLayer, "=")
-		env[e[0]] = e[1]
+
+		for _, binding := range config.HostConfig.PortBindings[nat.Port(port)] {
+			if binding.HostIP != "" && binding.HostIP != "0.0.0.0" && binding.HostIP != "::" {
+				continue
+			}
+
+			th := &threat{
+				Param: "ports",
+				Value: fmt.Sprintf("0.0.0.0:%s:%s", binding.HostPort, port),
+				Describe: fmt.Sprintf("Port of %s is published on all the interfaces of host, "+
+					"which is reachable from the outside.", service),
+				Remediation: "Publish the port on `127.0.0.1` only, or remove it from `-p`.",
+				Severity:    "high",
+			}
+			tlist = append(tlist, th)
+			vuln = true
+		}
 	}
 
-	return env
+	return vuln, tlist
+}
+
+// checkNetRaw check whether the container can exploit CVE-2020-14386 with CAP_NET_RAW,
+// which is granted by docker by default unless it is dropped explicitly
+func checkNetRaw(config *types.ContainerJSON, netRawKernel bool) (bool, []*threat) {
+	tlist := []*threat{}
+
+	if !netRawKernel {
+		return false, tlist
+	}
+
+	held, how := holdsCapability("NET_RAW", config.HostConfig.CapAdd,
+		config.HostConfig.CapDrop, config.HostConfig.Privileged)
+	if !held {
+		return false, tlist
+	}
+
+	th := &threat{
+		Param: "capabilities",
+		Value: fmt.Sprintf("NET_RAW (%s)", how),
+		Describe: fmt.Sprintf("Kernel is vulnerable to CVE-2020-14386 and %s, "+
+			"the container escape is exploitable.", netRawGrant(how)),
+		Reference:   "https://nvd.nist.gov/vuln/detail/CVE-2020-14386",
+		Remediation: "Add `--cap-drop NET_RAW` to the container or upgrade the kernel.",
+		Severity:    "critical",
+	}
+	tlist = append(tlist, th)
+
+	return true, tlist
+}
+
+// checkCgroupEscape check whether the container can exploit CVE-2022-0492,
+// which needs CAP_SYS_ADMIN and a writable cgroup filesystem
+func checkCgroupEscape(config *types.ContainerJSON, cgroupKernel bool) (bool, []*threat) {
+	tlist := []*threat{}
+
+	if !cgroupKernel {
+		return false, tlist
+	}
+
+	held, how := holdsCapability("SYS_ADMIN", config.HostConfig.CapAdd,
+		config.HostConfig.CapDrop, config.HostConfig.Privileged)
+	// SYS_ADMIN is not granted by default
+	if !held || how == "default" {
+		return false, tlist
+	}
+
+	// Privileged container gets the cgroup filesystem mounted writable
+	writable := ""
+	if config.HostConfig.Privileged {
+		writable = "/sys/fs/cgroup (privileged)"
+	}
+
+	for _, mount := range config.Mounts {
+		if mount.RW && strings.HasPrefix(mount.Destination, "/sys/fs/cgroup") {
+			writable = fmt.Sprintf("%s:%s", mount.Source, mount.Destination)
+			break
+		}
+	}
+
+	if writable == "" {
+		return false, tlist
+	}
+
+	th := &threat{
+		Param: "capabilities",
+		Value: fmt.Sprintf("SYS_ADMIN (%s) | cgroup: %s", how, writable),
+		Describe: "Kernel is vulnerable to CVE-2022-0492 and the container holds CAP_SYS_ADMIN " +
+			"with a writable cgroup, the container escape by `release_agent` is exploitable.",
+		Reference:   "https://nvd.nist.gov/vuln/detail/CVE-2022-0492",
+		Remediation: "Remove SYS_ADMIN from `--cap-add`, do not mount the cgroup writable, or upgrade the kernel.",
+		Severity:    "critical",
+	}
+	tlist = append(tlist, th)
+
+	return true, tlist
+}
+
+// checkDevices check the host devices mapped into the container by `--device`
+func checkDevices(config *types.ContainerJSON) (bool, []*threat) {
+	var vuln = false
+
+	tlist := []*threat{}
+
+	for _, device := range config.HostConfig.Devices {
+		th := &threat{
+			Param: "device",
+			Value: fmt.Sprintf("%s:%s (%s)", device.PathOnHost,
+				device.PathInContainer, device.CgroupPermissions),
+			Remediation: "Remove the `--device` mapping unless the device is required by the container.",
+		}
+
+		switch {
+		case hasPrefixIn(device.PathOnHost, sensitiveDevices):
+			th.Describe = fmt.Sprintf("Host device `%s` is exposed to the container, "+
+				"the memory or disk of host can be accessed directly and cause the container escape.",
+				device.PathOnHost)
+			th.Severity = "critical"
+		case hasPrefixIn(device.PathOnHost, gpuDevices):
+			th.Describe = fmt.Sprintf("GPU device `%s` of host is exposed to the container, "+
+				"the memory of GPU is shared with other workloads.", device.PathOnHost)
+			th.Severity = "medium"
+		case hasPrefixIn(device.PathOnHost, benignDevices):
+			th.Describe = fmt.Sprintf("Host device `%s` is exposed to the container.", device.PathOnHost)
+			th.Severity = "warning"
+		default:
+			th.Describe = fmt.Sprintf("Host device `%s` is exposed to the container, "+
+				"make sure the device is necessary.", device.PathOnHost)
+			th.Severity = "low"
+		}
+
+		tlist = append(tlist, th)
+		vuln = true
+	}
+
+	return vuln, tlist
+}
+
+// restartThreshold is the count of restarts regarded as a crash loop
+const restartThreshold = 5
+
+// checkRestarts check whether the container is restarted repeatedly,
+// it is the context for investigating rather than a misconfiguration
+func checkRestarts(config *types.ContainerJSON) (bool, []*threat) {
+	tlist := []*threat{}
+
+	if config.RestartCount < restartThreshold {
+		return false, tlist
+	}
+
+	// Value is kept stable for the fingerprint and baseline, the count changes on every restart
+	reason := "unknown"
+	detail := ""
+	if st := config.State; st != nil {
+		reason = fmt.Sprintf("exit code %d", st.ExitCode)
+		if st.OOMKilled {
+			reason = "OOMKilled, " + reason
+		}
+		if st.Error != "" {
+			detail = fmt.Sprintf(" The last error is '%s'.", st.Error)
+		}
+	}
+
+	th := &threat{
+		Param: "restart count",
+		Value: fmt.Sprintf("last exit: %s", reason),
+		Describe: fmt.Sprintf("Docker container has restarted %d times, "+
+			"which could be caused by an exploit attempt or a broken security control.%s", config.RestartCount, detail),
+		Remediation: "Check the logs of the container with `docker logs` for the cause of the crashes.",
+		Severity:    "low",
+	}
+
+	tlist = append(tlist, th)
+
+	return true, tlist
+}
+
+// defaultWritableLayerLimit is the size of writable layer regarded as unusual growth
+const defaultWritableLayerLimit int64 = 1 << 30
+
+// checkWritableLayer check the size of the writable layer of container,
+// the size is reported by docker inspect with `--size` only
+func checkWritableLayer(config *types.ContainerJSON, limit int64) (bool, []*threat) {
+	tlist := []*threat{}
+
+	if config.SizeRw == nil || limit <= 0 || *config.SizeRw < limit {
+		return false, tlist
+	}
+
+	th := &threat{
+		Param: "writable layer",
+		Value: fmt.Sprintf("size: %s | limit: %s", units.BytesSize(float64(*config.SizeRw)),
+			units.BytesSize(float64(limit))),
+		Describe: fmt.Sprintf("Writable layer of the container has grown to %s, "+
+			"the files written into the container could be the downloaded tools or the staged data.",
+			units.BytesSize(float64(*config.SizeRw))),
+		Remediation: "Check the changed files with `docker diff`, write the data to volumes " +
+			"and run the container with `--read-only`.",
+		Severity: "warning",
+	}
+
+	tlist = append(tlist, th)
+
+	return true, tlist
+}
+
+// defaultDebugCommands are the commands regarded as the debug workload when running as the main process,
+// it is replaced by the option `debugCommands`
+var defaultDebugCommands = []string{"sh", "bash", "ash", "dash", "zsh",
+	"apt", "apt-get", "yum", "dnf", "apk", "curl", "wget", "sleep"}
+
+// shells run the script of `-c` or the script file, they are interactive without them
+var shells = map[string]bool{"sh": true, "bash": true, "ash": true, "dash": true, "zsh": true}
+
+// isInteractiveShell check whether the arguments of shell give neither `-c` nor a script file,
+// the shell waits for the input
+func isInteractiveShell(args []string) bool {
+	for _, arg := range args {
+		if arg == "-c" || !strings.HasPrefix(arg, "-") {
+			return false
+		}
+	}
+
+	return true
+}
+
+// checkMainProcess check whether the main process of container is a shell or a package manager,
+// the shell running a script is flagged only if the script calls the debug commands
+func checkMainProcess(config *types.ContainerJSON, debugCommands []string) (bool, []*threat) {
+	tlist := []*threat{}
+
+	if config.Path == "" {
+		return false, tlist
+	}
+
+	listed := map[string]bool{}
+	for _, c := range debugCommands {
+		listed[c] = true
+	}
+
+	main := path.Base(config.Path)
+	command := strings.TrimSpace(strings.Join(append([]string{config.Path}, config.Args...), " "))
+
+	detected := ""
+	switch {
+	case !shells[main]:
+		if listed[main] {
+			detected = main
+		}
+
+	case isInteractiveShell(config.Args):
+		if listed[main] {
+			detected = main
+		}
+
+	default:
+		for i, arg := range config.Args {
+			if arg != "-c" || i+1 >= len(config.Args) {
+				continue
+			}
+
+			fields := strings.FieldsFunc(config.Args[i+1], func(r rune) bool {
+				return strings.ContainsRune(" \t\n;|&()", r)
+			})
+			for _, f := range fields {
+				if name := path.Base(f); listed[name] && !shells[name] {
+					detected = name
+					break
+				}
+			}
+		}
+	}
+
+	if detected == "" {
+		return false, tlist
+	}
+
+	if len(command) > 100 {
+		command = command[:100] + "..."
+	}
+
+	th := &threat{
+		Param: "main process",
+		Value: fmt.Sprintf("detected: %s | command: %s", detected, command),
+		Describe: fmt.Sprintf("Main process of the container runs '%s', "+
+			"which is likely a debug container left running or a compromised workload.", detected),
+		Remediation: "Remove the container if it is for debugging, " +
+			"or run the application directly as the entrypoint of image.",
+		Severity: "low",
+	}
+
+	tlist = append(tlist, th)
+
+	return true, tlist
+}
+
+// checkRuntimeFeatures check the deprecated or risky runtime features
+// on the detected engine version
+func checkRuntimeFeatures(config *types.ContainerJSON, engineVersion, serverVersion string) (bool, []*threat) {
+	var vuln = false
+
+	tlist := []*threat{}
+
+	for _, rule := range config.HostConfig.DeviceCgroupRules {
+		fields := strings.Fields(rule)
+		if len(fields) < 2 {
+			continue
+		}
+
+		// Rule of the device cgroup is formatted as `type major:minor access`,
+		// the access is all of `rwm` if it is omitted
+		access := "rwm"
+		if len(fields) > 2 {
+			access = fields[2]
+		}
+
+		// Devices could not be read or written by `mknod` only
+		if !strings.ContainsAny(access, "rw") {
+			continue
+		}
+
+		var devices, severity string
+		switch {
+		case fields[0] == "a":
+			devices, severity = "all the devices", "high"
+		case fields[0] == "b" && strings.HasPrefix(fields[1], "*:"):
+			devices, severity = "all the block devices, host disks can be read", "high"
+		case fields[0] == "c" && strings.HasPrefix(fields[1], "*:"):
+			devices, severity = "all the character devices", "medium"
+		default:
+			continue
+		}
+
+		th := &threat{
+			Param: "device-cgroup-rule",
+			Value: rule,
+			Describe: fmt.Sprintf("Device cgroup rule '%s' allows the access '%s' of %s by `mknod` "+
+				"on engine version %s.", rule, access, devices, engineVersion),
+			Remediation: "Allow the needed devices only in `--device-cgroup-rule` instead of the wildcard.",
+			Severity:    severity,
+		}
+
+		if config.HostConfig.Privileged {
+			th.Describe = fmt.Sprintf("Device cgroup rule '%s' is ignored in privileged mode "+
+				"on engine version %s, all the devices are allowed.", rule, engineVersion)
+			th.Severity = "warning"
+		}
+
+		tlist = append(tlist, th)
+		vuln = true
+	}
+
+	if config.HostConfig.KernelMemory > 0 {
+		th := &threat{
+			Param: "kernel-memory",
+			Value: fmt.Sprintf("%d", config.HostConfig.KernelMemory),
+			Describe: fmt.Sprintf("Kernel memory limit is unstable on Docker server version %s, "+
+				"which can exhaust the kernel memory of host.", serverVersion),
+			Remediation: "Remove `--kernel-memory` and limit the memory by `--memory`.",
+			Severity:    "low",
+		}
+
+		if compareVersion(serverVersion, "=99.0", "=20.10") {
+			th.Describe = fmt.Sprintf("Kernel memory limit is deprecated and ignored "+
+				"since Docker server version 20.10, current version is %s.", serverVersion)
+			th.Severity = "warning"
+		}
+
+		tlist = append(tlist, th)
+		vuln = true
+	}
+
+	// Links of the user-defined networks are the aliases only,
+	// the legacy links of the default bridge share the environment variables
+	networkMode := config.HostConfig.NetworkMode
+	if len(config.HostConfig.Links) > 0 && (networkMode.IsDefault() || networkMode.IsBridge()) {
+		th := &threat{
+			Param: "link",
+			Value: strings.Join(config.HostConfig.Links, ", "),
+			Describe: fmt.Sprintf("Container links of the default bridge are a legacy feature which may be removed, "+
+				"environment variables of the linked containers are shared on Docker server version %s.", serverVersion),
+			Reference:   "https://docs.docker.com/network/links/",
+			Remediation: "Replace `--link` with a user-defined network.",
+			Severity:    "warning",
+		}
+
+		tlist = append(tlist, th)
+		vuln = true
+	}
+
+	return vuln, tlist
+}
+
+// checkDockerUnauthorized check whether the API of docker is exposed at the address without TLS
+func checkDockerUnauthorized(address string) (bool, []*threat) {
+	log.Printf(_config.Yellow("Begin unauthorized analyzing"))
+
+	var vuln = false
+
+	tlist := []*threat{}
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+	}
+	var request *http.Request
+
+	request, err := http.NewRequest("GET", fmt.Sprintf("http://%s/info", address), nil)
+	if err != nil {
+		return vuln, tlist
+	}
+
+	resp, err := client.Do(request)
+
+	if err != nil {
+		return vuln, tlist
+	}
+
+	defer resp.Body.Close()
+
+	content, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return vuln, tlist
+	}
+
+	value := gjson.Parse(string(content))
+
+	if value.Get("Containers").Value() != nil {
+		th := &threat{
+			Param:       "Docker unauthorized",
+			Value:       address,
+			Describe:    "Exporting 2375 port is suffering the container escape.",
+			Reference:   "Delete row which contained `tcp://0.0.0.0:2375`.",
+			Remediation: "Remove `tcp://0.0.0.0:2375` from the hosts of dockerd, or enable TLS by `--tlsverify` on port 2376.",
+			Severity:    "critical",
+		}
+
+		tlist = append(tlist, th)
+		vuln = true
+	}
+
+	return vuln, tlist
+}
+
+func checkImages(images []*_image.ImageInfo, concurrency int) (bool, []*threat) {
+	log.Printf(_config.Yellow("Begin image analyzing"))
+
+	tlist := analyzeImages(images, concurrency, checkImageTag)
+
+	return len(tlist) > 0, tlist
+}
+
+// checkImageTag check whether the image is untagged or using the latest tag
+func checkImageTag(image *_image.ImageInfo) []*threat {
+	tlist := []*threat{}
+
+	if len(image.Summary.RepoTags) < 1 {
+		sha := shortImageID(image.Summary.ID)
+		th := &threat{
+			Param:       "Image ID",
+			Value:       sha,
+			Describe:    fmt.Sprintf("Image Id %s is not tagged, suspectable image.", sha),
+			Remediation: "Tag the image by its source, or remove it if the source is unknown.",
+			Severity:    "low",
+		}
+		tlist = append(tlist, th)
+
+		return tlist
+	}
+
+	repoTag := strings.Split(image.Summary.RepoTags[0], ":")
+	if len(repoTag) > 1 && repoTag[1] == "latest" {
+		th := &threat{
+			Param:       "Image Name",
+			Value:       image.Summary.RepoTags[0],
+			Describe:    "Using the latest tag will be suffered potential image hijack.",
+			Remediation: "Pin the image to a specific version tag or digest.",
+			Severity:    "low",
+		}
+		tlist = append(tlist, th)
+	}
+
+	return tlist
+}
+
+func checkHistories(images []*_image.ImageInfo, concurrency int) (bool, []*threat) {
+	log.Printf(_config.Yellow("Begin image histories analyzing"))
+
+	tlist := analyzeImages(images, concurrency, checkHistory)
+
+	return len(tlist) > 0, tlist
+}
+
+// checkHistory check the weak password in the history of image
+func checkHistory(img *_image.ImageInfo) []*threat {
+	tlist := []*threat{}
+
+	echoReg := regexp.MustCompile(`echo ["|'](.*?)["|']`)
+
+	env := getEnv(img.History)
+	for _, layer := range img.History {
+		pruneLayerAfter1 := strings.TrimPrefix(layer.CreatedBy, "/bin/sh -c ")
+		pruneLayerAfter2 := strings.TrimPrefix(pruneLayerAfter1, "#(nop)")
+		pruneLayer := strings.TrimSpace(pruneLayerAfter2)
+
+		link := strings.Split(pruneLayer, " ")[0]
+		switch link {
+		case "CMD", "ADD", "ARG", "LABEL", "WORKDIR", "COPY", "EXPOSE", "ENTRYPOINT", "USER", "ENV":
+			continue
+		}
+
+		commands := strings.Split(pruneLayer, "&&")
+		for _, cmd := range commands {
+			echoMatch := echoReg.FindStringSubmatch(cmd)
+			if len(echoMatch) > 1 {
+				pass := echoPass(echoMatch[1], env)
+				if len(pass) < 1 {
+					continue
+				}
+				switch checkWeakPassword(pass) {
+				case "Weak":
+					th := &threat{
+						Param: "Image History",
+						Value: fmt.Sprintf("Image name: %s | "+
+							"Image ID: %s", img.Summary.RepoTags[0],
+							shortImageID(img.Summary.ID)),
+						Describe: fmt.Sprintf("Weak password found in command: '%s' "+
+							"with the password '%s'.", cmd, pass),
+						Remediation: "Remove the password from the Dockerfile and pass it at runtime by secrets.",
+						Severity:    "high",
+					}
+
+					tlist = append(tlist, th)
+
+				case "Medium":
+					th := &threat{
+						Param: "Image History",
+						Value: fmt.Sprintf("Image name: %s | "+
+							"Image ID: %s", img.Summary.RepoTags[0],
+							shortImageID(img.Summary.ID)),
+						Describe:    fmt.Sprintf("Password need need to be reinforeced, found in command: '%s'.", cmd),
+						Remediation: "Remove the password from the Dockerfile and pass it at runtime by secrets.",
+						Severity:    "medium",
+					}
+
+					tlist = append(tlist, th)
+				}
+			}
+		}
+
+	}
+
+	return tlist
+}
+
+// Commands downloading from the network, the downloads piped into a shell and ADD of the remote files
+var (
+	downloadReg    = regexp.MustCompile(`\b(curl|wget|fetch)\b[^;&|]*(https?|ftp)://`)
+	pipeToShellReg = regexp.MustCompile(`\b(curl|wget)\b[^;&]*\|\s*(sudo\s+)?(ba|z|da|a)?sh\b`)
+	remoteAddReg   = regexp.MustCompile(`^(--\S+\s+)*(https?|ftp)://`)
+)
+
+func checkImageLayers(images []*_image.ImageInfo, concurrency int) (bool, []*threat) {
+	log.Printf(_config.Yellow("Begin image layers analyzing"))
+
+	tlist := analyzeImages(images, concurrency, checkLateLayers)
+
+	return len(tlist) > 0, tlist
+}
+
+// checkLateLayers check the layers added after the last ENTRYPOINT or CMD of image,
+// and the layers downloading from the network, they are the heuristic indicators of tampering
+func checkLateLayers(img *_image.ImageInfo) []*threat {
+	tlist := []*threat{}
+
+	name := shortImageID(img.Summary.ID)
+	if len(img.Summary.RepoTags) > 0 {
+		name = img.Summary.RepoTags[0]
+	}
+
+	// History is ordered from the newest layer, the layers before the entrypoint are the late ones
+	late := true
+	total := len(img.History)
+
+	for i, layer := range img.History {
+		instruction, command := parseHistory(layer.CreatedBy)
+		if instruction == "" {
+			continue
+		}
+
+		if instruction == "ENTRYPOINT" || instruction == "CMD" {
+			late = false
+			continue
+		}
+
+		download := downloadReg.MatchString(command) ||
+			(instruction == "ADD" && remoteAddReg.MatchString(command))
+		pipeToShell := pipeToShellReg.MatchString(command)
+
+		isLate := late && (instruction == "RUN" || instruction == "ADD" || instruction == "COPY")
+		if !isLate && !pipeToShell {
+			continue
+		}
+
+		th := &threat{
+			Param: "Image History",
+			Value: fmt.Sprintf("Image name: %s | layer: %d/%d | command: %s %s",
+				name, total-i, total, instruction, command),
+			Describe: fmt.Sprintf("Layer %d of image '%s' is added after the last ENTRYPOINT or CMD, "+
+				"it may be changed by a derived or tampered image.", total-i, name),
+			Remediation: "Rebuild the image from the trusted Dockerfile and compare the layers with the source.",
+			Severity:    "warning",
+		}
+
+		switch {
+		case pipeToShell:
+			th.Describe = fmt.Sprintf("Layer %d of image '%s' pipes the download from the network into a shell, "+
+				"the script run in the build is not verified.", total-i, name)
+			th.Remediation = "Download the script with the pinned checksum and verify it before running."
+			th.Severity = "medium"
+		case download:
+			th.Describe = fmt.Sprintf("Layer %d of image '%s' is added after the last ENTRYPOINT or CMD "+
+				"and downloads from the network, it is a common way of backdooring an image.", total-i, name)
+			th.Severity = "medium"
+		}
+
+		tlist = append(tlist, th)
+	}
+
+	return tlist
+}
+
+// parseHistory get the instruction and its arguments from the command creating the layer,
+// both the legacy builder and BuildKit are parsed, the empty instruction is for the imported base layers
+func parseHistory(createdBy string) (string, string) {
+	command := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(createdBy), "# buildkit"))
+	if command == "" {
+		return "", ""
+	}
+
+	command = strings.TrimPrefix(command, "/bin/sh -c ")
+	if strings.HasPrefix(command, "#(nop)") {
+		command = strings.TrimSpace(strings.TrimPrefix(command, "#(nop)"))
+		instruction := strings.SplitN(command, " ", 2)[0]
+
+		return instruction, strings.TrimSpace(strings.TrimPrefix(command, instruction))
+	}
+
+	instruction := strings.SplitN(command, " ", 2)[0]
+	switch instruction {
+	case "ADD", "ARG", "CMD", "COPY", "ENTRYPOINT", "ENV", "EXPOSE", "HEALTHCHECK", "LABEL",
+		"ONBUILD", "SHELL", "STOPSIGNAL", "USER", "VOLUME", "WORKDIR", "MAINTAINER":
+		return instruction, strings.TrimSpace(strings.TrimPrefix(command, instruction))
+	case "RUN":
+		command = strings.TrimSpace(strings.TrimPrefix(command, "RUN"))
+		command = strings.TrimPrefix(command, "/bin/sh -c ")
+	}
+
+	return "RUN", strings.TrimSpace(command)
+}
+
+func checkImageUsers(images []*_image.ImageInfo, concurrency int) (bool, []*threat) {
+	log.Printf(_config.Yellow("Begin image users analyzing"))
+
+	tlist := analyzeImages(images, concurrency, checkImageUser)
+
+	return len(tlist) > 0, tlist
+}
+
+// checkImageUser check whether the image runs as root by default,
+// the containers of the image run as root unless the user is given at runtime
+func checkImageUser(img *_image.ImageInfo) []*threat {
+	tlist := []*threat{}
+
+	user, known := imageUser(img)
+	if !known || !isRootUser(user) {
+		return tlist
+	}
+
+	name := shortImageID(img.Summary.ID)
+	if len(img.Summary.RepoTags) > 0 {
+		name = img.Summary.RepoTags[0]
+	}
+
+	configured := user
+	if configured == "" {
+		configured = "unset"
+	}
+
+	th := &threat{
+		Param: "Image User",
+		Value: fmt.Sprintf("Image name: %s | user: %s", name, configured),
+		Describe: fmt.Sprintf("Image '%s' runs as root by default, "+
+			"the containers of the image run as root unless the user is given at runtime.", name),
+		Remediation: "Create a non-root user in the Dockerfile and switch to it by `USER`.",
+		Severity:    "low",
+	}
+
+	tlist = append(tlist, th)
+
+	return tlist
+}
+
+// imageUser get the user configured in the image, the config of image takes precedence
+// and the last `USER` instruction in the history is used if the image is not inspected
+func imageUser(img *_image.ImageInfo) (string, bool) {
+	if img.Config != nil {
+		return img.Config.User, true
+	}
+
+	if len(img.History) < 1 {
+		return "", false
+	}
+
+	// History is ordered from the newest layer
+	for _, layer := range img.History {
+		pruneLayer := strings.TrimPrefix(layer.CreatedBy, "/bin/sh -c ")
+		pruneLayer = strings.TrimSpace(strings.TrimPrefix(pruneLayer, "#(nop)"))
+
+		if strings.HasPrefix(pruneLayer, "USER ") {
+			return strings.TrimSpace(strings.TrimPrefix(pruneLayer, "USER ")), true
+		}
+	}
+
+	return "", true
+}
+
+// analyzeImages analyze the images by a bounded pool of workers,
+// threats are gathered by the collector and sorted, the order does not depend on the workers finishing first
+func analyzeImages(images []*_image.ImageInfo, concurrency int, fn func(img *_image.ImageInfo) []*threat) []*threat {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	ths := &threatCollector{}
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	var done int32
+
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for i := range jobs {
+				ths.Add(fn(images[i])...)
+
+				n := atomic.AddInt32(&done, 1)
+				log.Printf("Analyzed images: %d/%d", n, len(images))
+			}
+		}()
+	}
+
+	for i := range images {
+		jobs <- i
+	}
+	close(jobs)
+
+	wg.Wait()
+
+	tlist := ths.Drain()
+	sortSeverity(tlist)
+
+	return tlist
+}
+
+func echoPass(cmd string, env map[string]string) string {
+
+	var pass string
+	match := false
+	for _, p := range passKey {
+		if p.MatchString(cmd) {
+			match = true
+			break
+		}
+	}
+
+	if !match {
+		return pass
+	}
+
+	prune := strings.TrimSpace(cmd)
+
+	if len(strings.Split(prune, "=")) > 1 {
+		pass = strings.Split(prune, "=")[1]
+	} else if len(strings.Split(prune, ":")) > 1 {
+		pass = strings.Split(prune, ":")[1]
+	}
+
+	pass = strings.TrimSpace(pass)
+
+	// Get true value from format `${env}`
+	envReg := regexp.MustCompile(`\${(.*)}`)
+	envMatch := envReg.FindStringSubmatch(pass)
+	if len(envMatch) > 1 {
+		if value, ok := env[envMatch[1]]; ok {
+			pass = value
+		}
+	}
+
+	return pass
+}
+
+func getEnv(images []imagev1.HistoryResponseItem) map[string]string {
+	env := map[string]string{}
+
+	for _, layer := range images {
+		pruneLayerAfter1 := strings.TrimPrefix(layer.CreatedBy, "/bin/sh -c ")
+		pruneLayerAfter2 := strings.TrimPrefix(pruneLayerAfter1, "#(nop)")
+		pruneLayer := strings.TrimSpace(pruneLayerAfter2)
+
+		link := strings.Split(pruneLayer, " ")[0]
+		if link != "ENV" {
+			continue
+		}
+		envLayer := strings.TrimPrefix(pruneLayer, "ENV ")
+		e := strings.Split(envLayer, "=")
+		env[e[0]] = e[1]
+	}
+
+	return env
+}
+
+// shortImageID get the first 12 characters of the image ID without the digest algorithm,
+// the ID shorter than it is returned as it is
+func shortImageID(id string) string {
+	id = strings.TrimPrefix(id, "sha256:")
+	if len(id) > 12 {
+		return id[:12]
+	}
+
+	return id
 }
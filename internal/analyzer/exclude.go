@@ -0,0 +1,85 @@
+package analyzer
+
+import (
+	"context"
+	"path"
+	"strings"
+
+	_image "github.com/kvesta/vesta/pkg/inspector"
+)
+
+// excludedPatterns get the patterns of the option `excludeImages`
+func excludedPatterns(ctx context.Context) []string {
+	patterns, ok := ctx.Value("excludeImages").([]string)
+	if !ok {
+		return nil
+	}
+
+	return patterns
+}
+
+// matchExcluded match the references of image with the patterns,
+// a pattern is an image name with the wildcards such as `vendor/*`, a repository matching all the tags or a digest
+func matchExcluded(img *_image.ImageInfo, patterns []string) bool {
+	refs := append([]string{img.Summary.ID}, img.Summary.RepoTags...)
+	refs = append(refs, img.Summary.RepoDigests...)
+
+	for _, ref := range refs {
+		repo := ref
+		if i := strings.Index(repo, "@"); i >= 0 {
+			repo = repo[:i]
+		}
+		if i := strings.LastIndex(repo, ":"); i > strings.LastIndex(repo, "/") {
+			repo = repo[:i]
+		}
+
+		for _, p := range patterns {
+			if p == "" {
+				continue
+			}
+
+			if ok, err := path.Match(p, ref); err == nil && ok {
+				return true
+			}
+
+			if ok, err := path.Match(p, repo); err == nil && ok {
+				return true
+			}
+
+			// Digest is given by `repo@sha256:...` or `sha256:...`
+			if strings.HasPrefix(p, "sha256:") && strings.HasSuffix(ref, p) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// splitExcludedImages separate the images matching the patterns from the ones to be analyzed
+func splitExcludedImages(images []*_image.ImageInfo, patterns []string) ([]*_image.ImageInfo, []*_image.ImageInfo) {
+	if len(patterns) < 1 {
+		return images, nil
+	}
+
+	included, excluded := []*_image.ImageInfo{}, []*_image.ImageInfo{}
+	for _, img := range images {
+		if matchExcluded(img, patterns) {
+			excluded = append(excluded, img)
+			continue
+		}
+
+		included = append(included, img)
+	}
+
+	return included, excluded
+}
+
+// imageName get the name of image for the report
+func imageName(img *_image.ImageInfo) string {
+	if len(img.Summary.RepoTags) > 0 {
+		return img.Summary.RepoTags[0]
+	}
+
+	return strings.TrimPrefix(img.Summary.ID, "sha256:")
+}
@@ -0,0 +1,58 @@
+package analyzer
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	helmManagedByLabel    = "app.kubernetes.io/managed-by"
+	helmInstanceLabel     = "app.kubernetes.io/instance"
+	helmChartLabel        = "helm.sh/chart"
+	helmReleaseAnnotation = "meta.helm.sh/release-name"
+)
+
+// getHelmRelease get the release name and the chart of Helm from the metadata of objects,
+// the objects are looked up in order and the empty strings are returned for the non-Helm workloads.
+// The chart label is formatted as `<chart>-<version>`
+func getHelmRelease(metas ...metav1.ObjectMeta) (string, string) {
+	release, chart := "", ""
+
+	for _, meta := range metas {
+		isHelm := meta.Labels[helmManagedByLabel] == "Helm" || meta.Labels[helmChartLabel] != "" ||
+			meta.Annotations[helmReleaseAnnotation] != ""
+		if !isHelm {
+			continue
+		}
+
+		if release == "" {
+			release = meta.Annotations[helmReleaseAnnotation]
+		}
+		if release == "" {
+			release = meta.Labels[helmInstanceLabel]
+		}
+
+		if chart == "" {
+			chart = meta.Labels[helmChartLabel]
+		}
+	}
+
+	return release, chart
+}
+
+// getPodHelmRelease get the Helm release of the pod, the labels of pod template are checked first,
+// then the Deployment owning the pod which has the annotations of Helm
+func (ks *KScanner) getPodHelmRelease(meta metav1.ObjectMeta, ownerKind, ownerName string) (string, string) {
+	metas := []metav1.ObjectMeta{meta}
+
+	if ownerKind == "Deployment" {
+		if deploys, err := ks.listDeployments(meta.Namespace); err == nil {
+			for _, d := range deploys.Items {
+				if d.Name == ownerName {
+					metas = append(metas, d.ObjectMeta)
+				}
+			}
+		}
+	}
+
+	return getHelmRelease(metas...)
+}
@@ -0,0 +1,217 @@
+package analyzer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/kvesta/vesta/config"
+	"github.com/kvesta/vesta/pkg/vulnlib"
+)
+
+// incrementalState is the findings of objects in the previous scan,
+// the findings are reused if the resourceVersion and the settings of scan are unchanged
+type incrementalState struct {
+	Cluster  string                     `json:"cluster"`
+	Settings string                     `json:"settings"`
+	Objects  map[string]*objectFindings `json:"objects"`
+}
+
+// incrementalFile is the state file holding the states of the clusters by the address of API server,
+// the clusters of a multi-cluster scan do not overwrite each other
+type incrementalFile map[string]*incrementalState
+
+type objectFindings struct {
+	ResourceVersion string    `json:"resourceVersion"`
+	Threats         []*threat `json:"threats"`
+}
+
+// incrementalScan keeps the state of the previous scan and records the state of this scan
+type incrementalScan struct {
+	path string
+	prev *incrementalState
+	next *incrementalState
+
+	// count of objects whose findings are reused
+	unchanged int
+}
+
+// incrementalPath get the location of state file from the option `incremental`,
+// `default` is the file in the folder of vulnerability database
+func incrementalPath(location string) (string, error) {
+	if location != "default" {
+		return location, nil
+	}
+
+	store, err := vulnlib.StoreDir()
+	if err != nil {
+		return "", err
+	}
+
+	err = os.MkdirAll(store, os.FileMode(0755))
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(store, "incremental.json"), nil
+}
+
+// scanSettings digest the options and the version of vesta changing the findings of pods,
+// the state of a scan with the different options or by another version is not reused
+func (ks *KScanner) scanSettings(ctx context.Context) string {
+	disabled, _ := ctx.Value("disable").([]string)
+
+	blocked := []string{}
+	for _, b := range ks.blocklist {
+		blocked = append(blocked, b.pattern)
+	}
+
+	settings := fmt.Sprintf("version=%s;disable=%s;blocklist=%s", config.Version,
+		strings.Join(disabled, ","), strings.Join(blocked, ","))
+	sum := sha256.Sum256([]byte(settings))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// openIncremental load the state of the previous scan of the cluster, the state is empty for the first scan
+func openIncremental(location, cluster, settings string) (*incrementalScan, error) {
+	path, err := incrementalPath(location)
+	if err != nil {
+		return nil, err
+	}
+
+	inc := &incrementalScan{
+		path: path,
+		next: &incrementalState{Cluster: cluster, Settings: settings, Objects: map[string]*objectFindings{}},
+	}
+
+	states, err := readIncremental(path)
+	if err != nil {
+		config.ErrorLog.Printf("state of the previous scan is invalid, all the objects are checked: %v", err)
+		return inc, nil
+	}
+
+	if prev, ok := states[cluster]; ok && prev.Settings == settings {
+		inc.prev = prev
+	}
+
+	return inc, nil
+}
+
+// readIncremental read the states of the clusters, it is empty if the file does not exist
+func readIncremental(path string) (incrementalFile, error) {
+	states := incrementalFile{}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return states, nil
+	}
+	if err != nil {
+		return states, err
+	}
+
+	err = json.Unmarshal(data, &states)
+	if err != nil {
+		return incrementalFile{}, err
+	}
+
+	return states, nil
+}
+
+// dependencyVersion digest the states of the other objects which the findings of pods in the namespace
+// depend on, such as the Secrets and ConfigMaps referenced by the environment, and the kernel of nodes
+func (ks *KScanner) dependencyVersion(ns string) string {
+	versions := []string{}
+
+	if ks.KClient != nil || ks.cache != nil {
+		if secrets, err := ks.listSecrets(ns); err == nil {
+			for _, se := range secrets.Items {
+				versions = append(versions, fmt.Sprintf("Secret/%s=%s", se.Name, se.ResourceVersion))
+			}
+		}
+
+		if configMaps, err := ks.listConfigMaps(ns); err == nil {
+			for _, cm := range configMaps.Items {
+				versions = append(versions, fmt.Sprintf("ConfigMap/%s=%s", cm.Name, cm.ResourceVersion))
+			}
+		}
+	}
+
+	for name := range ks.MasterNodes {
+		versions = append(versions, fmt.Sprintf("Node/%s", name))
+	}
+	sort.Strings(versions)
+
+	versions = append(versions, fmt.Sprintf("netRawKernel=%t", ks.netRawKernel),
+		fmt.Sprintf("cgroupKernel=%t", ks.cgroupKernel))
+	sum := sha256.Sum256([]byte(strings.Join(versions, ";")))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// reuse get the findings of the object in the previous scan if the version is unchanged,
+// version is the resourceVersion and the states of the other objects affecting the findings
+func (inc *incrementalScan) reuse(key, version string) ([]*threat, bool) {
+	if inc == nil || inc.prev == nil {
+		return nil, false
+	}
+
+	obj, ok := inc.prev.Objects[key]
+	if !ok || obj.ResourceVersion != version {
+		return nil, false
+	}
+
+	inc.unchanged++
+
+	return copyThreats(obj.Threats), true
+}
+
+// record keep the findings of the object for the next scan
+func (inc *incrementalScan) record(key, version string, threats []*threat) {
+	if inc == nil {
+		return
+	}
+
+	inc.next.Objects[key] = &objectFindings{ResourceVersion: version, Threats: copyThreats(threats)}
+}
+
+// save write the state of this scan, the states of the other clusters are kept
+func (inc *incrementalScan) save() error {
+	states, err := readIncremental(inc.path)
+	if err != nil {
+		states = incrementalFile{}
+	}
+	states[inc.next.Cluster] = inc.next
+
+	data, err := json.Marshal(states)
+	if err != nil {
+		return err
+	}
+
+	tmp := inc.path + ".tmp"
+	err = ioutil.WriteFile(tmp, data, 0600)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, inc.path)
+}
+
+// copyThreats copy the threats, the findings are changed by the severity remapping and the redaction
+func copyThreats(threats []*threat) []*threat {
+	copied := make([]*threat, 0, len(threats))
+	for _, th := range threats {
+		c := *th
+		c.CISControls = append([]string{}, th.CISControls...)
+		copied = append(copied, &c)
+	}
+
+	return copied
+}
@@ -0,0 +1,197 @@
+package analyzer
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/kvesta/vesta/config"
+	appsv1 "k8s.io/api/apps/v1"
+)
+
+// nodeAgent is the access needed by a common logging or monitoring agent running as DaemonSet
+type nodeAgent struct {
+	name string
+
+	// prefixes of the image names of agent
+	images []string
+
+	// host paths read by the agent, they should be mounted read-only
+	readPaths []string
+
+	// host paths written by the agent for its state
+	writePaths []string
+
+	hostPID     bool
+	hostNetwork bool
+}
+
+var nodeAgents = []nodeAgent{
+	{name: "fluent-bit", images: []string{"fluent-bit"},
+		readPaths:  []string{"/var/log", "/var/lib/docker/containers", "/run/log/journal", "/etc/machine-id"},
+		writePaths: []string{"/var/fluent-bit", "/var/lib/fluent-bit"}},
+	{name: "fluentd", images: []string{"fluentd"},
+		readPaths:  []string{"/var/log", "/var/lib/docker/containers", "/run/log/journal"},
+		writePaths: []string{"/var/log/fluentd-buffers", "/var/lib/fluentd"}},
+	{name: "filebeat", images: []string{"filebeat"},
+		readPaths:  []string{"/var/log", "/var/lib/docker/containers"},
+		writePaths: []string{"/var/lib/filebeat-data"}},
+	{name: "promtail", images: []string{"promtail"},
+		readPaths:  []string{"/var/log", "/var/lib/docker/containers", "/run/log/journal"},
+		writePaths: []string{"/run/promtail"}},
+	{name: "vector", images: []string{"vector"},
+		readPaths:  []string{"/var/log", "/var/lib/docker/containers", "/proc", "/sys"},
+		writePaths: []string{"/var/lib/vector"}},
+	{name: "node-exporter", images: []string{"node-exporter"},
+		readPaths: []string{"/", "/proc", "/sys"},
+		hostPID:   true, hostNetwork: true},
+}
+
+// matchNodeAgent get the profile of agent by the image name of container
+func matchNodeAgent(image string) (nodeAgent, bool) {
+	name := strings.SplitN(image, "@", 2)[0]
+	name = path.Base(name)
+	name = strings.SplitN(name, ":", 2)[0]
+
+	for _, agent := range nodeAgents {
+		for _, prefix := range agent.images {
+			if strings.HasPrefix(name, prefix) {
+				return agent, true
+			}
+		}
+	}
+
+	return nodeAgent{}, false
+}
+
+// underPaths check whether the path is one of the paths or under them
+func underPaths(p string, paths []string) bool {
+	p = path.Clean(p)
+
+	for _, allowed := range paths {
+		if p == allowed || allowed == "/" || strings.HasPrefix(p, allowed+"/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkAgentPrivilege check whether the logging and monitoring agents of DaemonSet
+// request more access than they need, the agents only read the logs and metrics of node
+func checkAgentPrivilege(da appsv1.DaemonSet) []*threat {
+	tlist := []*threat{}
+
+	podSpec := da.Spec.Template.Spec
+
+	hostPaths := map[string]string{}
+	for _, vol := range podSpec.Volumes {
+		if vol.HostPath != nil {
+			hostPaths[vol.Name] = vol.HostPath.Path
+		}
+	}
+
+	for _, c := range podSpec.Containers {
+		agent, ok := matchNodeAgent(c.Image)
+		if !ok {
+			continue
+		}
+
+		mounts, excess := []string{}, []string{}
+		severity := "low"
+
+		raise := func(s string) {
+			if config.SeverityMap[s] > config.SeverityMap[severity] {
+				severity = s
+			}
+		}
+
+		for _, vm := range c.VolumeMounts {
+			hostPath, ok := hostPaths[vm.Name]
+			if !ok {
+				continue
+			}
+
+			mode := "rw"
+			if vm.ReadOnly {
+				mode = "ro"
+			}
+			mounts = append(mounts, fmt.Sprintf("%s (%s)", hostPath, mode))
+
+			switch {
+			case underPaths(hostPath, agent.writePaths):
+			case underPaths(hostPath, agent.readPaths):
+				if vm.ReadOnly {
+					continue
+				}
+
+				excess = append(excess, fmt.Sprintf("'%s' is writable", hostPath))
+				raise("medium")
+				if path.Clean(hostPath) == "/" {
+					raise("high")
+				}
+			default:
+				excess = append(excess, fmt.Sprintf("'%s' is not needed", hostPath))
+				raise("medium")
+				if path.Clean(hostPath) == "/" || !vm.ReadOnly {
+					raise("high")
+				}
+			}
+		}
+
+		caps := []string{}
+		privileged := false
+		if sc := c.SecurityContext; sc != nil {
+			if sc.Privileged != nil && *sc.Privileged {
+				privileged = true
+				excess = append(excess, "privileged")
+				raise("high")
+			}
+
+			if sc.Capabilities != nil {
+				for _, capability := range sc.Capabilities.Add {
+					caps = append(caps, string(capability))
+				}
+			}
+		}
+
+		if len(caps) > 0 {
+			excess = append(excess, fmt.Sprintf("capabilities %s", strings.Join(caps, ", ")))
+			raise("medium")
+		}
+
+		if podSpec.HostPID && !agent.hostPID {
+			excess = append(excess, "hostPID")
+			raise("medium")
+		}
+
+		if podSpec.HostNetwork && !agent.hostNetwork {
+			excess = append(excess, "hostNetwork")
+		}
+
+		if len(excess) < 1 {
+			continue
+		}
+
+		if len(caps) < 1 {
+			caps = append(caps, "none")
+		}
+
+		th := &threat{
+			Param: fmt.Sprintf("DaemonSet: %s | namespace: %s | agent: %s | sidecar name: %s",
+				da.Name, da.Namespace, agent.name, c.Name),
+			Value: fmt.Sprintf("mounts: %s | capabilities: %s | privileged: %t",
+				strings.Join(mounts, ", "), strings.Join(caps, ", "), privileged),
+			Type: "Node Agent",
+			Describe: fmt.Sprintf("Agent %s requests more access than it needs: %s.",
+				agent.name, strings.Join(excess, "; ")),
+			Remediation: "Mount only the log and metric paths of the agent read-only, " +
+				"and remove `privileged` and the added capabilities.",
+			Severity: severity,
+		}
+
+		tlist = append(tlist, th)
+	}
+
+	return tlist
+}
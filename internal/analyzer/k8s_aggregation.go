@@ -0,0 +1,256 @@
+package analyzer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	rv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// aggregatedRole is the union of the rules aggregated into a clusterrole,
+// sources are the paths to the source clusterroles, e.g. `monitoring <- monitoring-endpoints`
+type aggregatedRole struct {
+	rules   []rv1.PolicyRule
+	sources map[string][]rv1.PolicyRule
+}
+
+// adminPaths get the paths to the sources giving the rules of admin-equivalent permission
+func (ar *aggregatedRole) adminPaths(namespaced bool) []string {
+	paths := []string{}
+	for path, rules := range ar.sources {
+		for _, rul := range rules {
+			if isAdminRule(rul, namespaced) {
+				paths = append(paths, path)
+				break
+			}
+		}
+	}
+	sort.Strings(paths)
+
+	return paths
+}
+
+// checkAggregatedRoles check the subjects which get the admin-equivalent permission
+// through the aggregation rules of clusterroles
+func (ks *KScanner) checkAggregatedRoles() error {
+	clr, err := ks.listClusterRoles()
+	if err != nil {
+		return err
+	}
+
+	aggregated := map[string]*aggregatedRole{}
+	for _, r := range clr.Items {
+		if r.AggregationRule == nil {
+			continue
+		}
+
+		ar := &aggregatedRole{sources: map[string][]rv1.PolicyRule{}}
+		resolveAggregatedRules(r, clr.Items, map[string]bool{r.Name: true}, r.Name, ar)
+
+		// The permission in a namespace is a part of the one of the whole cluster
+		if isAdminEquivalent(ar.rules, false) {
+			aggregated[r.Name] = ar
+		}
+	}
+
+	if len(aggregated) < 1 {
+		return nil
+	}
+
+	clrb, err := ks.listClusterRoleBindings()
+	if err != nil {
+		return err
+	}
+
+	for _, rb := range clrb.Items {
+		ks.addAggregatedThreats("ClusterRoleBinding", rb.Name, "", rb.RoleRef, rb.Subjects, aggregated)
+	}
+
+	rbs, err := ks.listRoleBindings("")
+	if err != nil {
+		return err
+	}
+
+	for _, rb := range rbs.Items {
+		ks.addAggregatedThreats("RoleBinding", rb.Name, rb.Namespace, rb.RoleRef, rb.Subjects, aggregated)
+	}
+
+	return nil
+}
+
+func (ks *KScanner) addAggregatedThreats(bindingKind, bindingName, ns string, roleRef rv1.RoleRef,
+	subjects []rv1.Subject, aggregated map[string]*aggregatedRole) {
+
+	if roleRef.Kind != "ClusterRole" {
+		return
+	}
+
+	ar, ok := aggregated[roleRef.Name]
+	if !ok {
+		return
+	}
+
+	// Rolebinding grants the namespaced resources of the clusterrole in its namespace only
+	namespaced := ns != ""
+	if !isAdminEquivalent(ar.rules, namespaced) {
+		return
+	}
+
+	describe := fmt.Sprintf("Clusterrole '%s' is equivalent to cluster-admin through the aggregation rules, "+
+		"the subject can take over the whole cluster.", roleRef.Name)
+	severity := "critical"
+	if namespaced {
+		describe = fmt.Sprintf("Clusterrole '%s' grants all the permissions through the aggregation rules, "+
+			"the subject takes full control of the namespace '%s'.", roleRef.Name, ns)
+		severity = "high"
+	}
+
+	for _, sub := range subjects {
+		param := fmt.Sprintf("binding name: %s | rolename: %s | role kind: ClusterRole "+
+			"| subject kind: %s | subject name: %s", bindingName, roleRef.Name, sub.Kind, sub.Name)
+		if namespaced {
+			param += fmt.Sprintf(" | namespace: %s", ns)
+		}
+
+		th := &threat{
+			Param:       param,
+			Value:       fmt.Sprintf("aggregation: %s", strings.Join(ar.adminPaths(namespaced), ", ")),
+			Type:        bindingKind,
+			Describe:    describe,
+			Remediation: "Remove the aggregation labels of the admin-equivalent rules, or narrow the aggregated clusterroles.",
+			Severity:    severity,
+		}
+
+		ks.VulnConfigures = append(ks.VulnConfigures, th)
+	}
+}
+
+// resolveAggregatedRules resolve the aggregation rules of clusterrole recursively,
+// and collect the rules of the source clusterroles by the paths to them
+func resolveAggregatedRules(role rv1.ClusterRole, roles []rv1.ClusterRole, visited map[string]bool,
+	path string, ar *aggregatedRole) {
+
+	if role.AggregationRule == nil {
+		return
+	}
+
+	for _, sel := range role.AggregationRule.ClusterRoleSelectors {
+		selector, err := metav1.LabelSelectorAsSelector(&sel)
+		if err != nil {
+			continue
+		}
+
+		for _, r := range roles {
+			if visited[r.Name] || !selector.Matches(labels.Set(r.Labels)) {
+				continue
+			}
+
+			visited[r.Name] = true
+			source := fmt.Sprintf("%s <- %s", path, r.Name)
+
+			// Rules of the aggregated clusterrole are filled by controller,
+			// only the sources are taken into account
+			if r.AggregationRule == nil {
+				ar.rules = append(ar.rules, r.Rules...)
+				ar.sources[source] = r.Rules
+				continue
+			}
+
+			resolveAggregatedRules(r, roles, visited, source, ar)
+		}
+	}
+}
+
+// ruleAllows check whether the rule allows any of the verbs on the resource of the api group,
+// the rule restricted by the resource names is not taken into account
+func ruleAllows(rul rv1.PolicyRule, group, resource string, verbs ...string) bool {
+	if len(rul.ResourceNames) > 0 {
+		return false
+	}
+
+	contains := func(items []string, targets ...string) bool {
+		for _, i := range items {
+			if i == "*" {
+				return true
+			}
+			for _, t := range targets {
+				if i == t {
+					return true
+				}
+			}
+		}
+
+		return false
+	}
+
+	return contains(rul.APIGroups, group) && contains(rul.Resources, resource) && contains(rul.Verbs, verbs...)
+}
+
+// isAdminRule check whether the rule takes part in the admin-equivalent permission
+func isAdminRule(rul rv1.PolicyRule, namespaced bool) bool {
+	if isWildcardRule(rul) {
+		return true
+	}
+
+	if namespaced {
+		return false
+	}
+
+	return ruleAllows(rul, "", "users", "impersonate") || ruleAllows(rul, "", "groups", "impersonate") ||
+		ruleAllows(rul, "rbac.authorization.k8s.io", "clusterroles", "escalate", "bind") ||
+		ruleAllows(rul, "rbac.authorization.k8s.io", "clusterrolebindings", "create", "update", "patch")
+}
+
+// isWildcardRule check whether the rule allows all the verbs on all the resources of all the api groups
+func isWildcardRule(rul rv1.PolicyRule) bool {
+	for _, fields := range [][]string{rul.APIGroups, rul.Resources, rul.Verbs} {
+		wildcard := false
+		for _, f := range fields {
+			if f == "*" {
+				wildcard = true
+			}
+		}
+
+		if !wildcard {
+			return false
+		}
+	}
+
+	return len(rul.ResourceNames) < 1
+}
+
+// isAdminEquivalent check whether the union of rules is equivalent to cluster-admin.
+// Besides the wildcard permission, the cluster is taken over by impersonating any user or group
+// such as system:masters, or by binding any clusterrole to itself.
+// In a namespace, only the wildcard permission gives the full control of it
+func isAdminEquivalent(rules []rv1.PolicyRule, namespaced bool) bool {
+	allows := func(group, resource string, verbs ...string) bool {
+		for _, rul := range rules {
+			if ruleAllows(rul, group, resource, verbs...) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	for _, rul := range rules {
+		if isWildcardRule(rul) {
+			return true
+		}
+	}
+
+	if namespaced {
+		return false
+	}
+
+	if allows("", "users", "impersonate") || allows("", "groups", "impersonate") {
+		return true
+	}
+
+	return allows("rbac.authorization.k8s.io", "clusterroles", "escalate", "bind") &&
+		allows("rbac.authorization.k8s.io", "clusterrolebindings", "create", "update", "patch")
+}
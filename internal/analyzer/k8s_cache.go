@@ -0,0 +1,157 @@
+package analyzer
+
+import (
+	"context"
+	"sync"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	rv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// listCache keeps the results of listing the kubernetes objects in a scan,
+// the objects are fetched once and shared by the checks
+type listCache struct {
+	mu    sync.Mutex
+	lists map[string]interface{}
+
+	// lists are filled from the manifests,
+	// the missing ones are empty instead of requesting API server
+	static bool
+}
+
+func newListCache() *listCache {
+	return &listCache{lists: map[string]interface{}{}}
+}
+
+func newStaticListCache() *listCache {
+	return &listCache{lists: map[string]interface{}{}, static: true}
+}
+
+// cachedList return the cached list of the key,
+// the list is fetched and cached if it is missing or the scan has no cache
+func cachedList[T any](c *listCache, key string, fetch func() (*T, error)) (*T, error) {
+	if c == nil {
+		return fetch()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if list, ok := c.lists[key]; ok {
+		return list.(*T), nil
+	}
+
+	if c.static {
+		return new(T), nil
+	}
+
+	list, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+	c.lists[key] = list
+
+	return list, nil
+}
+
+func (ks *KScanner) listNamespaces() (*v1.NamespaceList, error) {
+	return cachedList(ks.cache, "namespaces", func() (*v1.NamespaceList, error) {
+		list, err := ks.KClient.CoreV1().Namespaces().List(context.TODO(), metav1.ListOptions{})
+		ks.run.swallow(err)
+
+		return list, err
+	})
+}
+
+func (ks *KScanner) listNodes() (*v1.NodeList, error) {
+	return cachedList(ks.cache, "nodes", func() (*v1.NodeList, error) {
+		list, err := ks.KClient.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+		ks.run.swallow(err)
+
+		return list, err
+	})
+}
+
+func (ks *KScanner) listPods(ns string) (*v1.PodList, error) {
+	return cachedList(ks.cache, "pods/"+ns, func() (*v1.PodList, error) {
+		list, err := ks.KClient.CoreV1().Pods(ns).List(context.TODO(), metav1.ListOptions{})
+		ks.run.swallow(err)
+
+		return list, err
+	})
+}
+
+func (ks *KScanner) listSecrets(ns string) (*v1.SecretList, error) {
+	return cachedList(ks.cache, "secrets/"+ns, func() (*v1.SecretList, error) {
+		list, err := ks.KClient.CoreV1().Secrets(ns).List(context.TODO(), metav1.ListOptions{})
+		ks.run.swallow(err)
+
+		return list, err
+	})
+}
+
+func (ks *KScanner) listConfigMaps(ns string) (*v1.ConfigMapList, error) {
+	return cachedList(ks.cache, "configmaps/"+ns, func() (*v1.ConfigMapList, error) {
+		list, err := ks.KClient.CoreV1().ConfigMaps(ns).List(context.TODO(), metav1.ListOptions{})
+		ks.run.swallow(err)
+
+		return list, err
+	})
+}
+
+func (ks *KScanner) listDeployments(ns string) (*appsv1.DeploymentList, error) {
+	return cachedList(ks.cache, "deployments/"+ns, func() (*appsv1.DeploymentList, error) {
+		list, err := ks.KClient.AppsV1().Deployments(ns).List(context.TODO(), metav1.ListOptions{})
+		ks.run.swallow(err)
+
+		return list, err
+	})
+}
+
+func (ks *KScanner) listPodDisruptionBudgets(ns string) (*policyv1.PodDisruptionBudgetList, error) {
+	return cachedList(ks.cache, "poddisruptionbudgets/"+ns, func() (*policyv1.PodDisruptionBudgetList, error) {
+		list, err := ks.KClient.PolicyV1().PodDisruptionBudgets(ns).List(context.TODO(), metav1.ListOptions{})
+		ks.run.swallow(err)
+
+		return list, err
+	})
+}
+
+func (ks *KScanner) listRoles(ns string) (*rv1.RoleList, error) {
+	return cachedList(ks.cache, "roles/"+ns, func() (*rv1.RoleList, error) {
+		list, err := ks.KClient.RbacV1().Roles(ns).List(context.TODO(), metav1.ListOptions{})
+		ks.run.swallow(err)
+
+		return list, err
+	})
+}
+
+func (ks *KScanner) listRoleBindings(ns string) (*rv1.RoleBindingList, error) {
+	return cachedList(ks.cache, "rolebindings/"+ns, func() (*rv1.RoleBindingList, error) {
+		list, err := ks.KClient.RbacV1().RoleBindings(ns).List(context.TODO(), metav1.ListOptions{})
+		ks.run.swallow(err)
+
+		return list, err
+	})
+}
+
+func (ks *KScanner) listClusterRoles() (*rv1.ClusterRoleList, error) {
+	return cachedList(ks.cache, "clusterroles", func() (*rv1.ClusterRoleList, error) {
+		list, err := ks.KClient.RbacV1().ClusterRoles().List(context.TODO(), metav1.ListOptions{})
+		ks.run.swallow(err)
+
+		return list, err
+	})
+}
+
+func (ks *KScanner) listClusterRoleBindings() (*rv1.ClusterRoleBindingList, error) {
+	return cachedList(ks.cache, "clusterrolebindings", func() (*rv1.ClusterRoleBindingList, error) {
+		list, err := ks.KClient.RbacV1().ClusterRoleBindings().List(context.TODO(), metav1.ListOptions{})
+		ks.run.swallow(err)
+
+		return list, err
+	})
+}
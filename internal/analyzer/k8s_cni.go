@@ -27,10 +27,10 @@ import (
 func (ks *KScanner) checkCNI() error {
 
 	// Init database
-	vulnCli := vulnlib.Client{}
+	vulnCli := vulnlib.Client{Fuzzy: ks.fuzzyMatch}
 	err := vulnCli.Init()
 	if err != nil {
-		log.Printf("init database failed, %v", err)
+		config.ErrorLog.Printf("init database failed, %v", err)
 	}
 
 	// Check Envoy configuration
@@ -168,8 +168,9 @@ func checkEnvoy() (bool, []*threat) {
 				Type:  "Envoy",
 				Describe: fmt.Sprintf("Envoy admin is activated and exposed to '%s:%s', "+
 					"which includes sensitive api and unauthorized.", address, port),
-				Reference: "https://www.envoyproxy.io/docs/envoy/latest/operations/admin#administration-interface",
-				Severity:  "medium",
+				Reference:   "https://www.envoyproxy.io/docs/envoy/latest/operations/admin#administration-interface",
+				Remediation: "Bind the Envoy admin interface to 127.0.0.1 only.",
+				Severity:    "medium",
 			}
 
 			if address == "0.0.0.0" {
@@ -201,7 +202,8 @@ func (ks KScanner) checkIstio(vulnCli vulnlib.Client) (bool, []*threat) {
 			return vuln, tlist
 		}
 
-		log.Printf("check istio version failed, %v", err)
+		ks.run.swallow(err)
+		config.ErrorLog.Printf("check istio version failed, %v", err)
 		return vuln, tlist
 	}
 
@@ -221,7 +223,7 @@ func (ks KScanner) checkIstio(vulnCli vulnlib.Client) (bool, []*threat) {
 
 	rows, err := vulnCli.QueryVulnByName("istio")
 	if err != nil {
-		log.Printf("check envoy version failed, %v", err)
+		config.ErrorLog.Printf("check envoy version failed, %v", err)
 		return vuln, tlist
 	}
 
@@ -235,12 +237,13 @@ func (ks KScanner) checkIstio(vulnCli vulnlib.Client) (bool, []*threat) {
 			}
 
 			th := &threat{
-				Param:     "Istio version",
-				Value:     istioVersion,
-				Type:      "Istio",
-				Describe:  description,
-				Reference: fmt.Sprintf("https://nvd.nist.gov/vuln/detail/%s", row.CVEID),
-				Severity:  row.Level,
+				Param:       "Istio version",
+				Value:       istioVersion,
+				Type:        "Istio",
+				Describe:    description,
+				Reference:   fmt.Sprintf("https://nvd.nist.gov/vuln/detail/%s", row.CVEID),
+				Remediation: "Upgrade Istio to a version without the vulnerability.",
+				Severity:    row.Level,
 			}
 
 			tlist = append(tlist, th)
@@ -290,6 +293,7 @@ func (ks KScanner) checkIstioHeader(podname, ns, cname string) (bool, []*threat)
 	})
 
 	if err != nil {
+		ks.run.swallow(err)
 		return vuln, tlist
 	}
 
@@ -306,8 +310,9 @@ func (ks KScanner) checkIstioHeader(podname, ns, cname string) (bool, []*threat)
 			Type:  "Istio",
 			Describe: "Istio detected and request header " +
 				"is leaking sensitive information",
-			Reference: "https://github.com/istio/istio/issues/17635",
-			Severity:  "low",
+			Reference:   "https://github.com/istio/istio/issues/17635",
+			Remediation: "Remove the `X-Envoy-Peer-Metadata` headers from the outbound requests by an EnvoyFilter.",
+			Severity:    "low",
 		}
 
 		tlist = append(tlist, th)
@@ -333,7 +338,8 @@ func (ks KScanner) checkCilium(vulnCli vulnlib.Client) (bool, []*threat) {
 			return vuln, tlist
 		}
 
-		log.Printf("check envoy version failed, %v", err)
+		ks.run.swallow(err)
+		config.ErrorLog.Printf("check envoy version failed, %v", err)
 		return vuln, tlist
 	}
 
@@ -352,7 +358,7 @@ func (ks KScanner) checkCilium(vulnCli vulnlib.Client) (bool, []*threat) {
 	ciliumVersion := versionMatch[2][1:]
 	rows, err := vulnCli.QueryVulnByName("cilium")
 	if err != nil {
-		log.Printf("check envoy version failed, %v", err)
+		config.ErrorLog.Printf("check envoy version failed, %v", err)
 		return vuln, tlist
 	}
 
@@ -366,12 +372,13 @@ func (ks KScanner) checkCilium(vulnCli vulnlib.Client) (bool, []*threat) {
 			}
 
 			th := &threat{
-				Param:     "Cilium version",
-				Value:     ciliumVersion,
-				Type:      "Cilium",
-				Describe:  description,
-				Reference: fmt.Sprintf("https://nvd.nist.gov/vuln/detail/%s", row.CVEID),
-				Severity:  row.Level,
+				Param:       "Cilium version",
+				Value:       ciliumVersion,
+				Type:        "Cilium",
+				Describe:    description,
+				Reference:   fmt.Sprintf("https://nvd.nist.gov/vuln/detail/%s", row.CVEID),
+				Remediation: "Upgrade Cilium to a version without the vulnerability.",
+				Severity:    row.Level,
 			}
 
 			tlist = append(tlist, th)
@@ -413,7 +420,8 @@ func checkKubelet() (bool, []*threat) {
 					Type:  "Kubelet",
 					Describe: "Kubelet 'read-only-port' is opened and unauthorized, " +
 						"which has a sensitive data leakage.",
-					Severity: "high",
+					Remediation: "Set `--read-only-port=0` for kubelet.",
+					Severity:    "high",
 				}
 
 				tlist = append(tlist, th)
@@ -475,7 +483,8 @@ func checkKubectlProxy() (bool, []*threat) {
 					Describe: fmt.Sprintf("Kubectl proxy command is used "+
 						"and the exposed address is '%s', "+
 						"which will cause unauthorized vulnerability.", address),
-					Severity: "medium",
+					Remediation: "Stop the `kubectl proxy`, or bind it to 127.0.0.1 with `--accept-hosts`.",
+					Severity:    "medium",
 				}
 				tlist = append(tlist, th)
 				vuln = true
@@ -490,7 +499,8 @@ func checkKubectlProxy() (bool, []*threat) {
 				Type:  "Kubectl",
 				Describe: "Kubectl proxy command is used " +
 					"which will cause unauthorized vulnerability.",
-				Severity: "low",
+				Remediation: "Stop the `kubectl proxy`, or bind it to 127.0.0.1 with `--accept-hosts`.",
+				Severity:    "low",
 			}
 
 			tlist = append(tlist, th)
@@ -508,7 +518,7 @@ func (ks KScanner) checkEtcd() (bool, []*threat) {
 	var vuln = false
 	tlist := []*threat{}
 
-	pods, err := ks.KClient.CoreV1().Pods("kube-system").List(context.TODO(), metav1.ListOptions{})
+	pods, err := ks.listPods("kube-system")
 	if err != nil {
 		return vuln, tlist
 	}
@@ -542,7 +552,8 @@ func (ks KScanner) checkEtcd() (bool, []*threat) {
 			Type:  "Etcd",
 			Describe: "Etcd config lacks `client-cert-auth`, " +
 				"which has a potential container escape.",
-			Severity: "high",
+			Remediation: "Set `--client-cert-auth=true` for etcd.",
+			Severity:    "high",
 		}
 
 		if !configs["peer-client-cert-auth"] {
@@ -562,8 +573,9 @@ func (ks KScanner) checkEtcd() (bool, []*threat) {
 			Describe: "Etcd config lacks `peer-client-cert-auth`. " +
 				"All peers attempting to communicate with the etcd server " +
 				"will require a valid client certificate for authentication.",
-			Reference: "https://workbench.cisecurity.org/files/3371",
-			Severity:  "medium",
+			Reference:   "https://workbench.cisecurity.org/files/3371",
+			Remediation: "Set `--peer-client-cert-auth=true` for etcd.",
+			Severity:    "medium",
 		}
 
 		tlist = append(tlist, th)
@@ -13,6 +13,7 @@ import (
 	"github.com/kvesta/vesta/pkg/inspector"
 	"github.com/kvesta/vesta/pkg/osrelease"
 	"github.com/kvesta/vesta/pkg/vulnlib"
+	batchv1 "k8s.io/api/batch/v1"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/tools/clientcmd"
@@ -20,9 +21,7 @@ import (
 )
 
 func (ks *KScanner) getNodeInfor(ctx context.Context) error {
-	nodes, err := ks.KClient.
-		CoreV1().
-		Nodes().List(context.TODO(), metav1.ListOptions{})
+	nodes, err := ks.listNodes()
 	if err != nil {
 		return err
 	}
@@ -37,7 +36,7 @@ func (ks *KScanner) getNodeInfor(ctx context.Context) error {
 		for role, _ := range node.Labels {
 			if strings.HasPrefix(role, "node-role.kubernetes") {
 				roleName := strings.Split(role, "/")[1]
-				if roleName == "master" {
+				if roleName == "master" || roleName == "control-plane" {
 					rolesInfo.IsMaster = true
 				}
 				roles = append(roles, roleName)
@@ -62,21 +61,29 @@ func (ks *KScanner) dockershimCheck(ctx context.Context) error {
 		DCli: cli,
 	}
 
-	vulnCli := vulnlib.Client{}
+	vulnCli := vulnlib.Client{Fuzzy: ks.fuzzyMatch}
 	err = vulnCli.Init()
 	if err != nil {
 		return err
 	}
 
 	serverVersion, _ := c.GetDockerServerVersion(ctx)
+
+	// Users of the local images are correlated with the containers of pods
+	if images, err := c.GetAllImage(); err == nil {
+		ks.imageUsers = getImageUsers(images)
+	}
 	c.DCli.Close()
 
 	// Checking kernel version
 	kernelVersion, err := osrelease.GetKernelVersion(context.Background())
 	if err != nil {
-		log.Printf("failed to get kernel version: %v", err)
+		config.ErrorLog.Printf("failed to get kernel version: %v", err)
 	}
 
+	ks.netRawKernel = checkNetRawKernel(vulnCli, kernelVersion)
+	ks.cgroupKernel = checkCgroupKernel(vulnCli, kernelVersion)
+
 	if ok, tlist := checkKernelVersion(vulnCli, kernelVersion); ok {
 		ks.VulnConfigures = append(ks.VulnConfigures, tlist...)
 	}
@@ -89,6 +96,24 @@ func (ks *KScanner) dockershimCheck(ctx context.Context) error {
 	return nil
 }
 
+// getImageUsers get the users configured in the images keyed by the tags of image
+func getImageUsers(images []*inspector.ImageInfo) map[string]string {
+	users := map[string]string{}
+
+	for _, img := range images {
+		user, known := imageUser(img)
+		if !known {
+			continue
+		}
+
+		for _, tag := range img.Summary.RepoTags {
+			users[tag] = user
+		}
+	}
+
+	return users
+}
+
 // kernelCheck get /proc/version directly for non-Docker-Desktop
 func (ks *KScanner) kernelCheck(ctx context.Context) error {
 
@@ -99,13 +124,15 @@ func (ks *KScanner) kernelCheck(ctx context.Context) error {
 		return err
 	}
 
-	vulnCli := vulnlib.Client{}
+	vulnCli := vulnlib.Client{Fuzzy: ks.fuzzyMatch}
 	err = vulnCli.Init()
 	if err != nil {
 		return err
 	}
 
 	kernelVersion := osrelease.KernelParse(string(stdout))
+	ks.netRawKernel = checkNetRawKernel(vulnCli, kernelVersion)
+	ks.cgroupKernel = checkCgroupKernel(vulnCli, kernelVersion)
 
 	if ok, tlist := checkKernelVersion(vulnCli, kernelVersion); ok {
 		for _, th := range tlist {
@@ -126,42 +153,60 @@ func (ks *KScanner) checkPersistentVolume() error {
 		PersistentVolumes().
 		List(context.TODO(), metav1.ListOptions{})
 	if err != nil {
-		log.Printf("list persistentvolumes failed: %v", err)
+		config.ErrorLog.Printf("list persistentvolumes failed: %v", err)
 		return err
 	}
 	for _, pv := range pvs.Items {
-
-		// Check whether using the host mount
-		if pv.Spec.HostPath == nil {
-			continue
+		if th := getHostPathPVThreat(pv); th != nil {
+			tlist = append(tlist, th)
 		}
+	}
+	ks.VulnConfigures = append(ks.VulnConfigures, tlist...)
+	return nil
+}
 
-		//pvPath := filepath.Dir(pv.Spec.HostPath.Path)
-		pvPath := pv.Spec.HostPath.Path
+// getHostPathPVThreat get the threat of the persistent volume backed by hostPath,
+// it returns nil if the persistent volume uses other sources
+func getHostPathPVThreat(pv v1.PersistentVolume) *threat {
+	if pv.Spec.HostPath == nil {
+		return nil
+	}
 
-		if isVuln := checkMountPath(pvPath); isVuln {
-			th := &threat{
-				Param: pv.Name,
-				Value: pvPath,
-				Type:  "PersistentVolume",
-				Describe: fmt.Sprintf("Mount path '%s' is suffer vulnerable of "+
-					"container escape and it is in using", pvPath),
-				Severity: "critical",
-			}
+	pvPath := pv.Spec.HostPath.Path
 
-			// Check whether it is in using
-			if pv.Status.Phase != "Bound" {
-				th.Severity = "medium"
-				th.Describe = fmt.Sprintf("Mount path '%s' is suffer vulnerable of "+
-					"container escape but the status is '%s'", pvPath, pv.Status.Phase)
-			}
+	th := &threat{
+		Param: pv.Name,
+		Value: pvPath,
+		Type:  "PersistentVolume",
+		Describe: fmt.Sprintf("PersistentVolume is backed by the host path '%s', "+
+			"it bypasses the storage isolation and binds the pods to the filesystem of node.", pvPath),
+		Remediation: "Replace the hostPath persistent volume with a storage class, or restrict the path to a dedicated directory.",
+		Severity:    "medium",
+	}
 
-			tlist = append(tlist, th)
-		}
+	// Root and system paths lead to the container escape
+	if checkMountPath(pvPath) {
+		th.Severity = "critical"
+		th.Describe = fmt.Sprintf("PersistentVolume is backed by the host path '%s', "+
+			"it is suffer vulnerable of container escape.", pvPath)
+	}
 
+	if pv.Spec.ClaimRef != nil {
+		th.Describe += fmt.Sprintf(" It is claimed by '%s/%s'.", pv.Spec.ClaimRef.Namespace, pv.Spec.ClaimRef.Name)
 	}
-	ks.VulnConfigures = append(ks.VulnConfigures, tlist...)
-	return nil
+
+	// Check whether it is in using
+	if pv.Status.Phase != v1.VolumeBound {
+		th.Describe += fmt.Sprintf(" The status is '%s'.", pv.Status.Phase)
+
+		if th.Severity == "critical" {
+			th.Severity = "medium"
+		} else {
+			th.Severity = "low"
+		}
+	}
+
+	return th
 }
 
 type RBACVuln struct {
@@ -176,34 +221,59 @@ func (ks *KScanner) checkPod(ns string) error {
 		return ks.checkKuberDashboard()
 	}
 
-	pods, err := ks.KClient.
-		CoreV1().
-		Pods(ns).
-		List(context.TODO(), metav1.ListOptions{})
+	pods, err := ks.listPods(ns)
 	if err != nil {
 		return err
 	}
 
 	rv := ks.getRBACVulnType(ns)
 
+	var dependencies string
+	if ks.incremental != nil {
+		dependencies = ks.dependencyVersion(ns)
+	}
+
+	// Collapse the identical findings of replicas into one
+	collapsed := map[string]*container{}
+
 	for _, pod := range pods.Items {
 
-		vList := ks.podAnalyze(pod.Spec, rv, ns, pod.Name)
+		ownerKind, ownerName := ks.getPodOwner(pod)
 
-		// Check pod annotations
-		if ok, tlist := checkPodAnnotation(pod.Annotations); ok {
-			vList = append(vList, tlist...)
+		// Findings depend on the pod, the RBAC of namespace and the objects referenced by the pod
+		key := fmt.Sprintf("Pod/%s/%s", ns, pod.Name)
+		version := fmt.Sprintf("%s|%s|%s|%s|%s", pod.ResourceVersion, rv.Severity, rv.RoleBinding, rv.ClusterRoleBinding,
+			dependencies)
+
+		vList, unchanged := ks.incremental.reuse(key, version)
+		if !unchanged {
+			vList = ks.analyzePod(pod, rv, ns, ownerKind)
 		}
+		ks.incremental.record(key, version, vList)
 
 		if len(vList) > 0 {
 			sortSeverity(vList)
+
+			fingerprint := getFingerprint(ns, ownerKind, ownerName, vList)
+
+			if con, ok := collapsed[fingerprint]; ok {
+				con.Replicas += 1
+				continue
+			}
+
 			con := &container{
 				ContainerName: pod.Name,
 				Namepsace:     ns,
 				Status:        string(pod.Status.Phase),
 				NodeName:      pod.Spec.NodeName,
+				OwnerKind:     ownerKind,
+				OwnerName:     ownerName,
+				Replicas:      1,
+				Fingerprint:   fingerprint,
 				Threats:       vList,
 			}
+			con.HelmRelease, con.HelmChart = ks.getPodHelmRelease(pod.ObjectMeta, ownerKind, ownerName)
+			collapsed[fingerprint] = con
 			ks.VulnContainers = append(ks.VulnContainers, con)
 		}
 
@@ -212,6 +282,42 @@ func (ks *KScanner) checkPod(ns string) error {
 	return nil
 }
 
+// analyzePod run the pod-level checks against the running pod
+func (ks *KScanner) analyzePod(pod v1.Pod, rv RBACVuln, ns, ownerKind string) []*threat {
+	vList := ks.podAnalyze(pod.Spec, pod.Annotations, rv, ns, pod.Name)
+
+	// Check pod annotations
+	if ok, tlist := checkPodAnnotation(pod.Annotations); ok {
+		vList = append(vList, tlist...)
+	}
+
+	if ok, tlist := checkPodBlocklist(pod, ks.blocklist); ok {
+		vList = append(vList, tlist...)
+	}
+
+	if ok, tlist := checkPodRestarts(pod); ok {
+		vList = append(vList, tlist...)
+	}
+
+	// System components are expected on the control-plane nodes
+	if !isSystemNamespace(ns) {
+		if ok, tlist := checkPodScheduling(pod, ks.MasterNodes); ok {
+			vList = append(vList, tlist...)
+		}
+	}
+
+	// Only the long-running workloads need probes, jobs and one-shot pods are exempt
+	if ownerKind == "Deployment" || ownerKind == "StatefulSet" {
+		for _, sp := range pod.Spec.Containers {
+			if ok, tlist := checkPodProbes(sp); ok {
+				vList = append(vList, tlist...)
+			}
+		}
+	}
+
+	return vList
+}
+
 func (ks *KScanner) checkDaemonSet(ns string) error {
 	das, err := ks.KClient.
 		AppsV1().
@@ -226,6 +332,9 @@ func (ks *KScanner) checkDaemonSet(ns string) error {
 
 	for _, da := range das.Items {
 
+		// Logging and monitoring agents are audited for the least privilege
+		ks.VulnConfigures = append(ks.VulnConfigures, checkAgentPrivilege(da)...)
+
 		p := v1.Pod{}
 
 		for k, v := range da.Spec.Selector.MatchLabels {
@@ -238,6 +347,7 @@ func (ks *KScanner) checkDaemonSet(ns string) error {
 					})
 
 			if err != nil {
+				ks.run.swallow(err)
 				continue
 			}
 
@@ -248,7 +358,7 @@ func (ks *KScanner) checkDaemonSet(ns string) error {
 			}
 		}
 
-		vList := ks.podAnalyze(da.Spec.Template.Spec, rv, ns, p.Name)
+		vList := ks.podAnalyze(da.Spec.Template.Spec, da.Spec.Template.Annotations, rv, ns, p.Name)
 
 		if len(vList) > 0 {
 
@@ -266,11 +376,12 @@ func (ks *KScanner) checkDaemonSet(ns string) error {
 			}
 
 			th := &threat{
-				Param:    fmt.Sprintf("name: %s | namespace: %s", da.Name, da.Namespace),
-				Value:    fmt.Sprintf("images: %s", containerImages),
-				Type:     "DaemonSet",
-				Describe: fmt.Sprintf("Daemonset has set the unsafe pod \"%s\".", p.Name),
-				Severity: severity,
+				Param:       fmt.Sprintf("name: %s | namespace: %s", da.Name, da.Namespace),
+				Value:       fmt.Sprintf("images: %s", containerImages),
+				Type:        "DaemonSet",
+				Describe:    fmt.Sprintf("Daemonset has set the unsafe pod \"%s\".", p.Name),
+				Remediation: "Fix the findings of the pod template in the DaemonSet.",
+				Severity:    severity,
 			}
 
 			ks.VulnConfigures = append(ks.VulnConfigures, th)
@@ -278,8 +389,13 @@ func (ks *KScanner) checkDaemonSet(ns string) error {
 			// Check the results whether the daemonset pod has been checked
 			isChecked := false
 			for _, vulnPod := range ks.VulnContainers {
-				if vulnPod.ContainerName == p.Name &&
-					vulnPod.Namepsace == da.Namespace {
+				if vulnPod.Namepsace != da.Namespace {
+					continue
+				}
+
+				// Replicas of daemonset may have been collapsed into another pod
+				if vulnPod.ContainerName == p.Name ||
+					(vulnPod.OwnerKind == "DaemonSet" && vulnPod.OwnerName == da.Name) {
 					isChecked = true
 
 					break
@@ -292,6 +408,10 @@ func (ks *KScanner) checkDaemonSet(ns string) error {
 					Namepsace:     da.Namespace,
 					Status:        string(p.Status.Phase),
 					NodeName:      p.Spec.NodeName,
+					OwnerKind:     "DaemonSet",
+					OwnerName:     da.Name,
+					Replicas:      1,
+					Fingerprint:   getFingerprint(da.Namespace, "DaemonSet", da.Name, vList),
 					Threats:       vList,
 				}
 
@@ -304,8 +424,8 @@ func (ks *KScanner) checkDaemonSet(ns string) error {
 	return nil
 }
 
-// checkJobsOrCornJob check job and cronjob whether have malicious command
-func (ks *KScanner) checkJobsOrCornJob(ns string) error {
+// checkJobs check job whether have malicious command
+func (ks *KScanner) checkJobs(ns string) error {
 	jobs, err := ks.KClient.
 		BatchV1().
 		Jobs(ns).
@@ -313,7 +433,7 @@ func (ks *KScanner) checkJobsOrCornJob(ns string) error {
 
 	if err != nil {
 		if strings.Contains(err.Error(), "could not find the requested resource") {
-			goto cronJob
+			return nil
 		}
 
 		return err
@@ -330,19 +450,23 @@ func (ks *KScanner) checkJobsOrCornJob(ns string) error {
 			}
 
 			th := &threat{
-				Type:     "Job",
-				Param:    fmt.Sprintf("Job Name: %s Namespace: %s", job.Name, ns),
-				Value:    fmt.Sprintf("Command: %s", command),
-				Describe: fmt.Sprintf("Active job %s is not setting any security policy.", job.Name),
-				Severity: "low",
+				Type:        "Job",
+				Param:       fmt.Sprintf("Job Name: %s Namespace: %s", job.Name, ns),
+				Value:       fmt.Sprintf("Command: %s", command),
+				Describe:    fmt.Sprintf("Active job %s is not setting any security policy.", job.Name),
+				Remediation: "Set `securityContext` of the job with `runAsNonRoot: true` and `allowPrivilegeEscalation: false`.",
+				Severity:    "low",
 			}
 
 			ks.VulnConfigures = append(ks.VulnConfigures, th)
 		}
 	}
 
-cronJob:
+	return nil
+}
 
+// checkCronJobs check cronjob whether have malicious command or privileges
+func (ks *KScanner) checkCronJobs(ns string) error {
 	cronjobs, err := ks.KClient.
 		BatchV1().
 		CronJobs(ns).
@@ -365,18 +489,123 @@ cronJob:
 				Type: "CronJob",
 				Param: fmt.Sprintf("CronJob Name: %s Namespace: %s "+
 					"Schedule: %s", cronjob.Name, ns, cronjob.Spec.Schedule),
-				Value:    fmt.Sprintf("Command: %s", command),
-				Describe: fmt.Sprintf("Active Cronjob %s is not setting any security policy.", cronjob.Name),
-				Severity: "low",
+				Value:       fmt.Sprintf("Command: %s", command),
+				Describe:    fmt.Sprintf("Active Cronjob %s is not setting any security policy.", cronjob.Name),
+				Remediation: "Set `securityContext` of the cronjob with `runAsNonRoot: true` and `allowPrivilegeEscalation: false`.",
+				Severity:    "low",
 			}
 
 			ks.VulnConfigures = append(ks.VulnConfigures, th)
 		}
+
+		ks.VulnConfigures = append(ks.VulnConfigures, checkCronJobPrivileges(cronjob)...)
 	}
 
 	return nil
 }
 
+// checkCronJobPrivileges check the cronjob which is privileged, mounting host paths or running as root,
+// the schedule is given in the value for assessing the frequency
+func checkCronJobPrivileges(cronjob batchv1.CronJob) []*threat {
+	tlist := []*threat{}
+
+	podSpec := cronjob.Spec.JobTemplate.Spec.Template.Spec
+	param := fmt.Sprintf("CronJob Name: %s Namespace: %s", cronjob.Name, cronjob.Namespace)
+	schedule := fmt.Sprintf("schedule: %s", cronjob.Spec.Schedule)
+
+	podRoot := podSpec.SecurityContext != nil &&
+		podSpec.SecurityContext.RunAsUser != nil && *podSpec.SecurityContext.RunAsUser == 0
+
+	privileged, roots := []string{}, []string{}
+
+	containers := append([]v1.Container{}, podSpec.InitContainers...)
+	containers = append(containers, podSpec.Containers...)
+	for _, c := range containers {
+		isRoot := podRoot
+
+		if c.SecurityContext != nil {
+			if c.SecurityContext.Privileged != nil && *c.SecurityContext.Privileged {
+				privileged = append(privileged, c.Name)
+			}
+
+			if c.SecurityContext.RunAsUser != nil {
+				isRoot = *c.SecurityContext.RunAsUser == 0
+			}
+		}
+
+		if isRoot {
+			roots = append(roots, c.Name)
+		}
+	}
+
+	if len(privileged) > 0 {
+		th := &threat{
+			Param: param + " | Privileged",
+			Value: fmt.Sprintf("%s | containers: %s", schedule, strings.Join(privileged, ", ")),
+			Type:  "CronJob",
+			Describe: fmt.Sprintf("Scheduled CronJob %s runs privileged containers, "+
+				"which has a potential container escape.", cronjob.Name),
+			Remediation: "Set `securityContext.privileged: false` in the job template.",
+			Severity:    "critical",
+		}
+
+		tlist = append(tlist, th)
+
+		// Concurrency policy is allowed by default
+		if cronjob.Spec.ConcurrencyPolicy == "" || cronjob.Spec.ConcurrencyPolicy == batchv1.AllowConcurrent {
+			th := &threat{
+				Param: param + " | concurrencyPolicy",
+				Value: fmt.Sprintf("%s | concurrencyPolicy: Allow", schedule),
+				Type:  "CronJob",
+				Describe: fmt.Sprintf("CronJob %s allows the privileged jobs running concurrently, "+
+					"the overlapping jobs can exhaust the node or be abused to escalate.", cronjob.Name),
+				Remediation: "Set `concurrencyPolicy: Forbid` for the cronjob.",
+				Severity:    "high",
+			}
+
+			tlist = append(tlist, th)
+		}
+	}
+
+	for _, vol := range podSpec.Volumes {
+		if vol.HostPath == nil {
+			continue
+		}
+
+		th := &threat{
+			Param:       param + fmt.Sprintf(" | volumes name: %s", vol.Name),
+			Value:       fmt.Sprintf("%s | hostPath: %s", schedule, vol.HostPath.Path),
+			Type:        "CronJob",
+			Describe:    fmt.Sprintf("Scheduled CronJob %s mounts the host path '%s'.", cronjob.Name, vol.HostPath.Path),
+			Remediation: "Remove the hostPath volume from the job template.",
+			Severity:    "medium",
+		}
+
+		if checkMountPath(vol.HostPath.Path) {
+			th.Describe = fmt.Sprintf("Scheduled CronJob %s mounts '%s' which is suffer vulnerable of "+
+				"container escape.", cronjob.Name, vol.HostPath.Path)
+			th.Severity = "critical"
+		}
+
+		tlist = append(tlist, th)
+	}
+
+	if len(roots) > 0 {
+		th := &threat{
+			Param:       param + " | runAsUser",
+			Value:       fmt.Sprintf("%s | containers: %s", schedule, strings.Join(roots, ", ")),
+			Type:        "CronJob",
+			Describe:    fmt.Sprintf("Scheduled CronJob %s runs containers as root.", cronjob.Name),
+			Remediation: "Set `securityContext.runAsNonRoot: true` and a non-zero `runAsUser` in the job template.",
+			Severity:    "medium",
+		}
+
+		tlist = append(tlist, th)
+	}
+
+	return tlist
+}
+
 func (ks *KScanner) checkCerts() error {
 	log.Printf(config.Yellow("Begin cert analyzing"))
 
@@ -397,11 +626,12 @@ func (ks *KScanner) checkCerts() error {
 
 	if expiration.Before(now.AddDate(0, 0, 30)) {
 		th := &threat{
-			Param:    "Kubernetes certificate expiration",
-			Value:    fmt.Sprintf("expire time: %s", expiration.Format("2006-02-01")),
-			Type:     "certification",
-			Describe: "Your certificate will be expired after 30 days.",
-			Severity: "medium",
+			Param:       "Kubernetes certificate expiration",
+			Value:       fmt.Sprintf("expire time: %s", expiration.Format("2006-02-01")),
+			Type:        "certification",
+			Describe:    "Your certificate will be expired after 30 days.",
+			Remediation: "Renew the certificates by `kubeadm certs renew all`.",
+			Severity:    "medium",
 		}
 
 		ks.VulnConfigures = append(ks.VulnConfigures, th)
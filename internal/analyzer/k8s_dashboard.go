@@ -1,21 +1,16 @@
 package analyzer
 
 import (
-	"context"
 	"log"
 
 	rv1 "k8s.io/api/rbac/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 // checkKuberDashboard extra checks Kubernetes dashboard
 func (ks *KScanner) checkKuberDashboard() error {
 	log.Printf("Begin Dashboard analyzing")
 
-	deploys, err := ks.KClient.
-		AppsV1().
-		Deployments("kubernetes-dashboard").
-		List(context.TODO(), metav1.ListOptions{})
+	deploys, err := ks.listDeployments("kubernetes-dashboard")
 	if err != nil {
 		return err
 	}
@@ -28,11 +23,12 @@ func (ks *KScanner) checkKuberDashboard() error {
 		for _, arg := range args {
 			if arg == "--enable-skip-login" {
 				th := &threat{
-					Param:    "Kubernetes-dashboard --args",
-					Value:    "--enable-skip-login",
-					Type:     "Deployment",
-					Describe: "Staring with --enable-skip-login has a potential sensitive data leakage.",
-					Severity: "low",
+					Param:       "Kubernetes-dashboard --args",
+					Value:       "--enable-skip-login",
+					Type:        "Deployment",
+					Describe:    "Staring with --enable-skip-login has a potential sensitive data leakage.",
+					Remediation: "Remove `--enable-skip-login` from the args of dashboard.",
+					Severity:    "low",
 				}
 
 				ks.checkDashboardRBAC(th)
@@ -47,17 +43,11 @@ func (ks *KScanner) checkKuberDashboard() error {
 }
 
 func (ks *KScanner) checkDashboardRBAC(th *threat) {
-	clrb, err := ks.KClient.
-		RbacV1().
-		ClusterRoleBindings().
-		List(context.TODO(), metav1.ListOptions{})
+	clrb, err := ks.listClusterRoleBindings()
 	if err != nil {
 		return
 	}
-	clr, err := ks.KClient.
-		RbacV1().
-		ClusterRoles().
-		List(context.TODO(), metav1.ListOptions{})
+	clr, err := ks.listClusterRoles()
 	if err != nil {
 		return
 	}
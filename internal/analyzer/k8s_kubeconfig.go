@@ -0,0 +1,84 @@
+package analyzer
+
+import (
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/kvesta/vesta/config"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// checkKubeconfigTLS check the clusters of the kubeconfig loaded by vesta
+// for `insecure-skip-tls-verify: true`
+func (ks *KScanner) checkKubeconfigTLS() error {
+	log.Printf(config.Yellow("Begin kubeconfig analyzing"))
+
+	if ks.KConfigPath == "" {
+		if ks.KConfig != nil && ks.KConfig.Insecure {
+			th := getInsecureTLSThreat("in-cluster config", ks.KConfig.Host, true)
+			ks.VulnConfigures = append(ks.VulnConfigures, th)
+		}
+
+		return nil
+	}
+
+	kubeconfig, err := clientcmd.LoadFromFile(ks.KConfigPath)
+	if err != nil {
+		return err
+	}
+
+	host := ""
+	if ks.KConfig != nil {
+		host = ks.KConfig.Host
+	}
+
+	ks.VulnConfigures = append(ks.VulnConfigures, checkKubeconfigClusters(ks.KConfigPath, kubeconfig, host)...)
+
+	return nil
+}
+
+// checkKubeconfigClusters check each cluster of the kubeconfig,
+// the cluster connected by the scan is more severe
+func checkKubeconfigClusters(path string, kubeconfig *clientcmdapi.Config, host string) []*threat {
+	tlist := []*threat{}
+
+	names := []string{}
+	for name := range kubeconfig.Clusters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		cluster := kubeconfig.Clusters[name]
+		if !cluster.InsecureSkipTLSVerify {
+			continue
+		}
+
+		where := fmt.Sprintf("kubeconfig: %s | cluster: %s", path, name)
+		tlist = append(tlist, getInsecureTLSThreat(where, cluster.Server, cluster.Server == host))
+	}
+
+	return tlist
+}
+
+func getInsecureTLSThreat(where, server string, inUse bool) *threat {
+	th := &threat{
+		Param: where,
+		Value: fmt.Sprintf("insecure-skip-tls-verify: true | server: %s", server),
+		Type:  "Kubeconfig",
+		Describe: "TLS verification of the API server is disabled, " +
+			"the credentials can be stolen by the man-in-the-middle attack.",
+		Remediation: "Remove `insecure-skip-tls-verify` and set `certificate-authority-data` of the cluster.",
+		Severity:    "medium",
+	}
+
+	if inUse {
+		th.Describe = "TLS verification of the API server used by the scan is disabled, " +
+			"the credentials can be stolen by the man-in-the-middle attack."
+		th.Severity = "high"
+	}
+
+	return th
+}
@@ -0,0 +1,333 @@
+package analyzer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kvesta/vesta/config"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	yamlutil "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+// Manifest is a kubernetes object decoded from the manifest file
+type Manifest struct {
+	Source string
+	Object runtime.Object
+}
+
+// LoadManifests decode the kubernetes objects from the YAML or JSON files,
+// the directories are walked for the files with the extension .yaml, .yml or .json.
+// Multi-document YAML such as the output of `helm template` is supported
+func LoadManifests(paths []string) ([]*Manifest, error) {
+	manifests := []*Manifest{}
+
+	for _, path := range paths {
+		err := filepath.Walk(path, func(file string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if info.IsDir() {
+				return nil
+			}
+
+			// Files given directly are loaded whatever the extension is
+			ext := strings.ToLower(filepath.Ext(file))
+			if file != path && ext != ".yaml" && ext != ".yml" && ext != ".json" {
+				return nil
+			}
+
+			f, err := os.Open(file)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+
+			objects, err := decodeManifests(f)
+			if err != nil {
+				return fmt.Errorf("%s: %v", file, err)
+			}
+
+			for _, obj := range objects {
+				manifests = append(manifests, &Manifest{Source: file, Object: obj})
+			}
+
+			return nil
+		})
+
+		if err != nil {
+			return manifests, err
+		}
+	}
+
+	return manifests, nil
+}
+
+// decodeManifests decode the documents of the stream,
+// the empty documents and the unknown kinds such as CRDs are skipped
+func decodeManifests(r io.Reader) ([]runtime.Object, error) {
+	objects := []runtime.Object{}
+	decoder := scheme.Codecs.UniversalDeserializer()
+	reader := yamlutil.NewYAMLReader(bufio.NewReader(r))
+
+	for i := 1; ; i++ {
+		doc, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return objects, err
+		}
+
+		if isEmptyDocument(doc) {
+			continue
+		}
+
+		obj, gvk, err := decoder.Decode(doc, nil, nil)
+		if err != nil {
+			if runtime.IsNotRegisteredError(err) {
+				config.ErrorLog.Printf("unknown kind in document %d is skipped: %v", i, err)
+				continue
+			}
+
+			return objects, fmt.Errorf("document %d: %v", i, err)
+		}
+
+		if gvk.Kind == "List" {
+			list := obj.(*v1.List)
+			for _, item := range list.Items {
+				itemObj, _, err := decoder.Decode(item.Raw, nil, nil)
+				if err != nil {
+					return objects, fmt.Errorf("item of document %d: %v", i, err)
+				}
+				objects = append(objects, itemObj)
+			}
+
+			continue
+		}
+
+		objects = append(objects, obj)
+	}
+
+	return objects, nil
+}
+
+// isEmptyDocument check whether the document has the comments only
+func isEmptyDocument(doc []byte) bool {
+	for _, line := range bytes.Split(doc, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) > 0 && line[0] != '#' && string(line) != "---" {
+			return false
+		}
+	}
+
+	return true
+}
+
+// manifestWorkload is the pod template of the workload in the manifest
+type manifestWorkload struct {
+	kind string
+	meta metav1.ObjectMeta
+	pod  v1.PodTemplateSpec
+}
+
+// option get the kind of the option `kinds` selecting the workload
+func (wl *manifestWorkload) option() string {
+	switch wl.kind {
+	case "DaemonSet", "Job", "CronJob":
+		return strings.ToLower(wl.kind)
+	}
+
+	return "pod"
+}
+
+// getManifestWorkload get the pod template of the object,
+// it returns false if the object is not a workload
+func getManifestWorkload(obj runtime.Object) (*manifestWorkload, bool) {
+	switch o := obj.(type) {
+	case *v1.Pod:
+		return &manifestWorkload{kind: "Pod", meta: o.ObjectMeta,
+			pod: v1.PodTemplateSpec{ObjectMeta: o.ObjectMeta, Spec: o.Spec}}, true
+	case *v1.ReplicationController:
+		if o.Spec.Template == nil {
+			return nil, false
+		}
+		return &manifestWorkload{kind: "ReplicationController", meta: o.ObjectMeta, pod: *o.Spec.Template}, true
+	case *appsv1.Deployment:
+		return &manifestWorkload{kind: "Deployment", meta: o.ObjectMeta, pod: o.Spec.Template}, true
+	case *appsv1.StatefulSet:
+		return &manifestWorkload{kind: "StatefulSet", meta: o.ObjectMeta, pod: o.Spec.Template}, true
+	case *appsv1.DaemonSet:
+		return &manifestWorkload{kind: "DaemonSet", meta: o.ObjectMeta, pod: o.Spec.Template}, true
+	case *appsv1.ReplicaSet:
+		return &manifestWorkload{kind: "ReplicaSet", meta: o.ObjectMeta, pod: o.Spec.Template}, true
+	case *batchv1.Job:
+		return &manifestWorkload{kind: "Job", meta: o.ObjectMeta, pod: o.Spec.Template}, true
+	case *batchv1.CronJob:
+		return &manifestWorkload{kind: "CronJob", meta: o.ObjectMeta, pod: o.Spec.JobTemplate.Spec.Template}, true
+	case *batchv1beta1.CronJob:
+		return &manifestWorkload{kind: "CronJob", meta: o.ObjectMeta, pod: o.Spec.JobTemplate.Spec.Template}, true
+	}
+
+	return nil, false
+}
+
+// AnalyzeManifests run the pod-level checks against the workloads of the manifests without a cluster,
+// the secrets and configmaps of the manifests are used for resolving the references
+func (ks *KScanner) AnalyzeManifests(ctx context.Context, manifests []*Manifest) error {
+	ks.StartedAt = time.Now()
+	defer func() {
+		ks.FinishedAt = time.Now()
+	}()
+
+	validateChecks(ctx)
+	validateSeverities(ctx)
+
+	ks.stream = newFindingStream(ctx, ks.OnFinding)
+
+	if location, ok := ctx.Value("blocklist").(string); ok && location != "" {
+		blocklist, err := loadBlocklist(location)
+		if err != nil {
+			return err
+		}
+		ks.blocklist = blocklist
+	}
+
+	baseline, err := baselineOf(ctx)
+	if err != nil {
+		return err
+	}
+	ks.baseline = baseline
+
+	ks.cache = newStaticListCache()
+	defer func() {
+		ks.cache = nil
+	}()
+
+	// The objects of manifest are listed instead of requesting API server
+	secrets, configMaps := map[string]*v1.SecretList{}, map[string]*v1.ConfigMapList{}
+	for _, m := range manifests {
+		switch o := m.Object.(type) {
+		case *v1.Secret:
+			ns := manifestNamespace(o.ObjectMeta)
+			if secrets[ns] == nil {
+				secrets[ns] = &v1.SecretList{}
+			}
+			secrets[ns].Items = append(secrets[ns].Items, *o)
+		case *v1.ConfigMap:
+			ns := manifestNamespace(o.ObjectMeta)
+			if configMaps[ns] == nil {
+				configMaps[ns] = &v1.ConfigMapList{}
+			}
+			configMaps[ns].Items = append(configMaps[ns].Items, *o)
+		}
+	}
+
+	for ns, list := range secrets {
+		ks.cache.lists["secrets/"+ns] = list
+	}
+	for ns, list := range configMaps {
+		ks.cache.lists["configmaps/"+ns] = list
+	}
+
+	if !isCheckEnabled(ctx, "checkPod") {
+		return nil
+	}
+	ks.Checks = append(ks.Checks, "checkPod")
+
+	log.Printf(config.Yellow("Begin manifest analyzing"))
+
+	start := time.Now()
+	configures, containers := len(ks.VulnConfigures), len(ks.VulnContainers)
+
+	for _, m := range manifests {
+		wl, ok := getManifestWorkload(m.Object)
+		if !ok || !isKindSelected(ctx, wl.option()) {
+			continue
+		}
+
+		ns := manifestNamespace(wl.meta)
+		pod := v1.Pod{ObjectMeta: wl.pod.ObjectMeta, Spec: wl.pod.Spec}
+
+		vList := ks.podAnalyze(wl.pod.Spec, wl.pod.Annotations, RBACVuln{}, ns, wl.meta.Name)
+
+		if ok, tlist := checkPodAnnotation(wl.pod.Annotations); ok {
+			vList = append(vList, tlist...)
+		}
+
+		if ok, tlist := checkPodBlocklist(pod, ks.blocklist); ok {
+			vList = append(vList, tlist...)
+		}
+
+		if da, ok := m.Object.(*appsv1.DaemonSet); ok {
+			vList = append(vList, checkAgentPrivilege(*da)...)
+		}
+
+		if wl.kind == "Deployment" || wl.kind == "StatefulSet" {
+			for _, sp := range wl.pod.Spec.Containers {
+				if ok, tlist := checkPodProbes(sp); ok {
+					vList = append(vList, tlist...)
+				}
+			}
+		}
+
+		if len(vList) < 1 {
+			continue
+		}
+
+		sortSeverity(vList)
+		tagCISControls(vList)
+
+		con := &container{
+			ContainerName: wl.meta.Name,
+			Namepsace:     ns,
+			OwnerKind:     wl.kind,
+			OwnerName:     wl.meta.Name,
+			Replicas:      1,
+			Threats:       vList,
+		}
+		con.HelmRelease, con.HelmChart = getHelmRelease(wl.meta, wl.pod.ObjectMeta)
+
+		ks.VulnContainers = append(ks.VulnContainers, con)
+	}
+
+	ks.Timings = addTiming(ks.Timings, "checkPod", time.Since(start))
+	ks.remapFindings(ctx, "checkPod", configures, containers)
+	ks.streamFindings("checkPod", configures, containers)
+
+	sortContainers(ks.VulnContainers)
+
+	if ks.baseline != nil {
+		ks.VulnContainers, _, ks.Suppressed = ks.baseline.suppress("", ks.VulnContainers, nil, time.Now())
+		log.Printf("%d known findings are suppressed by the baseline", ks.Suppressed)
+	}
+
+	if fields := redactFields(ctx); len(fields) > 0 {
+		redactContainers(fields, ks.VulnContainers)
+	}
+
+	return nil
+}
+
+// manifestNamespace get the namespace of the object, `default` is used if it is unset
+func manifestNamespace(meta metav1.ObjectMeta) string {
+	if meta.Namespace == "" {
+		return "default"
+	}
+
+	return meta.Namespace
+}
@@ -0,0 +1,140 @@
+package analyzer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/kvesta/vesta/config"
+	v1 "k8s.io/api/core/v1"
+)
+
+// kubeletConfiguration is the part of kubelet configuration served by `configz`
+type kubeletConfiguration struct {
+	FailSwapOn *bool `json:"failSwapOn"`
+	MemorySwap struct {
+		SwapBehavior string `json:"swapBehavior"`
+	} `json:"memorySwap"`
+	ProtectKernelDefaults bool     `json:"protectKernelDefaults"`
+	ReadOnlyPort          int32    `json:"readOnlyPort"`
+	AllowedUnsafeSysctls  []string `json:"allowedUnsafeSysctls"`
+}
+
+// getKubeletConfig get the running configuration of kubelet through the node proxy of API server
+func (ks *KScanner) getKubeletConfig(ctx context.Context, node string) (*kubeletConfiguration, error) {
+	data, err := ks.KClient.CoreV1().RESTClient().Get().
+		AbsPath("/api/v1/nodes", node, "proxy", "configz").DoRaw(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	configz := struct {
+		KubeletConfig *kubeletConfiguration `json:"kubeletconfig"`
+	}{}
+
+	err = json.Unmarshal(data, &configz)
+	if err != nil {
+		return nil, err
+	}
+
+	if configz.KubeletConfig == nil {
+		return nil, fmt.Errorf("empty kubelet configuration")
+	}
+
+	return configz.KubeletConfig, nil
+}
+
+// checkNodePosture check the swap and the kernel settings of nodes by the kubelet configuration
+func (ks *KScanner) checkNodePosture(ctx context.Context) error {
+	nodes, err := ks.listNodes()
+	if err != nil {
+		return err
+	}
+
+	for _, node := range nodes.Items {
+		kc, err := ks.getKubeletConfig(ctx, node.Name)
+		if err != nil {
+			ks.run.swallow(err)
+			config.ErrorLog.Printf("failed to get kubelet configuration of node %s: %v", node.Name, err)
+		}
+
+		ks.VulnConfigures = append(ks.VulnConfigures, getNodePostureThreats(node, kc)...)
+	}
+
+	return nil
+}
+
+func getNodePostureThreats(node v1.Node, kc *kubeletConfiguration) []*threat {
+	tlist := []*threat{}
+
+	if kc == nil {
+		return tlist
+	}
+
+	if kc.FailSwapOn != nil && !*kc.FailSwapOn {
+		value := "failSwapOn: false"
+
+		th := &threat{
+			Param: fmt.Sprintf("node name: %s | swap", node.Name),
+			Value: value,
+			Type:  "Node Posture",
+			Describe: fmt.Sprintf("Swap is enabled on node '%s', the memory of containers "+
+				"including the secrets can be written to the disk.", node.Name),
+			Remediation: "Disable swap on the node and set `failSwapOn: true` for kubelet.",
+			Severity:    "low",
+		}
+
+		// Memory of containers is swapped without limit
+		if kc.MemorySwap.SwapBehavior == "UnlimitedSwap" {
+			th.Value = fmt.Sprintf("%s | swapBehavior: UnlimitedSwap", value)
+			th.Severity = "medium"
+		}
+
+		tlist = append(tlist, th)
+	}
+
+	if !kc.ProtectKernelDefaults {
+		th := &threat{
+			Param: fmt.Sprintf("node name: %s | protectKernelDefaults", node.Name),
+			Value: "protectKernelDefaults: false",
+			Type:  "Node Posture",
+			Describe: fmt.Sprintf("Kubelet of node '%s' modifies the kernel parameters "+
+				"which differ from the defaults of kubelet instead of failing.", node.Name),
+			Remediation: "Set `protectKernelDefaults: true` for kubelet.",
+			Severity:    "low",
+		}
+
+		tlist = append(tlist, th)
+	}
+
+	if kc.ReadOnlyPort > 0 {
+		th := &threat{
+			Param: fmt.Sprintf("node name: %s | readOnlyPort", node.Name),
+			Value: fmt.Sprintf("readOnlyPort: %d", kc.ReadOnlyPort),
+			Type:  "Kubelet",
+			Describe: fmt.Sprintf("Kubelet of node '%s' serves the read-only port without authentication, "+
+				"the pods and the node information are leaked.", node.Name),
+			Remediation: "Set `readOnlyPort: 0` for kubelet.",
+			Severity:    "high",
+		}
+
+		tlist = append(tlist, th)
+	}
+
+	if len(kc.AllowedUnsafeSysctls) > 0 {
+		th := &threat{
+			Param: fmt.Sprintf("node name: %s | allowedUnsafeSysctls", node.Name),
+			Value: strings.Join(kc.AllowedUnsafeSysctls, ", "),
+			Type:  "Node Posture",
+			Describe: fmt.Sprintf("Kubelet of node '%s' allows the pods to set the unsafe sysctls, "+
+				"which are not namespaced and affect the kernel of node.", node.Name),
+			Remediation: "Remove the sysctls from `allowedUnsafeSysctls` of kubelet.",
+			Severity:    "medium",
+		}
+
+		tlist = append(tlist, th)
+	}
+
+	return tlist
+}
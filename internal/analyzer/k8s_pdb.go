@@ -0,0 +1,131 @@
+package analyzer
+
+import (
+	"fmt"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// criticalPriorityClasses are the workloads expected to block the eviction
+var criticalPriorityClasses = map[string]bool{"system-cluster-critical": true, "system-node-critical": true}
+
+// checkDisruptionBudgets check the PodDisruptionBudgets allowing no disruption of the workloads,
+// such budget blocks draining the nodes and pins the pods to the nodes
+func (ks *KScanner) checkDisruptionBudgets(ns string) error {
+	pdbs, err := ks.listPodDisruptionBudgets(ns)
+	if err != nil {
+		return err
+	}
+
+	if len(pdbs.Items) < 1 {
+		return nil
+	}
+
+	pods, err := ks.listPods(ns)
+	if err != nil {
+		return err
+	}
+
+	for _, pdb := range pdbs.Items {
+		tlist := getDisruptionBudgetThreats(pdb, pods.Items, func(pod v1.Pod) string {
+			kind, name := ks.getPodOwner(pod)
+			return fmt.Sprintf("%s/%s", kind, name)
+		})
+
+		ks.VulnConfigures = append(ks.VulnConfigures, tlist...)
+	}
+
+	return nil
+}
+
+func getDisruptionBudgetThreats(pdb policyv1.PodDisruptionBudget, pods []v1.Pod, ownerOf func(pod v1.Pod) string) []*threat {
+	tlist := []*threat{}
+
+	// Budget with an empty selector matches nothing in policy/v1
+	if pdb.Spec.Selector == nil {
+		return tlist
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+	if err != nil || selector.Empty() {
+		return tlist
+	}
+
+	matched := []v1.Pod{}
+	for _, pod := range pods {
+		if pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
+			continue
+		}
+
+		if selector.Matches(labels.Set(pod.Labels)) {
+			matched = append(matched, pod)
+		}
+	}
+
+	if len(matched) < 1 {
+		return tlist
+	}
+
+	owners := []string{}
+	seen := map[string]bool{}
+	for _, pod := range matched {
+		if criticalPriorityClasses[pod.Spec.PriorityClassName] {
+			return tlist
+		}
+
+		owner := ownerOf(pod)
+		if !seen[owner] {
+			seen[owner] = true
+			owners = append(owners, owner)
+		}
+	}
+
+	value, blocked := disruptionBlocked(pdb.Spec, len(matched))
+	if !blocked {
+		return tlist
+	}
+
+	workload := strings.Join(owners, ", ")
+	th := &threat{
+		Param: fmt.Sprintf("PodDisruptionBudget: %s | Namespace: %s | workload: %s", pdb.Name, pdb.Namespace, workload),
+		Value: fmt.Sprintf("%s | pods: %d", value, len(matched)),
+		Type:  "PodDisruptionBudget",
+		Describe: fmt.Sprintf("PodDisruptionBudget '%s' allows no voluntary disruption of %s, "+
+			"the nodes running the pods can not be drained for the patching.", pdb.Name, workload),
+		Remediation: "Set `maxUnavailable` to at least 1 or `minAvailable` below the count of replicas.",
+		Severity:    "low",
+	}
+
+	tlist = append(tlist, th)
+
+	return tlist
+}
+
+// disruptionBlocked check whether the budget allows no pod to be evicted,
+// the percentages are rounded up as the disruption controller does
+func disruptionBlocked(spec policyv1.PodDisruptionBudgetSpec, pods int) (string, bool) {
+	if spec.MaxUnavailable != nil {
+		maxUnavailable, err := intstr.GetScaledValueFromIntOrPercent(spec.MaxUnavailable, pods, true)
+		if err != nil {
+			return "", false
+		}
+
+		return fmt.Sprintf("maxUnavailable: %s", spec.MaxUnavailable.String()), maxUnavailable < 1
+	}
+
+	if spec.MinAvailable != nil {
+		minAvailable, err := intstr.GetScaledValueFromIntOrPercent(spec.MinAvailable, pods, true)
+		if err != nil {
+			return "", false
+		}
+
+		return fmt.Sprintf("minAvailable: %s", spec.MinAvailable.String()), minAvailable >= pods
+	}
+
+	return "", false
+}
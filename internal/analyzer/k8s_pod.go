@@ -2,20 +2,34 @@ package analyzer
 
 import (
 	"fmt"
+	"path"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/kvesta/vesta/config"
 	v1 "k8s.io/api/core/v1"
 )
 
-func (ks KScanner) podAnalyze(podSpec v1.PodSpec, rv RBACVuln, ns, podName string) []*threat {
+func (ks KScanner) podAnalyze(podSpec v1.PodSpec, annotations map[string]string, rv RBACVuln, ns, podName string) []*threat {
 	vList := []*threat{}
 
 	for _, v := range podSpec.Volumes {
 		if ok, tlist := checkPodVolume(v); ok {
 			vList = append(vList, tlist...)
 		}
+
+		if ok, tlist := checkPodTokenExpiration(v); ok {
+			vList = append(vList, tlist...)
+		}
+	}
+
+	// Objects referenced by envFrom, nil if they are not accessible
+	var secrets *v1.SecretList
+	var configMaps *v1.ConfigMapList
+	if ks.KClient != nil || ks.cache != nil {
+		secrets, _ = ks.listSecrets(ns)
+		configMaps, _ = ks.listConfigMaps(ns)
 	}
 
 	for _, sp := range podSpec.Containers {
@@ -23,7 +37,12 @@ func (ks KScanner) podAnalyze(podSpec v1.PodSpec, rv RBACVuln, ns, podName strin
 		// Skip some sidecars
 		if sp.Name == "istio-proxy" {
 			// Try to check the istio header `X-Envoy-Peer-Metadata`
-			// reference: https://github.com/istio/istio/issues/17635
+			// reference: https://github.com/istio/istio/issues/17635,
+			// it needs a running pod which is absent in the manifest analysis
+			if ks.KClient == nil {
+				continue
+			}
+
 			if ok, tlist := ks.checkIstioHeader(podName, ns, podSpec.Containers[0].Name); ok {
 				vList = append(vList, tlist...)
 			}
@@ -31,14 +50,54 @@ func (ks KScanner) podAnalyze(podSpec v1.PodSpec, rv RBACVuln, ns, podName strin
 			continue
 		}
 
+		privileged := []*threat{}
 		if ok, tlist := checkPodPrivileged(sp); ok {
-			vList = append(vList, tlist...)
+			privileged = append(privileged, tlist...)
+		}
+
+		if ok, tlist := checkPodConfinement(sp, podSpec, annotations); ok {
+			privileged = append(privileged, tlist...)
 		}
 
+		correlateUnconfined(privileged)
+		vList = append(vList, privileged...)
+
 		if ok, tlist := checkPodAccountService(sp, rv); ok {
 			vList = append(vList, tlist...)
 		}
 
+		if ok, tlist := checkPodTokenMount(sp, podSpec.Volumes); ok {
+			vList = append(vList, tlist...)
+		}
+
+		if ok, tlist := checkPodCapabilitiesDrop(sp); ok {
+			vList = append(vList, tlist...)
+		}
+
+		if ok, tlist := checkPodNetRaw(sp, ks.netRawKernel); ok {
+			vList = append(vList, tlist...)
+		}
+
+		if ok, tlist := checkPodPtrace(sp, podSpec); ok {
+			vList = append(vList, tlist...)
+		}
+
+		if ok, tlist := checkPodDACCapabilities(sp, podSpec.Volumes); ok {
+			vList = append(vList, tlist...)
+		}
+
+		if ok, tlist := checkPodRootUser(sp, podSpec, ks.imageUsers); ok {
+			vList = append(vList, tlist...)
+		}
+
+		if ok, tlist := checkPodRootGroup(sp, podSpec); ok {
+			vList = append(vList, tlist...)
+		}
+
+		if ok, tlist := checkPodCgroupEscape(sp, podSpec.Volumes, ks.cgroupKernel); ok {
+			vList = append(vList, tlist...)
+		}
+
 		if ok, tlist := checkResourcesLimits(sp); ok {
 			vList = append(vList, tlist...)
 		}
@@ -47,10 +106,22 @@ func (ks KScanner) podAnalyze(podSpec v1.PodSpec, rv RBACVuln, ns, podName strin
 			vList = append(vList, tlist...)
 		}
 
+		if ok, tlist := checkPodLoaderEnv(sp); ok {
+			vList = append(vList, tlist...)
+		}
+
+		if ok, tlist := checkPodEnvFrom(sp, secrets, configMaps); ok {
+			vList = append(vList, tlist...)
+		}
+
 		if ok, tlist := ks.checkPodCommand(sp, ns); ok {
 			vList = append(vList, tlist...)
 		}
 
+		if ok, tlist := checkPodInsecureTLS(sp); ok {
+			vList = append(vList, tlist...)
+		}
+
 	}
 
 	return vList
@@ -72,7 +143,8 @@ func checkPodVolume(container v1.Volume) (bool, []*threat) {
 				Type:  string(*hostPath.Type),
 				Describe: fmt.Sprintf("Mounting '%s' is suffer vulnerable of "+
 					"container escape.", volumePath),
-				Severity: "critical",
+				Remediation: "Remove the hostPath volume, or mount it with `readOnly: true`.",
+				Severity:    "critical",
 			}
 
 			tlist = append(tlist, th)
@@ -83,12 +155,62 @@ func checkPodVolume(container v1.Volume) (bool, []*threat) {
 	return vuln, tlist
 }
 
+// maxTokenExpiration is the longest expiration of the projected service account token,
+// tokens living longer than one day are hard to contain once leaked
+const maxTokenExpiration = 24 * 60 * 60
+
+// checkPodTokenExpiration check the expiration of the projected service account token
+func checkPodTokenExpiration(volume v1.Volume) (bool, []*threat) {
+	tlist := []*threat{}
+	var vuln = false
+
+	if volume.Projected == nil {
+		return vuln, tlist
+	}
+
+	for _, source := range volume.Projected.Sources {
+		token := source.ServiceAccountToken
+		if token == nil {
+			continue
+		}
+
+		th := &threat{
+			Param:       fmt.Sprintf("volumes name: %s | serviceAccountToken", volume.Name),
+			Type:        "Sidecar Token",
+			Remediation: "Set `expirationSeconds` of the serviceAccountToken to 3600 or less.",
+		}
+
+		switch {
+		case token.ExpirationSeconds == nil:
+			th.Value = "expirationSeconds: unset"
+			th.Describe = "Projected service account token is not setting the expiration, " +
+				"which depends on the `--service-account-max-token-expiration` of API server."
+			th.Severity = "low"
+		case *token.ExpirationSeconds > maxTokenExpiration:
+			th.Value = fmt.Sprintf("expirationSeconds: %d (%s)", *token.ExpirationSeconds,
+				time.Duration(*token.ExpirationSeconds)*time.Second)
+			th.Describe = "Projected service account token is valid longer than one day, " +
+				"a leaked token can be used for a long time."
+			th.Severity = "medium"
+		default:
+			continue
+		}
+
+		tlist = append(tlist, th)
+		vuln = true
+	}
+
+	return vuln, tlist
+}
+
 func checkPodPrivileged(container v1.Container) (bool, []*threat) {
 	tlist := []*threat{}
 	var vuln = false
 
 	if container.SecurityContext != nil {
 
+		isPrivileged := container.SecurityContext.Privileged != nil && *container.SecurityContext.Privileged
+
 		// check capabilities of pod
 		// Ignore the checking of cap_drop refer to:
 		// https://stackoverflow.com/questions/63162665/docker-compose-order-of-cap-drop-and-cap-add
@@ -103,35 +225,49 @@ func checkPodPrivileged(container v1.Container) (bool, []*threat) {
 					break
 				}
 
-				for _, c := range dangerCaps {
-					if string(ad) == c {
-						capList += c + " "
-						vuln = true
-					}
+				if isDangerCap(string(ad)) {
+					capList += string(ad) + " "
+					vuln = true
 				}
 			}
+		}
 
-			if vuln {
-				th := &threat{
-					Param: fmt.Sprintf("sidecar name: %s | "+
-						"capabilities", container.Name),
-					Value:    capList,
-					Type:     "capabilities.add",
-					Describe: "There has a potential container escape in privileged module.",
-					Severity: "critical",
-				}
-				tlist = append(tlist, th)
+		// Privileged container is granted all the capabilities whatever the cap list is
+		if isPrivileged {
+			th := &threat{
+				Param: fmt.Sprintf("sidecar name: %s | "+
+					"capabilities", container.Name),
+				Value: "ALL (privileged)",
+				Type:  "capabilities.add",
+				Describe: "Privileged container is granted all the capabilities implicitly, " +
+					"there has a potential container escape.",
+				Remediation: "Set `securityContext.privileged: false` and add the needed capabilities only.",
+				Severity:    "critical",
+			}
+			tlist = append(tlist, th)
+			vuln = true
+		} else if vuln {
+			th := &threat{
+				Param: fmt.Sprintf("sidecar name: %s | "+
+					"capabilities", container.Name),
+				Value:       capList,
+				Type:        "capabilities.add",
+				Describe:    "There has a potential container escape in privileged module.",
+				Remediation: "Remove the dangerous capabilities from `securityContext.capabilities.add`.",
+				Severity:    "critical",
 			}
+			tlist = append(tlist, th)
 		}
 
-		if container.SecurityContext.Privileged != nil && *container.SecurityContext.Privileged {
+		if isPrivileged {
 			th := &threat{
 				Param: fmt.Sprintf("sidecar name: %s | "+
 					"Privileged", container.Name),
-				Value:    "true",
-				Type:     "Sidecar Privileged",
-				Describe: "There has a potential container escape in privileged module.",
-				Severity: "critical",
+				Value:       "true",
+				Type:        "Sidecar Privileged",
+				Describe:    "There has a potential container escape in privileged module.",
+				Remediation: "Set `securityContext.privileged: false`.",
+				Severity:    "critical",
 			}
 			tlist = append(tlist, th)
 			vuln = true
@@ -141,10 +277,11 @@ func checkPodPrivileged(container v1.Container) (bool, []*threat) {
 			th := &threat{
 				Param: fmt.Sprintf("sidecar name: %s | "+
 					"AllowPrivilegeEscalation", container.Name),
-				Value:    "true",
-				Type:     "Sidecar Privileged",
-				Describe: "There has a potential container escape in privileged module.",
-				Severity: "critical",
+				Value:       "true",
+				Type:        "Sidecar Privileged",
+				Describe:    "There has a potential container escape in privileged module.",
+				Remediation: "Set `securityContext.allowPrivilegeEscalation: false`.",
+				Severity:    "critical",
 			}
 			tlist = append(tlist, th)
 			vuln = true
@@ -155,6 +292,722 @@ func checkPodPrivileged(container v1.Container) (bool, []*threat) {
 	return vuln, tlist
 }
 
+// AppArmor profile of container is given by the beta annotation,
+// seccomp profile is given by the deprecated annotations before v1.19
+const (
+	apparmorAnnotationPrefix = "container.apparmor.security.beta.kubernetes.io/"
+	apparmorUnconfined       = "unconfined"
+
+	seccompPodAnnotationKey          = "seccomp.security.alpha.kubernetes.io/pod"
+	seccompContainerAnnotationPrefix = "container.seccomp.security.alpha.kubernetes.io/"
+)
+
+// checkPodCapabilitiesDrop check whether the container drops all the capabilities
+func checkPodCapabilitiesDrop(container v1.Container) (bool, []*threat) {
+	tlist := []*threat{}
+
+	drops := []string{}
+	if container.SecurityContext != nil && container.SecurityContext.Capabilities != nil {
+		for _, d := range container.SecurityContext.Capabilities.Drop {
+			if strings.ToUpper(string(d)) == "ALL" {
+				// Dropped capabilities are ignored in privileged module
+				if container.SecurityContext.Privileged != nil && *container.SecurityContext.Privileged {
+					th := &threat{
+						Param: fmt.Sprintf("sidecar name: %s | "+
+							"capabilities", container.Name),
+						Value:       "drop: ALL (ignored by privileged)",
+						Type:        "capabilities.drop",
+						Describe:    "Container drops ALL the capabilities but is privileged, all the capabilities are still granted.",
+						Remediation: "Set `securityContext.privileged: false` so that `capabilities.drop: [ALL]` takes effect.",
+						Severity:    "low",
+					}
+					tlist = append(tlist, th)
+
+					return true, tlist
+				}
+
+				return false, tlist
+			}
+
+			drops = append(drops, string(d))
+		}
+	}
+
+	if len(drops) < 1 {
+		drops = append(drops, "none")
+	}
+
+	th := &threat{
+		Param: fmt.Sprintf("sidecar name: %s | "+
+			"capabilities", container.Name),
+		Value:       fmt.Sprintf("drop: %s", strings.Join(drops, ", ")),
+		Type:        "capabilities.drop",
+		Describe:    "Container is not dropping ALL the capabilities, only the needed ones should be added back.",
+		Remediation: "Add `capabilities.drop: [ALL]` and add back the needed capabilities only.",
+		Severity:    "low",
+	}
+	tlist = append(tlist, th)
+
+	return true, tlist
+}
+
+// checkPodPtrace check whether the container holding CAP_SYS_PTRACE can access the other processes
+// by `hostPID` or `shareProcessNamespace`
+func checkPodPtrace(container v1.Container, podSpec v1.PodSpec) (bool, []*threat) {
+	tlist := []*threat{}
+
+	if container.SecurityContext == nil {
+		return false, tlist
+	}
+
+	adds := []string{}
+	privileged := container.SecurityContext.Privileged != nil && *container.SecurityContext.Privileged
+	if container.SecurityContext.Capabilities != nil {
+		for _, c := range container.SecurityContext.Capabilities.Add {
+			adds = append(adds, string(c))
+		}
+	}
+
+	held, how := holdsCapability("SYS_PTRACE", adds, nil, privileged)
+
+	// SYS_PTRACE is not granted by default
+	if !held || how == "default" {
+		return false, tlist
+	}
+
+	th := &threat{
+		Param: fmt.Sprintf("sidecar name: %s | "+
+			"capabilities", container.Name),
+		Value: fmt.Sprintf("SYS_PTRACE (%s) | pid: private", how),
+		Type:  "capabilities.add",
+		Describe: "Container holds CAP_SYS_PTRACE, " +
+			"the processes in the container can be debugged and their memory can be read.",
+		Remediation: "Remove SYS_PTRACE from `securityContext.capabilities.add`.",
+		Severity:    "low",
+	}
+
+	switch {
+	case podSpec.HostPID:
+		th.Value = fmt.Sprintf("SYS_PTRACE (%s) | hostPID: true", how)
+		th.Describe = "Container holds CAP_SYS_PTRACE with `hostPID`, " +
+			"the memory of processes on node can be read and the code can be injected."
+		th.Remediation = "Remove SYS_PTRACE from `securityContext.capabilities.add` and set `hostPID: false`."
+		th.Severity = "high"
+	case podSpec.ShareProcessNamespace != nil && *podSpec.ShareProcessNamespace:
+		th.Value = fmt.Sprintf("SYS_PTRACE (%s) | shareProcessNamespace: true", how)
+		th.Describe = "Container holds CAP_SYS_PTRACE with `shareProcessNamespace`, " +
+			"the memory of processes in the other containers of pod can be read and the code can be injected."
+		th.Remediation = "Remove SYS_PTRACE from `securityContext.capabilities.add` or set `shareProcessNamespace: false`."
+		th.Severity = "high"
+	}
+
+	tlist = append(tlist, th)
+
+	return true, tlist
+}
+
+// checkPodDACCapabilities check whether the capabilities bypassing the file permissions are added,
+// the files of the hostPath volumes mounted by the container are accessible whatever the owners and modes
+func checkPodDACCapabilities(container v1.Container, volumes []v1.Volume) (bool, []*threat) {
+	tlist := []*threat{}
+
+	sc := container.SecurityContext
+	if sc == nil || sc.Capabilities == nil || (sc.Privileged != nil && *sc.Privileged) {
+		return false, tlist
+	}
+
+	adds, drops := []string{}, []string{}
+	for _, c := range sc.Capabilities.Add {
+		adds = append(adds, string(c))
+	}
+	for _, c := range sc.Capabilities.Drop {
+		drops = append(drops, string(c))
+	}
+
+	caps := []string{}
+	for _, c := range dacCaps {
+		if held, how := holdsCapability(c, adds, drops, false); held && how == "added" {
+			caps = append(caps, c)
+		}
+	}
+
+	if len(caps) < 1 {
+		return false, tlist
+	}
+
+	hostPaths := map[string]string{}
+	for _, vol := range volumes {
+		if vol.HostPath != nil {
+			hostPaths[vol.Name] = vol.HostPath.Path
+		}
+	}
+
+	mounts := []string{}
+	for _, vm := range container.VolumeMounts {
+		hostPath, ok := hostPaths[vm.Name]
+		if !ok {
+			continue
+		}
+
+		if vm.ReadOnly {
+			hostPath += " (ro)"
+		}
+		mounts = append(mounts, hostPath)
+	}
+
+	th := &threat{
+		Param: fmt.Sprintf("sidecar name: %s | "+
+			"capabilities", container.Name),
+		Value: fmt.Sprintf("%s (added)", strings.Join(caps, ", ")),
+		Type:  "capabilities.add",
+		Describe: fmt.Sprintf("Container holds %s, "+
+			"the permissions of files in the container are bypassed.", strings.Join(caps, ", ")),
+		Remediation: fmt.Sprintf("Remove %s from `securityContext.capabilities.add`.", strings.Join(caps, ", ")),
+		Severity:    "medium",
+	}
+
+	if len(mounts) > 0 {
+		th.Value = fmt.Sprintf("%s (added) | hostPath: %s", strings.Join(caps, ", "), strings.Join(mounts, ", "))
+		th.Describe = fmt.Sprintf("Container holds %s with the hostPath volumes mounted, "+
+			"the files of node under the volumes are accessible whatever the owners and modes.", strings.Join(caps, ", "))
+		th.Severity = "high"
+	}
+
+	tlist = append(tlist, th)
+
+	return true, tlist
+}
+
+// checkPodRootUser check whether the container runs as root without `runAsNonRoot`,
+// the user of the image is used when `runAsUser` is unset
+func checkPodRootUser(container v1.Container, podSpec v1.PodSpec, imageUsers map[string]string) (bool, []*threat) {
+	tlist := []*threat{}
+
+	var runAsUser *int64
+	var runAsNonRoot *bool
+
+	if psc := podSpec.SecurityContext; psc != nil {
+		runAsUser, runAsNonRoot = psc.RunAsUser, psc.RunAsNonRoot
+	}
+
+	// Container settings take precedence over the pod
+	if csc := container.SecurityContext; csc != nil {
+		if csc.RunAsUser != nil {
+			runAsUser = csc.RunAsUser
+		}
+		if csc.RunAsNonRoot != nil {
+			runAsNonRoot = csc.RunAsNonRoot
+		}
+	}
+
+	if runAsUser != nil && *runAsUser != 0 {
+		return false, tlist
+	}
+
+	// Kubelet refuses to start the container running as root
+	if runAsUser == nil && runAsNonRoot != nil && *runAsNonRoot {
+		return false, tlist
+	}
+
+	th := &threat{
+		Param: fmt.Sprintf("sidecar name: %s | "+
+			"securityContext", container.Name),
+		Type:        "Sidecar User",
+		Remediation: "Set `runAsNonRoot: true` and a non-zero `runAsUser` in the securityContext.",
+		Severity:    "low",
+	}
+
+	if runAsUser != nil {
+		th.Value = "runAsUser: 0"
+		th.Describe = "Container runs as root by `runAsUser: 0`."
+		th.Severity = "medium"
+
+		tlist = append(tlist, th)
+		return true, tlist
+	}
+
+	nonRoot := "unset"
+	if runAsNonRoot != nil {
+		nonRoot = fmt.Sprintf("%t", *runAsNonRoot)
+	}
+
+	user, known := imageUsers[container.Image]
+	switch {
+	case !known:
+		th.Value = fmt.Sprintf("image: %s | image user: unknown | runAsNonRoot: %s", container.Image, nonRoot)
+		th.Describe = "Container runs as the user of the image without `runAsNonRoot`, " +
+			"it is root if the image configures no user."
+	case isRootUser(user):
+		if user == "" {
+			user = "unset"
+		}
+		th.Value = fmt.Sprintf("image: %s | image user: %s | runAsNonRoot: %s", container.Image, user, nonRoot)
+		th.Describe = fmt.Sprintf("Container runs as root, the image '%s' is configured with user '%s' "+
+			"and `runAsUser` is not set.", container.Image, user)
+	default:
+		return false, tlist
+	}
+
+	tlist = append(tlist, th)
+
+	return true, tlist
+}
+
+// checkPodRootGroup check whether the container runs with the root group by `runAsGroup`,
+// `fsGroup` or `supplementalGroups`, the files owned by the root group are accessible even for a non-root user
+func checkPodRootGroup(container v1.Container, podSpec v1.PodSpec) (bool, []*threat) {
+	tlist := []*threat{}
+
+	var runAsUser, runAsGroup, fsGroup *int64
+	supplementalGroups := []int64{}
+
+	if psc := podSpec.SecurityContext; psc != nil {
+		runAsUser, runAsGroup, fsGroup = psc.RunAsUser, psc.RunAsGroup, psc.FSGroup
+		supplementalGroups = psc.SupplementalGroups
+	}
+
+	// Container settings take precedence over the pod
+	if csc := container.SecurityContext; csc != nil {
+		if csc.RunAsUser != nil {
+			runAsUser = csc.RunAsUser
+		}
+		if csc.RunAsGroup != nil {
+			runAsGroup = csc.RunAsGroup
+		}
+	}
+
+	isRootGroup := (runAsGroup != nil && *runAsGroup == 0) || (fsGroup != nil && *fsGroup == 0)
+	for _, g := range supplementalGroups {
+		if g == 0 {
+			isRootGroup = true
+		}
+	}
+
+	if !isRootGroup {
+		return false, tlist
+	}
+
+	formatID := func(id *int64) string {
+		if id == nil {
+			return "unset"
+		}
+		return fmt.Sprintf("%d", *id)
+	}
+
+	th := &threat{
+		Param: fmt.Sprintf("sidecar name: %s | "+
+			"securityContext", container.Name),
+		Value: fmt.Sprintf("runAsUser: %s | runAsGroup: %s | fsGroup: %s | supplementalGroups: %v",
+			formatID(runAsUser), formatID(runAsGroup), formatID(fsGroup), supplementalGroups),
+		Type: "Sidecar Group",
+		Describe: "Container runs with the root group (GID 0), " +
+			"the files owned by the root group on the volumes and the image are accessible.",
+		Remediation: "Set non-zero `runAsGroup` and `fsGroup`, and remove 0 from `supplementalGroups`.",
+		Severity:    "low",
+	}
+
+	// Root group is easily missed when the user is not root
+	if runAsUser != nil && *runAsUser != 0 {
+		th.Describe = fmt.Sprintf("Container runs as the non-root user %d but with the root group (GID 0), "+
+			"the files owned by the root group on the volumes and the image are accessible.", *runAsUser)
+		th.Severity = "medium"
+	}
+
+	tlist = append(tlist, th)
+
+	return true, tlist
+}
+
+// checkPodCgroupEscape check whether the container can exploit CVE-2022-0492,
+// it needs CAP_SYS_ADMIN and the cgroup of host mounted writable
+func checkPodCgroupEscape(container v1.Container, volumes []v1.Volume, cgroupKernel bool) (bool, []*threat) {
+	tlist := []*threat{}
+
+	if !cgroupKernel || container.SecurityContext == nil {
+		return false, tlist
+	}
+
+	adds := []string{}
+	privileged := container.SecurityContext.Privileged != nil && *container.SecurityContext.Privileged
+	if container.SecurityContext.Capabilities != nil {
+		for _, c := range container.SecurityContext.Capabilities.Add {
+			adds = append(adds, string(c))
+		}
+	}
+
+	held, how := holdsCapability("SYS_ADMIN", adds, nil, privileged)
+	// SYS_ADMIN is not granted by default
+	if !held || how == "default" {
+		return false, tlist
+	}
+
+	writable := ""
+	if privileged {
+		writable = "/sys/fs/cgroup (privileged)"
+	}
+
+	hostPaths := map[string]string{}
+	for _, v := range volumes {
+		if v.HostPath != nil {
+			hostPaths[v.Name] = v.HostPath.Path
+		}
+	}
+
+	for _, mount := range container.VolumeMounts {
+		path, ok := hostPaths[mount.Name]
+		if ok && !mount.ReadOnly && strings.HasPrefix(path, "/sys/fs/cgroup") {
+			writable = fmt.Sprintf("%s:%s", path, mount.MountPath)
+			break
+		}
+	}
+
+	if writable == "" {
+		return false, tlist
+	}
+
+	th := &threat{
+		Param: fmt.Sprintf("sidecar name: %s | "+
+			"capabilities", container.Name),
+		Value: fmt.Sprintf("SYS_ADMIN (%s) | cgroup: %s", how, writable),
+		Type:  "capabilities.add",
+		Describe: "Kernel of node is vulnerable to CVE-2022-0492 and the container holds CAP_SYS_ADMIN " +
+			"with a writable cgroup, the container escape by `release_agent` is exploitable.",
+		Reference:   "https://nvd.nist.gov/vuln/detail/CVE-2022-0492",
+		Remediation: "Remove SYS_ADMIN from `securityContext.capabilities.add`, mount the cgroup read-only, or upgrade the kernel of node.",
+		Severity:    "critical",
+	}
+	tlist = append(tlist, th)
+
+	return true, tlist
+}
+
+// Arguments disabling the TLS verification of the clusters and registries
+var insecureTLSArgReg = regexp.MustCompile(`^--?(insecure-skip-tls-verify|kubelet-insecure-tls|skip-tls-verify|insecure-registry|insecure)(=(.+))?$|^--tls-verify=false$`)
+
+// Environments disabling the TLS verification, matched by the name and the value
+var insecureTLSEnvs = map[string][]string{
+	"INSECURE_SKIP_TLS_VERIFY":     {"true", "1"},
+	"KUBE_INSECURE_SKIP_TLS":       {"true", "1"},
+	"GIT_SSL_NO_VERIFY":            {"true", "1"},
+	"NODE_TLS_REJECT_UNAUTHORIZED": {"0"},
+	"PYTHONHTTPSVERIFY":            {"0"},
+}
+
+// checkPodInsecureTLS check whether the container disables the TLS verification
+// of the clusters or registries by the arguments or the environments
+func checkPodInsecureTLS(container v1.Container) (bool, []*threat) {
+	tlist := []*threat{}
+
+	found := []string{}
+	for _, arg := range append(append([]string{}, container.Command...), container.Args...) {
+		if m := insecureTLSArgReg.FindStringSubmatch(arg); m != nil && m[3] != "false" {
+			found = append(found, fmt.Sprintf("args: %s", arg))
+		}
+	}
+
+	for _, env := range container.Env {
+		for name, values := range insecureTLSEnvs {
+			if !strings.HasSuffix(strings.ToUpper(env.Name), name) {
+				continue
+			}
+
+			for _, v := range values {
+				if strings.EqualFold(env.Value, v) {
+					found = append(found, fmt.Sprintf("env: %s=%s", env.Name, env.Value))
+				}
+			}
+		}
+	}
+
+	if len(found) < 1 {
+		return false, tlist
+	}
+
+	th := &threat{
+		Param: fmt.Sprintf("sidecar name: %s | "+
+			"TLS verification", container.Name),
+		Value: strings.Join(found, "; "),
+		Type:  "Sidecar TLS",
+		Describe: "Container disables the TLS verification of the cluster or registry, " +
+			"the connection is exposed to the man-in-the-middle attack.",
+		Remediation: "Remove the insecure option and trust the CA of the server instead.",
+		Severity:    "medium",
+	}
+	tlist = append(tlist, th)
+
+	return true, tlist
+}
+
+// checkPodNetRaw check whether the container can exploit CVE-2020-14386 with CAP_NET_RAW,
+// the capability is granted by the container runtime by default
+func checkPodNetRaw(container v1.Container, netRawKernel bool) (bool, []*threat) {
+	tlist := []*threat{}
+
+	if !netRawKernel {
+		return false, tlist
+	}
+
+	adds, drops := []string{}, []string{}
+	privileged := false
+	if container.SecurityContext != nil {
+		privileged = container.SecurityContext.Privileged != nil && *container.SecurityContext.Privileged
+
+		if container.SecurityContext.Capabilities != nil {
+			for _, c := range container.SecurityContext.Capabilities.Add {
+				adds = append(adds, string(c))
+			}
+
+			for _, c := range container.SecurityContext.Capabilities.Drop {
+				drops = append(drops, string(c))
+			}
+		}
+	}
+
+	held, how := holdsCapability("NET_RAW", adds, drops, privileged)
+	if !held {
+		return false, tlist
+	}
+
+	// The capability granted by the runtime is not added by the spec
+	tp, remediation := "capabilities.default", "Add NET_RAW to `securityContext.capabilities.drop` or upgrade the kernel of node."
+	switch how {
+	case "privileged":
+		tp, remediation = "Sidecar Privileged", "Remove `securityContext.privileged` or upgrade the kernel of node."
+	case "added":
+		tp, remediation = "capabilities.add", "Remove NET_RAW from `securityContext.capabilities.add` or upgrade the kernel of node."
+	}
+
+	th := &threat{
+		Param: fmt.Sprintf("sidecar name: %s | "+
+			"capabilities", container.Name),
+		Value: fmt.Sprintf("NET_RAW (%s)", how),
+		Type:  tp,
+		Describe: fmt.Sprintf("Kernel of node is vulnerable to CVE-2020-14386 and %s, "+
+			"the container escape is exploitable.", netRawGrant(how)),
+		Reference:   "https://nvd.nist.gov/vuln/detail/CVE-2020-14386",
+		Remediation: remediation,
+		Severity:    "critical",
+	}
+	tlist = append(tlist, th)
+
+	return true, tlist
+}
+
+// checkPodConfinement check whether the container is unconfined by AppArmor or seccomp
+func checkPodConfinement(container v1.Container, podSpec v1.PodSpec, annotations map[string]string) (bool, []*threat) {
+	tlist := []*threat{}
+	var vuln = false
+
+	if annotations[apparmorAnnotationPrefix+container.Name] == apparmorUnconfined {
+		th := &threat{
+			Param: fmt.Sprintf("sidecar name: %s | "+
+				"apparmor", container.Name),
+			Value:       apparmorUnconfined,
+			Type:        "Sidecar Confinement",
+			Describe:    "Container is unconfined by AppArmor.",
+			Remediation: "Set the AppArmor annotation of the container to `runtime/default`.",
+			Severity:    "medium",
+		}
+		tlist = append(tlist, th)
+		vuln = true
+	}
+
+	switch getSeccompProfileType(container, podSpec, annotations) {
+	case v1.SeccompProfileTypeUnconfined:
+		th := &threat{
+			Param: fmt.Sprintf("sidecar name: %s | "+
+				"seccompProfile", container.Name),
+			Value:       string(v1.SeccompProfileTypeUnconfined),
+			Type:        "Sidecar Confinement",
+			Describe:    "Container is unconfined by seccomp.",
+			Remediation: "Set `securityContext.seccompProfile.type: RuntimeDefault`.",
+			Severity:    "medium",
+		}
+		tlist = append(tlist, th)
+		vuln = true
+
+	case "":
+		th := &threat{
+			Param: fmt.Sprintf("sidecar name: %s | "+
+				"seccompProfile", container.Name),
+			Value:       "unset",
+			Type:        "Sidecar Confinement",
+			Describe:    "Container is not setting seccompProfile, which runs unconfined on many runtimes.",
+			Remediation: "Set `securityContext.seccompProfile.type: RuntimeDefault` for the pod or the container.",
+			Severity:    "low",
+		}
+		tlist = append(tlist, th)
+		vuln = true
+	}
+
+	return vuln, tlist
+}
+
+// getSeccompProfileType get the type of seccomp profile of container,
+// container overrides the pod and the fields override the deprecated annotations
+func getSeccompProfileType(container v1.Container, podSpec v1.PodSpec, annotations map[string]string) v1.SeccompProfileType {
+	if container.SecurityContext != nil && container.SecurityContext.SeccompProfile != nil {
+		return container.SecurityContext.SeccompProfile.Type
+	}
+
+	if profile, ok := annotations[seccompContainerAnnotationPrefix+container.Name]; ok {
+		return getSeccompAnnotationType(profile)
+	}
+
+	if podSpec.SecurityContext != nil && podSpec.SecurityContext.SeccompProfile != nil {
+		return podSpec.SecurityContext.SeccompProfile.Type
+	}
+
+	if profile, ok := annotations[seccompPodAnnotationKey]; ok {
+		return getSeccompAnnotationType(profile)
+	}
+
+	return ""
+}
+
+func getSeccompAnnotationType(profile string) v1.SeccompProfileType {
+	switch {
+	case profile == "runtime/default" || profile == "docker/default":
+		return v1.SeccompProfileTypeRuntimeDefault
+	case strings.HasPrefix(profile, "localhost/"):
+		return v1.SeccompProfileTypeLocalhost
+	case profile == "unconfined":
+		return v1.SeccompProfileTypeUnconfined
+	}
+
+	return ""
+}
+
+// correlateUnconfined escalates the findings of a container which is both privileged
+// and unconfined explicitly by AppArmor or seccomp, the compounding findings reference each other.
+// The unset seccompProfile is left alone, it is the default of most pods
+func correlateUnconfined(tlist []*threat) {
+	var privileged *threat
+	unconfined := []*threat{}
+
+	for _, th := range tlist {
+		switch {
+		case th.Type == "Sidecar Privileged" && strings.HasSuffix(th.Param, "| Privileged"):
+			privileged = th
+		case th.Type == "Sidecar Confinement" && strings.EqualFold(th.Value, apparmorUnconfined):
+			unconfined = append(unconfined, th)
+		}
+	}
+
+	if privileged == nil || len(unconfined) < 1 {
+		return
+	}
+
+	params := []string{}
+	for _, th := range unconfined {
+		th.Severity = "critical"
+		th.Describe += fmt.Sprintf(" Container is also privileged, refer to '%s', "+
+			"the container escape is straightforward.", privileged.Param)
+
+		params = append(params, th.Param)
+	}
+
+	privileged.Describe += fmt.Sprintf(" Container is also unconfined, refer to '%s'.",
+		strings.Join(params, "', '"))
+}
+
+// checkPodScheduling check whether the pod is scheduled on the control-plane nodes
+// or tolerating the taint of control-plane
+func checkPodScheduling(pod v1.Pod, nodes map[string]*nodeInfo) (bool, []*threat) {
+	tlist := []*threat{}
+	var vuln = false
+
+	tolerations := []string{}
+	for _, t := range pod.Spec.Tolerations {
+		isControlPlane := t.Key == "" && t.Operator == v1.TolerationOpExists
+		for _, taint := range controlPlaneTaints {
+			if t.Key == taint {
+				isControlPlane = true
+			}
+		}
+
+		if isControlPlane {
+			key := t.Key
+			if key == "" {
+				key = "*"
+			}
+			tolerations = append(tolerations, fmt.Sprintf("%s:%s", key, t.Effect))
+		}
+	}
+
+	node, ok := nodes[pod.Spec.NodeName]
+	if ok && node.IsMaster {
+		th := &threat{
+			Param: fmt.Sprintf("node name: %s | "+
+				"tolerations", pod.Spec.NodeName),
+			Value:       fmt.Sprintf("node: %s | tolerations: %s", pod.Spec.NodeName, strings.Join(tolerations, ", ")),
+			Type:        "Pod Scheduling",
+			Describe:    "User workload is scheduled on the control-plane node, which increases the blast radius.",
+			Remediation: "Remove the nodeName or nodeSelector of the control-plane node from the pod.",
+			Severity:    "medium",
+		}
+		tlist = append(tlist, th)
+		vuln = true
+
+	} else if len(tolerations) > 0 {
+		th := &threat{
+			Param: fmt.Sprintf("node name: %s | "+
+				"tolerations", pod.Spec.NodeName),
+			Value:       fmt.Sprintf("node: %s | tolerations: %s", pod.Spec.NodeName, strings.Join(tolerations, ", ")),
+			Type:        "Pod Scheduling",
+			Describe:    "User workload is tolerating the taint of control-plane, which can be scheduled on the control-plane node.",
+			Remediation: "Remove the toleration of the control-plane taint from the pod.",
+			Severity:    "low",
+		}
+		tlist = append(tlist, th)
+		vuln = true
+	}
+
+	return vuln, tlist
+}
+
+// checkPodRestarts check the containers restarted repeatedly from the pod status,
+// a crash loop can be caused by the exploit attempt or the broken security control
+func checkPodRestarts(pod v1.Pod) (bool, []*threat) {
+	tlist := []*threat{}
+
+	statuses := append(append([]v1.ContainerStatus{}, pod.Status.InitContainerStatuses...),
+		pod.Status.ContainerStatuses...)
+
+	for _, st := range statuses {
+		if st.RestartCount < restartThreshold {
+			continue
+		}
+
+		// Value is kept stable for collapsing the replicas, the count differs between them
+		reason := "unknown"
+		if term := st.LastTerminationState.Terminated; term != nil {
+			reason = fmt.Sprintf("%s (exit code %d)", term.Reason, term.ExitCode)
+		}
+
+		waiting := ""
+		if st.State.Waiting != nil && st.State.Waiting.Reason != "" {
+			waiting = fmt.Sprintf(" The container is waiting for '%s'.", st.State.Waiting.Reason)
+		}
+
+		th := &threat{
+			Param: fmt.Sprintf("sidecar name: %s | "+
+				"restartCount", st.Name),
+			Value: fmt.Sprintf("last exit: %s", reason),
+			Type:  "Sidecar Restarts",
+			Describe: fmt.Sprintf("Container '%s' has restarted %d times, "+
+				"the crash loop could be an exploit attempt or a broken security control.%s",
+				st.Name, st.RestartCount, waiting),
+			Remediation: "Check the logs of the previous container with `kubectl logs --previous`.",
+			Severity:    "low",
+		}
+
+		tlist = append(tlist, th)
+	}
+
+	return len(tlist) > 0, tlist
+}
+
 func (ks KScanner) checkSidecarEnv(container v1.Container, ns string) (bool, []*threat) {
 	var vuln = false
 	tlist := []*threat{}
@@ -201,11 +1054,12 @@ func (ks KScanner) checkSidecarEnv(container v1.Container, ns string) (bool, []*
 			switch checkWeakPassword(env.Value) {
 			case "Weak":
 				th := &threat{
-					Param:    fmt.Sprintf("sidecar name: %s | env", container.Name),
-					Value:    fmt.Sprintf("%s: %s", env.Name, env.Value),
-					Type:     "Sidecar Env",
-					Describe: fmt.Sprintf("Container '%s' has found weak password: '%s'.", container.Name, env.Value),
-					Severity: "high",
+					Param:       fmt.Sprintf("sidecar name: %s | env", container.Name),
+					Value:       fmt.Sprintf("%s: %s", env.Name, env.Value),
+					Type:        "Sidecar Env",
+					Describe:    fmt.Sprintf("Container '%s' has found weak password: '%s'.", container.Name, env.Value),
+					Remediation: "Use a strong password of at least 8 characters mixing cases, digits and symbols. Pass it by a secret instead of the plain env.",
+					Severity:    "high",
 				}
 
 				tlist = append(tlist, th)
@@ -218,7 +1072,8 @@ func (ks KScanner) checkSidecarEnv(container v1.Container, ns string) (bool, []*
 					Type:  "Sidecar Env",
 					Describe: fmt.Sprintf("Container '%s' has found password '%s' "+
 						"need to be reinforeced.", container.Name, env.Value),
-					Severity: "medium",
+					Remediation: "Use a strong password of at least 8 characters mixing cases, digits and symbols. Pass it by a secret instead of the plain env.",
+					Severity:    "medium",
 				}
 
 				tlist = append(tlist, th)
@@ -233,7 +1088,8 @@ func (ks KScanner) checkSidecarEnv(container v1.Container, ns string) (bool, []*
 				Type:  "Secret",
 				Describe: fmt.Sprintf("Container '%s' has found extraordinary length of content, "+
 					"need to identify whether it is malicious payload.", container.Name),
-				Severity: "medium",
+				Remediation: "Move the content to a secret and reference it by `valueFrom.secretKeyRef`.",
+				Severity:    "medium",
 			}
 
 			tlist = append(tlist, th)
@@ -274,6 +1130,113 @@ func (ks KScanner) checkSidecarEnv(container v1.Container, ns string) (bool, []*
 	return vuln, tlist
 }
 
+// checkPodLoaderEnv check the variables of dynamic loader in the env of container,
+// the values from the references are not resolved
+func checkPodLoaderEnv(container v1.Container) (bool, []*threat) {
+	tlist := []*threat{}
+
+	writable := []string{}
+	for _, vm := range container.VolumeMounts {
+		if !vm.ReadOnly {
+			writable = append(writable, vm.MountPath)
+		}
+	}
+
+	for _, env := range container.Env {
+		if th := getLoaderEnvThreat(env.Name, env.Value, writable); th != nil {
+			th.Param = fmt.Sprintf("sidecar name: %s | env", container.Name)
+			th.Type = "Sidecar Env"
+			tlist = append(tlist, th)
+		}
+	}
+
+	return len(tlist) > 0, tlist
+}
+
+// largeConfigMapKeys is the count of keys regarded as a large ConfigMap for envFrom
+const largeConfigMapKeys = 20
+
+// checkPodEnvFrom check the envFrom injecting all the keys of Secret or large ConfigMap into the environment,
+// the environment is inherited by the child processes and exposed by the crash dumps and the debug endpoints
+func checkPodEnvFrom(container v1.Container, secrets *v1.SecretList, configMaps *v1.ConfigMapList) (bool, []*threat) {
+	tlist := []*threat{}
+
+	for _, envFrom := range container.EnvFrom {
+		switch {
+		case envFrom.SecretRef != nil:
+			name := envFrom.SecretRef.Name
+			value := fmt.Sprintf("Secret: %s", name)
+			if keys, ok := countSecretKeys(secrets, name); ok {
+				value = fmt.Sprintf("Secret: %s (%d keys)", name, keys)
+			}
+
+			th := &threat{
+				Param: fmt.Sprintf("sidecar name: %s | "+
+					"envFrom", container.Name),
+				Value: value,
+				Type:  "Sidecar EnvFrom",
+				Describe: fmt.Sprintf("All the keys of Secret '%s' are injected into the environment by envFrom, "+
+					"the credentials are exposed to every process of the container.", name),
+				Remediation: "Reference the required keys by `valueFrom.secretKeyRef`, " +
+					"or mount the Secret as a volume.",
+				Severity: "medium",
+			}
+
+			tlist = append(tlist, th)
+
+		case envFrom.ConfigMapRef != nil:
+			name := envFrom.ConfigMapRef.Name
+			keys, ok := countConfigMapKeys(configMaps, name)
+			if !ok || keys < largeConfigMapKeys {
+				continue
+			}
+
+			th := &threat{
+				Param: fmt.Sprintf("sidecar name: %s | "+
+					"envFrom", container.Name),
+				Value: fmt.Sprintf("ConfigMap: %s (%d keys)", name, keys),
+				Type:  "Sidecar EnvFrom",
+				Describe: fmt.Sprintf("All the %d keys of ConfigMap '%s' are injected into the environment by envFrom, "+
+					"which likely includes more than the container needs.", keys, name),
+				Remediation: "Reference the required keys by `valueFrom.configMapKeyRef`.",
+				Severity:    "low",
+			}
+
+			tlist = append(tlist, th)
+		}
+	}
+
+	return len(tlist) > 0, tlist
+}
+
+func countSecretKeys(secrets *v1.SecretList, name string) (int, bool) {
+	if secrets == nil {
+		return 0, false
+	}
+
+	for _, se := range secrets.Items {
+		if se.Name == name {
+			return len(se.Data) + len(se.StringData), true
+		}
+	}
+
+	return 0, false
+}
+
+func countConfigMapKeys(configMaps *v1.ConfigMapList, name string) (int, bool) {
+	if configMaps == nil {
+		return 0, false
+	}
+
+	for _, cm := range configMaps.Items {
+		if cm.Name == name {
+			return len(cm.Data) + len(cm.BinaryData), true
+		}
+	}
+
+	return 0, false
+}
+
 func checkResourcesLimits(container v1.Container) (bool, []*threat) {
 	var vuln = false
 	tlist := []*threat{}
@@ -282,11 +1245,12 @@ func checkResourcesLimits(container v1.Container) (bool, []*threat) {
 		th := &threat{
 			Param: fmt.Sprintf("sidecar name: %s | "+
 				"Resource", container.Name),
-			Value:     "memory, cpu, ephemeral-storage",
-			Type:      "Sidecar Resource",
-			Describe:  "None of resources is be limited.",
-			Reference: "https://kubernetes.io/docs/concepts/configuration/manage-resources-containers/",
-			Severity:  "low",
+			Value:       "memory, cpu, ephemeral-storage",
+			Type:        "Sidecar Resource",
+			Describe:    "None of resources is be limited.",
+			Reference:   "https://kubernetes.io/docs/concepts/configuration/manage-resources-containers/",
+			Remediation: "Set `resources.limits` of memory, cpu and ephemeral-storage.",
+			Severity:    "low",
 		}
 
 		tlist = append(tlist, th)
@@ -299,11 +1263,12 @@ func checkResourcesLimits(container v1.Container) (bool, []*threat) {
 		th := &threat{
 			Param: fmt.Sprintf("sidecar name: %s | "+
 				"Resource", container.Name),
-			Value:     "memory",
-			Type:      "Sidecar Resource",
-			Describe:  "Memory usage is not limited.",
-			Reference: "https://kubernetes.io/docs/concepts/configuration/manage-resources-containers/",
-			Severity:  "low",
+			Value:       "memory",
+			Type:        "Sidecar Resource",
+			Describe:    "Memory usage is not limited.",
+			Reference:   "https://kubernetes.io/docs/concepts/configuration/manage-resources-containers/",
+			Remediation: "Set `resources.limits.memory`.",
+			Severity:    "low",
 		}
 
 		tlist = append(tlist, th)
@@ -314,11 +1279,52 @@ func checkResourcesLimits(container v1.Container) (bool, []*threat) {
 		th := &threat{
 			Param: fmt.Sprintf("sidecar name: %s | "+
 				"Resource", container.Name),
-			Value:     "cpu",
-			Type:      "Sidecar Resource",
-			Describe:  "CPU usage is not limited.",
-			Reference: "https://kubernetes.io/docs/concepts/configuration/manage-resources-containers/",
-			Severity:  "low",
+			Value:       "cpu",
+			Type:        "Sidecar Resource",
+			Describe:    "CPU usage is not limited.",
+			Reference:   "https://kubernetes.io/docs/concepts/configuration/manage-resources-containers/",
+			Remediation: "Set `resources.limits.cpu`.",
+			Severity:    "low",
+		}
+
+		tlist = append(tlist, th)
+		vuln = true
+	}
+
+	return vuln, tlist
+}
+
+// checkPodProbes check whether the long-running container lacks liveness or readiness probe,
+// crashed or hung container will not be restarted and become an attack surface
+func checkPodProbes(container v1.Container) (bool, []*threat) {
+	var vuln = false
+	tlist := []*threat{}
+
+	// Skip the sidecar of istio
+	if container.Name == "istio-proxy" {
+		return vuln, tlist
+	}
+
+	missing := []string{}
+	if container.LivenessProbe == nil {
+		missing = append(missing, "livenessProbe")
+	}
+
+	if container.ReadinessProbe == nil {
+		missing = append(missing, "readinessProbe")
+	}
+
+	if len(missing) > 0 {
+		th := &threat{
+			Param: fmt.Sprintf("sidecar name: %s | "+
+				"probes", container.Name),
+			Value: strings.Join(missing, ", "),
+			Type:  "Sidecar Probe",
+			Describe: fmt.Sprintf("Long-running container is not setting %s, "+
+				"crashed or hung container will not be detected.", strings.Join(missing, " and ")),
+			Reference:   "https://kubernetes.io/docs/tasks/configure-pod-container/configure-liveness-readiness-startup-probes/",
+			Remediation: "Add the missing probes to the container.",
+			Severity:    "warning",
 		}
 
 		tlist = append(tlist, th)
@@ -328,6 +1334,127 @@ func checkResourcesLimits(container v1.Container) (bool, []*threat) {
 	return vuln, tlist
 }
 
+// serviceAccountPaths are the paths of the service account token in container, /var/run is linked to /run
+var serviceAccountPaths = []string{"/var/run/secrets/kubernetes.io/serviceaccount", "/run/secrets/kubernetes.io/serviceaccount"}
+
+// checkPodTokenMount check whether the mount of service account token is writable,
+// or the token is shadowed by another volume mounted on or under its path
+func checkPodTokenMount(container v1.Container, volumes []v1.Volume) (bool, []*threat) {
+	tlist := []*threat{}
+
+	volumeOf := map[string]v1.Volume{}
+	for _, vol := range volumes {
+		volumeOf[vol.Name] = vol
+	}
+
+	for _, vm := range container.VolumeMounts {
+		mountPath := path.Clean(vm.MountPath)
+
+		onToken, underToken := false, false
+		for _, p := range serviceAccountPaths {
+			onToken = onToken || mountPath == p
+			underToken = underToken || strings.HasPrefix(mountPath, p+"/")
+		}
+
+		if !onToken && !underToken {
+			continue
+		}
+
+		vol, ok := volumeOf[vm.Name]
+		volType := "unknown"
+		if ok {
+			volType = getVolumeType(vol)
+		}
+
+		// Token volume injected by the admission is absent in the manifest
+		isToken := strings.HasPrefix(vm.Name, "kube-api-access-") || legacyTokenReg.MatchString(vm.Name) ||
+			(ok && isTokenVolume(vol))
+
+		th := &threat{
+			Param: fmt.Sprintf("sidecar name: %s | "+
+				"volumeMounts", container.Name),
+			Value: fmt.Sprintf("mountPath: %s | volume: %s (%s) | readOnly: %t",
+				vm.MountPath, vm.Name, volType, vm.ReadOnly),
+			Type:     "Sidecar Token",
+			Severity: "medium",
+		}
+
+		switch {
+		case onToken && isToken:
+			if vm.ReadOnly {
+				continue
+			}
+
+			th.Describe = "Service account token is mounted writable, " +
+				"the token and the CA certificate read by the processes can be tampered."
+			th.Remediation = "Mount the service account token with `readOnly: true`."
+			th.Severity = "low"
+		case onToken:
+			th.Describe = fmt.Sprintf("Volume '%s' is mounted on the path of service account token, "+
+				"the token and the CA certificate read by the clients are replaced by the content of volume.", vm.Name)
+			th.Remediation = "Mount the volume on another path, and mount the token by a projected volume."
+		default:
+			th.Describe = fmt.Sprintf("Volume '%s' is mounted under the path of service account token, "+
+				"the files of token are overlaid by the content of volume.", vm.Name)
+			th.Remediation = "Mount the volume on another path."
+		}
+
+		// Content of these volumes is written by the other containers or the node
+		shared := volType == "emptyDir" || volType == "hostPath" || volType == "persistentVolumeClaim"
+		if !isToken && shared && !vm.ReadOnly {
+			th.Describe += " The volume is writable, the token can be forged by the other containers or the node."
+			th.Severity = "high"
+		}
+
+		tlist = append(tlist, th)
+	}
+
+	return len(tlist) > 0, tlist
+}
+
+// Secret of the service account token generated before kubernetes 1.24, such as default-token-x7k2p
+var legacyTokenReg = regexp.MustCompile(`-token-[a-z0-9]{5}$`)
+
+// isTokenVolume check whether the volume projects the service account token,
+// or is the secret of the token generated for the service account before kubernetes 1.24
+func isTokenVolume(vol v1.Volume) bool {
+	if vol.Secret != nil {
+		return legacyTokenReg.MatchString(vol.Secret.SecretName)
+	}
+
+	if vol.Projected == nil {
+		return false
+	}
+
+	for _, source := range vol.Projected.Sources {
+		if source.ServiceAccountToken != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getVolumeType get the type of the volume source
+func getVolumeType(vol v1.Volume) string {
+	switch {
+	case vol.Projected != nil:
+		return "projected"
+	case vol.Secret != nil:
+		return "secret"
+	case vol.ConfigMap != nil:
+		return "configMap"
+	case vol.EmptyDir != nil:
+		return "emptyDir"
+	case vol.HostPath != nil:
+		return "hostPath"
+	case vol.PersistentVolumeClaim != nil:
+		return "persistentVolumeClaim"
+	}
+
+	return "other"
+}
+
 // checkPodAccountService check the default mount of service account
 func checkPodAccountService(container v1.Container, rv RBACVuln) (bool, []*threat) {
 	var vuln = false
@@ -339,10 +1466,11 @@ func checkPodAccountService(container v1.Container, rv RBACVuln) (bool, []*threa
 			th := &threat{
 				Param: fmt.Sprintf("sidecar name: %s | "+
 					"automountServiceAccountToken", container.Name),
-				Value:    "true",
-				Type:     vc.Name,
-				Describe: "Mount service account has a potential sensitive data leakage.",
-				Severity: "low",
+				Value:       "true",
+				Type:        vc.Name,
+				Describe:    "Mount service account has a potential sensitive data leakage.",
+				Remediation: "Set `automountServiceAccountToken: false` unless the pod needs to access the API server.",
+				Severity:    "low",
 			}
 
 			switch rv.Severity {
@@ -391,7 +1519,8 @@ func checkPodAnnotation(ans map[string]string) (bool, []*threat) {
 					Type:  "Pod Annotation",
 					Describe: fmt.Sprintf("Pod Annotation has some unsafe configs from %s"+
 						" and value is `%s`.", t.component, v),
-					Severity: t.level,
+					Remediation: "Remove the unsafe annotation from the pod.",
+					Severity:    t.level,
 				}
 
 				tlist = append(tlist, th)
@@ -423,7 +1552,8 @@ func (ks KScanner) checkPodCommand(container v1.Container, ns string) (bool, []*
 				Type:  "Pod Command",
 				Describe: "Container command has found extraordinary length of content, " +
 					"need to identify whether it is malicious command.",
-				Severity: "medium",
+				Remediation: "Move the content of command to a configmap or secret mounted in the pod.",
+				Severity:    "medium",
 			}
 
 			tlist = append(tlist, th)
@@ -446,7 +1576,8 @@ func (ks KScanner) checkPodCommand(container v1.Container, ns string) (bool, []*
 				Type:  "Pod Command",
 				Describe: "Container command arg has found extraordinary length of content, " +
 					"need to identify whether it is malicious command.",
-				Severity: "medium",
+				Remediation: "Move the content of args to a configmap or secret mounted in the pod.",
+				Severity:    "medium",
 			}
 
 			tlist = append(tlist, th)
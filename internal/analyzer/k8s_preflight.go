@@ -0,0 +1,164 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/kvesta/vesta/config"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// neededPermission is a permission of the resources read by the checks
+type neededPermission struct {
+	verb        string
+	resource    string
+	subresource string
+	group       string
+
+	// namespace of the review, empty for the cluster-scoped resources and all the namespaces
+	namespace string
+
+	// resource is read in each of the namespaces selected for the analysis
+	namespaced bool
+
+	// check reading the resource, the permission is needed by all the checks if empty
+	check string
+}
+
+// String format the permission in the form of `kubectl auth can-i`
+func (p neededPermission) String() string {
+	s := fmt.Sprintf("%s %s", p.verb, p.resource)
+	if p.group != "" {
+		s = fmt.Sprintf("%s.%s", s, p.group)
+	}
+
+	if p.subresource != "" {
+		s = fmt.Sprintf("%s --subresource=%s", s, p.subresource)
+	}
+
+	if p.namespace != "" {
+		s = fmt.Sprintf("%s -n %s", s, p.namespace)
+	}
+
+	return s
+}
+
+var neededPermissions = []neededPermission{
+	{verb: "list", resource: "namespaces"},
+	{verb: "list", resource: "nodes"},
+	{verb: "get", resource: "nodes", subresource: "proxy", check: "checkNodePosture"},
+	{verb: "list", resource: "persistentvolumes", check: "checkPersistentVolume"},
+	{verb: "list", resource: "clusterroles", group: "rbac.authorization.k8s.io"},
+	{verb: "list", resource: "clusterrolebindings", group: "rbac.authorization.k8s.io"},
+	{verb: "list", resource: "validatingwebhookconfigurations", group: "admissionregistration.k8s.io", check: "checkWebhooks"},
+	{verb: "list", resource: "mutatingwebhookconfigurations", group: "admissionregistration.k8s.io", check: "checkWebhooks"},
+	{verb: "get", resource: "deployments", group: "apps", namespace: "istio-system", check: "checkCNI"},
+	{verb: "get", resource: "deployments", group: "apps", namespace: "kube-system", check: "checkCNI"},
+	{verb: "list", resource: "pods", namespaced: true},
+	{verb: "create", resource: "pods", subresource: "exec", namespaced: true, check: "checkPod"},
+	{verb: "list", resource: "secrets", namespaced: true},
+	{verb: "list", resource: "configmaps", namespaced: true},
+	{verb: "list", resource: "services", namespaced: true, check: "checkService"},
+	{verb: "list", resource: "deployments", group: "apps", namespaced: true},
+	{verb: "list", resource: "daemonsets", group: "apps", namespaced: true, check: "checkDaemonSet"},
+	{verb: "get", resource: "replicasets", group: "apps", namespaced: true},
+	{verb: "list", resource: "jobs", group: "batch", namespaced: true, check: "checkJobs"},
+	{verb: "get", resource: "jobs", group: "batch", namespaced: true},
+	{verb: "list", resource: "cronjobs", group: "batch", namespaced: true, check: "checkCronJobs"},
+	{verb: "list", resource: "roles", group: "rbac.authorization.k8s.io", namespaced: true},
+	{verb: "list", resource: "rolebindings", group: "rbac.authorization.k8s.io", namespaced: true},
+	{verb: "list", resource: "poddisruptionbudgets", group: "policy", namespaced: true, check: "checkDisruptionBudgets"},
+}
+
+// permissionsOf get the permissions needed by the enabled checks,
+// the namespaced ones are reviewed in each of the namespaces, or in all the namespaces if none is selected
+func permissionsOf(ctx context.Context, namespaces []string) []neededPermission {
+	permissions := []neededPermission{}
+
+	for _, p := range neededPermissions {
+		if p.check != "" && !isCheckEnabled(ctx, p.check) {
+			continue
+		}
+
+		if !p.namespaced || len(namespaces) < 1 {
+			permissions = append(permissions, p)
+			continue
+		}
+
+		for _, ns := range namespaces {
+			np := p
+			np.namespace = ns
+			permissions = append(permissions, np)
+		}
+	}
+
+	return permissions
+}
+
+// preflight review the permissions of the scanner in the namespaces by SelfSubjectAccessReview before the checks start,
+// the missing ones are reported up front instead of a half-complete scan
+func (ks *KScanner) preflight(ctx context.Context, namespaces []string) {
+	review := func(p neededPermission) (bool, error) {
+		sar := &authorizationv1.SelfSubjectAccessReview{
+			Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+				ResourceAttributes: &authorizationv1.ResourceAttributes{
+					Namespace:   p.namespace,
+					Verb:        p.verb,
+					Resource:    p.resource,
+					Subresource: p.subresource,
+					Group:       p.group,
+				},
+			},
+		}
+
+		res, err := ks.KClient.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, sar, metav1.CreateOptions{})
+		if err != nil {
+			return false, err
+		}
+
+		return res.Status.Allowed, nil
+	}
+
+	missing, err := getMissingPermissions(permissionsOf(ctx, namespaces), review)
+	if err != nil {
+		config.ErrorLog.Printf("failed to review the permissions of scanner, error: %v", err)
+		return
+	}
+
+	ks.MissingPermissions = missing
+	if len(missing) < 1 {
+		return
+	}
+
+	log.Printf(config.Yellow(fmt.Sprintf("Scanner is missing %d permissions, "+
+		"the checks of the resources will be incomplete:", len(missing))))
+	for _, p := range missing {
+		log.Printf("  kubectl auth can-i %s", p)
+	}
+}
+
+// getMissingPermissions get the permissions denied by the review,
+// the review failed at the first permission is returned as error since the others would fail in the same way
+func getMissingPermissions(permissions []neededPermission, review func(p neededPermission) (bool, error)) ([]string, error) {
+	missing := []string{}
+
+	for i, p := range permissions {
+		allowed, err := review(p)
+		if err != nil {
+			if i == 0 {
+				return nil, err
+			}
+
+			config.ErrorLog.Printf("failed to review the permission '%s', error: %v", p, err)
+			continue
+		}
+
+		if !allowed {
+			missing = append(missing, p.String())
+		}
+	}
+
+	return missing, nil
+}
@@ -1,38 +1,28 @@
 package analyzer
 
 import (
-	"context"
 	"fmt"
 	"log"
 	"regexp"
 	"strings"
 
 	"github.com/kvesta/vesta/config"
+	v1 "k8s.io/api/core/v1"
 	rv1 "k8s.io/api/rbac/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 func (ks *KScanner) checkRoleBinding(ns string) error {
-	rbs, err := ks.KClient.
-		RbacV1().
-		RoleBindings(ns).
-		List(context.TODO(), metav1.ListOptions{})
+	rbs, err := ks.listRoleBindings(ns)
 	if err != nil {
 		return err
 	}
 
-	rls, err := ks.KClient.
-		RbacV1().
-		Roles(ns).
-		List(context.TODO(), metav1.ListOptions{})
+	rls, err := ks.listRoles(ns)
 	if err != nil {
 		return err
 	}
 
-	clr, err := ks.KClient.
-		RbacV1().
-		ClusterRoles().
-		List(context.TODO(), metav1.ListOptions{})
+	clr, err := ks.listClusterRoles()
 	if err != nil {
 		return err
 	}
@@ -168,18 +158,12 @@ func (ks *KScanner) checkRoleBinding(ns string) error {
 func (ks *KScanner) checkClusterBinding() error {
 	log.Printf(config.Yellow("Begin ClusterRoleBinding analyzing"))
 
-	clrb, err := ks.KClient.
-		RbacV1().
-		ClusterRoleBindings().
-		List(context.TODO(), metav1.ListOptions{})
+	clrb, err := ks.listClusterRoleBindings()
 	if err != nil {
 		return err
 	}
 
-	clr, err := ks.KClient.
-		RbacV1().
-		ClusterRoles().
-		List(context.TODO(), metav1.ListOptions{})
+	clr, err := ks.listClusterRoles()
 	if err != nil {
 		return err
 	}
@@ -325,7 +309,10 @@ func checkMatchingRole(clr []rv1.ClusterRole, rol []rv1.Role, ruleName string) (
 				continue
 			}
 
-			th := &threat{}
+			th := &threat{
+				Remediation: "Grant the needed verbs and resources only, " +
+					"and bind the role to the specific service accounts instead of the groups or default account.",
+			}
 
 			// Check whether all permission are given
 			if rul.Verbs[0] == "*" && rul.Resources[0] == "*" {
@@ -384,10 +371,7 @@ func (ks *KScanner) checkConfigMap(ns string) error {
 
 	var password string
 
-	cfs, err := ks.KClient.
-		CoreV1().
-		ConfigMaps(ns).
-		List(context.TODO(), metav1.ListOptions{})
+	cfs, err := ks.listConfigMaps(ns)
 	if err != nil {
 		return err
 	}
@@ -416,11 +400,12 @@ func (ks *KScanner) checkConfigMap(ns string) error {
 				switch checkWeakPassword(password) {
 				case "Weak":
 					th := &threat{
-						Param:    fmt.Sprintf("ConfigMap Name: %s Namespace: %s", cf.Name, ns),
-						Value:    fmt.Sprintf("%s:%s", k, v),
-						Type:     "ConfigMap",
-						Describe: fmt.Sprintf("ConfigMap has found weak password: '%s'.", password),
-						Severity: "high",
+						Param:       fmt.Sprintf("ConfigMap Name: %s Namespace: %s", cf.Name, ns),
+						Value:       fmt.Sprintf("%s:%s", k, v),
+						Type:        "ConfigMap",
+						Describe:    fmt.Sprintf("ConfigMap has found weak password: '%s'.", password),
+						Remediation: "Use a strong password of at least 8 characters mixing cases, digits and symbols. Store it in a secret instead of the configmap.",
+						Severity:    "high",
 					}
 
 					ks.VulnConfigures = append(ks.VulnConfigures, th)
@@ -432,7 +417,8 @@ func (ks *KScanner) checkConfigMap(ns string) error {
 						Type:  "ConfigMap",
 						Describe: fmt.Sprintf("ConfigMap has found password '%s' "+
 							"need to be reinforeced.", password),
-						Severity: "medium",
+						Remediation: "Use a strong password of at least 8 characters mixing cases, digits and symbols. Store it in a secret instead of the configmap.",
+						Severity:    "medium",
 					}
 
 					ks.VulnConfigures = append(ks.VulnConfigures, th)
@@ -447,7 +433,8 @@ func (ks *KScanner) checkConfigMap(ns string) error {
 					Type:  "ConfigMap",
 					Describe: "ConfigMap finds extraordinary length of content, " +
 						"need to identify whether it is malicious payload.",
-					Severity: "medium",
+					Remediation: "Store the sensitive content in a secret instead of the configmap.",
+					Severity:    "medium",
 				}
 
 				ks.VulnConfigures = append(ks.VulnConfigures, th)
@@ -463,17 +450,31 @@ func (ks *KScanner) checkSecret(ns string) error {
 
 	var password string
 
-	ses, err := ks.KClient.
-		CoreV1().
-		Secrets(ns).
-		List(context.TODO(), metav1.ListOptions{})
+	ses, err := ks.listSecrets(ns)
 	if err != nil {
 		return err
 	}
 
+	var pods []v1.Pod
+
 	for _, se := range ses.Items {
 		data := se.Data
 
+		ks.recordSecretHash(se)
+
+		if isPullSecret(se) {
+			if pods == nil {
+				podList, err := ks.listPods(ns)
+				if err != nil {
+					config.ErrorLog.Printf("list pods for the pull secrets failed in namespace: %s, %v", ns, err)
+					podList = &v1.PodList{}
+				}
+				pods = append([]v1.Pod{}, podList.Items...)
+			}
+
+			ks.recordPullSecret(se, pods)
+		}
+
 		for k, v := range data {
 			needCheck := false
 
@@ -490,11 +491,12 @@ func (ks *KScanner) checkSecret(ns string) error {
 				switch checkWeakPassword(password) {
 				case "Weak":
 					th := &threat{
-						Param:    fmt.Sprintf("Secret Name: %s | Namspace: %s", se.Name, ns),
-						Value:    fmt.Sprintf("%s:%s", k, v),
-						Type:     "Secret",
-						Describe: fmt.Sprintf("Secret has found weak password: '%s'.", password),
-						Severity: "high",
+						Param:       fmt.Sprintf("Secret Name: %s | Namspace: %s", se.Name, ns),
+						Value:       fmt.Sprintf("%s:%s", k, v),
+						Type:        "Secret",
+						Describe:    fmt.Sprintf("Secret has found weak password: '%s'.", password),
+						Remediation: "Use a strong password of at least 8 characters mixing cases, digits and symbols.",
+						Severity:    "high",
 					}
 
 					ks.VulnConfigures = append(ks.VulnConfigures, th)
@@ -506,7 +508,8 @@ func (ks *KScanner) checkSecret(ns string) error {
 						Type:  "Secret",
 						Describe: fmt.Sprintf("Secret has found password '%s' "+
 							"need to be reinforeced.", password),
-						Severity: "medium",
+						Remediation: "Use a strong password of at least 8 characters mixing cases, digits and symbols.",
+						Severity:    "medium",
 					}
 
 					ks.VulnConfigures = append(ks.VulnConfigures, th)
@@ -522,7 +525,8 @@ func (ks *KScanner) checkSecret(ns string) error {
 					Type:  "Secret",
 					Describe: "Secret finds extraordinary length of content, " +
 						"need to identify whether it is malicious payload.",
-					Severity: "medium",
+					Remediation: "Check whether the content is a leaked credential and rotate it.",
+					Severity:    "medium",
 				}
 
 				ks.VulnConfigures = append(ks.VulnConfigures, th)
@@ -538,10 +542,7 @@ func (ks KScanner) checkSecretFromName(ns, key, seName, envName string) (bool, *
 	var vuln = false
 	th := &threat{}
 
-	ses, err := ks.KClient.
-		CoreV1().
-		Secrets(ns).
-		List(context.TODO(), metav1.ListOptions{})
+	ses, err := ks.listSecrets(ns)
 	if err != nil {
 		return vuln, th
 	}
@@ -563,10 +564,7 @@ func (ks KScanner) checkConfigFromName(ns, key, seName, envName string) (bool, *
 	var vuln = false
 	th := &threat{}
 
-	ses, err := ks.KClient.
-		CoreV1().
-		ConfigMaps(ns).
-		List(context.TODO(), metav1.ListOptions{})
+	ses, err := ks.listConfigMaps(ns)
 	if err != nil {
 		return vuln, th
 	}
@@ -588,10 +586,7 @@ func (ks KScanner) findSecretOrConfigMapValue(name, com, ns string) string {
 
 	switch com {
 	case "ConfigMap":
-		ses, err := ks.KClient.
-			CoreV1().
-			ConfigMaps(ns).
-			List(context.TODO(), metav1.ListOptions{})
+		ses, err := ks.listConfigMaps(ns)
 		if err != nil {
 			return ""
 		}
@@ -606,10 +601,7 @@ func (ks KScanner) findSecretOrConfigMapValue(name, com, ns string) string {
 		}
 
 	case "Secret":
-		ses, err := ks.KClient.
-			CoreV1().
-			Secrets(ns).
-			List(context.TODO(), metav1.ListOptions{})
+		ses, err := ks.listSecrets(ns)
 		if err != nil {
 			return ""
 		}
@@ -661,10 +653,11 @@ func findVulnEnvName[T []byte | string](data map[string]T, key, envName, tp stri
 		switch checkWeakPassword(password) {
 		case "Weak":
 			th = &threat{
-				Value:    fmt.Sprintf("%s:%s", k, v),
-				Type:     fmt.Sprintf("Sidecar Env %s", tp),
-				Describe: fmt.Sprintf("Sidecar env '%s' has found weak key: '%s'.", envName, password),
-				Severity: "high",
+				Value:       fmt.Sprintf("%s:%s", k, v),
+				Type:        fmt.Sprintf("Sidecar Env %s", tp),
+				Describe:    fmt.Sprintf("Sidecar env '%s' has found weak key: '%s'.", envName, password),
+				Remediation: "Use a strong password of at least 8 characters mixing cases, digits and symbols.",
+				Severity:    "high",
 			}
 			vuln = true
 			break
@@ -674,7 +667,8 @@ func findVulnEnvName[T []byte | string](data map[string]T, key, envName, tp stri
 				Type:  fmt.Sprintf("Sidecar Env %s", tp),
 				Describe: fmt.Sprintf("Sidecar env '%s' has found key '%s' "+
 					"need to be reinforeced.", envName, password),
-				Severity: "medium",
+				Remediation: "Use a strong password of at least 8 characters mixing cases, digits and symbols.",
+				Severity:    "medium",
 			}
 			vuln = true
 			break
@@ -0,0 +1,264 @@
+package analyzer
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// recordSecretHash record the hash of secret data for the correlation across namespaces,
+// only the hash is kept
+func (ks *KScanner) recordSecretHash(se v1.Secret) {
+	// Tokens of service account are generated per namespace,
+	// registry credentials are correlated by checkPullSecrets with the pods using them
+	if se.Type == v1.SecretTypeServiceAccountToken || isPullSecret(se) || len(se.Data) < 1 {
+		return
+	}
+
+	if ks.secretHashes == nil {
+		ks.secretHashes = map[string][]string{}
+	}
+
+	hash := getSecretHash(se.Data)
+	ks.secretHashes[hash] = append(ks.secretHashes[hash], fmt.Sprintf("%s/%s", se.Namespace, se.Name))
+}
+
+// getSecretHash computes the hash of secret data independent of the order of keys
+func getSecretHash(data map[string][]byte) string {
+	keys := []string{}
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	h := sha256.New()
+	for _, k := range keys {
+		h.Write([]byte(k))
+		h.Write([]byte{0})
+		h.Write(data[k])
+		h.Write([]byte{0})
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// checkSecretReuse check the identical secret data which is copied into many namespaces
+func (ks *KScanner) checkSecretReuse() error {
+	hashes := []string{}
+	for hash := range ks.secretHashes {
+		hashes = append(hashes, hash)
+	}
+	sort.Strings(hashes)
+
+	for _, hash := range hashes {
+		secrets := ks.secretHashes[hash]
+
+		namespaces := map[string]bool{}
+		for _, se := range secrets {
+			namespaces[strings.Split(se, "/")[0]] = true
+		}
+
+		if len(namespaces) < 2 {
+			continue
+		}
+
+		th := &threat{
+			Param: fmt.Sprintf("Secret data hash: %s | Namespaces: %d", hash[:12], len(namespaces)),
+			Value: strings.Join(secrets, ", "),
+			Type:  "Secret",
+			Describe: fmt.Sprintf("Identical secret data is reused across %d namespaces, "+
+				"one compromise affects all of them, consider centralizing and rotating it.", len(namespaces)),
+			Remediation: "Create a separate secret with different credentials for each namespace.",
+			Severity:    "warning",
+		}
+
+		if len(namespaces) > 2 {
+			th.Severity = "medium"
+		}
+
+		ks.VulnConfigures = append(ks.VulnConfigures, th)
+	}
+
+	return nil
+}
+
+// Usernames of the registries which log in with the short-lived tokens
+var shortLivedRegistryUsers = []string{
+	"oauth2accesstoken",                    // GCR and GAR
+	"aws",                                  // ECR, valid for 12 hours
+	"<token>",                              // Docker and ACR identity token
+	"00000000-0000-0000-0000-000000000000", // ACR refresh token
+}
+
+// pullSecretUsage is the usage of the identical registry credentials in the cluster
+type pullSecretUsage struct {
+	secrets    []string
+	namespaces map[string]bool
+	registries []string
+	pods       int
+	longLived  bool
+}
+
+type dockerAuth struct {
+	Username      string `json:"username"`
+	Password      string `json:"password"`
+	Auth          string `json:"auth"`
+	IdentityToken string `json:"identitytoken"`
+	RegistryToken string `json:"registrytoken"`
+}
+
+// isPullSecret check whether the secret holds the registry credentials
+func isPullSecret(se v1.Secret) bool {
+	return se.Type == v1.SecretTypeDockerConfigJson || se.Type == v1.SecretTypeDockercfg
+}
+
+// parseDockerAuths get the credentials of registries from the pull secret
+func parseDockerAuths(se v1.Secret) (map[string]dockerAuth, error) {
+	auths := map[string]dockerAuth{}
+
+	if data, ok := se.Data[v1.DockerConfigJsonKey]; ok {
+		config := struct {
+			Auths map[string]dockerAuth `json:"auths"`
+		}{}
+		err := json.Unmarshal(data, &config)
+
+		return config.Auths, err
+	}
+
+	if data, ok := se.Data[v1.DockerConfigKey]; ok {
+		err := json.Unmarshal(data, &auths)
+		return auths, err
+	}
+
+	return auths, nil
+}
+
+// isLongLivedAuth check whether the credential is a password instead of a short-lived token
+func isLongLivedAuth(auth dockerAuth) bool {
+	if auth.IdentityToken != "" || auth.RegistryToken != "" {
+		return false
+	}
+
+	username, password := auth.Username, auth.Password
+	if auth.Auth != "" {
+		decoded, err := base64.StdEncoding.DecodeString(auth.Auth)
+		if err == nil {
+			parts := strings.SplitN(string(decoded), ":", 2)
+			username = parts[0]
+			if len(parts) > 1 {
+				password = parts[1]
+			}
+		}
+	}
+
+	if password == "" {
+		return false
+	}
+
+	for _, u := range shortLivedRegistryUsers {
+		if strings.EqualFold(username, u) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// recordPullSecret record the registries and the pods referencing the pull secret,
+// the credentials are correlated by the hash only
+func (ks *KScanner) recordPullSecret(se v1.Secret, pods []v1.Pod) {
+	auths, err := parseDockerAuths(se)
+	if err != nil || len(auths) < 1 {
+		return
+	}
+
+	if ks.pullSecrets == nil {
+		ks.pullSecrets = map[string]*pullSecretUsage{}
+	}
+
+	hash := getSecretHash(se.Data)
+	usage, ok := ks.pullSecrets[hash]
+	if !ok {
+		usage = &pullSecretUsage{namespaces: map[string]bool{}}
+		ks.pullSecrets[hash] = usage
+
+		for registry, auth := range auths {
+			usage.registries = append(usage.registries, registry)
+			usage.longLived = usage.longLived || isLongLivedAuth(auth)
+		}
+		sort.Strings(usage.registries)
+	}
+
+	usage.secrets = append(usage.secrets, fmt.Sprintf("%s/%s", se.Namespace, se.Name))
+	usage.namespaces[se.Namespace] = true
+
+	for _, pod := range pods {
+		for _, ref := range pod.Spec.ImagePullSecrets {
+			if ref.Name == se.Name {
+				usage.pods++
+				break
+			}
+		}
+	}
+}
+
+// Pull secrets referenced by the pods or namespaces more than these are widely used
+const (
+	widePullSecretPods       = 10
+	widePullSecretNamespaces = 3
+)
+
+// checkPullSecrets check the registry credentials shared by many pods across namespaces,
+// the credentials are never printed
+func (ks *KScanner) checkPullSecrets() error {
+	hashes := []string{}
+	for hash := range ks.pullSecrets {
+		hashes = append(hashes, hash)
+	}
+	sort.Strings(hashes)
+
+	for _, hash := range hashes {
+		usage := ks.pullSecrets[hash]
+
+		isWide := usage.pods >= widePullSecretPods || len(usage.namespaces) >= widePullSecretNamespaces
+
+		kind := "short-lived tokens"
+		if usage.longLived {
+			kind = "long-lived passwords"
+		}
+
+		var severity string
+		switch {
+		case usage.longLived && isWide:
+			severity = "high"
+		case usage.longLived:
+			severity = "low"
+		case isWide:
+			severity = "warning"
+		default:
+			continue
+		}
+
+		th := &threat{
+			Param: fmt.Sprintf("Pull secret: %s | Pods: %d | Namespaces: %d",
+				strings.Join(usage.secrets, ", "), usage.pods, len(usage.namespaces)),
+			Value: fmt.Sprintf("registries: %s", strings.Join(usage.registries, ", ")),
+			Type:  "Secret",
+			Describe: fmt.Sprintf("Registry credentials with %s are referenced by %d pod(s) across %d namespace(s), "+
+				"a leak of them exposes the registries to all of them.", kind, usage.pods, len(usage.namespaces)),
+			Remediation: "Use the short-lived tokens of registry or the credential provider of kubelet, " +
+				"and create a separate credential for each namespace.",
+			Severity: severity,
+		}
+
+		ks.VulnConfigures = append(ks.VulnConfigures, th)
+	}
+
+	return nil
+}
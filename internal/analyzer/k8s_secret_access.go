@@ -0,0 +1,181 @@
+package analyzer
+
+import (
+	"fmt"
+	"strings"
+
+	rv1 "k8s.io/api/rbac/v1"
+)
+
+// secretReadVerbs are the verbs reading the content of secrets
+var secretReadVerbs = []string{"get", "list", "watch"}
+
+// checkSecretAccess check the subjects reading the secrets through the bindings,
+// the permission on all the namespaces is the credential of the whole cluster
+func (ks *KScanner) checkSecretAccess() error {
+	clr, err := ks.listClusterRoles()
+	if err != nil {
+		return err
+	}
+
+	rls, err := ks.listRoles("")
+	if err != nil {
+		return err
+	}
+
+	clrb, err := ks.listClusterRoleBindings()
+	if err != nil {
+		return err
+	}
+
+	for _, rb := range clrb.Items {
+		rules := getBindingRules(rb.RoleRef, "", clr.Items, rls.Items)
+		tlist := getSecretAccessThreats(rb.Name, "", rb.RoleRef, rb.Subjects, rules)
+		ks.VulnConfigures = append(ks.VulnConfigures, tlist...)
+	}
+
+	rbs, err := ks.listRoleBindings("")
+	if err != nil {
+		return err
+	}
+
+	for _, rb := range rbs.Items {
+		rules := getBindingRules(rb.RoleRef, rb.Namespace, clr.Items, rls.Items)
+		tlist := getSecretAccessThreats(rb.Name, rb.Namespace, rb.RoleRef, rb.Subjects, rules)
+		ks.VulnConfigures = append(ks.VulnConfigures, tlist...)
+	}
+
+	return nil
+}
+
+// getBindingRules get the rules of the role referenced by the binding,
+// role of RoleBinding is in the namespace of the binding
+func getBindingRules(ref rv1.RoleRef, ns string, clr []rv1.ClusterRole, rls []rv1.Role) []rv1.PolicyRule {
+	switch ref.Kind {
+	case "ClusterRole":
+		for _, r := range clr {
+			if r.Name == ref.Name {
+				return r.Rules
+			}
+		}
+	case "Role":
+		for _, r := range rls {
+			if r.Name == ref.Name && r.Namespace == ns {
+				return r.Rules
+			}
+		}
+	}
+
+	return nil
+}
+
+// getSecretReadVerbs get the verbs reading all the secrets granted by the rules,
+// the rules limited by resourceNames are skipped and the wildcard resources are left to the RBAC checks
+func getSecretReadVerbs(rules []rv1.PolicyRule) []string {
+	verbs := []string{}
+
+	for _, rul := range rules {
+		if len(rul.ResourceNames) > 0 {
+			continue
+		}
+
+		if !containsAny(rul.APIGroups, "", "*") || !containsAny(rul.Resources, "secrets") {
+			continue
+		}
+
+		for _, verb := range secretReadVerbs {
+			if containsAny(rul.Verbs, verb, "*") && !containsAny(verbs, verb) {
+				verbs = append(verbs, verb)
+			}
+		}
+	}
+
+	return verbs
+}
+
+func getSecretAccessThreats(bindingName, ns string, roleRef rv1.RoleRef,
+	subjects []rv1.Subject, rules []rv1.PolicyRule) []*threat {
+	tlist := []*threat{}
+
+	// Bindings of the control plane components are bootstrapped by kubernetes
+	if strings.HasPrefix(bindingName, "system:") {
+		return tlist
+	}
+
+	for _, wns := range namespaceWhileList {
+		if ns == wns {
+			return tlist
+		}
+	}
+
+	verbs := getSecretReadVerbs(rules)
+	if len(verbs) < 1 {
+		return tlist
+	}
+
+	scope := "cluster-wide"
+	if ns != "" {
+		scope = fmt.Sprintf("namespaced: %s", ns)
+	}
+
+	for _, sub := range subjects {
+		if isSystemSubject(sub) {
+			continue
+		}
+
+		th := &threat{
+			Param: fmt.Sprintf("binding name: %s | rolename: %s | role kind: %s "+
+				"| subject kind: %s | subject name: %s | scope: %s",
+				bindingName, roleRef.Name, roleRef.Kind, sub.Kind, sub.Name, scope),
+			Value: fmt.Sprintf("verbs: %s | resources: secrets", strings.Join(verbs, ", ")),
+			Type:  "Secret Access",
+			Describe: fmt.Sprintf("%s '%s' can read the secrets of all the namespaces, "+
+				"which gives the credentials of the whole cluster.", sub.Kind, sub.Name),
+			Remediation: "Bind the role by RoleBinding in the needed namespace, " +
+				"and limit the secrets by `resourceNames`.",
+			Severity: "critical",
+		}
+
+		if ns != "" {
+			th.Describe = fmt.Sprintf("%s '%s' can read all the secrets of namespace '%s', "+
+				"including the service account tokens.", sub.Kind, sub.Name, ns)
+			th.Remediation = "Limit the secrets by `resourceNames` of the role."
+			th.Severity = "medium"
+		}
+
+		tlist = append(tlist, th)
+	}
+
+	return tlist
+}
+
+// isSystemSubject check whether the subject is a component of control plane or the cluster administrators
+func isSystemSubject(sub rv1.Subject) bool {
+	switch sub.Kind {
+	case "User":
+		return strings.HasPrefix(sub.Name, "system:kube-")
+	case "Group":
+		return sub.Name == "system:masters"
+	case "ServiceAccount":
+		for _, wns := range namespaceWhileList {
+			if sub.Namespace == wns {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// containsAny check whether any of the targets is in the items
+func containsAny(items []string, targets ...string) bool {
+	for _, i := range items {
+		for _, t := range targets {
+			if i == t {
+				return true
+			}
+		}
+	}
+
+	return false
+}
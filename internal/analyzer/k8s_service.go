@@ -0,0 +1,207 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// checkService check the services exposing the sensitive workloads by NodePort or LoadBalancer,
+// and the dashboard which is bound with cluster-admin
+func (ks *KScanner) checkService(ns string) error {
+	svcs, err := ks.KClient.
+		CoreV1().
+		Services(ns).
+		List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	pods, err := ks.listPods(ns)
+	if err != nil {
+		return err
+	}
+
+	for _, svc := range svcs.Items {
+		if svc.Spec.Type != v1.ServiceTypeNodePort && svc.Spec.Type != v1.ServiceTypeLoadBalancer {
+			continue
+		}
+
+		if len(svc.Spec.Selector) < 1 {
+			continue
+		}
+
+		selector := labels.SelectorFromSet(svc.Spec.Selector)
+
+		for _, pod := range pods.Items {
+			if !selector.Matches(labels.Set(pod.Labels)) {
+				continue
+			}
+
+			images := getSensitiveImages(pod.Spec)
+			if len(images) < 1 {
+				continue
+			}
+
+			th := &threat{
+				Param: fmt.Sprintf("Service Name: %s | Namespace: %s | Type: %s",
+					svc.Name, ns, svc.Spec.Type),
+				Value: fmt.Sprintf("ports: %s", getExposedPorts(svc)),
+				Type:  "Service",
+				Describe: fmt.Sprintf("Service exposes the sensitive workload with images '%s' "+
+					"outside the cluster.", strings.Join(images, "', '")),
+				Remediation: "Change the type of service to ClusterIP, and expose it by an authenticated ingress if needed.",
+				Severity:    "medium",
+			}
+
+			if hasDashboardImage(images) && ks.isClusterAdmin(ns, pod.Spec.ServiceAccountName) {
+				th.Describe = "Kubernetes dashboard bound with cluster-admin is exposed outside the cluster, " +
+					"which will cause a potential takeover of the cluster."
+				th.Severity = "critical"
+			}
+
+			ks.VulnConfigures = append(ks.VulnConfigures, th)
+			break
+		}
+	}
+
+	deploys, err := ks.listDeployments(ns)
+	if err != nil {
+		return err
+	}
+
+	for _, dp := range deploys.Items {
+		if !hasDashboardImage(getSensitiveImages(dp.Spec.Template.Spec)) {
+			continue
+		}
+
+		sa := dp.Spec.Template.Spec.ServiceAccountName
+		if !ks.isClusterAdmin(ns, sa) {
+			continue
+		}
+
+		th := &threat{
+			Param:       fmt.Sprintf("Deployment Name: %s | Namespace: %s", dp.Name, ns),
+			Value:       fmt.Sprintf("serviceAccount: %s | clusterrole: cluster-admin", sa),
+			Type:        "Deployment",
+			Describe:    "Kubernetes dashboard is bound with cluster-admin, every login of dashboard can control the cluster.",
+			Remediation: "Bind the service account of dashboard to a read-only clusterrole instead of cluster-admin.",
+			Severity:    "high",
+		}
+
+		ks.VulnConfigures = append(ks.VulnConfigures, th)
+	}
+
+	return nil
+}
+
+// getSensitiveImages get the images of pod which are of the sensitive repositories
+func getSensitiveImages(podSpec v1.PodSpec) []string {
+	images := []string{}
+	seen := map[string]bool{}
+
+	for _, c := range podSpec.Containers {
+		if seen[c.Image] {
+			continue
+		}
+
+		repository := imageRepository(c.Image)
+		if isRepositoryIn(repository, sensitiveImages) || isRepositoryIn(repository, dashboardImages) {
+			images = append(images, c.Image)
+			seen[c.Image] = true
+		}
+	}
+
+	return images
+}
+
+func hasDashboardImage(images []string) bool {
+	for _, image := range images {
+		if isRepositoryIn(imageRepository(image), dashboardImages) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// imageRepository get the repository path of image without the registry, tag and digest,
+// e.g. `registry.example.com:5000/bitnami/redis:7.0` is `bitnami/redis`
+func imageRepository(image string) string {
+	name := strings.SplitN(image, "@", 2)[0]
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name = name[:i]
+	}
+
+	// The first segment is a registry if it looks like a host
+	parts := strings.SplitN(name, "/", 2)
+	if len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		name = parts[1]
+	}
+
+	return name
+}
+
+// isRepositoryIn check whether the segments of any name are the trailing path segments of repository,
+// e.g. `bitnami/redis` is `redis` but `redis-exporter` is not
+func isRepositoryIn(repository string, names []string) bool {
+	for _, name := range names {
+		if repository == name || strings.HasSuffix(repository, "/"+name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getExposedPorts format the ports of service, e.g. `30443->443/TCP`
+func getExposedPorts(svc v1.Service) string {
+	ports := []string{}
+
+	for _, p := range svc.Spec.Ports {
+		if p.NodePort > 0 {
+			ports = append(ports, fmt.Sprintf("%d->%d/%s", p.NodePort, p.Port, p.Protocol))
+		} else {
+			ports = append(ports, fmt.Sprintf("%d/%s", p.Port, p.Protocol))
+		}
+	}
+
+	return strings.Join(ports, ", ")
+}
+
+// isClusterAdmin check whether the service account is bound with cluster-admin
+func (ks *KScanner) isClusterAdmin(ns, sa string) bool {
+	if sa == "" {
+		sa = "default"
+	}
+
+	crbs, err := ks.listClusterRoleBindings()
+	if err != nil {
+		return false
+	}
+
+	for _, crb := range crbs.Items {
+		if crb.RoleRef.Kind != "ClusterRole" || crb.RoleRef.Name != "cluster-admin" {
+			continue
+		}
+
+		for _, sub := range crb.Subjects {
+			switch sub.Kind {
+			case "ServiceAccount":
+				if sub.Name == sa && sub.Namespace == ns {
+					return true
+				}
+			case "Group":
+				if sub.Name == "system:serviceaccounts" || sub.Name == "system:serviceaccounts:"+ns {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,187 @@
+package analyzer
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kvesta/vesta/config"
+	v1 "k8s.io/api/core/v1"
+)
+
+const tlsProbeTimeout = 3 * time.Second
+
+var tlsVersionNames = map[uint16]string{
+	tls.VersionTLS10: "TLS 1.0",
+	tls.VersionTLS11: "TLS 1.1",
+	tls.VersionTLS12: "TLS 1.2",
+	tls.VersionTLS13: "TLS 1.3",
+}
+
+// tlsProbe is the result of probing a TLS endpoint
+type tlsProbe struct {
+	// protocol and cipher negotiated by default
+	Version uint16
+	Cipher  uint16
+
+	// deprecated protocols accepted by the endpoint
+	LegacyVersions []uint16
+
+	// known-weak cipher suite accepted by the endpoint, 0 means none
+	WeakCipher uint16
+}
+
+// probeTLS handshake with the endpoint by default,
+// then by the deprecated protocols and the known-weak cipher suites
+func probeTLS(addr string) (*tlsProbe, error) {
+	dial := func(cfg *tls.Config) (tls.ConnectionState, error) {
+		cfg.InsecureSkipVerify = true
+
+		conn, err := tls.DialWithDialer(&net.Dialer{Timeout: tlsProbeTimeout}, "tcp", addr, cfg)
+		if err != nil {
+			return tls.ConnectionState{}, err
+		}
+		defer conn.Close()
+
+		return conn.ConnectionState(), nil
+	}
+
+	state, err := dial(&tls.Config{})
+	if err != nil {
+		return nil, err
+	}
+
+	probe := &tlsProbe{
+		Version: state.Version,
+		Cipher:  state.CipherSuite,
+	}
+
+	for _, version := range []uint16{tls.VersionTLS10, tls.VersionTLS11} {
+		if _, err := dial(&tls.Config{MinVersion: version, MaxVersion: version}); err == nil {
+			probe.LegacyVersions = append(probe.LegacyVersions, version)
+		}
+	}
+
+	weakCiphers := []uint16{}
+	for _, c := range tls.InsecureCipherSuites() {
+		weakCiphers = append(weakCiphers, c.ID)
+	}
+
+	// Cipher suites are not configurable in TLS 1.3
+	state, err = dial(&tls.Config{
+		MinVersion:   tls.VersionTLS10,
+		MaxVersion:   tls.VersionTLS12,
+		CipherSuites: weakCiphers,
+	})
+	if err == nil {
+		probe.WeakCipher = state.CipherSuite
+	}
+
+	return probe, nil
+}
+
+// getTLSThreats build the threats of the weak TLS configuration of the endpoint
+func getTLSThreats(component, addr string, probe *tlsProbe) []*threat {
+	tlist := []*threat{}
+
+	negotiated := fmt.Sprintf("%s, %s", tlsVersionNames[probe.Version], tls.CipherSuiteName(probe.Cipher))
+
+	if len(probe.LegacyVersions) > 0 {
+		names := []string{}
+		for _, v := range probe.LegacyVersions {
+			names = append(names, tlsVersionNames[v])
+		}
+		versions := strings.Join(names, ", ")
+
+		th := &threat{
+			Param: fmt.Sprintf("%s TLS: %s", component, addr),
+			Value: fmt.Sprintf("accepted: %s | negotiated: %s", versions, negotiated),
+			Type:  "TLS",
+			Describe: fmt.Sprintf("%s accepts the deprecated protocol %s, "+
+				"which is vulnerable to the downgrade attacks.", component, versions),
+			Remediation: "Set `--tls-min-version=VersionTLS12` or higher.",
+			Severity:    "medium",
+		}
+		tlist = append(tlist, th)
+	}
+
+	if probe.WeakCipher != 0 {
+		th := &threat{
+			Param: fmt.Sprintf("%s TLS: %s", component, addr),
+			Value: fmt.Sprintf("accepted: %s | negotiated: %s", tls.CipherSuiteName(probe.WeakCipher), negotiated),
+			Type:  "TLS",
+			Describe: fmt.Sprintf("%s accepts the known-weak cipher suite %s.",
+				component, tls.CipherSuiteName(probe.WeakCipher)),
+			Remediation: "Set `--tls-cipher-suites` with the ECDHE and AEAD cipher suites only.",
+			Severity:    "medium",
+		}
+		tlist = append(tlist, th)
+	}
+
+	return tlist
+}
+
+// checkTLS probe the API server and kubelet endpoints for the weak TLS configuration
+func (ks *KScanner) checkTLS() error {
+	log.Printf(config.Yellow("Begin TLS analyzing"))
+
+	endpoints := [][2]string{}
+
+	if ks.KConfig != nil {
+		if u, err := url.Parse(ks.KConfig.Host); err == nil && u.Scheme == "https" {
+			host := u.Host
+			if u.Port() == "" {
+				host = net.JoinHostPort(u.Hostname(), "443")
+			}
+
+			endpoints = append(endpoints, [2]string{"API server", host})
+		}
+	}
+
+	nodes, err := ks.listNodes()
+	if err != nil {
+		config.ErrorLog.Printf("list nodes failed: %v", err)
+	} else {
+		for _, node := range nodes.Items {
+			port := int(node.Status.DaemonEndpoints.KubeletEndpoint.Port)
+			if port == 0 {
+				port = 10250
+			}
+
+			for _, addr := range node.Status.Addresses {
+				if addr.Type == v1.NodeInternalIP {
+					endpoints = append(endpoints, [2]string{fmt.Sprintf("Kubelet of %s", node.Name),
+						net.JoinHostPort(addr.Address, strconv.Itoa(port))})
+					break
+				}
+			}
+		}
+	}
+
+	for _, ep := range endpoints {
+		component, addr := ep[0], ep[1]
+
+		probe, err := probeTLS(addr)
+		if err != nil {
+			th := &threat{
+				Param:    fmt.Sprintf("%s TLS: %s", component, addr),
+				Value:    "unreachable",
+				Type:     "TLS",
+				Describe: fmt.Sprintf("%s is not reachable, the TLS configuration is not checked: %v.", component, err),
+				Severity: "warning",
+			}
+			ks.VulnConfigures = append(ks.VulnConfigures, th)
+
+			continue
+		}
+
+		ks.VulnConfigures = append(ks.VulnConfigures, getTLSThreats(component, addr, probe)...)
+	}
+
+	return nil
+}
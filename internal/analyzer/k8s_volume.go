@@ -0,0 +1,148 @@
+package analyzer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// claimMount is a workload mounting the persistent volume claim
+type claimMount struct {
+	owner    string
+	fsGroup  int64
+	writable bool
+}
+
+// checkSharedVolumes check the persistent volume claims shared by the different workloads with the same fsGroup
+func (ks *KScanner) checkSharedVolumes(ns string) error {
+	pods, err := ks.listPods(ns)
+	if err != nil {
+		return err
+	}
+
+	tlist := getSharedVolumeThreats(ns, pods.Items, func(pod v1.Pod) string {
+		kind, name := ks.getPodOwner(pod)
+		return fmt.Sprintf("%s/%s", kind, name)
+	})
+
+	ks.VulnConfigures = append(ks.VulnConfigures, tlist...)
+
+	return nil
+}
+
+// getSharedVolumeThreats correlate the pods mounting the same claim with the same fsGroup,
+// the replicas of a workload are counted once
+func getSharedVolumeThreats(ns string, pods []v1.Pod, ownerOf func(pod v1.Pod) string) []*threat {
+	tlist := []*threat{}
+
+	// mounts of claim keyed by the claim and the owner
+	claims := map[string]map[string]*claimMount{}
+
+	for _, pod := range pods {
+		if pod.Spec.SecurityContext == nil || pod.Spec.SecurityContext.FSGroup == nil {
+			continue
+		}
+
+		volumes := map[string]string{}
+		for _, v := range pod.Spec.Volumes {
+			if v.PersistentVolumeClaim != nil {
+				volumes[v.Name] = v.PersistentVolumeClaim.ClaimName
+			}
+		}
+
+		if len(volumes) < 1 {
+			continue
+		}
+
+		owner := ownerOf(pod)
+		containers := append(append([]v1.Container{}, pod.Spec.InitContainers...), pod.Spec.Containers...)
+
+		for volume, claim := range volumes {
+			writable := false
+			for _, c := range containers {
+				for _, m := range c.VolumeMounts {
+					if m.Name == volume && !m.ReadOnly {
+						writable = true
+					}
+				}
+			}
+
+			// Read-only claim volume can not be written by any container
+			for _, v := range pod.Spec.Volumes {
+				if v.Name == volume && v.PersistentVolumeClaim.ReadOnly {
+					writable = false
+				}
+			}
+
+			if claims[claim] == nil {
+				claims[claim] = map[string]*claimMount{}
+			}
+
+			if cm, ok := claims[claim][owner]; ok {
+				cm.writable = cm.writable || writable
+				continue
+			}
+			claims[claim][owner] = &claimMount{owner: owner, fsGroup: *pod.Spec.SecurityContext.FSGroup, writable: writable}
+		}
+	}
+
+	names := []string{}
+	for claim := range claims {
+		names = append(names, claim)
+	}
+	sort.Strings(names)
+
+	for _, claim := range names {
+		// Workloads sharing the claim grouped by fsGroup
+		groups := map[int64][]*claimMount{}
+		for _, cm := range claims[claim] {
+			groups[cm.fsGroup] = append(groups[cm.fsGroup], cm)
+		}
+
+		for fsGroup, mounts := range groups {
+			if len(mounts) < 2 {
+				continue
+			}
+
+			sort.Slice(mounts, func(i, j int) bool {
+				return mounts[i].owner < mounts[j].owner
+			})
+
+			owners, writers := []string{}, 0
+			for _, cm := range mounts {
+				mode := "ro"
+				if cm.writable {
+					mode = "rw"
+					writers++
+				}
+				owners = append(owners, fmt.Sprintf("%s (%s)", cm.owner, mode))
+			}
+
+			// Data can be tampered by the other workloads only if one of them writes
+			if writers < 1 {
+				continue
+			}
+
+			th := &threat{
+				Param: fmt.Sprintf("PersistentVolumeClaim: %s | Namespace: %s | fsGroup: %d", claim, ns, fsGroup),
+				Value: strings.Join(owners, ", "),
+				Type:  "PersistentVolumeClaim",
+				Describe: fmt.Sprintf("%d workloads share the claim with the same fsGroup, "+
+					"a compromise of one of them can tamper with the data of the others.", len(mounts)),
+				Remediation: "Use a separate claim for each workload, or mount the shared claim with `readOnly: true` " +
+					"for the workloads which only read it.",
+				Severity: "low",
+			}
+
+			if writers > 1 {
+				th.Severity = "medium"
+			}
+
+			tlist = append(tlist, th)
+		}
+	}
+
+	return tlist
+}
@@ -0,0 +1,209 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/kvesta/vesta/config"
+	admissionv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Services of the policy engines serving the webhooks, keyed by the namespace of the default installation
+var policyEngineServices = map[string][]string{
+	"gatekeeper-system": {"gatekeeper-webhook-service"},
+	"kyverno":           {"kyverno-svc"},
+	"kubewarden":        {"kubewarden-policy-server-default"},
+	"opa":               {"opa"},
+}
+
+// webhookSpec is the common part of the validating and mutating webhooks
+type webhookSpec struct {
+	kind   string
+	config string
+	name   string
+
+	// service serving the webhook, nil for the webhook of URL
+	service *admissionv1.ServiceReference
+
+	rules             []admissionv1.RuleWithOperations
+	failurePolicy     *admissionv1.FailurePolicyType
+	namespaceSelector *metav1.LabelSelector
+	objectSelector    *metav1.LabelSelector
+}
+
+// checkWebhooks check the validating and mutating admission webhooks of the cluster
+func (ks *KScanner) checkWebhooks() error {
+	log.Printf(config.Yellow("Begin admission webhook analyzing"))
+
+	webhooks := []webhookSpec{}
+
+	vwcs, err := ks.KClient.
+		AdmissionregistrationV1().
+		ValidatingWebhookConfigurations().
+		List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	for _, cfg := range vwcs.Items {
+		for _, wh := range cfg.Webhooks {
+			webhooks = append(webhooks, webhookSpec{
+				kind: "ValidatingWebhookConfiguration", config: cfg.Name, name: wh.Name,
+				service: wh.ClientConfig.Service, rules: wh.Rules, failurePolicy: wh.FailurePolicy,
+				namespaceSelector: wh.NamespaceSelector, objectSelector: wh.ObjectSelector,
+			})
+		}
+	}
+
+	mwcs, err := ks.KClient.
+		AdmissionregistrationV1().
+		MutatingWebhookConfigurations().
+		List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	for _, cfg := range mwcs.Items {
+		for _, wh := range cfg.Webhooks {
+			webhooks = append(webhooks, webhookSpec{
+				kind: "MutatingWebhookConfiguration", config: cfg.Name, name: wh.Name,
+				service: wh.ClientConfig.Service, rules: wh.Rules, failurePolicy: wh.FailurePolicy,
+				namespaceSelector: wh.NamespaceSelector, objectSelector: wh.ObjectSelector,
+			})
+		}
+	}
+
+	for _, wh := range webhooks {
+		ks.VulnConfigures = append(ks.VulnConfigures, checkWebhook(wh)...)
+	}
+
+	return nil
+}
+
+// checkWebhook check whether the webhook intercepts the objects broadly,
+// or bypasses the security policy on failure
+func checkWebhook(wh webhookSpec) []*threat {
+	tlist := []*threat{}
+
+	isMutating := wh.kind == "MutatingWebhookConfiguration"
+	param := fmt.Sprintf("%s: %s | webhook: %s", wh.kind, wh.config, wh.name)
+
+	var wildcard, secrets, pods []string
+	for _, rule := range wh.rules {
+		operations := []string{}
+		for _, op := range rule.Operations {
+			operations = append(operations, string(op))
+		}
+
+		// Pods and secrets are of the core group, which is empty
+		allGroups, coreGroup := false, false
+		for _, group := range rule.APIGroups {
+			allGroups = allGroups || group == "*"
+			coreGroup = coreGroup || group == "" || group == "*"
+		}
+
+		for _, res := range rule.Resources {
+			match := fmt.Sprintf("resources: %s | operations: %s", res, strings.Join(operations, ", "))
+
+			switch strings.Split(res, "/")[0] {
+			case "*":
+				if allGroups {
+					wildcard = append(wildcard, match)
+				} else if coreGroup {
+					secrets = append(secrets, match)
+					pods = append(pods, match)
+				}
+			case "secrets":
+				if coreGroup {
+					secrets = append(secrets, match)
+				}
+			case "pods":
+				if coreGroup {
+					pods = append(pods, match)
+				}
+			}
+		}
+	}
+
+	if len(wildcard) > 0 {
+		th := &threat{
+			Param:       param,
+			Value:       strings.Join(wildcard, "; "),
+			Type:        "Webhook",
+			Describe:    "Admission webhook intercepts all the resources, every object sent to API server is exposed to it.",
+			Remediation: "Narrow the `rules` of webhook to the needed resources.",
+			Severity:    "medium",
+		}
+		if isMutating {
+			th.Describe = "Mutating admission webhook intercepts all the resources, every object can be modified by it."
+			th.Severity = "high"
+		}
+		tlist = append(tlist, th)
+	}
+
+	if len(secrets) > 0 {
+		th := &threat{
+			Param:       param,
+			Value:       strings.Join(secrets, "; "),
+			Type:        "Webhook",
+			Describe:    "Admission webhook intercepts the secrets, the secret data is sent to the webhook server.",
+			Remediation: "Remove secrets from the `rules` of webhook unless the webhook is trusted.",
+			Severity:    "medium",
+		}
+		if isMutating {
+			th.Severity = "high"
+		}
+		tlist = append(tlist, th)
+	}
+
+	// Mutating webhook without any selector can modify every pod in the cluster
+	if isMutating && len(pods) > 0 && isEmptySelector(wh.namespaceSelector) && isEmptySelector(wh.objectSelector) {
+		th := &threat{
+			Param:       param,
+			Value:       strings.Join(pods, "; "),
+			Type:        "Webhook",
+			Describe:    "Mutating admission webhook can modify pods cluster-wide, such as injecting containers or privileges.",
+			Remediation: "Restrict the webhook by `namespaceSelector` or `objectSelector`.",
+			Severity:    "high",
+		}
+		tlist = append(tlist, th)
+	}
+
+	isSecurity := isPolicyEngine(wh.service) || (!isMutating && (len(pods) > 0 || len(wildcard) > 0))
+	if isSecurity && wh.failurePolicy != nil && *wh.failurePolicy == admissionv1.Ignore {
+		th := &threat{
+			Param:       param,
+			Value:       "failurePolicy: Ignore",
+			Type:        "Webhook",
+			Describe:    "Security-relevant admission webhook fails open, the policy is bypassed once the webhook is unavailable.",
+			Remediation: "Set `failurePolicy: Fail` for the webhook.",
+			Severity:    "medium",
+		}
+		tlist = append(tlist, th)
+	}
+
+	return tlist
+}
+
+// isPolicyEngine check whether the webhook is served by a policy engine such as Gatekeeper and Kyverno
+func isPolicyEngine(service *admissionv1.ServiceReference) bool {
+	if service == nil {
+		return false
+	}
+
+	for _, name := range policyEngineServices[service.Namespace] {
+		if service.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isEmptySelector check whether the selector matches everything
+func isEmptySelector(selector *metav1.LabelSelector) bool {
+	return selector == nil || (len(selector.MatchLabels) < 1 && len(selector.MatchExpressions) < 1)
+}
@@ -0,0 +1,97 @@
+package analyzer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// maxOwnerDepth limits the walk of owner references
+const maxOwnerDepth = 5
+
+// getPodOwner resolves the workload which is controlling the pod by walking its owner references,
+// e.g. Pod -> ReplicaSet -> Deployment or Pod -> Job -> CronJob.
+// Orphan pods are attributed to themselves
+func (ks *KScanner) getPodOwner(pod v1.Pod) (string, string) {
+	ref := metav1.GetControllerOf(&pod)
+	if ref == nil {
+		return "Pod", pod.Name
+	}
+
+	kind, name := ref.Kind, ref.Name
+	for i := 0; i < maxOwnerDepth; i++ {
+		parent := ks.getControllerOwner(pod.Namespace, kind, name)
+		if parent == nil {
+			break
+		}
+
+		kind, name = parent.Kind, parent.Name
+	}
+
+	return kind, name
+}
+
+// getControllerOwner get the controller of an intermediate owner,
+// Deployment, StatefulSet, DaemonSet and CronJob are the top-level workloads
+func (ks *KScanner) getControllerOwner(ns, kind, name string) *metav1.OwnerReference {
+	if ks.owners == nil {
+		ks.owners = map[string]*metav1.OwnerReference{}
+	}
+
+	key := fmt.Sprintf("%s/%s/%s", ns, kind, name)
+	if ref, ok := ks.owners[key]; ok {
+		return ref
+	}
+
+	var ref *metav1.OwnerReference
+
+	switch kind {
+	case "ReplicaSet":
+		rs, err := ks.KClient.
+			AppsV1().
+			ReplicaSets(ns).
+			Get(context.TODO(), name, metav1.GetOptions{})
+		if err == nil {
+			ref = metav1.GetControllerOf(rs)
+		}
+		ks.run.swallow(err)
+
+	case "Job":
+		job, err := ks.KClient.
+			BatchV1().
+			Jobs(ns).
+			Get(context.TODO(), name, metav1.GetOptions{})
+		if err == nil {
+			ref = metav1.GetControllerOf(job)
+		}
+		ks.run.swallow(err)
+
+	default:
+		return nil
+	}
+
+	ks.owners[key] = ref
+
+	return ref
+}
+
+// getFingerprint computes a stable fingerprint of the findings of a workload,
+// identical replicas of the same workload get the same fingerprint
+func getFingerprint(ns, kind, name string, threats []*threat) string {
+	items := []string{}
+	for _, th := range threats {
+		items = append(items, strings.Join([]string{th.Type, th.Param, th.Value, th.Severity}, "|"))
+	}
+
+	sort.Strings(items)
+
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%s/%s/%s#%s", ns, kind, name, strings.Join(items, ";"))))
+
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,320 @@
+package analyzer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/kvesta/vesta/config"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Threat is the finding reported by the custom checks, it is the same finding of the built-in checks
+type Threat = threat
+
+// Check is a custom check compiled into vesta, it runs alongside the built-in checks
+// and its findings are reported, remapped and disabled by the name like the built-in ones.
+//
+// The target is one of:
+//   - *types.ContainerJSON: a container in the docker analysis, the findings are attached to the container
+//   - *NamespaceTarget: a namespace in the kubernetes analysis, the findings are reported as the configures
+//
+// Like the built-in namespace checks, the system namespaces of the white list are not targeted
+// unless they are selected by `--ns`, or all the namespaces are selected by `--ns all`.
+//
+// Run returns no finding for the targets it does not handle.
+type Check interface {
+	Name() string
+	Run(ctx context.Context, target interface{}) ([]*Threat, error)
+}
+
+// NamespaceTarget is the namespace checked by the custom checks in the kubernetes analysis
+type NamespaceTarget struct {
+	Namespace string
+
+	// Client of the cluster for querying the other objects
+	Client kubernetes.Interface
+
+	// Pods of the namespace, shared with the built-in checks
+	Pods []v1.Pod
+
+	// Users configured by the local images keyed by the image tag, the empty user is root
+	ImageUsers map[string]string
+}
+
+// RegisterCheck add the custom check to the registries of docker and kubernetes checks,
+// it is called by the init function of the package holding the check before the analysis starts
+func RegisterCheck(c Check) error {
+	name := c.Name()
+	if name == "" {
+		return fmt.Errorf("empty name of check")
+	}
+
+	for _, n := range checkNames() {
+		if n == name {
+			return fmt.Errorf("check %s is registered already", name)
+		}
+	}
+
+	dockerChecks = append(dockerChecks, dockerCheck{name: name, custom: c})
+	namespaceChecks = append(namespaceChecks, namespaceCheck{
+		name: name, desc: fmt.Sprintf("custom check %s", name), kind: "custom", skipWhiteList: true, custom: c})
+
+	return nil
+}
+
+// run the check against the container
+func (ch dockerCheck) run(ctx context.Context, s *Scanner, in *types.ContainerJSON) (bool, []*threat) {
+	if ch.custom == nil {
+		return ch.fn(s, in)
+	}
+
+	tlist, err := ch.custom.Run(ctx, in)
+	if err != nil {
+		config.ErrorLog.Printf("%s failed in container %s, %v", ch.name, in.ID[:12], err)
+		return false, nil
+	}
+
+	return len(tlist) > 0, tlist
+}
+
+// run the check in the namespace
+func (ch namespaceCheck) run(ctx context.Context, ks *KScanner, ns string) error {
+	if ch.custom == nil {
+		return ch.fn(ks, ns)
+	}
+
+	pods, err := ks.listPods(ns)
+	if err != nil {
+		return err
+	}
+
+	target := &NamespaceTarget{Namespace: ns, Pods: pods.Items, ImageUsers: ks.imageUsers}
+	if ks.KClient != nil {
+		target.Client = ks.KClient
+	}
+
+	tlist, err := ch.custom.Run(ctx, target)
+	for _, th := range tlist {
+		if th.Type == "" {
+			th.Type = ch.name
+		}
+	}
+	ks.VulnConfigures = append(ks.VulnConfigures, tlist...)
+
+	return err
+}
+
+// dockerFuncCheck is the docker check of a function registered by RegisterDockerCheck
+type dockerFuncCheck struct {
+	name string
+	fn   func(*types.ContainerJSON) (bool, []*Threat)
+}
+
+func (c *dockerFuncCheck) Name() string {
+	return c.name
+}
+
+func (c *dockerFuncCheck) Run(ctx context.Context, target interface{}) ([]*Threat, error) {
+	in, ok := target.(*types.ContainerJSON)
+	if !ok {
+		return nil, nil
+	}
+
+	if found, tlist := c.fn(in); found {
+		return tlist, nil
+	}
+
+	return nil, nil
+}
+
+// RegisterDockerCheck add the function checking the docker containers as a custom check,
+// the function has the same signature of the built-in docker checks
+func RegisterDockerCheck(name string, fn func(*types.ContainerJSON) (bool, []*Threat)) error {
+	if fn == nil {
+		return fmt.Errorf("nil function of check %s", name)
+	}
+
+	return RegisterCheck(&dockerFuncCheck{name: name, fn: fn})
+}
+
+// externalFinding is a finding reported by the policies and the plugin binaries,
+// a message only or an object with the fields of finding
+type externalFinding struct {
+	Msg         string `json:"msg"`
+	Severity    string `json:"severity"`
+	Param       string `json:"param"`
+	Value       string `json:"value"`
+	Type        string `json:"type"`
+	Reference   string `json:"reference"`
+	Remediation string `json:"remediation"`
+}
+
+// UnmarshalJSON accept the finding of a message only
+func (f *externalFinding) UnmarshalJSON(data []byte) error {
+	var msg string
+	if err := json.Unmarshal(data, &msg); err == nil {
+		f.Msg = msg
+		return nil
+	}
+
+	type finding externalFinding
+	return json.Unmarshal(data, (*finding)(f))
+}
+
+// externalThreats build the threats of the external findings,
+// the param describes the target when the finding does not give one, the severity is medium by default
+func externalThreats(findings []*externalFinding, param, typ string) []*threat {
+	tlist := []*threat{}
+	for _, f := range findings {
+		th := &threat{
+			Param:       f.Param,
+			Value:       f.Value,
+			Type:        f.Type,
+			Describe:    f.Msg,
+			Severity:    strings.ToLower(f.Severity),
+			Reference:   f.Reference,
+			Remediation: f.Remediation,
+		}
+
+		if th.Param == "" {
+			th.Param = param
+		}
+		if th.Type == "" {
+			th.Type = typ
+		}
+		if _, ok := config.SeverityMap[th.Severity]; !ok {
+			th.Severity = "medium"
+		}
+
+		tlist = append(tlist, th)
+	}
+
+	return tlist
+}
+
+// externalInput build the JSON input of the policies and the plugin binaries:
+//   - {"kind": "container", "container": <docker inspect>} in the docker analysis
+//   - {"kind": "namespace", "namespace": <name>, "pods": [<pod>]} in the kubernetes analysis
+//
+// the param describing the target is returned, the input is nil for the unknown targets
+func externalInput(target interface{}) (interface{}, string) {
+	switch t := target.(type) {
+	case *types.ContainerJSON:
+		param := ""
+		if t.ContainerJSONBase != nil {
+			param = fmt.Sprintf("container: %s", strings.TrimPrefix(t.Name, "/"))
+		}
+
+		return map[string]interface{}{"kind": "container", "container": t}, param
+	case *NamespaceTarget:
+		return map[string]interface{}{"kind": "namespace", "namespace": t.Namespace, "pods": t.Pods},
+			fmt.Sprintf("namespace: %s", t.Namespace)
+	}
+
+	return nil, ""
+}
+
+// externalTimeout is the time limit of running the plugin or OPA against a target
+var externalTimeout = 30 * time.Second
+
+// runExternal run the binary with the input as JSON on stdin, the stderr is included in the error,
+// the binary is killed if it does not finish in externalTimeout
+func runExternal(ctx context.Context, binary string, args []string, input interface{}) ([]byte, error) {
+	data, err := json.Marshal(input)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, externalTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, binary, args...)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("timed out after %s", externalTimeout)
+		}
+		return nil, fmt.Errorf("%v, %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// PluginCheck is a check of an executable shipped apart from vesta, the name of check is the file name.
+// The plugin reads the JSON input of target on stdin, see externalInput,
+// and writes a JSON array of the findings on stdout, each is a message or an object of
+// msg, severity, param, value, type, reference and remediation.
+// An empty array is written for the targets without findings
+type PluginCheck struct {
+	name string
+	path string
+}
+
+// NewPluginCheck check the executable of plugin
+func NewPluginCheck(path string) (*PluginCheck, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.IsDir() || info.Mode()&0111 == 0 {
+		return nil, fmt.Errorf("plugin %s is not executable", path)
+	}
+
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+
+	return &PluginCheck{name: name, path: path}, nil
+}
+
+func (p *PluginCheck) Name() string {
+	return p.name
+}
+
+// Run the plugin against the target
+func (p *PluginCheck) Run(ctx context.Context, target interface{}) ([]*Threat, error) {
+	input, param := externalInput(target)
+	if input == nil {
+		return nil, nil
+	}
+
+	output, err := runExternal(ctx, p.path, nil, input)
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s failed: %v", p.name, err)
+	}
+
+	findings := []*externalFinding{}
+	if err := json.Unmarshal(output, &findings); err != nil {
+		return nil, fmt.Errorf("invalid output of plugin %s: %v", p.name, err)
+	}
+
+	return externalThreats(findings, param, "Plugin"), nil
+}
+
+// RegisterPlugins register the executables as the custom checks
+func RegisterPlugins(paths []string) error {
+	for _, path := range paths {
+		p, err := NewPluginCheck(path)
+		if err != nil {
+			return err
+		}
+
+		err = RegisterCheck(p)
+		if err != nil {
+			return fmt.Errorf("plugin %s: %v", path, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,114 @@
+package analyzer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// policyQuery is the query of the violations, the policies define the rule `deny` in the package `vesta`
+// by the syntax of Rego v1, which is the default of OPA 1.0 and needs `import rego.v1` in the earlier versions:
+//
+//	package vesta
+//
+//	deny contains {"msg": msg, "severity": "high"} if {
+//		input.kind == "container"
+//		input.container.HostConfig.Privileged
+//		msg := sprintf("%s is privileged", [input.container.Name])
+//	}
+const policyQuery = "data.vesta.deny"
+
+// Policy is the check evaluating the Rego policies by the binary of OPA, which is not bundled with vesta
+// and must be found in PATH. `opa eval` is run once per target,
+// the input is the inspected docker container or the pods of a kubernetes namespace, see externalInput
+type Policy struct {
+	opa   string
+	files []string
+}
+
+// opaResult is the output of `opa eval --format json`
+type opaResult struct {
+	Result []struct {
+		Expressions []struct {
+			Value json.RawMessage `json:"value"`
+		} `json:"expressions"`
+	} `json:"result"`
+}
+
+// NewPolicy find the binary of OPA for the policy files or directories
+func NewPolicy(locations []string) (*Policy, error) {
+	opa, err := exec.LookPath("opa")
+	if err != nil {
+		return nil, fmt.Errorf("the binary of OPA is required in PATH for the policies, "+
+			"see https://www.openpolicyagent.org/docs/latest/#running-opa: %v", err)
+	}
+
+	for _, location := range locations {
+		if _, err := os.Stat(location); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Policy{opa: opa, files: locations}, nil
+}
+
+// RegisterPolicy register the Rego policies as the check `checkPolicy`
+func RegisterPolicy(locations []string) error {
+	p, err := NewPolicy(locations)
+	if err != nil {
+		return err
+	}
+
+	return RegisterCheck(p)
+}
+
+// Name of the policy check
+func (p *Policy) Name() string {
+	return "checkPolicy"
+}
+
+// Run evaluate the policies against the docker container or the pods of namespace
+func (p *Policy) Run(ctx context.Context, target interface{}) ([]*Threat, error) {
+	input, param := externalInput(target)
+	if input == nil {
+		return nil, nil
+	}
+
+	args := []string{"eval", "--format", "json", "--stdin-input"}
+	for _, f := range p.files {
+		args = append(args, "--data", f)
+	}
+	args = append(args, policyQuery)
+
+	output, err := runExternal(ctx, p.opa, args, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate the policies: %v", err)
+	}
+
+	return parseViolations(output, param)
+}
+
+// parseViolations build the findings from the result of `opa eval`,
+// the param describes the target when the violation does not give one
+func parseViolations(data []byte, param string) ([]*threat, error) {
+	result := &opaResult{}
+	if err := json.Unmarshal(data, result); err != nil {
+		return nil, fmt.Errorf("failed to parse the result of OPA: %v", err)
+	}
+
+	tlist := []*threat{}
+	for _, res := range result.Result {
+		for _, exp := range res.Expressions {
+			violations := []*externalFinding{}
+			if err := json.Unmarshal(exp.Value, &violations); err != nil {
+				return nil, fmt.Errorf("%s is not a set of violations: %v", policyQuery, err)
+			}
+
+			tlist = append(tlist, externalThreats(violations, param, "Policy Violation")...)
+		}
+	}
+
+	return tlist, nil
+}
@@ -0,0 +1,95 @@
+package analyzer
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/kvesta/vesta/config"
+)
+
+const redactMask = "****"
+
+// redactableFields are the fields of threat which can be masked by the option `redact`
+var redactableFields = []string{"Param", "Value", "Reference", "Describe", "Remediation"}
+
+// Separators are kept for the structure of the masked value,
+// e.g. `key: value | key: value`
+var redactSeparatorReg = regexp.MustCompile(`\s*[|:,=;/()]\s*|\s+`)
+
+// redactValue mask the words of value and keep the separators
+func redactValue(value string) string {
+	if value == "" {
+		return value
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, loc := range redactSeparatorReg.FindAllStringIndex(value, -1) {
+		if loc[0] > last {
+			b.WriteString(redactMask)
+		}
+		b.WriteString(value[loc[0]:loc[1]])
+		last = loc[1]
+	}
+
+	if last < len(value) {
+		b.WriteString(redactMask)
+	}
+
+	return b.String()
+}
+
+// redactFields get the fields to be masked from the option `redact`
+func redactFields(ctx context.Context) []string {
+	fields, ok := ctx.Value("redact").([]string)
+	if !ok || len(fields) < 1 {
+		return nil
+	}
+
+	selected := []string{}
+	for _, f := range fields {
+		isKnown := false
+		for _, rf := range redactableFields {
+			if strings.EqualFold(f, rf) {
+				selected = append(selected, rf)
+				isKnown = true
+				break
+			}
+		}
+
+		if !isKnown {
+			config.ErrorLog.Printf("unknown field of redaction: %s, ignored", f)
+		}
+	}
+
+	return selected
+}
+
+// redactThreats mask the selected fields of the threats,
+// the type and severity are kept for sharing the report
+func redactThreats(fields []string, threats []*threat) {
+	for _, th := range threats {
+		for _, f := range fields {
+			switch f {
+			case "Param":
+				th.Param = redactValue(th.Param)
+			case "Value":
+				th.Value = redactValue(th.Value)
+			case "Reference":
+				th.Reference = redactValue(th.Reference)
+			case "Describe":
+				th.Describe = redactValue(th.Describe)
+			case "Remediation":
+				th.Remediation = redactValue(th.Remediation)
+			}
+		}
+	}
+}
+
+// redactContainers mask the threats of the containers
+func redactContainers(fields []string, containers []*container) {
+	for _, c := range containers {
+		redactThreats(fields, c.Threats)
+	}
+}
@@ -0,0 +1,474 @@
+package analyzer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/kvesta/vesta/config"
+	"gopkg.in/yaml.v3"
+	v1 "k8s.io/api/core/v1"
+)
+
+// Rule is a custom check defined in YAML, the containers of docker and the pods of kubernetes
+// are flagged when their specs meet the conditions of match, e.g.
+//
+//	rules:
+//	  - id: privilegedExternalImage
+//	    description: privileged container of the image outside the internal registry
+//	    severity: high
+//	    match:
+//	      all:
+//	        - field: image
+//	          prefix: registry.example.com/
+//	          not: true
+//	      any:
+//	        - field: privileged
+//	          equals: "true"
+//	        - field: capabilities
+//	          equals: SYS_ADMIN
+type Rule struct {
+	ID          string    `yaml:"id"`
+	Description string    `yaml:"description"`
+	Severity    string    `yaml:"severity"`
+	Type        string    `yaml:"type"`
+	Reference   string    `yaml:"reference"`
+	Remediation string    `yaml:"remediation"`
+	Match       RuleMatch `yaml:"match"`
+}
+
+// RuleMatch is met when all the conditions of All and any one of Any are met
+type RuleMatch struct {
+	All []*RuleCondition `yaml:"all"`
+	Any []*RuleCondition `yaml:"any"`
+}
+
+// RuleCondition test a field of the container spec by one of the operators,
+// the field holding several values is met if any value is met.
+// The pattern of matches is a glob whose `*` does not cross `/`, use prefix for the repositories of a registry
+type RuleCondition struct {
+	Field string `yaml:"field"`
+
+	Equals   string `yaml:"equals"`
+	Matches  string `yaml:"matches"`
+	Prefix   string `yaml:"prefix"`
+	Contains string `yaml:"contains"`
+
+	// negate the result of the operator
+	Not bool `yaml:"not"`
+}
+
+// ruleFields are the fields of the container spec which the conditions test
+var ruleFields = []string{
+	"name", "image", "user", "privileged", "capabilities", "hostNetwork", "hostPID", "hostIPC",
+	"readOnlyRootFilesystem", "hostPaths", "env", "labels", "namespace",
+}
+
+// ruleFile is the content of a rule file
+type ruleFile struct {
+	Rules []*Rule `yaml:"rules"`
+}
+
+// validate check the severity and the conditions of the rule
+func (r *Rule) validate() error {
+	if r.ID == "" {
+		return fmt.Errorf("id is required")
+	}
+
+	if _, ok := config.SeverityMap[strings.ToLower(r.Severity)]; !ok {
+		return fmt.Errorf("rule %s: unknown severity '%s'", r.ID, r.Severity)
+	}
+
+	conditions := append(append([]*RuleCondition{}, r.Match.All...), r.Match.Any...)
+	if len(conditions) < 1 {
+		return fmt.Errorf("rule %s: no condition to match", r.ID)
+	}
+
+	for _, cond := range conditions {
+		known := false
+		for _, f := range ruleFields {
+			if cond.Field == f {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return fmt.Errorf("rule %s: unknown field '%s', available fields: %s",
+				r.ID, cond.Field, strings.Join(ruleFields, ", "))
+		}
+
+		operators := 0
+		for _, op := range []string{cond.Equals, cond.Matches, cond.Prefix, cond.Contains} {
+			if op != "" {
+				operators++
+			}
+		}
+		if operators != 1 {
+			return fmt.Errorf("rule %s: field %s requires one of equals, matches, prefix and contains", r.ID, cond.Field)
+		}
+
+		if cond.Matches != "" {
+			if _, err := path.Match(cond.Matches, ""); err != nil {
+				return fmt.Errorf("rule %s: invalid pattern '%s' of field %s", r.ID, cond.Matches, cond.Field)
+			}
+		}
+	}
+
+	return nil
+}
+
+// met test the condition against the values of field
+func (cond *RuleCondition) met(facts map[string][]string) bool {
+	met := false
+	for _, v := range facts[cond.Field] {
+		switch {
+		case cond.Equals != "":
+			met = strings.EqualFold(v, cond.Equals)
+		case cond.Matches != "":
+			met, _ = path.Match(cond.Matches, v)
+		case cond.Prefix != "":
+			met = strings.HasPrefix(v, cond.Prefix)
+		case cond.Contains != "":
+			met = strings.Contains(v, cond.Contains)
+		}
+
+		if met {
+			break
+		}
+	}
+
+	return met != cond.Not
+}
+
+// evaluate test the rule against the container spec, the fields of the conditions met are returned
+func (r *Rule) evaluate(facts map[string][]string) (bool, []string) {
+	fields := []string{}
+	add := func(field string) {
+		for _, f := range fields {
+			if f == field {
+				return
+			}
+		}
+		fields = append(fields, field)
+	}
+
+	for _, cond := range r.Match.All {
+		if !cond.met(facts) {
+			return false, nil
+		}
+		add(cond.Field)
+	}
+
+	if len(r.Match.Any) > 0 {
+		anyMet := false
+		for _, cond := range r.Match.Any {
+			if cond.met(facts) {
+				anyMet = true
+				add(cond.Field)
+			}
+		}
+
+		if !anyMet {
+			return false, nil
+		}
+	}
+
+	return true, fields
+}
+
+// newThreat build the finding of the container spec meeting the rule,
+// the value lists the fields which the rule tested
+func (r *Rule) newThreat(param string, facts map[string][]string, fields []string) *threat {
+	values := []string{}
+	for _, f := range fields {
+		if f == "env" || f == "labels" {
+			// The values of environment and labels could be secrets
+			continue
+		}
+		values = append(values, fmt.Sprintf("%s=%s", f, strings.Join(facts[f], ",")))
+	}
+
+	th := &threat{
+		Param:       param,
+		Value:       strings.Join(values, " "),
+		Type:        r.Type,
+		Describe:    r.Description,
+		Severity:    strings.ToLower(r.Severity),
+		Reference:   r.Reference,
+		Remediation: r.Remediation,
+	}
+	if th.Type == "" {
+		th.Type = "Custom Rule"
+	}
+
+	return th
+}
+
+// Name of the check is the id of rule
+func (r *Rule) Name() string {
+	return r.ID
+}
+
+// Run test the rule against the docker container or the pods of namespace
+func (r *Rule) Run(ctx context.Context, target interface{}) ([]*Threat, error) {
+	tlist := []*threat{}
+
+	switch t := target.(type) {
+	case *types.ContainerJSON:
+		facts := dockerFacts(t)
+		if ok, fields := r.evaluate(facts); ok {
+			tlist = append(tlist, r.newThreat(r.ID, facts, fields))
+		}
+
+	case *NamespaceTarget:
+		for _, pod := range t.Pods {
+			containers := append(append([]v1.Container{}, pod.Spec.InitContainers...), pod.Spec.Containers...)
+			for _, c := range containers {
+				facts := podFacts(pod, c, t.ImageUsers)
+				if ok, fields := r.evaluate(facts); ok {
+					param := fmt.Sprintf("pod: %s/%s | container: %s", pod.Namespace, pod.Name, c.Name)
+					tlist = append(tlist, r.newThreat(param, facts, fields))
+				}
+			}
+		}
+	}
+
+	return tlist, nil
+}
+
+// boolFact format the value of the boolean field
+func boolFact(b bool) []string {
+	return []string{fmt.Sprintf("%t", b)}
+}
+
+// labelFacts format the labels as key=value sorted by the key
+func labelFacts(labels map[string]string) []string {
+	facts := []string{}
+	for k, v := range labels {
+		facts = append(facts, fmt.Sprintf("%s=%s", k, v))
+	}
+	sort.Strings(facts)
+
+	return facts
+}
+
+// dockerFacts get the fields of the docker container tested by the rules
+func dockerFacts(in *types.ContainerJSON) map[string][]string {
+	facts := map[string][]string{}
+	if in.ContainerJSONBase != nil {
+		facts["name"] = []string{strings.TrimPrefix(in.Name, "/")}
+
+		if hc := in.HostConfig; hc != nil {
+			facts["privileged"] = boolFact(hc.Privileged)
+			facts["capabilities"] = []string(hc.CapAdd)
+			facts["hostNetwork"] = boolFact(hc.NetworkMode.IsHost())
+			facts["hostPID"] = boolFact(hc.PidMode.IsHost())
+			facts["hostIPC"] = boolFact(hc.IpcMode.IsHost())
+			facts["readOnlyRootFilesystem"] = boolFact(hc.ReadonlyRootfs)
+		}
+	}
+
+	for _, m := range in.Mounts {
+		if m.Type == "bind" {
+			facts["hostPaths"] = append(facts["hostPaths"], m.Source)
+		}
+	}
+
+	if in.Config != nil {
+		facts["image"] = []string{in.Config.Image}
+		// Container runs as root if no user is given
+		facts["user"] = []string{"0"}
+		if in.Config.User != "" {
+			facts["user"] = []string{in.Config.User}
+		}
+		facts["env"] = in.Config.Env
+		facts["labels"] = labelFacts(in.Config.Labels)
+	}
+
+	return facts
+}
+
+// podFacts get the fields of the container or init container of pod tested by the rules,
+// the user is resolved by the image users as checkPodRootUser if `runAsUser` is not set
+func podFacts(pod v1.Pod, c v1.Container, imageUsers map[string]string) map[string][]string {
+	facts := map[string][]string{
+		"name":        {c.Name},
+		"image":       {c.Image},
+		"namespace":   {pod.Namespace},
+		"hostNetwork": boolFact(pod.Spec.HostNetwork),
+		"hostPID":     boolFact(pod.Spec.HostPID),
+		"hostIPC":     boolFact(pod.Spec.HostIPC),
+		"labels":      labelFacts(pod.Labels),
+	}
+
+	var runAsUser *int64
+	var runAsNonRoot *bool
+	if psc := pod.Spec.SecurityContext; psc != nil {
+		runAsUser, runAsNonRoot = psc.RunAsUser, psc.RunAsNonRoot
+	}
+
+	privileged, readOnly := false, false
+	if sc := c.SecurityContext; sc != nil {
+		if sc.Privileged != nil {
+			privileged = *sc.Privileged
+		}
+		if sc.ReadOnlyRootFilesystem != nil {
+			readOnly = *sc.ReadOnlyRootFilesystem
+		}
+		if sc.RunAsUser != nil {
+			runAsUser = sc.RunAsUser
+		}
+		if sc.RunAsNonRoot != nil {
+			runAsNonRoot = sc.RunAsNonRoot
+		}
+		if sc.Capabilities != nil {
+			for _, capability := range sc.Capabilities.Add {
+				facts["capabilities"] = append(facts["capabilities"], string(capability))
+			}
+		}
+	}
+	facts["privileged"] = boolFact(privileged)
+	facts["readOnlyRootFilesystem"] = boolFact(readOnly)
+
+	// The user of image is unknown if the image is not found locally,
+	// and kubelet refuses to start the container whose image user is root by `runAsNonRoot`
+	if runAsUser != nil {
+		facts["user"] = []string{fmt.Sprintf("%d", *runAsUser)}
+	} else if user, known := imageUsers[c.Image]; known && !isRootUser(user) {
+		facts["user"] = []string{user}
+	} else if known && (runAsNonRoot == nil || !*runAsNonRoot) {
+		// Container runs as root if the image configures no user
+		facts["user"] = []string{"0"}
+		if user != "" {
+			facts["user"] = []string{user}
+		}
+	}
+
+	for _, env := range c.Env {
+		facts["env"] = append(facts["env"], fmt.Sprintf("%s=%s", env.Name, env.Value))
+	}
+
+	mounted := map[string]bool{}
+	for _, vm := range c.VolumeMounts {
+		mounted[vm.Name] = true
+	}
+	for _, vol := range pod.Spec.Volumes {
+		if vol.HostPath != nil && mounted[vol.Name] {
+			facts["hostPaths"] = append(facts["hostPaths"], vol.HostPath.Path)
+		}
+	}
+
+	return facts
+}
+
+// loadRuleFile read the rules of a file, the unknown keys are reported
+func loadRuleFile(filename string) ([]*Rule, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	rf := &ruleFile{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	err = decoder.Decode(rf)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("%s: %s", filename, strings.TrimPrefix(err.Error(), "yaml: "))
+	}
+
+	for _, r := range rf.Rules {
+		if err := r.validate(); err != nil {
+			return nil, fmt.Errorf("%s: %v", filename, err)
+		}
+	}
+
+	return rf.Rules, nil
+}
+
+// LoadRules read the rule files, the directories are searched for the files of .yaml and .yml
+func LoadRules(locations []string) ([]*Rule, error) {
+	rules := []*Rule{}
+	seen := map[string]string{}
+
+	for _, location := range locations {
+		info, err := os.Stat(location)
+		if err != nil {
+			return nil, err
+		}
+
+		files := []string{location}
+		if info.IsDir() {
+			files = []string{}
+			for _, pattern := range []string{"*.yaml", "*.yml"} {
+				matches, _ := filepath.Glob(filepath.Join(location, pattern))
+				files = append(files, matches...)
+			}
+			sort.Strings(files)
+		}
+
+		for _, f := range files {
+			fileRules, err := loadRuleFile(f)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, r := range fileRules {
+				if prev, ok := seen[r.ID]; ok {
+					return nil, fmt.Errorf("%s: rule %s is defined already in %s", f, r.ID, prev)
+				}
+				seen[r.ID] = f
+			}
+			rules = append(rules, fileRules...)
+		}
+	}
+
+	return rules, nil
+}
+
+// RegistryRule build the rule flagging the images pulled from none of the allowed registries,
+// a registry is matched as the prefix of image ending with `/`
+func RegistryRule(registries []string) *Rule {
+	r := &Rule{
+		ID:          "checkImageRegistry",
+		Description: "Image is not pulled from the allowed registries.",
+		Severity:    "medium",
+		Type:        "Image Registry",
+		Remediation: "Pull the image from one of the allowed registries: " + strings.Join(registries, ", "),
+	}
+
+	for _, registry := range registries {
+		r.Match.All = append(r.Match.All, &RuleCondition{
+			Field:  "image",
+			Prefix: strings.TrimSuffix(registry, "/") + "/",
+			Not:    true,
+		})
+	}
+
+	return r
+}
+
+// RegisterRules load the rule files and register the rules as the custom checks
+func RegisterRules(locations []string) (int, error) {
+	rules, err := LoadRules(locations)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, r := range rules {
+		err = RegisterCheck(r)
+		if err != nil {
+			return 0, fmt.Errorf("rule %s: %v", r.ID, err)
+		}
+	}
+
+	return len(rules), nil
+}
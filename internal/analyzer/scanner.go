@@ -1,6 +1,9 @@
 package analyzer
 
 import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 )
@@ -10,6 +13,69 @@ type Scanner struct {
 
 	EngineVersion string
 	ServerVersion string
+
+	// daemon serving the containers, docker or podman, empty in the offline analysis
+	Daemon string
+
+	// daemon is run by a non-root user
+	Rootless bool
+
+	// host of the remote daemon, whose paths of host are not accessible
+	Remote string
+
+	// kernel version of the remote host reported by the daemon
+	KernelVersion string
+
+	// docker daemon is run with `userns-remap`
+	UsernsRemap bool
+
+	// containers are loaded from the saved inspect data
+	// instead of a running docker daemon
+	Offline bool
+
+	// kernel is vulnerable to CVE-2020-14386
+	netRawKernel bool
+
+	// kernel is vulnerable to CVE-2022-0492
+	cgroupKernel bool
+
+	// workers of analyzing images
+	Concurrency int
+
+	// names of the checks ran in a scan
+	Checks []string
+
+	// duration of each check
+	Timings []*CheckTiming
+
+	// start and end of the scan
+	StartedAt  time.Time
+	FinishedAt time.Time
+
+	// known-malicious images
+	blocklist []*blockedImage
+
+	// match the aliases of product names in the vulnerability database
+	fuzzyMatch bool
+
+	// known findings which are not reported
+	baseline *Baseline
+
+	// count of findings suppressed by the baseline
+	Suppressed int
+
+	// size of writable layer flagged as unusual growth
+	writableLayerLimit int64
+
+	// commands regarded as the debug workload when running as the main process
+	debugCommands []string
+
+	// names of the images skipped by the image analyzing
+	ExcludedImages []string
+
+	// called with each finding as soon as it is discovered
+	OnFinding func(FindingEvent)
+	stream    *findingStream
 }
 
 type container struct {
@@ -19,8 +85,17 @@ type container struct {
 	NodeName      string
 
 	// For kubernetes
-	Namepsace string
-	Threats   []*threat
+	Namepsace   string
+	OwnerKind   string
+	OwnerName   string
+	Replicas    int
+	Fingerprint string
+
+	// Helm release and chart deploying the workload
+	HelmRelease string `json:",omitempty"`
+	HelmChart   string `json:",omitempty"`
+
+	Threats []*threat
 }
 
 type threat struct {
@@ -31,6 +106,15 @@ type threat struct {
 	Describe  string
 	Severity  string
 	Reference string
+
+	// concrete fix of the finding
+	Remediation string
+
+	// CIS Kubernetes Benchmark control IDs
+	CISControls []string
+
+	// name of the check finding the threat
+	Check string `json:",omitempty"`
 }
 
 type KScanner struct {
@@ -39,8 +123,73 @@ type KScanner struct {
 	Version     string
 	MasterNodes map[string]*nodeInfo
 
+	// location of the kubeconfig file, empty when running inside a pod
+	KConfigPath string
+
 	VulnConfigures []*threat
 	VulnContainers []*container
+
+	// names of the checks ran in a scan
+	Checks []string
+
+	// duration of each check
+	Timings []*CheckTiming
+
+	// start and end of the scan
+	StartedAt  time.Time
+	FinishedAt time.Time
+
+	// known-malicious images
+	blocklist []*blockedImage
+
+	// match the aliases of product names in the vulnerability database
+	fuzzyMatch bool
+
+	// known findings which are not reported
+	baseline *Baseline
+
+	// count of findings suppressed by the baseline
+	Suppressed int
+
+	// kernel is vulnerable to CVE-2020-14386
+	netRawKernel bool
+
+	// kernel is vulnerable to CVE-2022-0492
+	cgroupKernel bool
+
+	// users configured in the local images keyed by the image name, nil without docker
+	imageUsers map[string]string
+
+	// secrets grouped by the hash of data
+	secretHashes map[string][]string
+
+	// registry credentials of the pull secrets grouped by the hash of data
+	pullSecrets map[string]*pullSecretUsage
+
+	// owner references resolved in a scan
+	owners map[string]*metav1.OwnerReference
+
+	// checks skipped for the insufficient permission
+	Coverage []*CoverageGap
+
+	// forbidden errors swallowed by the running check
+	run *checkRun
+
+	// permissions denied to the scanner by the review before the checks start
+	MissingPermissions []string
+
+	// objects listed in a scan
+	cache *listCache
+
+	// findings of the previous scan reused for the unchanged objects
+	incremental *incrementalScan
+
+	// count of objects whose findings are reused from the previous scan
+	Unchanged int
+
+	// called with each finding as soon as it is discovered
+	OnFinding func(FindingEvent)
+	stream    *findingStream
 }
 
 type nodeInfo struct {
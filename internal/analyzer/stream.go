@@ -0,0 +1,78 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// FindingEvent is a finding emitted as soon as the check discovers it
+type FindingEvent struct {
+	Target string
+	Check  string
+	Threat *threat
+}
+
+// findingStream serializes the calls of the callback,
+// so the callback is not required to be safe for the concurrent use
+type findingStream struct {
+	mu     sync.Mutex
+	fn     func(FindingEvent)
+	fields []string
+}
+
+// newFindingStream return nil if there is no callback
+func newFindingStream(ctx context.Context, fn func(FindingEvent)) *findingStream {
+	if fn == nil {
+		return nil
+	}
+
+	return &findingStream{fn: fn, fields: redactFields(ctx)}
+}
+
+// emit call the callback with the copies of the threats,
+// the threats of the scan are kept unchanged by the callback and the redaction
+func (fs *findingStream) emit(target, check string, threats []*threat) {
+	if fs == nil || len(threats) < 1 {
+		return
+	}
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	for _, th := range threats {
+		cp := *th
+		tagCISControls([]*threat{&cp})
+		redactThreats(fs.fields, []*threat{&cp})
+
+		fs.fn(FindingEvent{Target: target, Check: check, Threat: &cp})
+	}
+}
+
+// dockerTarget describe the container of the findings in the docker analysis
+func dockerTarget(c *container) string {
+	if c.ContainerID == "None" {
+		return c.ContainerName
+	}
+
+	return fmt.Sprintf("container: %s (%s)", c.ContainerName, c.ContainerID)
+}
+
+// kubernetesTarget describe the pod or workload of the findings in the kubernetes analysis
+func kubernetesTarget(c *container) string {
+	if c.OwnerKind != "" && c.OwnerKind != "Pod" {
+		return fmt.Sprintf("%s: %s/%s", c.OwnerKind, c.Namepsace, c.OwnerName)
+	}
+
+	return fmt.Sprintf("pod: %s/%s", c.Namepsace, c.ContainerName)
+}
+
+// streamFindings emit the findings added by the check
+// since the given numbers of configures and containers
+func (ks *KScanner) streamFindings(name string, configures, containers int) {
+	ks.stream.emit("cluster", name, ks.VulnConfigures[configures:])
+
+	for _, c := range ks.VulnContainers[containers:] {
+		ks.stream.emit(kubernetesTarget(c), name, c.Threats)
+	}
+}
@@ -0,0 +1,45 @@
+package analyzer
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// CheckTiming is the total duration spent by a check in a scan
+type CheckTiming struct {
+	Name     string
+	Duration time.Duration
+}
+
+func (t *CheckTiming) String() string {
+	return fmt.Sprintf("%s: %.1fs", t.Name, t.Duration.Seconds())
+}
+
+// addTiming adds the duration to the timing of the check,
+// checks running once per namespace or container are summed up
+func addTiming(timings []*CheckTiming, name string, d time.Duration) []*CheckTiming {
+	for _, t := range timings {
+		if t.Name == name {
+			t.Duration += d
+			return timings
+		}
+	}
+
+	return append(timings, &CheckTiming{Name: name, Duration: d})
+}
+
+// logTimings print the timing breakdown of the checks
+func logTimings(timings []*CheckTiming) {
+	if len(timings) < 1 {
+		return
+	}
+
+	breakdown := []string{}
+	for _, t := range timings {
+		breakdown = append(breakdown, t.String())
+	}
+
+	log.Printf("Check timing: %s", strings.Join(breakdown, ", "))
+}
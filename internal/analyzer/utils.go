@@ -24,11 +24,52 @@ var (
 
 	dangerFullPaths = []string{"/", "/etc", "/proc", "/proc/1", "/sys", "/root", "/var/log"}
 
-	namespaceWhileList = []string{"istio-system", "kube-system", "kube-public",
+	systemNamespaces = []string{"istio-system", "kube-system", "kube-public",
 		"kubesphere-router-gateway", "kubesphere-system", "openshift-sdn", "openshift-node"}
 
+	namespaceWhileList = append([]string{}, systemNamespaces...)
+
+	controlPlaneTaints = []string{"node-role.kubernetes.io/master", "node-role.kubernetes.io/control-plane"}
+
+	// ports of the services which should not be exposed to the outside
+	sensitivePorts = map[string]string{
+		"22":    "ssh",
+		"2375":  "docker daemon",
+		"2376":  "docker daemon",
+		"2379":  "etcd",
+		"3306":  "mysql",
+		"5432":  "postgresql",
+		"6379":  "redis",
+		"9200":  "elasticsearch",
+		"10250": "kubelet",
+		"11211": "memcached",
+		"27017": "mongodb",
+	}
+
 	dangerCaps = []string{"SYS_ADMIN", "CAP_SYS_ADMIN", "CAP_SYS_PTRACE", "CAP_SYS_MODULE",
-		"CAP_SYS_CHROOT", "SYS_PTRACE", "CAP_BPF", "DAC_OVERRIDE", "CAP_DAC_READ_SEARCH", "NET_ADMIN"}
+		"CAP_SYS_CHROOT", "SYS_PTRACE", "CAP_BPF", "NET_ADMIN"}
+
+	// Capabilities bypassing the permission checks of files, DAC_OVERRIDE is granted by default,
+	// they are reported by checkDACCapabilities and checkPodDACCapabilities instead of dangerCaps
+	dacCaps = []string{"DAC_OVERRIDE", "DAC_READ_SEARCH"}
+
+	// Host devices of the memory and raw disks, which can be read or written directly
+	sensitiveDevices = []string{"/dev/mem", "/dev/kmem", "/dev/port", "/dev/kmsg",
+		"/dev/sd", "/dev/hd", "/dev/vd", "/dev/xvd", "/dev/nvme", "/dev/dm-", "/dev/mapper", "/dev/loop"}
+
+	gpuDevices = []string{"/dev/nvidia", "/dev/dri", "/dev/kfd"}
+
+	benignDevices = []string{"/dev/snd", "/dev/null", "/dev/zero", "/dev/random", "/dev/urandom"}
+
+	// Repositories of the workloads which should not be exposed outside the cluster,
+	// matched by the trailing path segments of the image repository
+	sensitiveImages = []string{"etcd", "redis", "mysql", "mysql-server", "mariadb", "postgres", "postgresql",
+		"mongo", "mongodb", "elasticsearch", "kibana", "memcached", "rabbitmq", "zookeeper", "kafka",
+		"jenkins", "grafana", "prometheus", "argocd"}
+
+	// Repositories of the Kubernetes dashboard, which are sensitive as well
+	dashboardImages = []string{"kubernetesui/dashboard", "kubernetesui/dashboard-api", "kubernetesui/dashboard-web",
+		"kubernetesui/dashboard-auth", "kubernetes-dashboard", "kubernetes-dashboard-amd64"}
 
 	unsafeAnnotations = map[string]AnType{
 		"sidecar.istio.io/proxyImage":          {component: "istio", level: "warning"},
@@ -37,6 +78,17 @@ var (
 	}
 )
 
+// hasPrefixIn reports whether the path starts with any of the prefixes
+func hasPrefixIn(path string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
 type AnType struct {
 	component string
 	level     string
@@ -163,12 +215,178 @@ func checkFullPaths(path string) bool {
 }
 
 func checkMountPath(path string) bool {
-	path = strings.TrimSuffix(path, "/")
+	if path != "/" {
+		path = strings.TrimSuffix(path, "/")
+	}
 	return checkPrefixMountPaths(path) || checkFullPaths(path)
 }
 
+// isDangerCap check whether the capability is dangerous,
+// both the names with or without the prefix `CAP_` are matched
+func isDangerCap(capability string) bool {
+	name := strings.TrimPrefix(strings.ToUpper(capability), "CAP_")
+
+	for _, c := range dangerCaps {
+		if name == strings.TrimPrefix(c, "CAP_") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// holdsCapability check whether the container holds the capability granted by default,
+// the way it is granted is returned: added, default or privileged
+func holdsCapability(capability string, adds, drops []string, privileged bool) (bool, string) {
+	if privileged {
+		return true, "privileged"
+	}
+
+	name := strings.TrimPrefix(strings.ToUpper(capability), "CAP_")
+	matches := func(caps []string) bool {
+		for _, c := range caps {
+			c = strings.TrimPrefix(strings.ToUpper(c), "CAP_")
+			if c == name || c == "ALL" {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	// Added capabilities take effect after the dropped ones
+	if matches(adds) {
+		return true, "added"
+	}
+
+	if matches(drops) {
+		return false, "dropped"
+	}
+
+	return true, "default"
+}
+
+// netRawGrant describe how CAP_NET_RAW is held by the container, by the result of holdsCapability
+func netRawGrant(how string) string {
+	switch how {
+	case "privileged":
+		return "the container is privileged and holds CAP_NET_RAW"
+	case "added":
+		return "the container holds CAP_NET_RAW added explicitly"
+	}
+
+	return "the container holds CAP_NET_RAW granted by the runtime by default, which is not dropped"
+}
+
+// isSystemNamespace check whether the namespace belongs to the system components
+func isSystemNamespace(ns string) bool {
+	for _, sn := range systemNamespaces {
+		if ns == sn {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sortSeverity sort the threats by the severity, the threats of the same severity are ordered
+// by the target, the type and the value, so the output of an unchanged target is reproducible
 func sortSeverity(threats []*threat) {
 	sort.SliceStable(threats, func(i, j int) bool {
-		return config.SeverityMap[threats[i].Severity] > config.SeverityMap[threats[j].Severity]
+		a, b := threats[i], threats[j]
+
+		if sa, sb := config.SeverityMap[a.Severity], config.SeverityMap[b.Severity]; sa != sb {
+			return sa > sb
+		}
+
+		switch {
+		case a.Param != b.Param:
+			return a.Param < b.Param
+		case a.Type != b.Type:
+			return a.Type < b.Type
+		case a.Value != b.Value:
+			return a.Value < b.Value
+		}
+
+		return a.Describe < b.Describe
+	})
+}
+
+// sortContainers sort the containers by the namespace, the name and the ID,
+// the order of containers appended by the checks depends on the order of API responses
+func sortContainers(containers []*container) {
+	sort.SliceStable(containers, func(i, j int) bool {
+		a, b := containers[i], containers[j]
+
+		switch {
+		case a.Namepsace != b.Namepsace:
+			return a.Namepsace < b.Namepsace
+		case a.ContainerName != b.ContainerName:
+			return a.ContainerName < b.ContainerName
+		}
+
+		return a.ContainerID < b.ContainerID
 	})
 }
+
+// loaderEnvs are the variables changing the libraries loaded by the dynamic loader,
+// with the severity of setting them to the read-only locations
+var loaderEnvs = map[string]string{
+	"LD_PRELOAD":      "medium",
+	"LD_AUDIT":        "medium",
+	"LD_LIBRARY_PATH": "low",
+	"GCONV_PATH":      "low",
+}
+
+// writableDirs are writable by any user of the container by default
+var writableDirs = []string{"/tmp", "/var/tmp", "/dev/shm"}
+
+// getLoaderEnvThreat check the variable of dynamic loader, the library in the writable location
+// is replaceable by the attacker to hijack the processes of container, writable is the destinations of writable mounts
+func getLoaderEnvThreat(name, value string, writable []string) *threat {
+	severity, ok := loaderEnvs[name]
+	if !ok || strings.TrimSpace(value) == "" {
+		return nil
+	}
+
+	th := &threat{
+		Value: fmt.Sprintf("%s=%s", name, value),
+		Describe: fmt.Sprintf("Variable '%s' changes the libraries loaded by the processes of container, "+
+			"which is a technique of persistence.", name),
+		Remediation: fmt.Sprintf("Remove `%s` and install the libraries into the image.", name),
+		Severity:    severity,
+	}
+
+	dirs := append(append([]string{}, writableDirs...), writable...)
+	paths := strings.FieldsFunc(value, func(r rune) bool {
+		return r == ':' || r == ';' || r == ' '
+	})
+
+	for _, p := range paths {
+		for _, dir := range dirs {
+			dir = strings.TrimSuffix(dir, "/")
+			if p != dir && !strings.HasPrefix(p, dir+"/") {
+				continue
+			}
+
+			th.Describe = fmt.Sprintf("Variable '%s' loads the libraries from the writable location '%s', "+
+				"the processes of container can be hijacked by replacing the libraries.", name, dir)
+			if th.Severity == "medium" {
+				th.Severity = "high"
+			} else {
+				th.Severity = "medium"
+			}
+
+			return th
+		}
+	}
+
+	return th
+}
+
+// isRootUser check whether the user of `uid:gid` is root, the empty user is root by default
+func isRootUser(user string) bool {
+	name := strings.SplitN(user, ":", 2)[0]
+
+	return name == "" || name == "root" || name == "0"
+}
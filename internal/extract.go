@@ -4,10 +4,10 @@ import (
 	"archive/tar"
 	"context"
 	"io"
-	"log"
 	"os"
 	"path/filepath"
 
+	"github.com/kvesta/vesta/config"
 	"github.com/kvesta/vesta/pkg"
 	"github.com/kvesta/vesta/pkg/layer"
 )
@@ -59,7 +59,7 @@ func Extract(ctx context.Context, tarPath string, tarIO []io.ReadCloser) (*layer
 	if ctx.Value("tarType") == "container" {
 		err := pkg.Walk(tarReader, tempPath)
 		if err != nil {
-			log.Printf("extract tar file failed: %v", err)
+			config.ErrorLog.Printf("extract tar file failed: %v", err)
 		}
 
 		// Get mount path
@@ -67,7 +67,7 @@ func Extract(ctx context.Context, tarPath string, tarIO []io.ReadCloser) (*layer
 			tarReader = tar.NewReader(mio)
 			err = pkg.Walk(tarReader, tempPath)
 			if err != nil {
-				log.Printf("decompress mount path failed, error: %v", err)
+				config.ErrorLog.Printf("decompress mount path failed, error: %v", err)
 				continue
 			}
 		}
@@ -83,7 +83,7 @@ func Extract(ctx context.Context, tarPath string, tarIO []io.ReadCloser) (*layer
 	// need temp folder path to get layer.tar
 	img, err := Inspect(ctx, tempPath, tarReader)
 	if err != nil {
-		log.Printf("Getting layers failed")
+		config.ErrorLog.Printf("Getting layers failed")
 		return nil, err
 	}
 
@@ -0,0 +1,153 @@
+package report
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/kvesta/vesta/config"
+)
+
+// cefSeverities map the severities of findings to the CEF severity of 0-10
+var cefSeverities = map[string]int{
+	"critical": 10,
+	"high":     8,
+	"medium":   5,
+	"low":      3,
+	"warning":  1,
+}
+
+// syslogSeverities map the severities of findings to the syslog severity of RFC 5424
+var syslogSeverities = map[string]int{
+	"critical": 2,
+	"high":     3,
+	"medium":   4,
+	"low":      5,
+	"warning":  6,
+}
+
+// syslogFacility is the facility `user-level messages` of the events
+const syslogFacility = 1
+
+var (
+	cefHeaderEscaper    = strings.NewReplacer(`\`, `\\`, `|`, `\|`, "\n", " ", "\r", " ")
+	cefExtensionEscaper = strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\n", `\n`, "\r", `\r`)
+)
+
+// FormatCEF format the finding as a CEF event, the fields of finding are the extensions
+func FormatCEF(f *Finding) string {
+	severity := strings.ToLower(f.Severity)
+
+	signature := f.Type
+	if signature == "" {
+		signature = f.Param
+	}
+
+	header := []string{"CEF:0", "kvesta", "vesta", config.Version, signature, f.Param,
+		fmt.Sprintf("%d", cefSeverities[severity])}
+	for i := 1; i < len(header); i++ {
+		header[i] = cefHeaderEscaper.Replace(header[i])
+	}
+
+	ext := []string{}
+	add := func(key, value string) {
+		if value != "" {
+			ext = append(ext, fmt.Sprintf("%s=%s", key, cefExtensionEscaper.Replace(value)))
+		}
+	}
+	addLabeled := func(n int, label, value string) {
+		if value != "" {
+			add(fmt.Sprintf("cs%dLabel", n), label)
+			add(fmt.Sprintf("cs%d", n), value)
+		}
+	}
+
+	add("cat", f.Type)
+	add("msg", f.Describe)
+	add("request", f.Reference)
+	addLabeled(1, "Target", f.Target)
+	addLabeled(2, "Param", f.Param)
+	addLabeled(3, "Value", f.Value)
+	addLabeled(4, "Remediation", f.Remediation)
+	addLabeled(5, "Namespace", f.Namespace)
+	addLabeled(6, "Cluster", f.Cluster)
+
+	return strings.Join(header, "|") + "|" + strings.Join(ext, " ")
+}
+
+// writeSyslog write the findings as the syslog messages of RFC 5424 carrying the CEF events,
+// the messages are separated by newline for the stream transports
+func writeSyslog(w io.Writer, findings []*Finding, hostname string, now time.Time) error {
+	for _, f := range findings {
+		severity, ok := syslogSeverities[strings.ToLower(f.Severity)]
+		if !ok {
+			severity = syslogSeverities["warning"]
+		}
+
+		msg := fmt.Sprintf("<%d>1 %s %s vesta - - - %s\n", syslogFacility*8+severity,
+			now.Format(time.RFC3339), hostname, FormatCEF(f))
+
+		if _, err := io.WriteString(w, msg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// syslogAddress get the network and address of the collector from the option `syslog`,
+// the address is `udp://host:port`, `tcp://host:port` or `host:port` for UDP
+func syslogAddress(location string) (string, string, error) {
+	network, address := "udp", location
+	if i := strings.Index(location, "://"); i >= 0 {
+		network, address = location[:i], location[i+3:]
+	}
+
+	if network != "udp" && network != "tcp" {
+		return "", "", fmt.Errorf("unsupported syslog protocol '%s'", network)
+	}
+
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		return "", "", fmt.Errorf("invalid syslog address '%s': %v", address, err)
+	}
+
+	return network, address, nil
+}
+
+// SendSyslog send the findings of the report as CEF events to the syslog collector of the option `syslog`
+func SendSyslog(ctx context.Context, rp *Report) error {
+	location, ok := ctx.Value("syslog").(string)
+	if !ok || location == "" {
+		return nil
+	}
+
+	network, address, err := syslogAddress(location)
+	if err != nil {
+		return err
+	}
+
+	conn, err := net.DialTimeout(network, address, 5*time.Second)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "-"
+	}
+
+	err = writeSyslog(conn, rp.Findings, hostname, time.Now())
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Sent %s findings to syslog: %s", config.Yellow(len(rp.Findings)), location)
+
+	return nil
+}
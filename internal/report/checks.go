@@ -0,0 +1,59 @@
+package report
+
+import (
+	"strings"
+	"time"
+)
+
+// CheckResult is the metadata of a check ran in the scan
+type CheckResult struct {
+	Name     string
+	Duration time.Duration
+	Findings int
+
+	// counts of the findings by severity
+	Severities map[string]int `json:",omitempty"`
+}
+
+// NewCheckResults count the findings of each check with its duration,
+// the checks are in the order they ran and the checks without findings are kept
+func NewCheckResults(rp *Report) []*CheckResult {
+	results := []*CheckResult{}
+	byName := map[string]*CheckResult{}
+
+	add := func(name string) *CheckResult {
+		if res, ok := byName[name]; ok {
+			return res
+		}
+
+		res := &CheckResult{Name: name}
+		byName[name] = res
+		results = append(results, res)
+
+		return res
+	}
+
+	for _, name := range rp.Checks {
+		add(name)
+	}
+
+	for _, t := range rp.Timings {
+		add(t.Name).Duration = t.Duration
+	}
+
+	for _, f := range rp.Findings {
+		if f.Check == "" {
+			continue
+		}
+
+		res := add(f.Check)
+		if res.Severities == nil {
+			res.Severities = map[string]int{}
+		}
+
+		res.Findings++
+		res.Severities[strings.ToLower(f.Severity)]++
+	}
+
+	return results
+}
@@ -0,0 +1,207 @@
+package report
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kvesta/vesta/config"
+	"github.com/kvesta/vesta/internal/analyzer"
+
+	"github.com/olekukonko/tablewriter"
+	"k8s.io/apimachinery/pkg/util/json"
+)
+
+// ClusterResult is the result of analyzing a cluster in the multi-cluster scan,
+// Err is set if the cluster could not be analyzed
+type ClusterResult struct {
+	Cluster string
+	Host    string
+	Err     error
+	Scanner analyzer.KScanner
+}
+
+// ClusterSummary is the counts of findings of a cluster or all the clusters
+type ClusterSummary struct {
+	Cluster    string `json:",omitempty"`
+	Host       string `json:",omitempty"`
+	Error      string `json:",omitempty"`
+	Findings   int
+	Severities map[string]int
+	Score      *Score `json:",omitempty"`
+}
+
+// clusterTotal is the summary of all the clusters with the count of the clusters failed to analyze
+type clusterTotal struct {
+	*ClusterSummary
+	Clusters int
+	Failed   int
+}
+
+// NewClusterSummary count the findings of the report by severity
+func NewClusterSummary(rp *Report) *ClusterSummary {
+	sum := &ClusterSummary{Findings: len(rp.Findings), Severities: map[string]int{}, Score: rp.Score}
+
+	for _, f := range rp.Findings {
+		sum.Severities[strings.ToLower(f.Severity)]++
+	}
+
+	return sum
+}
+
+// NewClustersReport merge the findings of the clusters into a report,
+// the findings are tagged with the name of cluster
+func NewClustersReport(ctx context.Context, results []*ClusterResult) *Report {
+	rp := &Report{}
+
+	for _, res := range results {
+		if res.Err != nil {
+			continue
+		}
+
+		crp := NewKuberReport(ctx, res.Scanner)
+		for _, f := range crp.Findings {
+			f.Cluster = res.Cluster
+		}
+
+		rp.Findings = append(rp.Findings, crp.Findings...)
+		rp.Checks = mergeChecks(rp.Checks, crp.Checks)
+	}
+
+	rp.Score = NewScore(ctx, rp.Findings)
+
+	return rp
+}
+
+// mergeChecks append the names of checks which are not in the list
+func mergeChecks(checks, more []string) []string {
+	seen := map[string]bool{}
+	for _, c := range checks {
+		seen[c] = true
+	}
+
+	for _, c := range more {
+		if !seen[c] {
+			seen[c] = true
+			checks = append(checks, c)
+		}
+	}
+
+	return checks
+}
+
+// ClustersToJson encode the results of the multi-cluster scan,
+// each cluster keeps the structure of the single-cluster output with its summary
+func ClustersToJson(ctx context.Context, results []*ClusterResult) ([]byte, error) {
+	type clusterOutput struct {
+		*ClusterSummary
+		StartedAt      *time.Time              `json:",omitempty"`
+		FinishedAt     *time.Time              `json:",omitempty"`
+		Checks         []string                `json:",omitempty"`
+		CheckResults   []*CheckResult          `json:",omitempty"`
+		Coverage       []*analyzer.CoverageGap `json:",omitempty"`
+		MissingPerms   []string                `json:"MissingPermissions,omitempty"`
+		VulnContainers interface{}             `json:",omitempty"`
+		VulnConfigures interface{}             `json:",omitempty"`
+		Groups         []*NamespaceGroup       `json:",omitempty"`
+	}
+
+	clusters := []*clusterOutput{}
+	failed := 0
+
+	for _, res := range results {
+		if res.Err != nil {
+			failed++
+			clusters = append(clusters, &clusterOutput{ClusterSummary: &ClusterSummary{
+				Cluster: res.Cluster, Host: res.Host, Error: res.Err.Error(), Severities: map[string]int{}}})
+			continue
+		}
+
+		rp := NewKuberReport(ctx, res.Scanner)
+		sum := NewClusterSummary(rp)
+		sum.Cluster, sum.Host = res.Cluster, res.Host
+
+		clusters = append(clusters, &clusterOutput{
+			ClusterSummary: sum,
+			StartedAt:      &res.Scanner.StartedAt,
+			FinishedAt:     &res.Scanner.FinishedAt,
+			Checks:         res.Scanner.Checks,
+			CheckResults:   NewCheckResults(rp),
+			Coverage:       res.Scanner.Coverage,
+			MissingPerms:   res.Scanner.MissingPermissions,
+			VulnContainers: res.Scanner.VulnContainers,
+			VulnConfigures: res.Scanner.VulnConfigures,
+			Groups:         GroupFindings(rp.Findings),
+		})
+	}
+
+	total := NewClusterSummary(NewClustersReport(ctx, results))
+
+	return json.Marshal(struct {
+		SchemaHeader
+		Clusters []*clusterOutput
+		Total    *clusterTotal
+	}{
+		SchemaHeader: NewSchemaHeader(),
+		Clusters:     clusters,
+		Total:        &clusterTotal{ClusterSummary: total, Clusters: len(results), Failed: failed},
+	})
+}
+
+// AnalyzeClustersToJson save the results of the multi-cluster scan as JSON
+func AnalyzeClustersToJson(ctx context.Context, results []*ClusterResult) error {
+	filename, err := getOutputFile(ctx)
+	if err != nil {
+		return err
+	}
+
+	data, err := ClustersToJson(ctx, results)
+	if err != nil {
+		return err
+	}
+
+	err = ioutil.WriteFile(filename, data, 0644)
+	if err != nil {
+		return err
+	}
+
+	logOutputFile(ctx, filename)
+
+	return nil
+}
+
+// ResolveClustersSummary print the counts of findings of each cluster and the combined total
+func ResolveClustersSummary(ctx context.Context, results []*ClusterResult) {
+	if q, ok := QuietMode(ctx); ok {
+		q.resolve(NewClustersReport(ctx, results))
+		return
+	}
+
+	fmt.Printf("\nSummary of %s clusters\n\n", config.Yellow(len(results)))
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Cluster", "Host", "Critical", "High", "Medium", "Low", "Warning", "Score"})
+
+	row := func(name, host string, sum *ClusterSummary) []string {
+		return []string{name, host,
+			strconv.Itoa(sum.Severities["critical"]), strconv.Itoa(sum.Severities["high"]),
+			strconv.Itoa(sum.Severities["medium"]), strconv.Itoa(sum.Severities["low"]),
+			strconv.Itoa(sum.Severities["warning"]), strconv.Itoa(sum.Score.Value)}
+	}
+
+	for _, res := range results {
+		if res.Err != nil {
+			table.Append([]string{res.Cluster, res.Host, "-", "-", "-", "-", "-", config.Red("failed")})
+			continue
+		}
+
+		table.Append(row(res.Cluster, res.Host, NewClusterSummary(NewKuberReport(ctx, res.Scanner))))
+	}
+
+	table.SetFooter(row("Total", "", NewClusterSummary(NewClustersReport(ctx, results))))
+	table.Render()
+}
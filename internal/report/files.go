@@ -4,9 +4,9 @@ import (
 	"context"
 	"fmt"
 	"io/ioutil"
-	"log"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/kvesta/vesta/config"
@@ -28,9 +28,47 @@ func exists(path string) bool {
 	return true
 }
 
+// OutputFormat get the format of the output by the option `format`,
+// the format follows the extension of output file if no format is given
+func OutputFormat(ctx context.Context) string {
+	if format, ok := ctx.Value("format").(string); ok && format != "" {
+		return strings.ToLower(format)
+	}
+
+	// `-o json` is the format with the default output file
+	outfile, _ := ctx.Value("output").(string)
+	if isOutputFormat(outfile) {
+		return strings.ToLower(outfile)
+	}
+
+	switch strings.ToLower(filepath.Ext(outfile)) {
+	case ".csv":
+		return "csv"
+	case ".sarif":
+		return "sarif"
+	case ".html", ".htm":
+		return "html"
+	case ".xml":
+		return "junit"
+	}
+
+	return "json"
+}
+
+// isOutputFormat check whether the output file is given as the name of a format
+func isOutputFormat(outfile string) bool {
+	for _, format := range config.OutputFormats {
+		if strings.EqualFold(outfile, format) {
+			return true
+		}
+	}
+
+	return false
+}
+
 func getOutputFile(ctx context.Context) (string, error) {
 	outfile := ctx.Value("output").(string)
-	if outfile == "output" {
+	if outfile == "output" || isOutputFormat(outfile) {
 		pwd, _ := os.Getwd()
 		folder := filepath.Join(pwd, "output")
 		if !exists(folder) {
@@ -40,7 +78,11 @@ func getOutputFile(ctx context.Context) (string, error) {
 			}
 		}
 		nowStamp := time.Now().Format("2006-01-02")
-		file := filepath.Join(folder, fmt.Sprintf("%s.json", nowStamp))
+		ext := OutputFormat(ctx)
+		if ext == "junit" {
+			ext = "xml"
+		}
+		file := filepath.Join(folder, fmt.Sprintf("%s.%s", nowStamp, ext))
 
 		return file, nil
 
@@ -65,7 +107,13 @@ func ScanToJson(ctx context.Context, r vulnscan.Scanner) error {
 		return err
 	}
 
-	data, err := json.Marshal(r.Vulns)
+	data, err := json.Marshal(struct {
+		SchemaHeader
+		Vulns interface{}
+	}{
+		SchemaHeader: NewSchemaHeader(),
+		Vulns:        r.Vulns,
+	})
 	if err != nil {
 		return err
 	}
@@ -74,8 +122,7 @@ func ScanToJson(ctx context.Context, r vulnscan.Scanner) error {
 		return err
 	}
 
-	fmt.Printf("\n")
-	log.Printf("Output file is saved in: %s", config.Yellow(filename))
+	logOutputFile(ctx, filename)
 
 	return nil
 }
@@ -86,7 +133,31 @@ func AnalyzeDockerToJson(ctx context.Context, r analyzer.Scanner) error {
 		return err
 	}
 
-	data, err := json.Marshal(r.VulnContainers)
+	rp := NewDockerReport(ctx, r)
+
+	data, err := json.Marshal(struct {
+		SchemaHeader
+		StartedAt      time.Time
+		FinishedAt     time.Time
+		Score          *Score
+		Checks         []string
+		Timings        []*analyzer.CheckTiming
+		CheckResults   []*CheckResult
+		ExcludedImages []string
+		Suppressed     int `json:",omitempty"`
+		VulnContainers interface{}
+	}{
+		SchemaHeader:   NewSchemaHeader(),
+		StartedAt:      r.StartedAt,
+		FinishedAt:     r.FinishedAt,
+		Score:          rp.Score,
+		Checks:         r.Checks,
+		Timings:        r.Timings,
+		CheckResults:   NewCheckResults(rp),
+		ExcludedImages: r.ExcludedImages,
+		Suppressed:     r.Suppressed,
+		VulnContainers: r.VulnContainers,
+	})
 	if err != nil {
 		return err
 	}
@@ -95,8 +166,7 @@ func AnalyzeDockerToJson(ctx context.Context, r analyzer.Scanner) error {
 		return err
 	}
 
-	fmt.Printf("\n")
-	log.Printf("Output file is saved in: %s", config.Yellow(filename))
+	logOutputFile(ctx, filename)
 
 	return nil
 }
@@ -108,38 +178,127 @@ func AnalyzeKubernetesToJson(ctx context.Context, r analyzer.KScanner) error {
 		return err
 	}
 
-	var f *os.File
-	if !exists(filename) {
-		f, err = os.Create(filename)
-	} else {
-		f, err = os.OpenFile(filename, os.O_WRONLY, 0644)
+	data, err := KubernetesToJson(ctx, r)
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(filename, data, 0644)
+	if err != nil {
+		return err
 	}
 
+	logOutputFile(ctx, filename)
+
+	return nil
+}
+
+// KubernetesToJson encode the result of analyze by kubernetes
+func KubernetesToJson(ctx context.Context, r analyzer.KScanner) ([]byte, error) {
+	rp := NewKuberReport(ctx, r)
+
+	return json.Marshal(struct {
+		SchemaHeader
+		StartedAt      time.Time
+		FinishedAt     time.Time
+		Score          *Score
+		Checks         []string
+		Timings        []*analyzer.CheckTiming
+		CheckResults   []*CheckResult
+		Coverage       []*analyzer.CoverageGap
+		Unchanged      int      `json:",omitempty"`
+		Suppressed     int      `json:",omitempty"`
+		MissingPerms   []string `json:"MissingPermissions,omitempty"`
+		VulnContainers interface{}
+		VulnConfigures interface{}
+
+		// findings grouped by namespace and severity
+		Groups []*NamespaceGroup
+	}{
+		SchemaHeader:   NewSchemaHeader(),
+		StartedAt:      r.StartedAt,
+		FinishedAt:     r.FinishedAt,
+		Score:          rp.Score,
+		Checks:         r.Checks,
+		Timings:        r.Timings,
+		CheckResults:   NewCheckResults(rp),
+		Coverage:       r.Coverage,
+		Unchanged:      r.Unchanged,
+		Suppressed:     r.Suppressed,
+		MissingPerms:   r.MissingPermissions,
+		VulnContainers: r.VulnContainers,
+		VulnConfigures: r.VulnConfigures,
+		Groups:         GroupFindings(rp.Findings),
+	})
+}
+
+// AnalyzeToCSV save the findings of analysis as CSV
+func AnalyzeToCSV(ctx context.Context, rp *Report) error {
+	filename, err := getOutputFile(ctx)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	err = rp.WriteCSV(f)
+	if err != nil {
+		return err
+	}
+
+	logOutputFile(ctx, filename)
+
+	return nil
+}
+
+// AnalyzeToSARIF save the findings of analysis as SARIF
+func AnalyzeToSARIF(ctx context.Context, rp *Report) error {
+	filename, err := getOutputFile(ctx)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(filename)
 	if err != nil {
 		return err
 	}
 
 	defer f.Close()
 
-	dataPods, err := json.Marshal(r.VulnContainers)
+	err = rp.WriteSARIF(f)
+	if err != nil {
+		return err
+	}
+
+	logOutputFile(ctx, filename)
+
+	return nil
+}
+
+// AnalyzeToJUnit save the findings of analysis as JUnit XML
+func AnalyzeToJUnit(ctx context.Context, rp *Report) error {
+	filename, err := getOutputFile(ctx)
 	if err != nil {
 		return err
 	}
 
-	_, err = f.Write(dataPods)
+	f, err := os.Create(filename)
 	if err != nil {
 		return err
 	}
 
-	dataConfig, err := json.Marshal(r.VulnConfigures)
+	defer f.Close()
 
-	_, err = f.Write(dataConfig)
+	err = rp.WriteJUnit(f)
 	if err != nil {
 		return err
 	}
 
-	fmt.Printf("\n")
-	log.Printf("Output file is saved in: %s", config.Yellow(filename))
+	logOutputFile(ctx, filename)
 
 	return nil
 }
@@ -0,0 +1,76 @@
+package report
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/kvesta/vesta/config"
+	"github.com/kvesta/vesta/internal/vulnscan"
+)
+
+// Gate is the CI gate of a scan, the scan exits with ExitCode
+// if any finding is at or above the severity
+type Gate struct {
+	Severity string
+	ExitCode int
+	Found    int
+}
+
+// GateOf get the gate of the options `exit-code` and `severity-threshold`
+func GateOf(ctx context.Context) (*Gate, bool) {
+	g, ok := ctx.Value("gate").(*Gate)
+
+	return g, ok && g != nil
+}
+
+// count count the severities at or above the severity of gate
+func (g *Gate) count(severities []string) int {
+	bar := config.SeverityMap[strings.ToLower(g.Severity)]
+
+	count := 0
+	for _, severity := range severities {
+		if config.SeverityMap[strings.ToLower(severity)] >= bar {
+			count++
+		}
+	}
+
+	g.Found += count
+
+	return count
+}
+
+// CheckGate count the findings of the report reaching the severity of gate
+func CheckGate(ctx context.Context, rp *Report) {
+	g, ok := GateOf(ctx)
+	if !ok {
+		return
+	}
+
+	severities := []string{}
+	for _, f := range rp.Findings {
+		severities = append(severities, f.Severity)
+	}
+
+	if n := g.count(severities); n > 0 {
+		log.Print(config.Red(fmt.Sprintf("%d findings are at or above the severity threshold %s", n, g.Severity)))
+	}
+}
+
+// CheckScanGate count the vulnerabilities of the image scan reaching the severity of gate
+func CheckScanGate(ctx context.Context, r vulnscan.Scanner) {
+	g, ok := GateOf(ctx)
+	if !ok {
+		return
+	}
+
+	severities := []string{}
+	for _, v := range r.Vulns {
+		severities = append(severities, v.Level)
+	}
+
+	if n := g.count(severities); n > 0 {
+		log.Print(config.Red(fmt.Sprintf("%d vulnerabilities are at or above the severity threshold %s", n, g.Severity)))
+	}
+}
@@ -0,0 +1,83 @@
+package report
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/kvesta/vesta/config"
+)
+
+// namespaceParam match the namespace in the param of configures,
+// e.g. `Namespace: default`, `namespace: default` and `scope: namespaced: default`
+var namespaceParam = regexp.MustCompile(`(?i)\bname?spaced?: ([^\s|]+)`)
+
+// namespaceOf get the namespace from the param of finding,
+// it is empty for the cluster-scoped findings and the subjects of all namespaces
+func namespaceOf(param string) string {
+	match := namespaceParam.FindStringSubmatch(param)
+	if len(match) < 2 || match[1] == "all" {
+		return ""
+	}
+
+	return match[1]
+}
+
+// NamespaceGroup is the findings of a namespace grouped by severity
+type NamespaceGroup struct {
+	// Namespace is empty for the cluster-scoped findings
+	Namespace  string
+	Count      int
+	Severities []*SeverityGroup
+}
+
+// SeverityGroup is the findings of a severity
+type SeverityGroup struct {
+	Severity string
+	Count    int
+	Findings []*Finding
+}
+
+// GroupFindings group the findings by namespace and then by severity,
+// the cluster-scoped group is the first and the severities are ordered from critical
+func GroupFindings(findings []*Finding) []*NamespaceGroup {
+	groups := map[string]map[string][]*Finding{}
+
+	for _, f := range findings {
+		if groups[f.Namespace] == nil {
+			groups[f.Namespace] = map[string][]*Finding{}
+		}
+
+		severity := strings.ToLower(f.Severity)
+		groups[f.Namespace][severity] = append(groups[f.Namespace][severity], f)
+	}
+
+	namespaces := []string{}
+	for ns := range groups {
+		namespaces = append(namespaces, ns)
+	}
+	sort.Strings(namespaces)
+
+	result := []*NamespaceGroup{}
+	for _, ns := range namespaces {
+		ng := &NamespaceGroup{Namespace: ns}
+
+		severities := []string{}
+		for severity := range groups[ns] {
+			severities = append(severities, severity)
+		}
+		sort.Slice(severities, func(i, j int) bool {
+			return config.SeverityMap[severities[i]] > config.SeverityMap[severities[j]]
+		})
+
+		for _, severity := range severities {
+			list := groups[ns][severity]
+			ng.Severities = append(ng.Severities, &SeverityGroup{Severity: severity, Count: len(list), Findings: list})
+			ng.Count += len(list)
+		}
+
+		result = append(result, ng)
+	}
+
+	return result
+}
@@ -0,0 +1,234 @@
+package report
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kvesta/vesta/config"
+	"github.com/kvesta/vesta/pkg/vulnlib"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/olekukonko/tablewriter"
+)
+
+// History is the local SQLite file keeping the findings of past scans
+type History struct {
+	DB *sql.DB
+}
+
+// ScanRecord is a past scan with the severity counts of its findings
+type ScanRecord struct {
+	ID        int64
+	CreatedAt time.Time
+	Target    string
+	DBVersion string
+	Score     int
+	Counts    map[string]int
+}
+
+var historyTables = []string{
+	`CREATE TABLE IF NOT EXISTS scans (
+		"ID" INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+		"CreatedAt" TEXT,
+		"Target" TEXT,
+		"DBVersion" TEXT,
+		"Score" INTEGER);`,
+	`CREATE TABLE IF NOT EXISTS findings (
+		"ID" INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+		"ScanID" INTEGER REFERENCES scans("ID"),
+		"Target" TEXT,
+		"Severity" TEXT,
+		"Type" TEXT,
+		"Param" TEXT,
+		"Value" TEXT,
+		"Describe" TEXT);`,
+	`CREATE INDEX IF NOT EXISTS findings_scan ON findings ("ScanID");`,
+}
+
+// historyPath get the location of history file from the option `history`,
+// `default` is the file in the folder of vulnerability database
+func historyPath(location string) (string, error) {
+	if location != "default" {
+		return location, nil
+	}
+
+	store, err := vulnlib.StoreDir()
+	if err != nil {
+		return "", err
+	}
+
+	err = os.MkdirAll(store, os.FileMode(0755))
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(store, "history.db"), nil
+}
+
+// OpenHistory open the history file, the tables are created if it is new
+func OpenHistory(location string) (*History, error) {
+	path, err := historyPath(location)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, table := range historyTables {
+		_, err = db.Exec(table)
+		if err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to init history %s: %v", path, err)
+		}
+	}
+
+	return &History{DB: db}, nil
+}
+
+func (h *History) Close() error {
+	return h.DB.Close()
+}
+
+// Save record the findings of the report in one transaction
+func (h *History) Save(rp *Report, target, dbVersion string, createdAt time.Time) (int64, error) {
+	tx, err := h.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	score := 0
+	if rp.Score != nil {
+		score = rp.Score.Value
+	}
+
+	res, err := tx.Exec(`INSERT INTO scans ("CreatedAt", "Target", "DBVersion", "Score") VALUES (?, ?, ?, ?)`,
+		createdAt.UTC().Format(time.RFC3339), target, dbVersion, score)
+	if err != nil {
+		return 0, err
+	}
+
+	scanID, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, f := range rp.Findings {
+		_, err = tx.Exec(`INSERT INTO findings
+			("ScanID", "Target", "Severity", "Type", "Param", "Value", "Describe")
+			VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			scanID, f.Target, strings.ToLower(f.Severity), f.Type, f.Param, f.Value, f.Describe)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return scanID, tx.Commit()
+}
+
+// List get the latest scans with the severity counts, at most limit scans are returned
+func (h *History) List(limit int) ([]*ScanRecord, error) {
+	records := []*ScanRecord{}
+
+	rows, err := h.DB.Query(`SELECT s."ID", s."CreatedAt", s."Target", s."DBVersion", s."Score", f."Severity", COUNT(f."ID")
+		FROM scans s LEFT JOIN findings f ON f."ScanID" = s."ID"
+		WHERE s."ID" IN (SELECT "ID" FROM scans ORDER BY "ID" DESC LIMIT ?)
+		GROUP BY s."ID", f."Severity"
+		ORDER BY s."ID" DESC`, limit)
+	if err != nil {
+		return records, err
+	}
+	defer rows.Close()
+
+	var last *ScanRecord
+	for rows.Next() {
+		r := &ScanRecord{Counts: map[string]int{}}
+		var createdAt string
+		var severity sql.NullString
+		var count int
+
+		err = rows.Scan(&r.ID, &createdAt, &r.Target, &r.DBVersion, &r.Score, &severity, &count)
+		if err != nil {
+			return records, err
+		}
+
+		if last == nil || last.ID != r.ID {
+			r.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+			records = append(records, r)
+			last = r
+		}
+
+		if severity.Valid {
+			last.Counts[severity.String] = count
+		}
+	}
+
+	return records, rows.Err()
+}
+
+// SaveHistory save the report to the history file of the option `history`
+func SaveHistory(ctx context.Context, rp *Report, target string) error {
+	location, ok := ctx.Value("history").(string)
+	if !ok || location == "" {
+		return nil
+	}
+
+	h, err := OpenHistory(location)
+	if err != nil {
+		return err
+	}
+	defer h.Close()
+
+	dbVersion := "unknown"
+	if meta, err := vulnlib.LoadMetadata(); err == nil {
+		dbVersion = meta.Version
+	}
+
+	scanID, err := h.Save(rp, target, dbVersion, time.Now())
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Scan is saved in the history, ID: %s", config.Yellow(scanID))
+
+	return nil
+}
+
+// ResolveHistory print the past scans of the history file
+func ResolveHistory(location string, limit int) error {
+	h, err := OpenHistory(location)
+	if err != nil {
+		return err
+	}
+	defer h.Close()
+
+	records, err := h.List(limit)
+	if err != nil {
+		return err
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"ID", "Time", "Target", "DB Version", "Critical", "High",
+		"Medium", "Low", "Warning", "Score"})
+
+	for _, r := range records {
+		table.Append([]string{strconv.FormatInt(r.ID, 10), r.CreatedAt.Local().Format("2006-01-02 15:04:05"),
+			r.Target, r.DBVersion,
+			strconv.Itoa(r.Counts["critical"]), strconv.Itoa(r.Counts["high"]),
+			strconv.Itoa(r.Counts["medium"]), strconv.Itoa(r.Counts["low"]),
+			strconv.Itoa(r.Counts["warning"]), strconv.Itoa(r.Score)})
+	}
+
+	table.Render()
+
+	return nil
+}
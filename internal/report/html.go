@@ -0,0 +1,103 @@
+package report
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/kvesta/vesta/internal/vulnscan"
+	htmlreport "github.com/kvesta/vesta/pkg/report"
+)
+
+// newHTMLDocument group the findings of the report by target,
+// the targets are prefixed by the cluster in the multi-cluster scan
+func newHTMLDocument(title string, rp *Report) *htmlreport.Document {
+	doc := &htmlreport.Document{Title: title, GeneratedAt: time.Now()}
+	if rp.Score != nil {
+		doc.Score = &rp.Score.Value
+	}
+
+	sections := map[string]*htmlreport.Section{}
+	for _, f := range rp.Findings {
+		name := f.Target
+		if f.Cluster != "" {
+			name = fmt.Sprintf("%s/%s", f.Cluster, f.Target)
+		}
+
+		s, ok := sections[name]
+		if !ok {
+			s = &htmlreport.Section{Name: name}
+			sections[name] = s
+			doc.Sections = append(doc.Sections, s)
+		}
+
+		s.Findings = append(s.Findings, &htmlreport.Finding{
+			Severity:    f.Severity,
+			Type:        f.Type,
+			Param:       f.Param,
+			Value:       f.Value,
+			Describe:    f.Describe,
+			Reference:   f.Reference,
+			Remediation: f.Remediation,
+		})
+	}
+
+	return doc
+}
+
+// writeHTMLFile save the document as HTML to the output file
+func writeHTMLFile(ctx context.Context, doc *htmlreport.Document) error {
+	filename, err := getOutputFile(ctx)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	err = htmlreport.WriteHTML(f, doc)
+	if err != nil {
+		return err
+	}
+
+	logOutputFile(ctx, filename)
+
+	return nil
+}
+
+// AnalyzeToHTML save the findings of analysis as HTML
+func AnalyzeToHTML(ctx context.Context, title string, rp *Report) error {
+	return writeHTMLFile(ctx, newHTMLDocument(title, rp))
+}
+
+// ScanToHTML save the vulnerabilities of the image scan as HTML, one section per package
+func ScanToHTML(ctx context.Context, title string, r vulnscan.Scanner) error {
+	doc := &htmlreport.Document{Title: title, GeneratedAt: time.Now()}
+
+	sections := map[string]*htmlreport.Section{}
+	for _, v := range r.Vulns {
+		name := fmt.Sprintf("%s: %s %s", v.Type, v.Name, v.CurrentVersion)
+
+		s, ok := sections[name]
+		if !ok {
+			s = &htmlreport.Section{Name: name}
+			sections[name] = s
+			doc.Sections = append(doc.Sections, s)
+		}
+
+		s.Findings = append(s.Findings, &htmlreport.Finding{
+			Severity: v.Level,
+			Type:     v.CVEID,
+			Param:    "vulnerable version",
+			Value:    v.VulnerableVersion,
+			Describe: v.Desc,
+		})
+	}
+
+	return writeHTMLFile(ctx, doc)
+}
@@ -0,0 +1,120 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name          `xml:"testsuites"`
+	Name     string            `xml:"name,attr"`
+	Tests    int               `xml:"tests,attr"`
+	Failures int               `xml:"failures,attr"`
+	Suites   []*junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Time     string           `xml:"time,attr"`
+	Cases    []*junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit write the findings as JUnit XML, each check is a test suite holding a failed
+// test case per finding, the check without findings is a passed test case.
+// The test reports of CI keep only one failure of a test case, so the findings are not merged
+func (rp *Report) WriteJUnit(w io.Writer) error {
+	suites := &junitTestSuites{Name: "vesta"}
+
+	byCheck := map[string][]*Finding{}
+	for _, f := range rp.Findings {
+		byCheck[f.Check] = append(byCheck[f.Check], f)
+	}
+
+	results := NewCheckResults(rp)
+	if len(byCheck[""]) > 0 {
+		results = append(results, &CheckResult{Name: ""})
+	}
+
+	for _, res := range results {
+		name := res.Name
+		if name == "" {
+			name = "vesta"
+		}
+
+		suite := &junitTestSuite{Name: name, Time: fmt.Sprintf("%.3f", res.Duration.Seconds())}
+
+		for _, f := range byCheck[res.Name] {
+			target := f.Target
+			if f.Cluster != "" {
+				target = fmt.Sprintf("%s/%s", f.Cluster, target)
+			}
+
+			message := f.Describe
+			if message == "" {
+				message = fmt.Sprintf("%s: %s", f.Param, f.Value)
+			}
+
+			text := []string{fmt.Sprintf("Target: %s", target), fmt.Sprintf("Param: %s", f.Param),
+				fmt.Sprintf("Value: %s", f.Value)}
+			if f.Remediation != "" {
+				text = append(text, fmt.Sprintf("Remediation: %s", f.Remediation))
+			}
+			if f.Reference != "" {
+				text = append(text, fmt.Sprintf("Reference: %s", f.Reference))
+			}
+
+			suite.Cases = append(suite.Cases, &junitTestCase{
+				Name:      f.Param,
+				ClassName: fmt.Sprintf("%s.%s", name, target),
+				Failure: &junitFailure{
+					Message: message,
+					Type:    strings.ToLower(f.Severity),
+					Text:    strings.Join(text, "\n"),
+				},
+			})
+		}
+
+		suite.Failures = len(suite.Cases)
+		if len(suite.Cases) < 1 {
+			suite.Cases = append(suite.Cases, &junitTestCase{Name: name, ClassName: name})
+		}
+		suite.Tests = len(suite.Cases)
+
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.Suites = append(suites.Suites, suite)
+	}
+
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+
+	err = enc.Encode(suites)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, "\n")
+
+	return err
+}
@@ -61,6 +61,18 @@ func ResolveAnalysisData(ctx context.Context, r vulnscan.Scanner) error {
 
 // ResolveDockerData print the result of analyze by docker
 func ResolveDockerData(ctx context.Context, r analyzer.Scanner) error {
+	if q, ok := QuietMode(ctx); ok {
+		q.resolve(NewDockerReport(ctx, r))
+		return nil
+	}
+
+	fmt.Printf("\nChecks: %s\n", strings.Join(r.Checks, ", "))
+
+	if len(r.ExcludedImages) > 0 {
+		fmt.Printf("\nExcluded %s images from image analyzing: %s\n",
+			config.Yellow(len(r.ExcludedImages)), strings.Join(r.ExcludedImages, ", "))
+	}
+
 	fmt.Printf("\nDetected %s vulnerabilities\n\n", config.Yellow(len(r.VulnContainers)))
 
 	table := tablewriter.NewWriter(os.Stdout)
@@ -83,16 +95,29 @@ func ResolveDockerData(ctx context.Context, r analyzer.Scanner) error {
 
 	table.Render()
 
+	rp := NewDockerReport(ctx, r)
+	printTopFindings(ctx, rp.Findings)
+	printScore(rp.Score)
+
 	return nil
 }
 
 // ResolveKuberData print the result of analyze by kubernetes
 func ResolveKuberData(ctx context.Context, r analyzer.KScanner) error {
+	if q, ok := QuietMode(ctx); ok {
+		q.resolve(NewKuberReport(ctx, r))
+		return nil
+	}
+
+	fmt.Printf("\nChecks: %s\n", strings.Join(r.Checks, ", "))
+
+	printCoverage(r.Coverage)
 
 	// Report pod condition
 	fmt.Printf("\nDetected %s vulnerabilities\n\n", config.Yellow(len(r.VulnContainers)+len(r.VulnConfigures)))
 
 	if len(r.VulnContainers)+len(r.VulnConfigures) == 0 {
+		printScore(NewKuberReport(ctx, r).Score)
 		return nil
 	}
 
@@ -108,19 +133,34 @@ func ResolveKuberData(ctx context.Context, r analyzer.KScanner) error {
 		for _, v := range p.Threats {
 
 			nodeName := ""
-			if r.MasterNodes[p.NodeName].IsMaster {
+			if node, ok := r.MasterNodes[p.NodeName]; ok && node.IsMaster {
 				nodeName = fmt.Sprintf("%s (%s)",
 					p.NodeName, config.Red("Master"))
 			} else {
 				nodeName = p.NodeName
 			}
 
+			podDetail := fmt.Sprintf("Name: %s | "+
+				"Namespace: %s | "+
+				"Status: %s | "+
+				"Node Name: %s", p.ContainerName, p.Namepsace,
+				p.Status, nodeName)
+
+			// Orphan pods are reported by the pod name only
+			if p.OwnerKind != "" && p.OwnerKind != "Pod" {
+				podDetail += fmt.Sprintf(" | Workload: %s/%s", p.OwnerKind, p.OwnerName)
+			}
+
+			if p.Replicas > 1 {
+				podDetail += fmt.Sprintf(" | Replicas: %d", p.Replicas)
+			}
+
+			if p.HelmRelease != "" || p.HelmChart != "" {
+				podDetail += fmt.Sprintf(" | Helm: %s (%s)", p.HelmRelease, p.HelmChart)
+			}
+
 			vulnData := []string{
-				strconv.Itoa(i + 1), fmt.Sprintf("Name: %s | "+
-					"Namespace: %s | "+
-					"Status: %s | "+
-					"Node Name: %s", p.ContainerName, p.Namepsace,
-					p.Status, nodeName),
+				strconv.Itoa(i + 1), podDetail,
 				v.Param, v.Value, v.Type,
 				judgeSeverity(v.Severity), v.Describe,
 			}
@@ -146,6 +186,59 @@ func ResolveKuberData(ctx context.Context, r analyzer.KScanner) error {
 
 	table.Render()
 
+	rp := NewKuberReport(ctx, r)
+	printTopFindings(ctx, rp.Findings)
+	printScore(rp.Score)
+
+	return nil
+}
+
+// printCoverage print the checks which could not look at the resources,
+// a clean result of them does not mean there is no vulnerability
+func printCoverage(gaps []*analyzer.CoverageGap) {
+	if len(gaps) < 1 {
+		return
+	}
+
+	fmt.Printf("\nScan coverage: %s checks are incomplete for the insufficient permission\n\n",
+		config.Yellow(len(gaps)))
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Check", "Scope", "Reason", "Needed Permission"})
+	table.SetRowLine(true)
+
+	for _, gap := range gaps {
+		table.Append([]string{gap.Check, gap.Scope(), gap.Reason, gap.Permission})
+	}
+
+	table.Render()
+}
+
+// printScore print the compliance score at the end of scan
+func printScore(score *Score) {
+	fmt.Printf("\nCompliance score: %s/100\n", config.Yellow(score.Value))
+	fmt.Printf("%s\n", score.Methodology)
+}
+
+// ResolveExplain print the ordered checks which would run in a scan
+func ResolveExplain(ctx context.Context, plans []*analyzer.CheckPlan) error {
+	fmt.Printf("\nExplain %s checks\n\n", config.Yellow(len(plans)))
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"ID", "Check", "Scope", "Run", "Reason"})
+	table.SetRowLine(true)
+
+	for i, p := range plans {
+		run := config.Green("yes")
+		if !p.Run {
+			run = config.Red("no")
+		}
+
+		table.Append([]string{strconv.Itoa(i + 1), p.Name, p.Scope, run, p.Reason})
+	}
+
+	table.Render()
+
 	return nil
 }
 
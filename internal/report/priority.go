@@ -0,0 +1,123 @@
+package report
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kvesta/vesta/config"
+	"github.com/olekukonko/tablewriter"
+)
+
+// defaultTopFindings is the number of the prioritized findings by default
+const defaultTopFindings = 10
+
+// Priority is a finding ranked by the severity and the exploitability
+type Priority struct {
+	*Finding
+
+	Rank    int
+	Score   int
+	Reasons []string
+}
+
+// exploitSignal raises the priority of the findings which are exploitable directly
+type exploitSignal struct {
+	reason  string
+	bonus   int
+	matches func(f *Finding) bool
+}
+
+var exploitSignals = []exploitSignal{
+	{reason: "privileged with host root mounted", bonus: 30,
+		matches: func(f *Finding) bool {
+			return f.Param == "Host Takeover"
+		}},
+	{reason: "vulnerable kernel with matching capability", bonus: 25,
+		matches: func(f *Finding) bool {
+			return strings.Contains(f.Param, "capabilities") &&
+				(strings.Contains(f.Reference, "CVE-2020-14386") || strings.Contains(f.Reference, "CVE-2022-0492"))
+		}},
+	{reason: "anonymous access", bonus: 25,
+		matches: func(f *Finding) bool {
+			text := f.Param + " " + f.Value
+			return strings.Contains(text, "system:anonymous") || strings.Contains(text, "system:unauthenticated")
+		}},
+	{reason: "container escape", bonus: 10,
+		matches: func(f *Finding) bool {
+			return strings.Contains(strings.ToLower(f.Describe), "container escape")
+		}},
+}
+
+// topCount get the number of the prioritized findings by the option `top`
+func topCount(ctx context.Context) int {
+	if n, ok := ctx.Value("top").(int); ok {
+		return n
+	}
+
+	return defaultTopFindings
+}
+
+// Prioritize rank the findings by the severity raised by the exploitability signals,
+// at most n findings are returned
+func Prioritize(findings []*Finding, n int) []*Priority {
+	priorities := []*Priority{}
+
+	for _, f := range findings {
+		p := &Priority{
+			Finding: f,
+			Score:   config.SeverityMap[strings.ToLower(f.Severity)] * 10,
+		}
+
+		for _, sig := range exploitSignals {
+			if sig.matches(f) {
+				p.Score += sig.bonus
+				p.Reasons = append(p.Reasons, sig.reason)
+			}
+		}
+
+		priorities = append(priorities, p)
+	}
+
+	sort.SliceStable(priorities, func(i, j int) bool {
+		return priorities[i].Score > priorities[j].Score
+	})
+
+	if n >= 0 && len(priorities) > n {
+		priorities = priorities[:n]
+	}
+
+	for i, p := range priorities {
+		p.Rank = i + 1
+	}
+
+	return priorities
+}
+
+// printTopFindings print the findings to fix first
+func printTopFindings(ctx context.Context, findings []*Finding) {
+	n := topCount(ctx)
+	if n <= 0 || len(findings) < 1 {
+		return
+	}
+
+	fmt.Printf("\nFix these first:\n")
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Rank", "Target", "Param", "Severity", "Why"})
+	table.SetRowLine(true)
+
+	for _, p := range Prioritize(findings, n) {
+		reason := strings.Join(p.Reasons, ", ")
+		if reason == "" {
+			reason = fmt.Sprintf("%s severity", p.Severity)
+		}
+
+		table.Append([]string{strconv.Itoa(p.Rank), p.Target, p.Param, judgeSeverity(p.Severity), reason})
+	}
+
+	table.Render()
+}
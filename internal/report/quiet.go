@@ -0,0 +1,62 @@
+package report
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/kvesta/vesta/config"
+)
+
+// Quiet is the quiet mode of analysis, the tables are replaced by the findings
+// at or above the severity, and the count of printed findings decides the exit code
+type Quiet struct {
+	Severity string
+	Found    int
+}
+
+// QuietMode get the quiet mode of the option `quiet`
+func QuietMode(ctx context.Context) (*Quiet, bool) {
+	q, ok := ctx.Value("quiet").(*Quiet)
+
+	return q, ok && q != nil
+}
+
+// resolve print the findings of the report at or above the severity of quiet mode
+func (q *Quiet) resolve(rp *Report) {
+	q.Found += writeQuiet(os.Stdout, rp.Findings, q.Severity)
+}
+
+// writeQuiet write the findings at or above the severity, one tab-separated line per finding
+func writeQuiet(w io.Writer, findings []*Finding, severity string) int {
+	bar := config.SeverityMap[strings.ToLower(severity)]
+
+	count := 0
+	for _, f := range findings {
+		if config.SeverityMap[strings.ToLower(f.Severity)] < bar {
+			continue
+		}
+
+		target := f.Target
+		if f.Cluster != "" {
+			target = fmt.Sprintf("%s/%s", f.Cluster, target)
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", strings.ToUpper(f.Severity), target, f.Param, f.Value)
+		count++
+	}
+
+	return count
+}
+
+// logOutputFile log the location of the saved output, the blank line is skipped in quiet mode
+func logOutputFile(ctx context.Context, filename string) {
+	if _, ok := QuietMode(ctx); !ok {
+		fmt.Printf("\n")
+	}
+
+	log.Printf("Output file is saved in: %s", config.Yellow(filename))
+}
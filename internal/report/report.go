@@ -0,0 +1,176 @@
+package report
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+
+	"github.com/kvesta/vesta/internal/analyzer"
+)
+
+// Finding is a single finding of analysis
+type Finding struct {
+	Target      string
+	Severity    string
+	Type        string
+	Param       string
+	Value       string
+	Describe    string
+	Reference   string
+	Remediation string
+	CISControls []string
+
+	// Namespace of the finding, empty for the cluster-scoped findings
+	Namespace string `json:",omitempty"`
+
+	// Cluster of the finding in the multi-cluster scan
+	Cluster string `json:",omitempty"`
+
+	// Helm release and chart of the workload, empty for the non-Helm workloads
+	HelmRelease string `json:",omitempty"`
+	HelmChart   string `json:",omitempty"`
+
+	// Check finding the finding
+	Check string `json:",omitempty"`
+
+	// Container or workload of the finding, empty for the findings of images, hosts and cluster
+	ContainerID string `json:",omitempty"`
+	Workload    string `json:",omitempty"`
+}
+
+// Report is the structured result of analysis,
+// which is shared by all the output formats
+type Report struct {
+	Score    *Score
+	Checks   []string
+	Timings  []*analyzer.CheckTiming
+	Findings []*Finding
+}
+
+// NewDockerReport build the report from the result of analyze by docker
+func NewDockerReport(ctx context.Context, r analyzer.Scanner) *Report {
+	rp := &Report{Checks: r.Checks, Timings: r.Timings}
+
+	for _, c := range r.VulnContainers {
+		target, containerID := fmt.Sprintf("container: %s", c.ContainerName), ""
+		if c.ContainerID != "None" {
+			target += fmt.Sprintf(" (%s)", c.ContainerID)
+			containerID = c.ContainerID
+		}
+
+		for _, v := range c.Threats {
+			rp.Findings = append(rp.Findings, &Finding{
+				Target:      target,
+				Severity:    v.Severity,
+				Type:        v.Type,
+				Param:       v.Param,
+				Value:       v.Value,
+				Describe:    v.Describe,
+				Reference:   v.Reference,
+				Remediation: v.Remediation,
+				CISControls: v.CISControls,
+				Check:       v.Check,
+				ContainerID: containerID,
+			})
+		}
+	}
+
+	rp.Score = NewScore(ctx, rp.Findings)
+
+	return rp
+}
+
+// NewKuberReport build the report from the result of analyze by kubernetes
+func NewKuberReport(ctx context.Context, r analyzer.KScanner) *Report {
+	rp := &Report{Checks: r.Checks, Timings: r.Timings}
+
+	for _, p := range r.VulnContainers {
+		target := fmt.Sprintf("pod: %s/%s", p.Namepsace, p.ContainerName)
+		workload := fmt.Sprintf("Pod/%s", p.ContainerName)
+		if p.OwnerKind != "" && p.OwnerKind != "Pod" {
+			target = fmt.Sprintf("%s: %s/%s", p.OwnerKind, p.Namepsace, p.OwnerName)
+			workload = fmt.Sprintf("%s/%s", p.OwnerKind, p.OwnerName)
+		}
+
+		for _, v := range p.Threats {
+			rp.Findings = append(rp.Findings, &Finding{
+				Target:      target,
+				Severity:    v.Severity,
+				Type:        v.Type,
+				Param:       v.Param,
+				Value:       v.Value,
+				Describe:    v.Describe,
+				Reference:   v.Reference,
+				Remediation: v.Remediation,
+				CISControls: v.CISControls,
+				Namespace:   p.Namepsace,
+				HelmRelease: p.HelmRelease,
+				HelmChart:   p.HelmChart,
+				Check:       v.Check,
+				Workload:    workload,
+			})
+		}
+	}
+
+	for _, c := range r.VulnConfigures {
+		rp.Findings = append(rp.Findings, &Finding{
+			Target:      "cluster",
+			Severity:    c.Severity,
+			Type:        c.Type,
+			Param:       c.Param,
+			Value:       c.Value,
+			Describe:    c.Describe,
+			Reference:   c.Reference,
+			Remediation: c.Remediation,
+			CISControls: c.CISControls,
+			Namespace:   namespaceOf(c.Param),
+			Check:       c.Check,
+		})
+	}
+
+	rp.Score = NewScore(ctx, rp.Findings)
+
+	return rp
+}
+
+// WriteCSV write one row per finding, fields containing commas,
+// quotes or newlines are quoted by encoding/csv.
+// The column of cluster is prepended for the report of the multi-cluster scan
+func (rp *Report) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+
+	multiCluster := false
+	for _, f := range rp.Findings {
+		if f.Cluster != "" {
+			multiCluster = true
+			break
+		}
+	}
+
+	header := []string{"Target", "Severity", "Type", "Param", "Value", "Describe"}
+	if multiCluster {
+		header = append([]string{"Cluster"}, header...)
+	}
+
+	err := cw.Write(header)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range rp.Findings {
+		record := []string{f.Target, f.Severity, f.Type, f.Param, f.Value, f.Describe}
+		if multiCluster {
+			record = append([]string{f.Cluster}, record...)
+		}
+
+		err = cw.Write(record)
+		if err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+
+	return cw.Error()
+}
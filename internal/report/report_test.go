@@ -0,0 +1,508 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"net"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kvesta/vesta/config"
+	"github.com/kvesta/vesta/internal/analyzer"
+)
+
+func TestWriteCSV(t *testing.T) {
+	rp := &Report{
+		Findings: []*Finding{
+			{
+				Target:   "container: nginx (0123456789ab)",
+				Severity: "critical",
+				Param:    "Privileged",
+				Value:    "true",
+				Describe: "There has a potential container escape,\nin privileged module with \"true\".",
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := rp.WriteCSV(&buf); err != nil {
+		t.Fatalf("WriteCSV() error = %v", err)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid CSV, error = %v", err)
+	}
+
+	want := []string{"container: nginx (0123456789ab)", "critical", "", "Privileged", "true",
+		"There has a potential container escape,\nin privileged module with \"true\"."}
+
+	if len(records) != 2 || !reflect.DeepEqual(records[1], want) {
+		t.Errorf("WriteCSV() got = %v, want %v", records, want)
+	}
+}
+
+func TestNewScore(t *testing.T) {
+	tests := []struct {
+		name     string
+		findings []*Finding
+		weights  map[string]int
+		want     int
+	}{
+		{
+			name: "noFindings",
+			want: 100,
+		},
+		{
+			name:     "defaultWeights",
+			findings: []*Finding{{Severity: "critical"}, {Severity: "high"}, {Severity: "warning"}},
+			want:     85,
+		},
+		{
+			name:     "customWeights",
+			findings: []*Finding{{Severity: "critical"}, {Severity: "low"}},
+			weights:  map[string]int{"critical": 60, "low": 50},
+			want:     0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.weights != nil {
+				ctx = context.WithValue(ctx, "scoreWeights", tt.weights)
+			}
+
+			if got := NewScore(ctx, tt.findings).Value; got != tt.want {
+				t.Errorf("NewScore() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrioritize(t *testing.T) {
+	findings := []*Finding{
+		{Target: "cluster", Severity: "critical", Param: "Docker server", Value: "18.09"},
+		{Target: "container: app", Severity: "high", Param: "pid", Value: "host"},
+		{Target: "container: web", Severity: "critical", Param: "Host Takeover",
+			Value: "privileged: true | mount: /:/host"},
+		{Target: "cluster", Severity: "high", Param: "ClusterRoleBinding: anonymous",
+			Value: "system:anonymous"},
+		{Target: "container: db", Severity: "critical", Param: "capabilities",
+			Value: "NET_RAW (default)", Reference: "https://nvd.nist.gov/vuln/detail/CVE-2020-14386"},
+	}
+
+	got := []string{}
+	for _, p := range Prioritize(findings, 3) {
+		got = append(got, p.Param)
+	}
+
+	want := []string{"Host Takeover", "capabilities", "ClusterRoleBinding: anonymous"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Prioritize() = %v, want %v", got, want)
+	}
+
+	if all := Prioritize(findings, 10); len(all) != len(findings) || all[4].Rank != 5 {
+		t.Errorf("Prioritize() returned %d findings", len(all))
+	}
+}
+
+func TestHistory(t *testing.T) {
+	h, err := OpenHistory(filepath.Join(t.TempDir(), "history.db"))
+	if err != nil {
+		t.Fatalf("OpenHistory() error = %v", err)
+	}
+	defer h.Close()
+
+	first := &Report{Score: &Score{Value: 80}, Findings: []*Finding{
+		{Target: "pod: default/web", Severity: "critical", Param: "privileged"},
+		{Target: "pod: default/web", Severity: "Low", Param: "limits"},
+		{Target: "cluster", Severity: "low", Param: "kubelet"},
+	}}
+	second := &Report{Score: &Score{Value: 100}}
+
+	now := time.Now()
+	if _, err := h.Save(first, "kubernetes: https://10.0.0.1:6443", "20261016", now.Add(-time.Hour)); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if _, err := h.Save(second, "docker", "20261016", now); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	records, err := h.List(10)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+
+	if len(records) != 2 {
+		t.Fatalf("List() got %d scans, want 2", len(records))
+	}
+
+	if records[0].Target != "docker" || len(records[0].Counts) != 0 || records[0].Score != 100 {
+		t.Errorf("unexpected latest scan: %+v", records[0])
+	}
+
+	if records[1].Counts["critical"] != 1 || records[1].Counts["low"] != 2 || records[1].DBVersion != "20261016" {
+		t.Errorf("unexpected counts of scan: %+v", records[1])
+	}
+
+	if records, _ := h.List(1); len(records) != 1 {
+		t.Errorf("List(1) got %d scans", len(records))
+	}
+}
+
+func TestKubernetesToJsonSchema(t *testing.T) {
+	data, err := KubernetesToJson(context.Background(), analyzer.KScanner{})
+	if err != nil {
+		t.Fatalf("KubernetesToJson() error = %v", err)
+	}
+
+	out := map[string]interface{}{}
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatalf("invalid JSON, error = %v", err)
+	}
+
+	if out["schemaVersion"] != float64(SchemaVersion) || out["version"] != config.Version {
+		t.Errorf("KubernetesToJson() header = %v, %v", out["schemaVersion"], out["version"])
+	}
+
+	for _, field := range []string{"StartedAt", "FinishedAt", "Score", "Checks", "CheckResults", "VulnContainers", "VulnConfigures"} {
+		if _, ok := out[field]; !ok {
+			t.Errorf("KubernetesToJson() missing field %s", field)
+		}
+	}
+}
+
+func TestNewCheckResults(t *testing.T) {
+	rp := &Report{
+		Checks:  []string{"checkPod", "checkSecret"},
+		Timings: []*analyzer.CheckTiming{{Name: "checkPod", Duration: time.Second}},
+		Findings: []*Finding{
+			{Check: "checkPod", Severity: "high"},
+			{Check: "checkPod", Severity: "High"},
+			{Check: "checkPod", Severity: "low"},
+			{Check: "checkKernelVersion", Severity: "critical"},
+			{Severity: "low"},
+		},
+	}
+
+	got := NewCheckResults(rp)
+	want := []*CheckResult{
+		{Name: "checkPod", Duration: time.Second, Findings: 3, Severities: map[string]int{"high": 2, "low": 1}},
+		{Name: "checkSecret"},
+		{Name: "checkKernelVersion", Findings: 1, Severities: map[string]int{"critical": 1}},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		gotJSON, _ := json.Marshal(got)
+		t.Errorf("NewCheckResults() = %s", gotJSON)
+	}
+}
+
+func TestOutputFormat(t *testing.T) {
+	tests := []struct {
+		output, format, want string
+	}{
+		{output: "output", want: "json"},
+		{output: "json", want: "json"},
+		{output: "SARIF", want: "sarif"},
+		{output: "result/vesta.csv", want: "csv"},
+		{output: "result/vesta.sarif", want: "sarif"},
+		{output: "result/vesta.txt", format: "csv", want: "csv"},
+	}
+
+	for _, tt := range tests {
+		ctx := context.WithValue(context.Background(), "output", tt.output)
+		ctx = context.WithValue(ctx, "format", tt.format)
+
+		if got := OutputFormat(ctx); got != tt.want {
+			t.Errorf("OutputFormat(%q, %q) = %s, want %s", tt.output, tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestGroupFindings(t *testing.T) {
+	params := map[string]string{
+		"Secret Name: db | Namspace: team-a":                                      "team-a",
+		"binding name: reader | subject name: backup | scope: namespaced: team-b": "team-b",
+		"binding name: admin | subject kind: Group | namespace: all":              "",
+		"Secret data hash: 0123456789ab | Namespaces: 3":                          "",
+	}
+	for param, want := range params {
+		if got := namespaceOf(param); got != want {
+			t.Errorf("namespaceOf(%q) = %q, want %q", param, got, want)
+		}
+	}
+
+	findings := []*Finding{
+		{Namespace: "team-b", Severity: "low"},
+		{Namespace: "team-a", Severity: "medium"},
+		{Namespace: "team-a", Severity: "critical"},
+		{Severity: "high"},
+		{Namespace: "team-a", Severity: "medium"},
+	}
+
+	groups := GroupFindings(findings)
+	if len(groups) != 3 || groups[0].Namespace != "" || groups[1].Namespace != "team-a" {
+		t.Fatalf("GroupFindings() got %d groups", len(groups))
+	}
+
+	teamA := groups[1]
+	if teamA.Count != 3 || len(teamA.Severities) != 2 ||
+		teamA.Severities[0].Severity != "critical" || teamA.Severities[1].Count != 2 {
+		t.Errorf("GroupFindings() group of team-a = %+v", teamA)
+	}
+}
+
+func TestClustersToJson(t *testing.T) {
+	results := []*ClusterResult{
+		{Cluster: "prod", Host: "https://prod:6443"},
+		{Cluster: "staging", Host: "https://staging:6443", Err: errors.New("connection refused")},
+	}
+
+	data, err := ClustersToJson(context.Background(), results)
+	if err != nil {
+		t.Fatalf("ClustersToJson() error = %v", err)
+	}
+
+	out := struct {
+		Clusters []struct {
+			Cluster string
+			Error   string
+		}
+		Total struct {
+			Clusters int
+			Failed   int
+			Findings int
+		}
+	}{}
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatalf("invalid JSON, error = %v", err)
+	}
+
+	if len(out.Clusters) != 2 || out.Clusters[1].Error != "connection refused" ||
+		out.Total.Clusters != 2 || out.Total.Failed != 1 {
+		t.Errorf("ClustersToJson() = %s", data)
+	}
+
+	rp := &Report{Findings: []*Finding{{Cluster: "prod", Target: "cluster", Severity: "high"}}}
+
+	var buf bytes.Buffer
+	if err := rp.WriteCSV(&buf); err != nil {
+		t.Fatalf("WriteCSV() error = %v", err)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil || records[0][0] != "Cluster" || records[1][0] != "prod" {
+		t.Errorf("WriteCSV() of clusters = %v, %v", records, err)
+	}
+}
+
+func TestWriteQuiet(t *testing.T) {
+	findings := []*Finding{
+		{Target: "container: nginx", Severity: "critical", Param: "Privileged", Value: "true"},
+		{Target: "pod: web", Severity: "medium", Param: "capabilities", Value: "NET_ADMIN", Cluster: "prod"},
+		{Target: "pod: api", Severity: "high", Param: "hostPath", Value: "/etc", Cluster: "prod"},
+	}
+
+	var buf bytes.Buffer
+	count := writeQuiet(&buf, findings, "high")
+
+	want := "CRITICAL\tcontainer: nginx\tPrivileged\ttrue\n" +
+		"HIGH\tprod/pod: api\thostPath\t/etc\n"
+	if count != 2 || buf.String() != want {
+		t.Errorf("writeQuiet() = %d, %q, want 2, %q", count, buf.String(), want)
+	}
+
+	buf.Reset()
+	if count := writeQuiet(&buf, findings[1:2], "high"); count != 0 || buf.Len() != 0 {
+		t.Errorf("writeQuiet() = %d, %q, want nothing", count, buf.String())
+	}
+}
+
+func TestFormatCEF(t *testing.T) {
+	f := &Finding{
+		Target:      "pod: web | namespace: default",
+		Severity:    "high",
+		Type:        "Sidecar Token",
+		Param:       "sidecar name: web | volumeMounts",
+		Value:       "readOnly=false",
+		Describe:    "Service account token is mounted writable.",
+		Remediation: "Mount it read-only.",
+		Namespace:   "default",
+	}
+
+	want := "CEF:0|kvesta|vesta|" + config.Version + "|Sidecar Token|sidecar name: web \\| volumeMounts|8|" +
+		"cat=Sidecar Token msg=Service account token is mounted writable. " +
+		"cs1Label=Target cs1=pod: web | namespace: default " +
+		"cs2Label=Param cs2=sidecar name: web | volumeMounts " +
+		"cs3Label=Value cs3=readOnly\\=false " +
+		"cs4Label=Remediation cs4=Mount it read-only. " +
+		"cs5Label=Namespace cs5=default"
+
+	if got := FormatCEF(f); got != want {
+		t.Errorf("FormatCEF() = %q, want %q", got, want)
+	}
+}
+
+func TestSendSyslog(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("udp is not available: %v", err)
+	}
+	defer conn.Close()
+
+	rp := &Report{Findings: []*Finding{{Target: "container: nginx", Severity: "critical", Param: "Privileged", Value: "true"}}}
+	ctx := context.WithValue(context.Background(), "syslog", "udp://"+conn.LocalAddr().String())
+
+	if err := SendSyslog(ctx, rp); err != nil {
+		t.Fatalf("SendSyslog() error: %v", err)
+	}
+
+	buf := make([]byte, 4096)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("failed to receive the event: %v", err)
+	}
+
+	msg := string(buf[:n])
+	if !strings.HasPrefix(msg, "<10>1 ") || !strings.Contains(msg, "|Privileged|Privileged|10|") {
+		t.Errorf("SendSyslog() sent %q", msg)
+	}
+
+	if _, _, err := syslogAddress("http://collector:514"); err == nil {
+		t.Errorf("syslogAddress() expected the error of protocol")
+	}
+}
+
+func TestWriteSARIF(t *testing.T) {
+	rp := &Report{Findings: []*Finding{
+		{Target: "pod: default/web", Severity: "medium", Type: "Sidecar Privileged", Check: "checkPod",
+			Param: "sidecar name: web | privileged", Value: "true", Namespace: "default", Workload: "Pod/web"},
+		{Target: "Deployment: prod/api", Severity: "critical", Type: "Sidecar Privileged", Check: "checkPod",
+			Param: "sidecar name: api | privileged", Value: "true", Namespace: "prod", Workload: "Deployment/api"},
+		{Target: "container: nginx (0123abcd)", Severity: "low", Check: "checkRootUser",
+			Param: "User", Value: "root", ContainerID: "0123abcd"},
+	}}
+
+	var buf bytes.Buffer
+	if err := rp.WriteSARIF(&buf); err != nil {
+		t.Fatalf("WriteSARIF() error: %v", err)
+	}
+
+	var log sarifLog
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatalf("invalid SARIF: %v", err)
+	}
+
+	if log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Fatalf("got version %s with %d runs", log.Version, len(log.Runs))
+	}
+
+	run := log.Runs[0]
+	if len(run.Tool.Driver.Rules) != 2 || len(run.Results) != 3 {
+		t.Fatalf("got %d rules and %d results, want 2 and 3", len(run.Tool.Driver.Rules), len(run.Results))
+	}
+
+	rule := run.Tool.Driver.Rules[0]
+	if rule.ID != "checkPod/sidecar-name.privileged" {
+		t.Errorf("rule id = %s", rule.ID)
+	}
+	if rule.DefaultConfiguration.Level != "error" || rule.Properties.SecuritySeverity != "9.5" {
+		t.Errorf("rule is not raised to the highest severity: %s %s",
+			rule.DefaultConfiguration.Level, rule.Properties.SecuritySeverity)
+	}
+
+	wants := []struct {
+		ruleID, level, fqn string
+	}{
+		{"checkPod/sidecar-name.privileged", "warning", "default/Pod/web"},
+		{"checkPod/sidecar-name.privileged", "error", "prod/Deployment/api"},
+		{"checkRootUser/user", "note", "container/0123abcd"},
+	}
+	for i, want := range wants {
+		res := run.Results[i]
+		if res.RuleID != want.ruleID || res.Level != want.level {
+			t.Errorf("result %d = %s %s, want %s %s", i, res.RuleID, res.Level, want.ruleID, want.level)
+		}
+		if fqn := res.Locations[0].LogicalLocations[0].FullyQualifiedName; fqn != want.fqn {
+			t.Errorf("result %d location = %s, want %s", i, fqn, want.fqn)
+		}
+	}
+
+	if run.Results[0].PartialFingerprints["vestaFinding/v1"] == run.Results[1].PartialFingerprints["vestaFinding/v1"] {
+		t.Errorf("fingerprints of different workloads are the same")
+	}
+}
+
+func TestWriteJUnit(t *testing.T) {
+	rp := &Report{
+		Checks:  []string{"checkPrivileged", "checkPid"},
+		Timings: []*analyzer.CheckTiming{{Name: "checkPrivileged", Duration: 1500 * time.Millisecond}},
+		Findings: []*Finding{
+			{Target: "container: web", Severity: "critical", Check: "checkPrivileged", Param: "Privileged", Value: "true",
+				Describe: "Container is privileged."},
+			{Target: "container: db", Severity: "High", Check: "checkPrivileged", Param: "CapAdd", Value: "SYS_ADMIN"},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := rp.WriteJUnit(&buf); err != nil {
+		t.Fatalf("WriteJUnit() error: %v", err)
+	}
+
+	var suites junitTestSuites
+	if err := xml.Unmarshal(buf.Bytes(), &suites); err != nil {
+		t.Fatalf("invalid JUnit XML: %v", err)
+	}
+
+	if suites.Tests != 3 || suites.Failures != 2 || len(suites.Suites) != 2 {
+		t.Fatalf("got %d tests, %d failures in %d suites, want 3, 2 in 2", suites.Tests, suites.Failures, len(suites.Suites))
+	}
+
+	privileged := suites.Suites[0]
+	if privileged.Name != "checkPrivileged" || privileged.Time != "1.500" || privileged.Failures != 2 {
+		t.Errorf("suite = %s %s %d", privileged.Name, privileged.Time, privileged.Failures)
+	}
+	if f := privileged.Cases[1].Failure; f == nil || f.Type != "high" || f.Message != "CapAdd: SYS_ADMIN" {
+		t.Errorf("failure of finding = %+v", f)
+	}
+
+	pid := suites.Suites[1]
+	if pid.Failures != 0 || len(pid.Cases) != 1 || pid.Cases[0].Failure != nil {
+		t.Errorf("check without findings is not a passed test case: %+v", pid.Cases)
+	}
+}
+
+func TestCheckGate(t *testing.T) {
+	rp := &Report{Findings: []*Finding{{Severity: "critical"}, {Severity: "High"}, {Severity: "medium"}, {Severity: "warning"}}}
+
+	tests := []struct {
+		severity string
+		want     int
+	}{
+		{severity: "critical", want: 1},
+		{severity: "high", want: 2},
+		{severity: "warning", want: 4},
+	}
+
+	for _, tt := range tests {
+		g := &Gate{Severity: tt.severity, ExitCode: 2}
+		CheckGate(context.WithValue(context.Background(), "gate", g), rp)
+
+		if g.Found != tt.want {
+			t.Errorf("CheckGate(%s) found %d, want %d", tt.severity, g.Found, tt.want)
+		}
+	}
+
+	// No gate is set without the option `exit-code`
+	CheckGate(context.Background(), rp)
+}
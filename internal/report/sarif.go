@@ -0,0 +1,282 @@
+package report
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/kvesta/vesta/config"
+)
+
+const (
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifVersion = "2.1.0"
+)
+
+// sarifLevels map the severities of findings to the levels of SARIF results
+var sarifLevels = map[string]string{
+	"critical": "error",
+	"high":     "error",
+	"medium":   "warning",
+	"low":      "note",
+	"warning":  "note",
+}
+
+// securitySeverities map the severities of findings to the scores sorted by GitHub Code Scanning
+var securitySeverities = map[string]string{
+	"critical": "9.5",
+	"high":     "8.0",
+	"medium":   "5.5",
+	"low":      "3.0",
+	"warning":  "1.0",
+}
+
+var ruleSlugReg = regexp.MustCompile(`[^a-z0-9]+`)
+
+type sarifLog struct {
+	Schema  string      `json:"$schema"`
+	Version string      `json:"version"`
+	Runs    []*sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool      `json:"tool"`
+	Results []*sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string       `json:"name"`
+	Version        string       `json:"version"`
+	InformationURI string       `json:"informationUri"`
+	Rules          []*sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string             `json:"id"`
+	Name                 string             `json:"name,omitempty"`
+	ShortDescription     sarifMessage       `json:"shortDescription"`
+	FullDescription      *sarifMessage      `json:"fullDescription,omitempty"`
+	Help                 *sarifMessage      `json:"help,omitempty"`
+	HelpURI              string             `json:"helpUri,omitempty"`
+	DefaultConfiguration sarifConfiguration `json:"defaultConfiguration"`
+	Properties           sarifProperties    `json:"properties"`
+
+	// highest severity of the findings of the rule
+	severity string
+}
+
+type sarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+type sarifProperties struct {
+	SecuritySeverity string   `json:"security-severity"`
+	Tags             []string `json:"tags,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID              string            `json:"ruleId"`
+	RuleIndex           int               `json:"ruleIndex"`
+	Level               string            `json:"level"`
+	Message             sarifMessage      `json:"message"`
+	Locations           []*sarifLocation  `json:"locations"`
+	PartialFingerprints map[string]string `json:"partialFingerprints"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation   `json:"physicalLocation"`
+	LogicalLocations []*sarifLogicalLocation `json:"logicalLocations"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+type sarifLogicalLocation struct {
+	Name               string `json:"name"`
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+	Kind               string `json:"kind"`
+}
+
+// ruleID get the stable rule of the finding, it is the name of check and the keys of Param,
+// the values of Param such as the names of containers are left out, e.g. `checkPod/sidecar-name.privileged`
+func ruleID(f *Finding) string {
+	check := f.Check
+	if check == "" {
+		check = "vesta"
+	}
+
+	keys := []string{}
+	for _, segment := range strings.Split(f.Param, "|") {
+		key := strings.SplitN(segment, ":", 2)[0]
+		key = strings.Trim(ruleSlugReg.ReplaceAllString(strings.ToLower(key), "-"), "-")
+		if key != "" {
+			keys = append(keys, key)
+		}
+	}
+
+	if len(keys) < 1 {
+		return check
+	}
+
+	return fmt.Sprintf("%s/%s", check, strings.Join(keys, "."))
+}
+
+// sarifLocationOf get the location of the finding, the container ID for docker
+// and the namespace and workload for kubernetes, prefixed by the cluster in the multi-cluster scan
+func sarifLocationOf(f *Finding) *sarifLocation {
+	parts := []string{}
+	if f.Cluster != "" {
+		parts = append(parts, f.Cluster)
+	}
+
+	switch {
+	case f.ContainerID != "":
+		parts = append(parts, "container", f.ContainerID)
+	case f.Workload != "":
+		if f.Namespace != "" {
+			parts = append(parts, f.Namespace)
+		}
+		parts = append(parts, f.Workload)
+	case f.Namespace != "":
+		parts = append(parts, f.Namespace)
+	default:
+		parts = append(parts, f.Target)
+	}
+
+	segments := []string{}
+	for _, part := range strings.Split(strings.Join(parts, "/"), "/") {
+		segments = append(segments, url.PathEscape(part))
+	}
+
+	return &sarifLocation{
+		PhysicalLocation: sarifPhysicalLocation{
+			ArtifactLocation: sarifArtifactLocation{URI: strings.Join(segments, "/")},
+			Region:           sarifRegion{StartLine: 1},
+		},
+		LogicalLocations: []*sarifLogicalLocation{{
+			Name:               f.Target,
+			FullyQualifiedName: strings.Join(parts, "/"),
+			Kind:               "resource",
+		}},
+	}
+}
+
+// WriteSARIF write the findings as a SARIF 2.1.0 log, which is uploaded to GitHub Code Scanning.
+// A rule is added per ruleID with the highest severity of its findings
+func (rp *Report) WriteSARIF(w io.Writer) error {
+	driver := sarifDriver{
+		Name:           "vesta",
+		Version:        config.Version,
+		InformationURI: "https://github.com/kvesta/vesta",
+		Rules:          []*sarifRule{},
+	}
+
+	ruleIndex := map[string]int{}
+	results := []*sarifResult{}
+
+	for _, f := range rp.Findings {
+		severity := strings.ToLower(f.Severity)
+		if _, ok := sarifLevels[severity]; !ok {
+			severity = "warning"
+		}
+
+		id := ruleID(f)
+		index, ok := ruleIndex[id]
+		if !ok {
+			index = len(driver.Rules)
+			ruleIndex[id] = index
+			driver.Rules = append(driver.Rules, newSarifRule(id, f, severity))
+		}
+
+		rule := driver.Rules[index]
+		if config.SeverityMap[severity] > config.SeverityMap[rule.severity] {
+			rule.severity = severity
+			rule.DefaultConfiguration.Level = sarifLevels[severity]
+			rule.Properties.SecuritySeverity = securitySeverities[severity]
+		}
+
+		text := f.Describe
+		if text == "" {
+			text = fmt.Sprintf("%s: %s", f.Param, f.Value)
+		}
+
+		location := sarifLocationOf(f)
+		fingerprint := sha256.Sum256([]byte(strings.Join([]string{id,
+			location.LogicalLocations[0].FullyQualifiedName, f.Param, f.Value}, "\x00")))
+
+		results = append(results, &sarifResult{
+			RuleID:    id,
+			RuleIndex: index,
+			Level:     sarifLevels[severity],
+			Message: sarifMessage{Text: fmt.Sprintf("%s\n%s: %s | %s",
+				text, f.Target, f.Param, f.Value)},
+			Locations:           []*sarifLocation{location},
+			PartialFingerprints: map[string]string{"vestaFinding/v1": fmt.Sprintf("%x", fingerprint[:16])},
+		})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(&sarifLog{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs:    []*sarifRun{{Tool: sarifTool{Driver: driver}, Results: results}},
+	})
+}
+
+// newSarifRule build the rule by the first finding of the rule
+func newSarifRule(id string, f *Finding, severity string) *sarifRule {
+	name := f.Type
+	if name == "" {
+		name = f.Param
+	}
+
+	rule := &sarifRule{
+		ID:                   id,
+		Name:                 name,
+		ShortDescription:     sarifMessage{Text: name},
+		DefaultConfiguration: sarifConfiguration{Level: sarifLevels[severity]},
+		Properties: sarifProperties{
+			SecuritySeverity: securitySeverities[severity],
+			Tags:             append([]string{"security"}, f.CISControls...),
+		},
+		severity: severity,
+	}
+
+	if f.Describe != "" {
+		rule.FullDescription = &sarifMessage{Text: f.Describe}
+	}
+
+	if strings.HasPrefix(f.Reference, "http") {
+		rule.HelpURI = f.Reference
+	}
+
+	if f.Remediation != "" {
+		rule.Help = &sarifMessage{Text: f.Remediation}
+	}
+
+	return rule
+}
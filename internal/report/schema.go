@@ -0,0 +1,19 @@
+package report
+
+import "github.com/kvesta/vesta/config"
+
+// SchemaVersion is the version of the structure of the JSON outputs,
+// it is bumped on the breaking changes such as the removed, renamed or retyped fields,
+// the added fields are not breaking. The structure is described by docs/report.schema.json
+const SchemaVersion = 1
+
+// SchemaHeader is the top-level fields of the JSON outputs,
+// consumers check the schemaVersion before parsing the rest
+type SchemaHeader struct {
+	SchemaVersion int    `json:"schemaVersion"`
+	Version       string `json:"version"`
+}
+
+func NewSchemaHeader() SchemaHeader {
+	return SchemaHeader{SchemaVersion: SchemaVersion, Version: config.Version}
+}
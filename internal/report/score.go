@@ -0,0 +1,63 @@
+package report
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/kvesta/vesta/config"
+)
+
+// Score is the compliance score of a scan
+type Score struct {
+	Value       int
+	Weights     map[string]int
+	Methodology string
+}
+
+// NewScore computes a 0-100 score of the findings, each finding takes the penalty
+// weighted by its severity, the weights can be overridden by the option `scoreWeights`
+func NewScore(ctx context.Context, findings []*Finding) *Score {
+	weights := map[string]int{}
+	for k, v := range config.ScoreWeights {
+		weights[k] = v
+	}
+
+	if custom, ok := ctx.Value("scoreWeights").(map[string]int); ok {
+		for k, v := range custom {
+			weights[strings.ToLower(k)] = v
+		}
+	}
+
+	penalty := 0
+	for _, f := range findings {
+		penalty += weights[strings.ToLower(f.Severity)]
+	}
+
+	value := 100 - penalty
+	if value < 0 {
+		value = 0
+	}
+
+	severities := []string{}
+	for k := range weights {
+		severities = append(severities, k)
+	}
+
+	sort.Slice(severities, func(i, j int) bool {
+		return config.SeverityMap[severities[i]] > config.SeverityMap[severities[j]]
+	})
+
+	items := []string{}
+	for _, k := range severities {
+		items = append(items, fmt.Sprintf("%s=%d", k, weights[k]))
+	}
+
+	return &Score{
+		Value:   value,
+		Weights: weights,
+		Methodology: fmt.Sprintf("score = max(0, 100 - sum of the weights of findings by severity), "+
+			"weights: %s", strings.Join(items, ", ")),
+	}
+}
@@ -0,0 +1,78 @@
+package internal
+
+import (
+	"context"
+	"io"
+	"log"
+	"os"
+
+	"github.com/kvesta/vesta/config"
+	"github.com/kvesta/vesta/pkg/inspector"
+	"github.com/kvesta/vesta/pkg/osrelease"
+	"github.com/kvesta/vesta/pkg/packages"
+)
+
+// DoSBOM extract the image or container and save its packages as the SBOM,
+// the SBOM is written to the standard output if no output file is given
+func DoSBOM(ctx context.Context, target, tarFile string, tarIO []io.ReadCloser) {
+	log.Printf(config.Green("Begin to discover the packages"))
+
+	m, err := Extract(ctx, tarFile, tarIO)
+	for _, f := range tarIO {
+		f.Close()
+	}
+	if err != nil {
+		log.Printf("Extract failed, error: %v", err)
+		return
+	}
+
+	defer func() {
+		if pwd, _ := os.Getwd(); pwd == m.Localpath {
+			return
+		}
+
+		if err := os.RemoveAll(m.Localpath); err != nil {
+			log.Printf("failed to remove %s : %v", m.Localpath, err)
+		}
+	}()
+
+	osVersion, _ := osrelease.DetectOs(ctx, *m)
+	log.Printf("Detect OS: %s", osVersion.OID)
+
+	packs := &packages.Packages{Mani: *m, OsRelease: *osVersion}
+	err = packs.GetApp(ctx)
+	if err != nil {
+		log.Printf("package error %v", err)
+	}
+
+	format, _ := ctx.Value("format").(string)
+	bom, err := inspector.NewSBOM(format, packs, target)
+	if err != nil {
+		log.Printf("%v", err)
+		return
+	}
+
+	var w io.Writer = os.Stdout
+	outfile := ctx.Value("output").(string)
+	if outfile != "" && outfile != "-" {
+		f, err := os.Create(outfile)
+		if err != nil {
+			log.Printf("Can not create the output file, error: %v", err)
+			return
+		}
+		defer f.Close()
+		w = f
+	}
+
+	err = bom.Write(w)
+	if err != nil {
+		log.Printf("Saving error %v", err)
+		return
+	}
+
+	if format == "" {
+		format = inspector.SBOMFormats[0]
+	}
+
+	log.Printf("SBOM of %s is saved in %s", target, config.Yellow(format))
+}
@@ -0,0 +1,154 @@
+package internal
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kvesta/vesta/config"
+	"github.com/kvesta/vesta/internal/report"
+	"k8s.io/client-go/kubernetes"
+	restclient "k8s.io/client-go/rest"
+)
+
+// scanServer runs the analysis of kubernetes on demand,
+// only one scan runs at a time to protect the API server
+type scanServer struct {
+	ctx   context.Context
+	token string
+
+	clientset *kubernetes.Clientset
+	kconfig   *restclient.Config
+	busy      chan struct{}
+}
+
+// DoServe serve the analysis of kubernetes by HTTP
+func DoServe(ctx context.Context) {
+	token := ctx.Value("token").(string)
+	if token == "" {
+		log.Printf("Token is required for the scan endpoint, set it by --token or VESTA_TOKEN")
+		return
+	}
+
+	kconfig, err := loadKubeConfig(ctx)
+	if err != nil {
+		log.Printf("Can not initialize kubernetes environment, error: %v", err)
+		return
+	}
+
+	clientset, err := kubernetes.NewForConfig(kconfig)
+	if err != nil {
+		log.Printf("Can not get all kubernetes inpector, error: %v", err)
+		return
+	}
+
+	ss := &scanServer{
+		ctx:       ctx,
+		token:     token,
+		clientset: clientset,
+		kconfig:   kconfig,
+		busy:      make(chan struct{}, 1),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", ss.healthz)
+	mux.HandleFunc("/scan", ss.requireToken(ss.scan))
+
+	server := &http.Server{
+		Addr:              ctx.Value("listen").(string),
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	log.Printf(config.Green("Listening on %s"), server.Addr)
+	err = server.ListenAndServe()
+	if err != nil {
+		log.Printf("Server error: %v", err)
+	}
+}
+
+func (ss *scanServer) healthz(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Write([]byte("ok"))
+}
+
+func (ss *scanServer) scan(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Overlapping scans are rejected instead of queued
+	select {
+	case ss.busy <- struct{}{}:
+		defer func() { <-ss.busy }()
+	default:
+		w.Header().Set("Retry-After", "60")
+		http.Error(w, "scan is in progress", http.StatusTooManyRequests)
+		return
+	}
+
+	ctx := ss.ctx
+	if ns := r.URL.Query().Get("ns"); ns != "" {
+		ctx = context.WithValue(ctx, "nameSpace", ns)
+	}
+
+	inspects := &Inpsectors{}
+	scanner := inspects.Kscan
+	scanner.KClient = ss.clientset
+	scanner.KConfig = ss.kconfig
+	scanner.KConfigPath = kubeconfigPath(ss.ctx)
+
+	err := scanner.Kanalyze(ctx)
+	if err != nil {
+		log.Printf("Analyze error: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	data, err := report.KubernetesToJson(ctx, scanner)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// requireToken reject the requests without the bearer token of server
+func (ss *scanServer) requireToken(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !ss.authorized(r) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// authorized check the bearer token of the request,
+// the digests are compared in constant time so that neither the content nor the length of token leaks
+func (ss *scanServer) authorized(r *http.Request) bool {
+	auth := r.Header.Get("Authorization")
+
+	const prefix = "Bearer "
+	if len(auth) <= len(prefix) || !strings.EqualFold(auth[:len(prefix)], prefix) {
+		return false
+	}
+
+	got := sha256.Sum256([]byte(auth[len(prefix):]))
+	want := sha256.Sum256([]byte(ss.token))
+
+	return subtle.ConstantTimeCompare(got[:], want[:]) == 1
+}
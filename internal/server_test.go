@@ -0,0 +1,48 @@
+package internal
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequireToken(t *testing.T) {
+	ss := &scanServer{token: "s3cret"}
+	handler := ss.requireToken(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+
+	tests := []struct {
+		name          string
+		authorization string
+		want          int
+	}{
+		{name: "bearer", authorization: "Bearer s3cret", want: http.StatusOK},
+		{name: "case-insensitive scheme", authorization: "bearer s3cret", want: http.StatusOK},
+		{name: "no header", want: http.StatusUnauthorized},
+		{name: "bare token", authorization: "s3cret", want: http.StatusUnauthorized},
+		{name: "basic", authorization: "Basic s3cret", want: http.StatusUnauthorized},
+		{name: "wrong token", authorization: "Bearer s3cre", want: http.StatusUnauthorized},
+		{name: "empty token", authorization: "Bearer ", want: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/scan", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+
+			rec := httptest.NewRecorder()
+			handler(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("requireToken() status = %d, want %d", rec.Code, tt.want)
+			}
+
+			if tt.want == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") != "Bearer" {
+				t.Errorf("requireToken() missing the WWW-Authenticate header")
+			}
+		})
+	}
+}
@@ -2,20 +2,26 @@ package internal
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"io"
 	"log"
+	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 
 	"github.com/kvesta/vesta/config"
+	"github.com/kvesta/vesta/internal/analyzer"
 	"github.com/kvesta/vesta/internal/report"
 	"github.com/kvesta/vesta/pkg/inspector"
 	"github.com/kvesta/vesta/pkg/osrelease"
 	"github.com/kvesta/vesta/pkg/packages"
 	"github.com/kvesta/vesta/pkg/vulnlib"
 
+	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/client"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
@@ -24,7 +30,9 @@ import (
 	"k8s.io/client-go/util/homedir"
 )
 
-func DoScan(ctx context.Context, tarFile string, tarIO []io.ReadCloser) {
+// DoScan scan the vulnerabilities of the image or container,
+// the error is returned if the layers could not be extracted or scanned
+func DoScan(ctx context.Context, tarFile string, tarIO []io.ReadCloser) error {
 
 	var wg sync.WaitGroup
 
@@ -32,17 +40,17 @@ func DoScan(ctx context.Context, tarFile string, tarIO []io.ReadCloser) {
 	if !ctx.Value("skip").(bool) {
 		err := vulnlib.Fetch(ctx)
 		if err != nil {
-			log.Printf("failed to get vulnerability database")
+			config.ErrorLog.Printf("failed to get vulnerability database")
 		}
 	}
+	vulnlib.LogFreshness()
 
 	log.Printf(config.Green("Begin to analyze the layer"))
 	// Extract tar file to local folder
 	m, err := Extract(ctx, tarFile, tarIO)
 	if err != nil {
-		log.Printf("Extract container failed, error: %v\n"+
+		return fmt.Errorf("extract container failed, error: %v\n"+
 			"\tTips: try to use the container scan", err)
-		return
 	}
 
 	osVersion, err := osrelease.DetectOs(ctx, *m)
@@ -60,14 +68,15 @@ func DoScan(ctx context.Context, tarFile string, tarIO []io.ReadCloser) {
 	packs := vulns.Packs
 	err = packs.GetApp(ctx)
 	if err != nil {
-		log.Printf("package error %v", err)
+		config.ErrorLog.Printf("package error %v", err)
 	}
 
 	scanner := vulns.Scan
 
-	err = scanner.Scan(ctx, m, packs)
-	if err != nil {
-		log.Printf("scan error %v", err)
+	// The vulnerabilities found are still reported if the scan is not complete
+	scanErr := scanner.Scan(ctx, m, packs)
+	if scanErr != nil {
+		scanErr = fmt.Errorf("scan error %v", scanErr)
 	}
 
 	go func() {
@@ -83,7 +92,7 @@ func DoScan(ctx context.Context, tarFile string, tarIO []io.ReadCloser) {
 		// Check directory is legal
 		pwd, err := os.Getwd()
 		if err != nil {
-			log.Printf("failed to remove %s : %v", m.Localpath, err)
+			config.ErrorLog.Printf("failed to remove %s : %v", m.Localpath, err)
 		}
 		if pwd == m.Localpath {
 			return
@@ -91,139 +100,626 @@ func DoScan(ctx context.Context, tarFile string, tarIO []io.ReadCloser) {
 
 		err = os.RemoveAll(m.Localpath)
 		if err != nil {
-			log.Printf("failed to remove %s : %v", m.Localpath, err)
+			config.ErrorLog.Printf("failed to remove %s : %v", m.Localpath, err)
 		}
 	}()
 
 	err = report.ResolveAnalysisData(ctx, scanner)
 	if err != nil {
-		log.Printf("report error %v", err)
+		config.ErrorLog.Printf("report error %v", err)
 	}
 
-	err = report.ScanToJson(ctx, scanner)
+	if report.OutputFormat(ctx) == "html" {
+		err = report.ScanToHTML(ctx, fmt.Sprintf("Vulnerabilities of %s", ctx.Value("tarType")), scanner)
+	} else {
+		err = report.ScanToJson(ctx, scanner)
+	}
 	if err != nil {
-		log.Printf("saving error %v", err)
+		err = fmt.Errorf("saving error %v", err)
 	}
 
+	report.CheckScanGate(ctx, scanner)
+
 	wg.Wait()
+
+	if scanErr != nil {
+		return scanErr
+	}
+
+	return err
 }
 
-// DoInspectInDocker inspect docker configure
-func DoInspectInDocker(ctx context.Context) {
+// DoInspectInDocker inspect docker configure,
+// the error is returned if the analysis could not be completed or saved
+func DoInspectInDocker(ctx context.Context) error {
+
+	if ctx.Value("explain").(bool) {
+		err := report.ResolveExplain(ctx, analyzer.ExplainDocker(ctx))
+		if err != nil {
+			return fmt.Errorf("report error %v", err)
+		}
+		return nil
+	}
 
 	log.Printf(config.Green("Start analysing"))
 
-	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
-	if err != nil {
-		log.Printf("Can not initialized docker environment, error: %v", err)
-		return
+	if location := ctx.Value("inspect").(string); location != "" {
+		dockerInps, err := inspector.LoadContainers(location)
+		if err != nil {
+			return fmt.Errorf("can not load the inspect data, error: %v", err)
+		}
+
+		inspects := &Inpsectors{}
+		scanner := inspects.Scan
+		scanner.Offline = true
+		scanner.EngineVersion = ctx.Value("engineVersion").(string)
+		scanner.ServerVersion = ctx.Value("serverVersion").(string)
+		scanner.UsernsRemap = ctx.Value("usernsRemap").(bool)
+		scanner.Concurrency = ctx.Value("concurrency").(int)
+
+		return resolveDockerAnalysis(ctx, scanner, dockerInps, []*inspector.ImageInfo{})
+	}
+
+	// Compose file is analyzed statically, the checks of kernel and daemon are skipped
+	if location := ctx.Value("compose").(string); location != "" {
+		dockerInps, err := inspector.LoadCompose(location)
+		if err != nil {
+			return fmt.Errorf("can not load the compose file, error: %v", err)
+		}
+
+		inspects := &Inpsectors{}
+		scanner := inspects.Scan
+		scanner.Offline = true
+		scanner.Concurrency = ctx.Value("concurrency").(int)
+
+		return resolveDockerAnalysis(ctx, scanner, dockerInps, []*inspector.ImageInfo{})
 	}
 
-	c := inspector.DockerApi{
-		DCli: cli,
+	// Containers of CRI runtime are analyzed on the nodes without docker
+	if endpoint := ctx.Value("cri").(string); endpoint != "" {
+		return doInspectInCRI(ctx, endpoint)
+	}
+
+	// Podman serves the API compatible with docker
+	if socket, _ := ctx.Value("podman").(string); socket != "" {
+		return doInspectInPodman(ctx, socket)
+	}
+
+	c, err := dockerClient(ctx)
+	if err != nil {
+		return fmt.Errorf("can not initialized docker environment, error: %v", err)
 	}
 
 	defer c.DCli.Close()
 
-	dockerInps, err := c.GetAllContainers()
+	dockerInps, err := c.GetAllContainers(analyzer.CheckEnabled(ctx, "checkWritableLayer"))
 	if err != nil {
 		if strings.Contains(err.Error(), "Is the docker daemon running") {
-			log.Printf("Can not connect to docker service")
-			return
+			return fmt.Errorf("can not connect to docker service")
 		}
-		log.Printf("Can not get all docker inpector, error: %v", err)
-		return
+		return fmt.Errorf("can not get all docker inpector, error: %v", err)
 	}
 
 	dockerImages, err := c.GetAllImage()
 	if err != nil {
-		log.Printf("Can not get all docker images, error: %v", err)
+		config.ErrorLog.Printf("Can not get all docker images, error: %v", err)
 	}
 
 	engineVersion, err := c.GetEngineVersion(ctx)
 	if err != nil {
-		log.Printf("Can not get engine version, error: %v", err)
+		config.ErrorLog.Printf("Can not get engine version, error: %v", err)
 	}
 
 	serverVersion, err := c.GetDockerServerVersion(ctx)
 	if err != nil {
-		log.Printf("Can not get server version, error: %v", err)
+		config.ErrorLog.Printf("Can not get server version, error: %v", err)
+	}
+	usernsRemap, err := c.GetUsernsRemap(ctx)
+	if err != nil {
+		config.ErrorLog.Printf("Can not get userns-remap of daemon, error: %v", err)
 	}
 	inspects := &Inpsectors{}
 	scanner := inspects.Scan
 	scanner.EngineVersion = engineVersion
 	scanner.ServerVersion = serverVersion
-	err = scanner.Analyze(ctx, dockerInps, dockerImages)
+	scanner.UsernsRemap = usernsRemap
+	scanner.Daemon = "docker"
+	scanner.Concurrency = ctx.Value("concurrency").(int)
+
+	if host, _ := ctx.Value("host").(string); host != "" {
+		u, _ := url.Parse(host)
+		scanner.Remote = u.Hostname()
+
+		scanner.KernelVersion, err = c.GetKernelVersion(ctx)
+		if err != nil {
+			config.ErrorLog.Printf("Can not get kernel version of the remote host, error: %v", err)
+		}
+	}
+
+	return resolveDockerAnalysis(ctx, scanner, dockerInps, dockerImages)
+}
 
+// dockerClient connect to the docker daemon of the option `host`,
+// the local daemon configured by the environment of docker is used if no host is given
+func dockerClient(ctx context.Context) (*inspector.DockerApi, error) {
+	host, _ := ctx.Value("host").(string)
+	if host == "" {
+		cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+		if err != nil {
+			return nil, err
+		}
+
+		return &inspector.DockerApi{DCli: cli}, nil
+	}
+
+	opts := inspector.RemoteOptions{Host: host}
+	opts.TLSVerify, _ = ctx.Value("tlsverify").(bool)
+	opts.CertPath, _ = ctx.Value("certPath").(string)
+
+	if strings.HasPrefix(host, "tcp://") && !opts.TLSVerify {
+		log.Printf(config.Yellow(fmt.Sprintf("Connecting to %s without TLS, use --tlsverify for the daemon on port 2376", host)))
+	}
+
+	return inspector.NewRemoteDockerApi(opts)
+}
+
+// doInspectInPodman inspect the containers and images of Podman by its docker-compatible API,
+// the versions of Podman are not compared with the vulnerabilities of docker and containerd
+func doInspectInPodman(ctx context.Context, socket string) error {
+	c, err := inspector.NewPodmanApi(socket)
 	if err != nil {
-		log.Printf("Snalyze error %v", err)
-		return
+		return fmt.Errorf("can not initialized Podman client, error: %v", err)
 	}
+	defer c.DCli.Close()
 
-	err = report.ResolveDockerData(ctx, scanner)
+	dockerInps, err := c.GetAllContainers(analyzer.CheckEnabled(ctx, "checkWritableLayer"))
+	if err != nil {
+		return fmt.Errorf("can not get all Podman containers, error: %v", err)
+	}
+
+	dockerImages, err := c.GetAllImage()
+	if err != nil {
+		config.ErrorLog.Printf("Can not get all Podman images, error: %v", err)
+	}
+
+	rootless, err := c.GetRootless(ctx)
+	if err != nil {
+		config.ErrorLog.Printf("Can not get rootless mode of Podman, error: %v", err)
+	}
+	log.Printf("Connected to Podman %s, rootless: %t", c.DCli.DaemonHost(), rootless)
+
+	inspects := &Inpsectors{}
+	scanner := inspects.Scan
+	scanner.Daemon = "podman"
+	scanner.Rootless = rootless
+	scanner.Concurrency = ctx.Value("concurrency").(int)
+
+	return resolveDockerAnalysis(ctx, scanner, dockerInps, dockerImages)
+}
+
+// doInspectInCRI inspect the containers of CRI runtime, the checks of kernel and daemon are skipped
+func doInspectInCRI(ctx context.Context, endpoint string) error {
+	c, err := inspector.NewCRIApi(endpoint)
 	if err != nil {
-		log.Printf("Report error %v", err)
+		return fmt.Errorf("can not initialized CRI client, error: %v", err)
 	}
 
-	err = report.AnalyzeDockerToJson(ctx, scanner)
+	runtime, err := c.Version(ctx)
 	if err != nil {
-		log.Printf("Saving error %v", err)
+		return fmt.Errorf("can not connect to CRI %s, error: %v", c.Endpoint, err)
+	}
+
+	log.Printf("Connected to CRI runtime %s %s", runtime.Name, runtime.Version)
+
+	dockerInps, err := c.GetAllContainers(ctx)
+	if err != nil {
+		return fmt.Errorf("can not get all CRI containers, error: %v", err)
+	}
+
+	inspects := &Inpsectors{}
+	scanner := inspects.Scan
+	scanner.Offline = true
+	scanner.Concurrency = ctx.Value("concurrency").(int)
+
+	// Engine version is the version of containerd
+	if runtime.Name == "containerd" {
+		scanner.EngineVersion = strings.TrimPrefix(runtime.Version, "v")
 	}
 
+	return resolveDockerAnalysis(ctx, scanner, dockerInps, []*inspector.ImageInfo{})
 }
 
-// DoInspectInKubernetes inspect kubernetes' configure
-func DoInspectInKubernetes(ctx context.Context) {
+// resolveDockerAnalysis analyze the containers and images, then output the result
+func resolveDockerAnalysis(ctx context.Context, scanner analyzer.Scanner,
+	dockerInps []*types.ContainerJSON, dockerImages []*inspector.ImageInfo) error {
+
+	onFinding, closeStream, err := openFindingStream(ctx)
+	if err != nil {
+		return fmt.Errorf("can not open the stream of findings, error: %v", err)
+	}
+	defer closeStream()
+	scanner.OnFinding = onFinding
+
+	err = scanner.Analyze(ctx, dockerInps, dockerImages)
+	if err != nil {
+		return fmt.Errorf("analyze error %v", err)
+	}
+
+	err = report.ResolveDockerData(ctx, scanner)
+	if err != nil {
+		config.ErrorLog.Printf("Report error %v", err)
+	}
+
+	target := "docker"
+	if file := ctx.Value("inspect").(string); file != "" {
+		target = fmt.Sprintf("docker inspect: %s", file)
+	} else if file := ctx.Value("compose").(string); file != "" {
+		target = fmt.Sprintf("compose: %s", file)
+	} else if endpoint := ctx.Value("cri").(string); endpoint != "" {
+		target = fmt.Sprintf("cri: %s", endpoint)
+	}
+
+	return writeReport(ctx, report.NewDockerReport(ctx, scanner), "Docker analysis", target,
+		func() error { return report.AnalyzeDockerToJson(ctx, scanner) })
+}
+
+// DoInspectInKubernetes inspect kubernetes' configure,
+// the error is returned if the analysis could not be completed or saved
+func DoInspectInKubernetes(ctx context.Context) error {
+
+	if ctx.Value("explain").(bool) {
+		err := report.ResolveExplain(ctx, analyzer.ExplainKubernetes(ctx, ctx.Value("k8sVersion").(string)))
+		if err != nil {
+			return fmt.Errorf("report error %v", err)
+		}
+		return nil
+	}
 
 	log.Printf(config.Green("Start analysing"))
 
-	var kubeconfig string
-	var kconfig *restclient.Config
-	var err error
+	if paths := ctx.Value("manifests").([]string); len(paths) > 0 {
+		return doInspectManifests(ctx, paths)
+	}
 
-	if ctx.Value("kubeconfig") != "default" {
-		kubeconfig = ctx.Value("kubeconfig").(string)
-	} else if home := homedir.HomeDir(); home != "" {
-		kubeconfig = filepath.Join(home, ".kube", "config")
-	} else {
-		// use original config of kubernetes
-		kubeconfig = "/etc/kubernetes/config/admin.conf"
+	if all, _ := ctx.Value("allContexts").(bool); all && !ctx.Value("inside").(bool) {
+		contexts, err := allKubeContexts(kubeconfigPath(ctx))
+		if err != nil {
+			return fmt.Errorf("can not list the contexts of kubeconfig, error: %v", err)
+		}
+
+		if ctx.Value("kubeContext").(string) != "" {
+			log.Printf(config.Yellow("The option context is ignored for analyzing all the contexts"))
+		}
+
+		log.Printf("Analyzing %d clusters of the contexts: %s", len(contexts), strings.Join(contexts, ", "))
+		return doInspectClusters(ctx, contexts)
 	}
 
-	// use the current context in kubeconfig
-	if ctx.Value("inside").(bool) {
-		kconfig, err = rest.InClusterConfig()
-	} else {
-		kconfig, err = clientcmd.BuildConfigFromFlags("", kubeconfig)
+	if contexts := kubeContexts(ctx); len(contexts) > 1 {
+		return doInspectClusters(ctx, contexts)
 	}
 
+	kconfig, err := loadKubeConfig(ctx)
 	if err != nil {
-		log.Printf("Can not initialize kubernetes environment, error: %v", err)
-		return
+		return fmt.Errorf("can not initialize kubernetes environment, error: %v", err)
 	}
 
 	clientset, err := kubernetes.NewForConfig(kconfig)
 	if err != nil {
-		log.Printf("Can not get all kubernetes inpector, error: %v", err)
+		return fmt.Errorf("can not get all kubernetes inpector, error: %v", err)
 	}
-
 	inspects := &Inpsectors{}
 	scanner := inspects.Kscan
 	scanner.KClient = clientset
 	scanner.KConfig = kconfig
+	scanner.KConfigPath = kubeconfigPath(ctx)
+
+	onFinding, closeStream, err := openFindingStream(ctx)
+	if err != nil {
+		return fmt.Errorf("can not open the stream of findings, error: %v", err)
+	}
+	defer closeStream()
+	scanner.OnFinding = onFinding
+
 	err = scanner.Kanalyze(ctx)
 
 	if err != nil {
-		log.Printf("Analyze error")
+		return fmt.Errorf("analyze error: %v", err)
 	}
 
 	err = report.ResolveKuberData(ctx, scanner)
 	if err != nil {
-		log.Printf("Report error %v", err)
+		config.ErrorLog.Printf("Report error %v", err)
 	}
 
-	err = report.AnalyzeKubernetesToJson(ctx, scanner)
+	return writeReport(ctx, report.NewKuberReport(ctx, scanner), "Kubernetes analysis", fmt.Sprintf("kubernetes: %s", kconfig.Host),
+		func() error { return report.AnalyzeKubernetesToJson(ctx, scanner) })
+}
+
+// kubeContexts get the names of context from the comma-separated option `kubeContext`
+func kubeContexts(ctx context.Context) []string {
+	contexts := []string{}
+	if ctx.Value("inside").(bool) {
+		return contexts
+	}
+
+	for _, name := range strings.Split(ctx.Value("kubeContext").(string), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			contexts = append(contexts, name)
+		}
+	}
+
+	return contexts
+}
+
+// allKubeContexts list the names of all the contexts in the kubeconfig file
+func allKubeContexts(kubeconfig string) ([]string, error) {
+	rawConfig, err := clientcmd.LoadFromFile(kubeconfig)
 	if err != nil {
-		log.Printf("Saving error %v", err)
+		return nil, err
+	}
+
+	contexts := []string{}
+	for name := range rawConfig.Contexts {
+		contexts = append(contexts, name)
+	}
+	sort.Strings(contexts)
+
+	if len(contexts) < 1 {
+		return nil, fmt.Errorf("no context is found in %s", kubeconfig)
 	}
+
+	return contexts, nil
+}
+
+// doInspectClusters analyze the clusters of the contexts one by one and merge the results,
+// the cluster which could not be analyzed is reported without aborting the others
+// and the error is returned after the results are saved
+func doInspectClusters(ctx context.Context, contexts []string) error {
+	onFinding, closeStream, err := openFindingStream(ctx)
+	if err != nil {
+		return fmt.Errorf("can not open the stream of findings, error: %v", err)
+	}
+	defer closeStream()
+
+	results := []*report.ClusterResult{}
+
+	for _, name := range contexts {
+		res := &report.ClusterResult{Cluster: name}
+		results = append(results, res)
+
+		log.Printf(config.Yellow(fmt.Sprintf("Begin analyzing the cluster of context %s", name)))
+
+		cctx := context.WithValue(ctx, "kubeContext", name)
+		cctx = context.WithValue(cctx, "cluster", name)
+
+		kconfig, err := buildKubeConfig(kubeconfigPath(cctx), name)
+		if err != nil {
+			res.Err = err
+			config.ErrorLog.Printf("Can not initialize the cluster of context %s, error: %v", name, err)
+			continue
+		}
+		res.Host = kconfig.Host
+
+		clientset, err := kubernetes.NewForConfig(kconfig)
+		if err != nil {
+			res.Err = err
+			config.ErrorLog.Printf("Can not connect the cluster of context %s, error: %v", name, err)
+			continue
+		}
+
+		scanner := analyzer.KScanner{KClient: clientset, KConfig: kconfig, KConfigPath: kubeconfigPath(cctx)}
+		if onFinding != nil {
+			cluster := name
+			scanner.OnFinding = func(ev analyzer.FindingEvent) {
+				ev.Target = fmt.Sprintf("cluster: %s | %s", cluster, ev.Target)
+				onFinding(ev)
+			}
+		}
+
+		err = scanner.Kanalyze(cctx)
+		if err != nil {
+			res.Err = err
+			config.ErrorLog.Printf("Analyze error of context %s: %v", name, err)
+			continue
+		}
+		res.Scanner = scanner
+
+		// Findings of all the clusters are printed together by the summary in quiet mode
+		if _, quiet := report.QuietMode(ctx); !quiet {
+			fmt.Printf("\nCluster: %s (%s)\n", config.Yellow(name), kconfig.Host)
+			err = report.ResolveKuberData(cctx, scanner)
+			if err != nil {
+				config.ErrorLog.Printf("Report error %v", err)
+			}
+		}
+
+		err = report.SaveHistory(ctx, report.NewKuberReport(cctx, scanner), fmt.Sprintf("kubernetes: %s", kconfig.Host))
+		if err != nil {
+			config.ErrorLog.Printf("Saving history error %v", err)
+		}
+	}
+
+	report.ResolveClustersSummary(ctx, results)
+
+	// History is saved per cluster above
+	err = writeReport(ctx, report.NewClustersReport(ctx, results), fmt.Sprintf("Kubernetes analysis of %d clusters", len(results)), "",
+		func() error { return report.AnalyzeClustersToJson(ctx, results) })
+	if err != nil {
+		return err
+	}
+
+	failed := []string{}
+	for _, res := range results {
+		if res.Err != nil {
+			failed = append(failed, res.Cluster)
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d clusters could not be analyzed: %s", len(failed), len(results), strings.Join(failed, ", "))
+	}
+
+	return nil
+}
+
+// doInspectManifests analyze the manifests statically without a cluster
+func doInspectManifests(ctx context.Context, paths []string) error {
+	manifests, err := analyzer.LoadManifests(paths)
+	if err != nil {
+		return fmt.Errorf("can not load the manifests, error: %v", err)
+	}
+
+	inspects := &Inpsectors{}
+	scanner := inspects.Kscan
+
+	onFinding, closeStream, err := openFindingStream(ctx)
+	if err != nil {
+		return fmt.Errorf("can not open the stream of findings, error: %v", err)
+	}
+	defer closeStream()
+	scanner.OnFinding = onFinding
+
+	err = scanner.AnalyzeManifests(ctx, manifests)
+	if err != nil {
+		return fmt.Errorf("analyze error: %v", err)
+	}
+
+	err = report.ResolveKuberData(ctx, scanner)
+	if err != nil {
+		config.ErrorLog.Printf("Report error %v", err)
+	}
+
+	return writeReport(ctx, report.NewKuberReport(ctx, scanner), "Manifest analysis", fmt.Sprintf("manifests: %s", strings.Join(paths, ", ")),
+		func() error { return report.AnalyzeKubernetesToJson(ctx, scanner) })
+}
+
+// writeReport save the report in the output format, the default JSON output is saved by saveJSON,
+// then keep it in the history of target, send it to syslog and check the gate,
+// the error of saving the output is returned
+func writeReport(ctx context.Context, rp *report.Report, title, target string, saveJSON func() error) error {
+	var err error
+
+	switch report.OutputFormat(ctx) {
+	case "csv":
+		err = report.AnalyzeToCSV(ctx, rp)
+	case "sarif":
+		err = report.AnalyzeToSARIF(ctx, rp)
+	case "html":
+		err = report.AnalyzeToHTML(ctx, title, rp)
+	case "junit":
+		err = report.AnalyzeToJUnit(ctx, rp)
+	default:
+		err = saveJSON()
+	}
+
+	saveErr := err
+	if saveErr != nil {
+		saveErr = fmt.Errorf("saving error %v", saveErr)
+	}
+
+	if target != "" {
+		err = report.SaveHistory(ctx, rp, target)
+		if err != nil {
+			config.ErrorLog.Printf("Saving history error %v", err)
+		}
+	}
+
+	err = report.SendSyslog(ctx, rp)
+	if err != nil {
+		config.ErrorLog.Printf("Sending syslog error %v", err)
+	}
+
+	report.CheckGate(ctx, rp)
+
+	return saveErr
+}
+
+// openFindingStream open the file of option `stream` to write the findings as JSON lines
+// once they are discovered, `-` is for the standard output
+func openFindingStream(ctx context.Context) (func(analyzer.FindingEvent), func(), error) {
+	location, ok := ctx.Value("stream").(string)
+	if !ok || location == "" {
+		return nil, func() {}, nil
+	}
+
+	var w io.WriteCloser = os.Stdout
+	closeFn := func() {}
+	if location != "-" {
+		f, err := os.Create(location)
+		if err != nil {
+			return nil, nil, err
+		}
+		w = f
+		closeFn = func() { f.Close() }
+	}
+
+	enc := json.NewEncoder(w)
+	onFinding := func(ev analyzer.FindingEvent) {
+		line := struct {
+			report.SchemaHeader
+			analyzer.FindingEvent
+		}{report.NewSchemaHeader(), ev}
+
+		if err := enc.Encode(line); err != nil {
+			config.ErrorLog.Printf("failed to write the finding to stream: %v", err)
+		}
+	}
+
+	return onFinding, closeFn, nil
+}
+
+// loadKubeConfig load the config of kubernetes by the kubeconfig file,
+// or by the service account token when running inside a pod
+func loadKubeConfig(ctx context.Context) (*restclient.Config, error) {
+	// use the current context in kubeconfig
+	if ctx.Value("inside").(bool) {
+		return rest.InClusterConfig()
+	}
+
+	return buildKubeConfig(kubeconfigPath(ctx), ctx.Value("kubeContext").(string))
+}
+
+// kubeconfigPath get the location of the kubeconfig file,
+// it is empty when running inside a pod
+func kubeconfigPath(ctx context.Context) string {
+	if ctx.Value("inside").(bool) {
+		return ""
+	}
+
+	if ctx.Value("kubeconfig") != "default" {
+		return ctx.Value("kubeconfig").(string)
+	} else if home := homedir.HomeDir(); home != "" {
+		return filepath.Join(home, ".kube", "config")
+	}
+
+	// use original config of kubernetes
+	return "/etc/kubernetes/config/admin.conf"
+}
+
+// buildKubeConfig build the config of kubernetes from the kubeconfig file,
+// the current context is used if the name of context is empty
+func buildKubeConfig(kubeconfig, kubeContext string) (*restclient.Config, error) {
+	if kubeContext == "" {
+		return clientcmd.BuildConfigFromFlags("", kubeconfig)
+	}
+
+	rawConfig, err := clientcmd.LoadFromFile(kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := rawConfig.Contexts[kubeContext]; !ok {
+		contexts := []string{}
+		for name := range rawConfig.Contexts {
+			contexts = append(contexts, name)
+		}
+		sort.Strings(contexts)
+
+		return nil, fmt.Errorf("context %q is not found in %s, available contexts: %s",
+			kubeContext, kubeconfig, strings.Join(contexts, ", "))
+	}
+
+	return clientcmd.NewNonInteractiveClientConfig(*rawConfig, kubeContext,
+		&clientcmd.ConfigOverrides{}, nil).ClientConfig()
 }
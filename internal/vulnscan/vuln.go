@@ -8,10 +8,12 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/kvesta/vesta/config"
 	"github.com/kvesta/vesta/pkg/layer"
 	"github.com/kvesta/vesta/pkg/match"
+	"github.com/kvesta/vesta/pkg/osrelease"
 	"github.com/kvesta/vesta/pkg/packages"
 	"github.com/kvesta/vesta/pkg/vulnlib"
 
@@ -25,50 +27,87 @@ func (ps *Scanner) Scan(ctx context.Context, m *layer.Manifest, p *packages.Pack
 	err := ps.VulnDB.Init()
 
 	if err != nil {
-		log.Printf("failed to fetch database")
+		config.ErrorLog.Printf("failed to fetch database")
 		return err
 	}
 
 	defer ps.VulnDB.DB.Close()
 
+	ps.checkEOL(&p.OsRelease)
+
 	err = ps.checkPackageVersion(ctx, p.Packs, p.OsRelease.OID)
 	if err != nil {
-		log.Printf("failed to check package's version")
+		config.ErrorLog.Printf("failed to check package's version")
 	}
 
 	err = ps.checkPythonModule(ctx, p.PythonPacks, m)
 	if err != nil {
-		log.Printf("failed to check python module")
+		config.ErrorLog.Printf("failed to check python module")
 	}
 
 	err = ps.checkNpmModule(ctx, p.NodePacks)
 	if err != nil {
-		log.Printf("failed to check node module")
+		config.ErrorLog.Printf("failed to check node module")
 	}
 
 	err = ps.checkGoMod(ctx, p.GOPacks)
 	if err != nil {
-		log.Printf("failed to check go mod")
+		config.ErrorLog.Printf("failed to check go mod")
 	}
 
 	err = ps.checkJavaPacks(ctx, p.JavaPacks)
 	if err != nil {
-		log.Printf("failed to check go mod")
+		config.ErrorLog.Printf("failed to check go mod")
 	}
 
 	err = ps.checkPHPPacks(ctx, p.PHPPacks)
 	if err != nil {
-		log.Printf("failed to check php packs")
+		config.ErrorLog.Printf("failed to check php packs")
 	}
 
 	err = ps.checkRustPacks(ctx, p.RustPacks)
 	if err != nil {
-		log.Printf("failed to check rust packs")
+		config.ErrorLog.Printf("failed to check rust packs")
 	}
 
 	return err
 }
 
+// checkEOL check whether the distribution of image is end of life,
+// the severity scales with how long it is past the end of life
+func (ps *Scanner) checkEOL(osv *osrelease.OsVersion) {
+	eol, ok := osrelease.GetEOL(osv)
+	if !ok {
+		return
+	}
+
+	past := time.Since(eol)
+	if past <= 0 {
+		return
+	}
+
+	level := "medium"
+	switch {
+	case past > 3*365*24*time.Hour:
+		level = "critical"
+	case past > 365*24*time.Hour:
+		level = "high"
+	}
+
+	vuln := &vulnComponent{
+		Name:              osv.NAME,
+		CurrentVersion:    osv.VERSION_ID,
+		Type:              "System",
+		CVEID:             "EOL",
+		VulnerableVersion: "EOL: " + eol.Format("2006-01-02"),
+		Level:             level,
+		Desc: fmt.Sprintf("%s %s reached the end of life on %s, "+
+			"it does not receive the security patches any more.", osv.NAME, osv.VERSION_ID, eol.Format("2006-01-02")),
+	}
+
+	ps.Vulns = append(ps.Vulns, vuln)
+}
+
 func getInfo(row *vulnlib.DBRow, version, packType string) *vulnComponent {
 	vuln := &vulnComponent{}
 
@@ -5,9 +5,10 @@ import (
 	"errors"
 	"io"
 	"io/ioutil"
-	"log"
 	"os"
 	"path/filepath"
+
+	"github.com/kvesta/vesta/config"
 )
 
 func exists(path string) bool {
@@ -50,7 +51,7 @@ func Walk(tarReader *tar.Reader, path string) error {
 			}
 			_, err = io.Copy(file, tarReader)
 			if err != nil {
-				log.Printf("file %s can not extract: %v", hdr.Name, err)
+				config.ErrorLog.Printf("file %s can not extract: %v", hdr.Name, err)
 			}
 		default:
 			// ignore
@@ -82,7 +83,7 @@ func AnalyzeTarLayer(tarReader *tar.Reader, tempPath string) (string, error) {
 			}
 			_, err = io.Copy(file, tarReader)
 			if err != nil {
-				log.Printf("file %s can not extract: %v", hdr.Name, err)
+				config.ErrorLog.Printf("file %s can not extract: %v", hdr.Name, err)
 			}
 
 		}
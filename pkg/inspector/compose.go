@@ -0,0 +1,280 @@
+package inspector
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/go-connections/nat"
+	"github.com/docker/go-units"
+	"gopkg.in/yaml.v3"
+)
+
+// composeFile is the part of the compose file used by the analysis
+type composeFile struct {
+	Services map[string]composeService `yaml:"services"`
+}
+
+type composeService struct {
+	Image       string         `yaml:"image"`
+	User        string         `yaml:"user"`
+	GroupAdd    []string       `yaml:"group_add"`
+	Privileged  bool           `yaml:"privileged"`
+	CapAdd      []string       `yaml:"cap_add"`
+	CapDrop     []string       `yaml:"cap_drop"`
+	NetworkMode string         `yaml:"network_mode"`
+	Pid         string         `yaml:"pid"`
+	Ipc         string         `yaml:"ipc"`
+	ShmSize     string         `yaml:"shm_size"`
+	Tmpfs       composeTmpfs   `yaml:"tmpfs"`
+	UsernsMode  string         `yaml:"userns_mode"`
+	SecurityOpt []string       `yaml:"security_opt"`
+	Devices     []string       `yaml:"devices"`
+	Environment composeEnv     `yaml:"environment"`
+	Volumes     []composeMount `yaml:"volumes"`
+	Ports       []composePort  `yaml:"ports"`
+}
+
+// composeEnv is the environment in the form of list or map
+type composeEnv []string
+
+func (e *composeEnv) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.SequenceNode:
+		var envs []string
+		if err := node.Decode(&envs); err != nil {
+			return err
+		}
+		*e = envs
+	case yaml.MappingNode:
+		var envs map[string]*string
+		if err := node.Decode(&envs); err != nil {
+			return err
+		}
+
+		for k, v := range envs {
+			if v == nil {
+				*e = append(*e, k)
+				continue
+			}
+			*e = append(*e, fmt.Sprintf("%s=%s", k, *v))
+		}
+		sort.Strings(*e)
+	}
+
+	return nil
+}
+
+// composeTmpfs is the tmpfs in a single path or a list of `path[:options]`
+type composeTmpfs []string
+
+func (t *composeTmpfs) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*t = []string{node.Value}
+		return nil
+	}
+
+	var paths []string
+	if err := node.Decode(&paths); err != nil {
+		return err
+	}
+	*t = paths
+
+	return nil
+}
+
+// composeMount is the volume in the short syntax `source:target:mode` or the long syntax
+type composeMount struct {
+	Type     string `yaml:"type"`
+	Source   string `yaml:"source"`
+	Target   string `yaml:"target"`
+	ReadOnly bool   `yaml:"read_only"`
+}
+
+func (m *composeMount) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.ScalarNode {
+		type plain composeMount
+		return node.Decode((*plain)(m))
+	}
+
+	parts := strings.Split(node.Value, ":")
+	switch len(parts) {
+	case 1:
+		m.Type, m.Target = "volume", parts[0]
+	default:
+		m.Source, m.Target = parts[0], parts[1]
+		if len(parts) > 2 {
+			m.ReadOnly = strings.Contains(parts[2], "ro")
+		}
+
+		m.Type = "volume"
+		if strings.HasPrefix(m.Source, "/") || strings.HasPrefix(m.Source, ".") || strings.HasPrefix(m.Source, "~") {
+			m.Type = "bind"
+		}
+	}
+
+	return nil
+}
+
+// composePort is the port in the short syntax `[host_ip:]published:target[/protocol]` or the long syntax
+type composePort struct {
+	spec string
+}
+
+func (p *composePort) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		p.spec = node.Value
+		return nil
+	}
+
+	var long struct {
+		Target    int    `yaml:"target"`
+		Published string `yaml:"published"`
+		HostIP    string `yaml:"host_ip"`
+		Protocol  string `yaml:"protocol"`
+	}
+	if err := node.Decode(&long); err != nil {
+		return err
+	}
+
+	p.spec = fmt.Sprintf("%d", long.Target)
+	if long.Published != "" {
+		p.spec = fmt.Sprintf("%s:%s", long.Published, p.spec)
+		if long.HostIP != "" {
+			p.spec = fmt.Sprintf("%s:%s", long.HostIP, p.spec)
+		}
+	}
+
+	if long.Protocol != "" {
+		p.spec += "/" + long.Protocol
+	}
+
+	return nil
+}
+
+// LoadCompose read the services of the compose file as the containers,
+// the services are sorted by name
+func LoadCompose(path string) ([]*types.ContainerJSON, error) {
+	inps := []*types.ContainerJSON{}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return inps, err
+	}
+
+	compose := composeFile{}
+	err = yaml.Unmarshal(data, &compose)
+	if err != nil {
+		return inps, fmt.Errorf("failed to parse the compose file %s: %v", path, err)
+	}
+
+	names := []string{}
+	for name := range compose.Services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		inp, err := composeToContainer(name, compose.Services[name])
+		if err != nil {
+			return inps, fmt.Errorf("invalid service %s in %s: %v", name, path, err)
+		}
+
+		inps = append(inps, inp)
+	}
+
+	return inps, nil
+}
+
+// composeToContainer convert the service to the inspect data of container
+func composeToContainer(name string, svc composeService) (*types.ContainerJSON, error) {
+	hostConfig := &container.HostConfig{
+		Privileged:   svc.Privileged,
+		CapAdd:       svc.CapAdd,
+		CapDrop:      svc.CapDrop,
+		NetworkMode:  container.NetworkMode(svc.NetworkMode),
+		PidMode:      container.PidMode(svc.Pid),
+		IpcMode:      container.IpcMode(svc.Ipc),
+		UsernsMode:   container.UsernsMode(svc.UsernsMode),
+		SecurityOpt:  svc.SecurityOpt,
+		GroupAdd:     svc.GroupAdd,
+		PortBindings: nat.PortMap{},
+	}
+
+	if svc.ShmSize != "" {
+		size, err := units.RAMInBytes(svc.ShmSize)
+		if err != nil {
+			return nil, fmt.Errorf("invalid shm_size: %v", err)
+		}
+		hostConfig.ShmSize = size
+	}
+
+	for _, t := range svc.Tmpfs {
+		if hostConfig.Tmpfs == nil {
+			hostConfig.Tmpfs = map[string]string{}
+		}
+
+		parts := strings.SplitN(t, ":", 2)
+		options := ""
+		if len(parts) > 1 {
+			options = parts[1]
+		}
+		hostConfig.Tmpfs[parts[0]] = options
+	}
+
+	for _, d := range svc.Devices {
+		parts := strings.Split(d, ":")
+		device := container.DeviceMapping{PathOnHost: parts[0], PathInContainer: parts[0], CgroupPermissions: "rwm"}
+		if len(parts) > 1 {
+			device.PathInContainer = parts[1]
+		}
+		if len(parts) > 2 {
+			device.CgroupPermissions = parts[2]
+		}
+
+		hostConfig.Devices = append(hostConfig.Devices, device)
+	}
+
+	for _, p := range svc.Ports {
+		mappings, err := nat.ParsePortSpec(p.spec)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, m := range mappings {
+			hostConfig.PortBindings[m.Port] = append(hostConfig.PortBindings[m.Port], m.Binding)
+		}
+	}
+
+	mounts := []types.MountPoint{}
+	for _, v := range svc.Volumes {
+		mounts = append(mounts, types.MountPoint{
+			Type:        mount.Type(v.Type),
+			Source:      v.Source,
+			Destination: v.Target,
+			RW:          !v.ReadOnly,
+		})
+	}
+
+	id := fmt.Sprintf("%x", sha256.Sum256([]byte(name)))
+
+	return &types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{
+			ID:         id,
+			Name:       "/" + name,
+			Image:      svc.Image,
+			HostConfig: hostConfig,
+		},
+		Mounts: mounts,
+		Config: &container.Config{
+			Image: svc.Image,
+			User:  svc.User,
+			Env:   svc.Environment,
+		},
+	}, nil
+}
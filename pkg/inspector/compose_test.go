@@ -0,0 +1,80 @@
+package inspector
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadCompose(t *testing.T) {
+	compose := `
+services:
+  web:
+    image: nginx:1.23
+    privileged: true
+    cap_add: [SYS_ADMIN]
+    ports:
+      - "8080:80"
+      - target: 6379
+        published: "6379"
+        host_ip: 0.0.0.0
+    volumes:
+      - /:/host:ro
+      - data:/var/lib/data
+      - type: bind
+        source: /var/run/docker.sock
+        target: /var/run/docker.sock
+    environment:
+      DB_PASSWORD: secret
+      DEBUG:
+  db:
+    image: postgres
+    pid: host
+    shm_size: 2gb
+    tmpfs:
+      - /run
+      - /tmp:size=64m
+    environment:
+      - POSTGRES_PASSWORD=secret
+`
+	path := filepath.Join(t.TempDir(), "docker-compose.yml")
+	if err := os.WriteFile(path, []byte(compose), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	inps, err := LoadCompose(path)
+	if err != nil {
+		t.Fatalf("LoadCompose() error = %v", err)
+	}
+
+	if len(inps) != 2 || inps[0].Name != "/db" || inps[1].Name != "/web" {
+		t.Fatalf("LoadCompose() loaded %d services", len(inps))
+	}
+
+	db, web := inps[0], inps[1]
+	if db.HostConfig.PidMode != "host" || !reflect.DeepEqual(db.Config.Env, []string{"POSTGRES_PASSWORD=secret"}) {
+		t.Errorf("unexpected service db: %+v", db.HostConfig)
+	}
+
+	if db.HostConfig.ShmSize != 2<<30 || !reflect.DeepEqual(db.HostConfig.Tmpfs, map[string]string{"/run": "", "/tmp": "size=64m"}) {
+		t.Errorf("shm and tmpfs of db = %d, %v", db.HostConfig.ShmSize, db.HostConfig.Tmpfs)
+	}
+
+	if !web.HostConfig.Privileged || len(web.HostConfig.CapAdd) != 1 || len(web.ID) < 12 {
+		t.Errorf("unexpected service web: %+v", web.HostConfig)
+	}
+
+	if !reflect.DeepEqual(web.Config.Env, []string{"DB_PASSWORD=secret", "DEBUG"}) {
+		t.Errorf("environment of web = %v", web.Config.Env)
+	}
+
+	if len(web.Mounts) != 3 || web.Mounts[0].Source != "/" || web.Mounts[0].RW ||
+		web.Mounts[1].Type != "volume" || web.Mounts[2].Type != "bind" {
+		t.Errorf("volumes of web = %+v", web.Mounts)
+	}
+
+	if b := web.HostConfig.PortBindings["6379/tcp"]; len(b) != 1 || b[0].HostIP != "0.0.0.0" || b[0].HostPort != "6379" {
+		t.Errorf("ports of web = %+v", web.HostConfig.PortBindings)
+	}
+}
@@ -2,7 +2,10 @@ package inspector
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"io"
+	"io/ioutil"
 	"log"
 	"strings"
 
@@ -60,7 +63,8 @@ func (da *DockerApi) GetContainerName(containerID string) ([]io.ReadCloser, erro
 	return containerIo, err
 }
 
-func (da DockerApi) GetAllContainers() ([]*types.ContainerJSON, error) {
+// GetAllContainers inspect the containers, the size of the writable layer is got if size is true
+func (da DockerApi) GetAllContainers(size bool) ([]*types.ContainerJSON, error) {
 	inps := []*types.ContainerJSON{}
 	containers, err := da.DCli.ContainerList(ctx, types.ContainerListOptions{})
 	if err != nil {
@@ -71,9 +75,10 @@ func (da DockerApi) GetAllContainers() ([]*types.ContainerJSON, error) {
 		if strings.Contains(c.Names[0], "k8s") {
 			continue
 		}
-		ins, err := da.DCli.ContainerInspect(ctx, c.ID[:12])
+		// size of the writable layer is calculated by the daemon only if asked, which is slow for many containers
+		ins, _, err := da.DCli.ContainerInspectWithRaw(ctx, c.ID[:12], size)
 		if err != nil {
-			log.Printf("%s can not inpsect, error: %v", c.Names, err)
+			config.ErrorLog.Printf("%s can not inpsect, error: %v", c.Names, err)
 		}
 		inps = append(inps, &ins)
 	}
@@ -114,3 +119,43 @@ func (da DockerApi) GetDockerServerVersion(ctx context.Context) (string, error)
 
 	return version, nil
 }
+
+// GetUsernsRemap check whether the docker daemon is run with `userns-remap`
+func (da DockerApi) GetUsernsRemap(ctx context.Context) (bool, error) {
+	info, err := da.DCli.Info(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	for _, opt := range info.SecurityOptions {
+		if strings.Contains(opt, "name=userns") {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// LoadContainers read the containers from the output of `docker inspect`
+func LoadContainers(path string) ([]*types.ContainerJSON, error) {
+	inps := []*types.ContainerJSON{}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return inps, err
+	}
+
+	err = json.Unmarshal(data, &inps)
+	if err != nil {
+		return inps, fmt.Errorf("failed to parse the inspect data of %s: %v", path, err)
+	}
+
+	for _, ins := range inps {
+		if ins.ContainerJSONBase == nil || ins.Config == nil || ins.HostConfig == nil ||
+			len(ins.ID) < 12 || ins.Name == "" {
+			return inps, fmt.Errorf("incomplete inspect data of container in %s", path)
+		}
+	}
+
+	return inps, nil
+}
@@ -0,0 +1,598 @@
+package inspector
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/go-connections/nat"
+	"github.com/kvesta/vesta/config"
+	"golang.org/x/net/http2"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// DefaultCRIEndpoints are the sockets of containerd, CRI-O and cri-dockerd in the order of detection
+var DefaultCRIEndpoints = []string{
+	"unix:///run/containerd/containerd.sock",
+	"unix:///var/run/crio/crio.sock",
+	"unix:///run/cri-dockerd.sock",
+}
+
+// criDefaultCaps are the capabilities granted by containerd and CRI-O by default,
+// which are the same as docker
+var criDefaultCaps = []string{
+	"CHOWN", "DAC_OVERRIDE", "FSETID", "FOWNER", "MKNOD", "NET_RAW", "SETGID",
+	"SETUID", "SETFCAP", "SETPCAP", "NET_BIND_SERVICE", "SYS_CHROOT", "KILL", "AUDIT_WRITE",
+}
+
+// CRIApi is the client of the runtime service of CRI on the unix socket.
+// Only a few unary calls of runtime.v1 are needed, they are framed over HTTP/2 and
+// encoded by protowire instead of depending on grpc and the generated client of k8s.io/cri-api,
+// whose versions are tied to the releases of kubernetes and conflict with client-go v0.22
+type CRIApi struct {
+	Endpoint string
+
+	cli *http.Client
+}
+
+// CRIRuntime is the runtime serving the CRI socket
+type CRIRuntime struct {
+	Name       string
+	Version    string
+	APIVersion string
+}
+
+// NewCRIApi connect to the CRI endpoint, `default` detects the socket of
+// `CONTAINER_RUNTIME_ENDPOINT` or the known runtimes
+func NewCRIApi(endpoint string) (*CRIApi, error) {
+	if endpoint == "" || endpoint == "default" {
+		endpoint = os.Getenv("CONTAINER_RUNTIME_ENDPOINT")
+	}
+
+	if endpoint == "" {
+		for _, ep := range DefaultCRIEndpoints {
+			if _, err := os.Stat(strings.TrimPrefix(ep, "unix://")); err == nil {
+				endpoint = ep
+				break
+			}
+		}
+	}
+
+	if endpoint == "" {
+		return nil, fmt.Errorf("no CRI socket is found in %s", strings.Join(DefaultCRIEndpoints, ", "))
+	}
+
+	if strings.Contains(endpoint, "://") && !strings.HasPrefix(endpoint, "unix://") {
+		return nil, fmt.Errorf("unsupported CRI endpoint %s, only unix socket is supported", endpoint)
+	}
+
+	path := strings.TrimPrefix(endpoint, "unix://")
+
+	// gRPC is HTTP/2 without TLS on the socket
+	transport := &http2.Transport{
+		AllowHTTP: true,
+		DialTLS: func(network, addr string, cfg *tls.Config) (net.Conn, error) {
+			return net.DialTimeout("unix", path, 5*time.Second)
+		},
+	}
+
+	return &CRIApi{
+		Endpoint: endpoint,
+		cli:      &http.Client{Transport: transport, Timeout: 30 * time.Second},
+	}, nil
+}
+
+// invoke call the unary method of the runtime service with the encoded request
+func (c *CRIApi) invoke(ctx context.Context, method string, req []byte) ([]byte, error) {
+	body := make([]byte, 5+len(req))
+	binary.BigEndian.PutUint32(body[1:5], uint32(len(req)))
+	copy(body[5:], req)
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		"http://localhost/runtime.v1.RuntimeService/"+method, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/grpc")
+	httpReq.Header.Set("TE", "trailers")
+
+	resp, err := c.cli.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	// The status is in the headers if the response has no message
+	status, message := resp.Trailer.Get("Grpc-Status"), resp.Trailer.Get("Grpc-Message")
+	if status == "" {
+		status, message = resp.Header.Get("Grpc-Status"), resp.Header.Get("Grpc-Message")
+	}
+
+	if status != "" && status != "0" {
+		return nil, fmt.Errorf("%s failed, code: %s, message: %s", method, status, message)
+	}
+
+	if len(data) < 5 {
+		return nil, fmt.Errorf("%s failed, empty response", method)
+	}
+
+	if data[0] != 0 {
+		return nil, fmt.Errorf("%s failed, compressed response is not supported", method)
+	}
+
+	size := binary.BigEndian.Uint32(data[1:5])
+	if int(size) > len(data)-5 {
+		return nil, fmt.Errorf("%s failed, truncated response", method)
+	}
+
+	return data[5 : 5+size], nil
+}
+
+// Version get the name and the version of runtime
+func (c *CRIApi) Version(ctx context.Context) (*CRIRuntime, error) {
+	req := protowire.AppendTag(nil, 1, protowire.BytesType)
+	req = protowire.AppendString(req, "v1")
+
+	data, err := c.invoke(ctx, "Version", req)
+	if err != nil {
+		return nil, err
+	}
+
+	msg, err := parseProto(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CRIRuntime{
+		Name:       msg.str(2),
+		Version:    msg.str(3),
+		APIVersion: msg.str(4),
+	}, nil
+}
+
+// criContainer is the container listed by CRI
+type criContainer struct {
+	ID          string
+	PodSandbox  string
+	Name        string
+	Attempt     uint64
+	Image       string
+	Labels      map[string]string
+	Annotations map[string]string
+}
+
+// listContainers get the running containers
+func (c *CRIApi) listContainers(ctx context.Context) ([]*criContainer, error) {
+	// filter { state { state: CONTAINER_RUNNING } }
+	state := protowire.AppendTag(nil, 1, protowire.VarintType)
+	state = protowire.AppendVarint(state, 1)
+	filter := protowire.AppendTag(nil, 2, protowire.BytesType)
+	filter = protowire.AppendBytes(filter, state)
+	req := protowire.AppendTag(nil, 1, protowire.BytesType)
+	req = protowire.AppendBytes(req, filter)
+
+	data, err := c.invoke(ctx, "ListContainers", req)
+	if err != nil {
+		return nil, err
+	}
+
+	msg, err := parseProto(data)
+	if err != nil {
+		return nil, err
+	}
+
+	containers := []*criContainer{}
+	for _, m := range msg.msgs(1) {
+		containers = append(containers, &criContainer{
+			ID:          m.str(1),
+			PodSandbox:  m.str(2),
+			Name:        m.msg(3).str(1),
+			Attempt:     m.msg(3).uint(2),
+			Image:       m.msg(4).str(1),
+			Labels:      m.strMap(8),
+			Annotations: m.strMap(9),
+		})
+	}
+
+	return containers, nil
+}
+
+// containerStatus get the status and the verbose information of the container
+func (c *CRIApi) containerStatus(ctx context.Context, id string) (protoMessage, map[string]string, error) {
+	req := protowire.AppendTag(nil, 1, protowire.BytesType)
+	req = protowire.AppendString(req, id)
+	req = protowire.AppendTag(req, 2, protowire.VarintType)
+	req = protowire.AppendVarint(req, 1)
+
+	data, err := c.invoke(ctx, "ContainerStatus", req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	msg, err := parseProto(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return msg.msg(1), msg.strMap(2), nil
+}
+
+// GetAllContainers get the running containers of CRI in the format of docker inspect,
+// the containers are inspected by the verbose status and skipped if the status can not be parsed
+func (c *CRIApi) GetAllContainers(ctx context.Context) ([]*types.ContainerJSON, error) {
+	inps := []*types.ContainerJSON{}
+
+	containers, err := c.listContainers(ctx)
+	if err != nil {
+		return inps, err
+	}
+
+	for _, ct := range containers {
+		status, info, err := c.containerStatus(ctx, ct.ID)
+		if err != nil {
+			config.ErrorLog.Printf("%s can not get the status, error: %v", ct.Name, err)
+			continue
+		}
+
+		inp, err := criToContainer(ct, status, info)
+		if err != nil {
+			config.ErrorLog.Printf("%s can not parse the status, error: %v", ct.Name, err)
+			continue
+		}
+
+		inps = append(inps, inp)
+	}
+
+	return inps, nil
+}
+
+// criSpec is the part of OCI runtime spec in the verbose information of containerd and CRI-O
+type criSpec struct {
+	Process *struct {
+		User struct {
+			UID            uint32   `json:"uid"`
+			GID            uint32   `json:"gid"`
+			AdditionalGids []uint32 `json:"additionalGids"`
+		} `json:"user"`
+		Env          []string `json:"env"`
+		Capabilities *struct {
+			Bounding []string `json:"bounding"`
+		} `json:"capabilities"`
+		NoNewPrivileges bool   `json:"noNewPrivileges"`
+		ApparmorProfile string `json:"apparmorProfile"`
+	} `json:"process"`
+	Root *struct {
+		Readonly bool `json:"readonly"`
+	} `json:"root"`
+	Linux *struct {
+		Namespaces []struct {
+			Type string `json:"type"`
+		} `json:"namespaces"`
+		Devices []struct {
+			Path string `json:"path"`
+		} `json:"devices"`
+		Seccomp json.RawMessage `json:"seccomp"`
+	} `json:"linux"`
+}
+
+type criInfo struct {
+	RuntimeSpec *criSpec `json:"runtimeSpec"`
+
+	// CRI-O reports the privileged mode directly
+	Privileged bool `json:"privileged"`
+
+	// containerd reports the config of container
+	Config struct {
+		Linux struct {
+			SecurityContext struct {
+				Privileged bool `json:"privileged"`
+			} `json:"security_context"`
+		} `json:"linux"`
+	} `json:"config"`
+}
+
+// criPortsAnnotation is the annotation of the container ports set by kubelet on the containers of CRI
+const criPortsAnnotation = "io.kubernetes.container.ports"
+
+// criPropagations are the mount propagations of CRI in the order of the enum
+var criPropagations = []mount.Propagation{mount.PropagationRPrivate, mount.PropagationRSlave, mount.PropagationRShared}
+
+// criPortBindings get the host ports of the container from the annotation of kubelet,
+// the ports without host port are not published
+func criPortBindings(annotations map[string]string) nat.PortMap {
+	raw, ok := annotations[criPortsAnnotation]
+	if !ok {
+		return nil
+	}
+
+	ports := []struct {
+		HostPort      int32  `json:"hostPort"`
+		ContainerPort int32  `json:"containerPort"`
+		Protocol      string `json:"protocol"`
+		HostIP        string `json:"hostIP"`
+	}{}
+	if err := json.Unmarshal([]byte(raw), &ports); err != nil {
+		return nil
+	}
+
+	bindings := nat.PortMap{}
+	for _, p := range ports {
+		if p.HostPort < 1 {
+			continue
+		}
+
+		protocol := strings.ToLower(p.Protocol)
+		if protocol == "" {
+			protocol = "tcp"
+		}
+
+		port := nat.Port(fmt.Sprintf("%d/%s", p.ContainerPort, protocol))
+		bindings[port] = append(bindings[port], nat.PortBinding{HostIP: p.HostIP, HostPort: fmt.Sprintf("%d", p.HostPort)})
+	}
+
+	return bindings
+}
+
+// criToContainer convert the container of CRI to the inspect data of docker,
+// the fields of docker checks are translated as following:
+//   - privileged: the privileged mode of containerd config or CRI-O information
+//   - capabilities: the bounding set of runtime spec compared with the default set
+//   - mounts: the mounts of container status, which are the volumes of pod, with the propagation
+//   - port bindings: the host ports in the annotation of kubelet
+//   - read-only root filesystem: the root of runtime spec
+//   - pid, ipc and network mode: `host` if the namespace is absent in runtime spec
+//   - user and group: the user of runtime spec
+//   - no-new-privileges, seccomp and AppArmor: the process and linux of runtime spec
+//   - devices: the devices of runtime spec
+//   - restart count: the attempt of container metadata
+//
+// The image history and the shm size are not available in CRI
+func criToContainer(ct *criContainer, status protoMessage, info map[string]string) (*types.ContainerJSON, error) {
+	hostConfig := &container.HostConfig{PortBindings: criPortBindings(ct.Annotations)}
+
+	name := ct.Name
+	if pod, ok := ct.Labels["io.kubernetes.pod.name"]; ok {
+		name = fmt.Sprintf("%s_%s_%s", ct.Labels["io.kubernetes.pod.namespace"], pod, ct.Name)
+	}
+
+	inp := &types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{
+			ID:           ct.ID,
+			Name:         "/" + name,
+			Image:        ct.Image,
+			RestartCount: int(ct.Attempt),
+			State:        &types.ContainerState{Status: "running", Running: true},
+			HostConfig:   hostConfig,
+		},
+		Config: &container.Config{
+			Image:  ct.Image,
+			Labels: ct.Labels,
+		},
+		Mounts: []types.MountPoint{},
+	}
+
+	if status != nil {
+		inp.State.ExitCode = int(status.uint(7))
+
+		for _, m := range status.msgs(14) {
+			mp := types.MountPoint{
+				Type:        mount.TypeBind,
+				Source:      m.str(2),
+				Destination: m.str(1),
+				RW:          m.uint(3) == 0,
+			}
+			if p := m.uint(5); p < uint64(len(criPropagations)) {
+				mp.Propagation = criPropagations[p]
+			}
+
+			inp.Mounts = append(inp.Mounts, mp)
+		}
+	}
+
+	raw, ok := info["info"]
+	if !ok {
+		return inp, nil
+	}
+
+	ci := &criInfo{}
+	err := json.Unmarshal([]byte(raw), ci)
+	if err != nil {
+		return inp, err
+	}
+
+	hostConfig.Privileged = ci.Privileged || ci.Config.Linux.SecurityContext.Privileged
+
+	spec := ci.RuntimeSpec
+	if spec == nil {
+		return inp, nil
+	}
+
+	if p := spec.Process; p != nil {
+		inp.Config.User = fmt.Sprintf("%d:%d", p.User.UID, p.User.GID)
+		inp.Config.Env = p.Env
+
+		for _, gid := range p.User.AdditionalGids {
+			hostConfig.GroupAdd = append(hostConfig.GroupAdd, fmt.Sprintf("%d", gid))
+		}
+
+		if p.Capabilities != nil && !hostConfig.Privileged {
+			hostConfig.CapAdd, hostConfig.CapDrop = diffCaps(p.Capabilities.Bounding)
+		}
+
+		if p.NoNewPrivileges {
+			hostConfig.SecurityOpt = append(hostConfig.SecurityOpt, "no-new-privileges")
+		}
+
+		if p.ApparmorProfile == "unconfined" {
+			hostConfig.SecurityOpt = append(hostConfig.SecurityOpt, "apparmor=unconfined")
+		}
+	}
+
+	if spec.Root != nil {
+		hostConfig.ReadonlyRootfs = spec.Root.Readonly
+	}
+
+	if l := spec.Linux; l != nil {
+		namespaces := map[string]bool{}
+		for _, ns := range l.Namespaces {
+			namespaces[ns.Type] = true
+		}
+
+		if !namespaces["pid"] {
+			hostConfig.PidMode = "host"
+		}
+		if !namespaces["ipc"] {
+			hostConfig.IpcMode = "host"
+		}
+		if !namespaces["network"] {
+			hostConfig.NetworkMode = "host"
+		}
+
+		if len(l.Seccomp) < 1 || string(l.Seccomp) == "null" {
+			hostConfig.SecurityOpt = append(hostConfig.SecurityOpt, "seccomp=unconfined")
+		}
+
+		for _, d := range l.Devices {
+			hostConfig.Devices = append(hostConfig.Devices, container.DeviceMapping{
+				PathOnHost: d.Path, PathInContainer: d.Path, CgroupPermissions: "rwm"})
+		}
+	}
+
+	return inp, nil
+}
+
+// diffCaps get the capabilities added to and dropped from the default set
+func diffCaps(bounding []string) ([]string, []string) {
+	granted := map[string]bool{}
+	for _, c := range bounding {
+		granted[strings.TrimPrefix(strings.ToUpper(c), "CAP_")] = true
+	}
+
+	defaults := map[string]bool{}
+	drops := []string{}
+	for _, c := range criDefaultCaps {
+		defaults[c] = true
+		if !granted[c] {
+			drops = append(drops, c)
+		}
+	}
+
+	adds := []string{}
+	for c := range granted {
+		if !defaults[c] {
+			adds = append(adds, c)
+		}
+	}
+	sort.Strings(adds)
+
+	return adds, drops
+}
+
+// protoMessage is the fields of a decoded protobuf message by the field number,
+// only the varint and the length-delimited fields used by CRI are kept
+type protoMessage map[protowire.Number][]protoValue
+
+type protoValue struct {
+	varint uint64
+	bytes  []byte
+}
+
+func parseProto(b []byte) (protoMessage, error) {
+	msg := protoMessage{}
+
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return msg, protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		var v protoValue
+		switch typ {
+		case protowire.VarintType:
+			v.varint, n = protowire.ConsumeVarint(b)
+		case protowire.BytesType:
+			v.bytes, n = protowire.ConsumeBytes(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+
+		if n < 0 {
+			return msg, protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		msg[num] = append(msg[num], v)
+	}
+
+	return msg, nil
+}
+
+func (m protoMessage) str(num protowire.Number) string {
+	values := m[num]
+	if len(values) < 1 {
+		return ""
+	}
+
+	return string(values[len(values)-1].bytes)
+}
+
+func (m protoMessage) uint(num protowire.Number) uint64 {
+	values := m[num]
+	if len(values) < 1 {
+		return 0
+	}
+
+	return values[len(values)-1].varint
+}
+
+func (m protoMessage) msg(num protowire.Number) protoMessage {
+	values := m[num]
+	if len(values) < 1 {
+		return protoMessage{}
+	}
+
+	msg, _ := parseProto(values[len(values)-1].bytes)
+
+	return msg
+}
+
+func (m protoMessage) msgs(num protowire.Number) []protoMessage {
+	msgs := []protoMessage{}
+	for _, v := range m[num] {
+		msg, err := parseProto(v.bytes)
+		if err == nil {
+			msgs = append(msgs, msg)
+		}
+	}
+
+	return msgs
+}
+
+// strMap get the map<string, string> which is encoded as the entries of key 1 and value 2
+func (m protoMessage) strMap(num protowire.Number) map[string]string {
+	entries := map[string]string{}
+	for _, e := range m.msgs(num) {
+		entries[e.str(1)] = e.str(2)
+	}
+
+	return entries
+}
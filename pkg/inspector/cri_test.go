@@ -0,0 +1,283 @@
+package inspector
+
+import (
+	"archive/tar"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/go-connections/nat"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func TestParseProto(t *testing.T) {
+	entry := protowire.AppendTag(nil, 1, protowire.BytesType)
+	entry = protowire.AppendString(entry, "io.kubernetes.pod.name")
+	entry = protowire.AppendTag(entry, 2, protowire.BytesType)
+	entry = protowire.AppendString(entry, "web")
+
+	meta := protowire.AppendTag(nil, 1, protowire.BytesType)
+	meta = protowire.AppendString(meta, "nginx")
+	meta = protowire.AppendTag(meta, 2, protowire.VarintType)
+	meta = protowire.AppendVarint(meta, 7)
+
+	b := protowire.AppendTag(nil, 1, protowire.BytesType)
+	b = protowire.AppendString(b, "abc123")
+	b = protowire.AppendTag(b, 3, protowire.BytesType)
+	b = protowire.AppendBytes(b, meta)
+	b = protowire.AppendTag(b, 7, protowire.VarintType)
+	b = protowire.AppendVarint(b, 1670000000)
+	b = protowire.AppendTag(b, 8, protowire.BytesType)
+	b = protowire.AppendBytes(b, entry)
+
+	msg, err := parseProto(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if msg.str(1) != "abc123" || msg.msg(3).str(1) != "nginx" || msg.msg(3).uint(2) != 7 {
+		t.Errorf("parseProto() = %v", msg)
+	}
+
+	if labels := msg.strMap(8); labels["io.kubernetes.pod.name"] != "web" {
+		t.Errorf("labels = %v", labels)
+	}
+}
+
+func TestCriToContainer(t *testing.T) {
+	ct := &criContainer{
+		ID:      "abc123",
+		Name:    "app",
+		Attempt: 3,
+		Image:   "nginx:1.23",
+		Labels:  map[string]string{"io.kubernetes.pod.name": "web", "io.kubernetes.pod.namespace": "default"},
+	}
+
+	mountMsg := protowire.AppendTag(nil, 1, protowire.BytesType)
+	mountMsg = protowire.AppendString(mountMsg, "/host")
+	mountMsg = protowire.AppendTag(mountMsg, 2, protowire.BytesType)
+	mountMsg = protowire.AppendString(mountMsg, "/")
+	mountMsg = protowire.AppendTag(mountMsg, 3, protowire.VarintType)
+	mountMsg = protowire.AppendVarint(mountMsg, 1)
+	status := protowire.AppendTag(nil, 14, protowire.BytesType)
+	status = protowire.AppendBytes(status, mountMsg)
+
+	statusMsg, err := parseProto(status)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info := map[string]string{"info": `{
+		"config": {"linux": {"security_context": {"privileged": false}}},
+		"runtimeSpec": {
+			"process": {
+				"user": {"uid": 0, "gid": 0},
+				"env": ["DB_PASSWORD=secret"],
+				"capabilities": {"bounding": ["CAP_CHOWN", "CAP_SYS_ADMIN", "CAP_SETUID", "CAP_SETGID"]}
+			},
+			"linux": {"namespaces": [{"type": "mount"}, {"type": "network", "path": "/var/run/netns/cni-1"}]}
+		}
+	}`}
+
+	inp, err := criToContainer(ct, statusMsg, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if inp.Name != "/default_web_app" || inp.RestartCount != 3 || inp.Config.User != "0:0" {
+		t.Errorf("unexpected container: %s %d %s", inp.Name, inp.RestartCount, inp.Config.User)
+	}
+
+	if !reflect.DeepEqual([]string(inp.HostConfig.CapAdd), []string{"SYS_ADMIN"}) || len(inp.HostConfig.CapDrop) != 11 {
+		t.Errorf("capabilities = %v, %v", inp.HostConfig.CapAdd, inp.HostConfig.CapDrop)
+	}
+
+	if inp.HostConfig.PidMode != "host" || inp.HostConfig.IpcMode != "host" || inp.HostConfig.NetworkMode == "host" {
+		t.Errorf("namespaces = %s %s %s", inp.HostConfig.PidMode, inp.HostConfig.IpcMode, inp.HostConfig.NetworkMode)
+	}
+
+	if len(inp.Mounts) != 1 || inp.Mounts[0].Source != "/" || inp.Mounts[0].RW {
+		t.Errorf("mounts = %+v", inp.Mounts)
+	}
+
+	if !reflect.DeepEqual(inp.HostConfig.SecurityOpt, []string{"seccomp=unconfined"}) {
+		t.Errorf("security options = %v", inp.HostConfig.SecurityOpt)
+	}
+}
+
+func TestCriToContainerCRIO(t *testing.T) {
+	ct := &criContainer{
+		ID:    "c0ffee123456",
+		Name:  "router",
+		Image: "quay.io/openshift/origin-haproxy-router:4.12",
+		Labels: map[string]string{
+			"io.kubernetes.pod.name": "router-default", "io.kubernetes.pod.namespace": "openshift-ingress"},
+		Annotations: map[string]string{criPortsAnnotation: `[
+			{"name": "http", "hostPort": 80, "containerPort": 80, "protocol": "TCP"},
+			{"name": "metrics", "containerPort": 1936, "protocol": "TCP"},
+			{"name": "redis", "hostPort": 6379, "containerPort": 6379, "hostIP": "127.0.0.1"}
+		]`},
+	}
+
+	mountMsg := protowire.AppendTag(nil, 1, protowire.BytesType)
+	mountMsg = protowire.AppendString(mountMsg, "/var/lib/kubelet")
+	mountMsg = protowire.AppendTag(mountMsg, 2, protowire.BytesType)
+	mountMsg = protowire.AppendString(mountMsg, "/var/lib/kubelet")
+	mountMsg = protowire.AppendTag(mountMsg, 5, protowire.VarintType)
+	mountMsg = protowire.AppendVarint(mountMsg, 2)
+	status := protowire.AppendTag(nil, 14, protowire.BytesType)
+	status = protowire.AppendBytes(status, mountMsg)
+
+	statusMsg, err := parseProto(status)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// CRI-O reports the privileged mode and the pid beside the runtime spec
+	info := map[string]string{"info": `{
+		"sandboxID": "5e5e5e",
+		"pid": 4242,
+		"privileged": true,
+		"runtimeSpec": {
+			"root": {"path": "/var/lib/containers/storage/overlay/abc/merged", "readonly": true},
+			"process": {"user": {"uid": 1000, "gid": 0}, "env": ["ROUTER_PASSWORD=secret"]},
+			"linux": {"namespaces": [{"type": "pid"}, {"type": "ipc"}, {"type": "mount"}],
+				"seccomp": {"defaultAction": "SCMP_ACT_ERRNO"}}
+		}
+	}`}
+
+	inp, err := criToContainer(ct, statusMsg, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !inp.HostConfig.Privileged || !inp.HostConfig.ReadonlyRootfs || inp.HostConfig.NetworkMode != "host" {
+		t.Errorf("host config = %+v", inp.HostConfig)
+	}
+
+	if len(inp.Mounts) != 1 || inp.Mounts[0].Propagation != mount.PropagationRShared || !inp.Mounts[0].RW {
+		t.Errorf("mounts = %+v", inp.Mounts)
+	}
+
+	want := nat.PortMap{
+		"80/tcp":   {{HostPort: "80"}},
+		"6379/tcp": {{HostIP: "127.0.0.1", HostPort: "6379"}},
+	}
+	if !reflect.DeepEqual(inp.HostConfig.PortBindings, want) {
+		t.Errorf("port bindings = %v, want %v", inp.HostConfig.PortBindings, want)
+	}
+
+	if !reflect.DeepEqual(inp.Config.Env, []string{"ROUTER_PASSWORD=secret"}) || inp.Config.User != "1000:0" {
+		t.Errorf("config = %+v", inp.Config)
+	}
+}
+
+func TestMatchCRIContainer(t *testing.T) {
+	containers := []*criContainer{
+		{ID: "4f1a2b3c4d5e", Name: "nginx", Labels: map[string]string{
+			"io.kubernetes.pod.name": "web", "io.kubernetes.pod.namespace": "default"}},
+		{ID: "4f1a9e8d7c6b", Name: "nginx", Labels: map[string]string{
+			"io.kubernetes.pod.name": "web", "io.kubernetes.pod.namespace": "staging"}},
+		{ID: "9a8b7c6d5e4f", Name: "redis"},
+	}
+
+	tests := []struct {
+		id      string
+		want    string
+		wantErr bool
+	}{
+		{id: "default_web_nginx", want: "4f1a2b3c4d5e"},
+		{id: "9a8b", want: "9a8b7c6d5e4f"},
+		{id: "redis", want: "9a8b7c6d5e4f"},
+		{id: "4f1a", wantErr: true},
+		{id: "nginx", wantErr: true},
+		{id: "mysql", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		ct, err := matchCRIContainer(containers, tt.id)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("matchCRIContainer(%s) = %s, want error", tt.id, ct.ID)
+			}
+			continue
+		}
+
+		if err != nil || ct.ID != tt.want {
+			t.Errorf("matchCRIContainer(%s) = %v, %v, want %s", tt.id, ct, err, tt.want)
+		}
+	}
+}
+
+func TestArchiveRootfs(t *testing.T) {
+	dir := t.TempDir()
+	rootfs := filepath.Join(dir, "rootfs")
+	for _, dir := range []string{"etc", "proc/1", "usr/lib", "host/etc"} {
+		if err := os.MkdirAll(filepath.Join(rootfs, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(rootfs, "etc/os-release"), []byte("ID=alpine\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(rootfs, "proc/1/status"), []byte("Name: sh\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(rootfs, "host/etc/shadow"), []byte("root:*:19000::::::\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("/usr/lib/os-release", filepath.Join(rootfs, "usr/lib/os-release")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Root of the process is a symbolic link as /proc/<pid>/root
+	root := filepath.Join(dir, "root")
+	if err := os.Symlink(rootfs, root); err != nil {
+		t.Fatal(err)
+	}
+
+	entries := map[string]string{}
+	tr := tar.NewReader(archiveRootfs(root, []string{"/host"}))
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("archiveRootfs() error: %v", err)
+		}
+
+		data, _ := ioutil.ReadAll(tr)
+		entries[header.Name] = string(data) + header.Linkname
+	}
+
+	want := map[string]string{
+		"etc/":               "",
+		"etc/os-release":     "ID=alpine\n",
+		"usr/":               "",
+		"usr/lib/":           "",
+		"usr/lib/os-release": "/usr/lib/os-release",
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("archiveRootfs() = %v, want %v", entries, want)
+	}
+}
+
+func TestMountPoints(t *testing.T) {
+	mountinfo := `1383 1234 0:118 / / rw,relatime master:361 - overlay overlay rw,lowerdir=/var/lib/l1
+1384 1383 0:121 / /proc rw,nosuid,nodev,noexec,relatime - proc proc rw
+1390 1383 253:1 / /host rw,relatime - ext4 /dev/vda1 rw
+1391 1383 253:1 /var/lib/kubelet/pods/x/volumes/data /var/my\040data rw,relatime - ext4 /dev/vda1 rw
+`
+
+	got := mountPoints(strings.NewReader(mountinfo))
+	want := []string{"/proc", "/host", "/var/my data"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("mountPoints() = %v, want %v", got, want)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
 	imagev1 "github.com/docker/docker/api/types/image"
 	"github.com/kvesta/vesta/config"
 )
@@ -49,6 +50,9 @@ func (da *DockerApi) GetImageName(imageID string) ([]io.ReadCloser, error) {
 type ImageInfo struct {
 	Summary types.ImageSummary
 	History []imagev1.HistoryResponseItem
+
+	// Config of the image, nil if the image could not be inspected
+	Config *container.Config
 }
 
 func (da *DockerApi) GetAllImage() ([]*ImageInfo, error) {
@@ -67,6 +71,10 @@ func (da *DockerApi) GetAllImage() ([]*ImageInfo, error) {
 			History: his,
 		}
 
+		if inspect, _, err := da.DCli.ImageInspectWithRaw(ctx, im.ID); err == nil {
+			image.Config = inspect.Config
+		}
+
 		images = append(images, image)
 	}
 
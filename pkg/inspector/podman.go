@@ -0,0 +1,71 @@
+package inspector
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/docker/docker/client"
+)
+
+// podmanSockets get the sockets of the API service of Podman in the order of detection,
+// the socket of rootless Podman in the runtime directory of user goes first
+func podmanSockets() []string {
+	runtimeDir := os.Getenv("XDG_RUNTIME_DIR")
+	if runtimeDir == "" {
+		runtimeDir = fmt.Sprintf("/run/user/%d", os.Getuid())
+	}
+
+	return []string{
+		filepath.Join(runtimeDir, "podman", "podman.sock"),
+		"/run/podman/podman.sock",
+	}
+}
+
+// NewPodmanApi connect to the docker-compatible API of Podman,
+// `default` detects the socket of rootless or rootful Podman
+func NewPodmanApi(socket string) (*DockerApi, error) {
+	if socket == "" || socket == "default" {
+		socket = ""
+		for _, s := range podmanSockets() {
+			if _, err := os.Stat(s); err == nil {
+				socket = s
+				break
+			}
+		}
+
+		if socket == "" {
+			return nil, fmt.Errorf("no Podman socket is found in %s, start it by `systemctl --user start podman.socket`",
+				strings.Join(podmanSockets(), ", "))
+		}
+	}
+
+	if !strings.Contains(socket, "://") {
+		socket = "unix://" + socket
+	}
+
+	cli, err := client.NewClientWithOpts(client.WithHost(socket), client.WithAPIVersionNegotiation())
+	if err != nil {
+		return nil, err
+	}
+
+	return &DockerApi{DCli: cli}, nil
+}
+
+// GetRootless check whether the daemon of docker or Podman is run by a non-root user
+func (da DockerApi) GetRootless(ctx context.Context) (bool, error) {
+	info, err := da.DCli.Info(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	for _, opt := range info.SecurityOptions {
+		if strings.Contains(opt, "name=rootless") {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
@@ -0,0 +1,46 @@
+package inspector
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewPodmanApi(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_RUNTIME_DIR", dir)
+
+	sockets := podmanSockets()
+	if sockets[0] != filepath.Join(dir, "podman", "podman.sock") {
+		t.Errorf("podmanSockets() got %v, the rootless socket goes first", sockets)
+	}
+
+	if _, err := os.Stat(sockets[1]); err != nil {
+		if _, err := NewPodmanApi("default"); err == nil {
+			t.Errorf("NewPodmanApi() found a socket not existing")
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(sockets[0]), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(sockets[0], []byte{}, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := NewPodmanApi("default")
+	if err != nil {
+		t.Fatalf("NewPodmanApi() error = %v", err)
+	}
+	defer c.DCli.Close()
+
+	if host := c.DCli.DaemonHost(); host != "unix://"+sockets[0] {
+		t.Errorf("DaemonHost() = %s, want the rootless socket", host)
+	}
+
+	c, err = NewPodmanApi("/run/podman/podman.sock")
+	if err != nil || !strings.HasPrefix(c.DCli.DaemonHost(), "unix:///run/podman") {
+		t.Errorf("NewPodmanApi() of the given socket got error: %v", err)
+	}
+}
@@ -0,0 +1,191 @@
+package inspector
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/client"
+)
+
+// RemoteOptions are the options of connecting to the remote docker daemon
+type RemoteOptions struct {
+	// tcp://host:2376 or ssh://user@host
+	Host string
+
+	// verify the daemon by the CA of CertPath and authenticate by the client certificate
+	TLSVerify bool
+
+	// directory of ca.pem, cert.pem and key.pem, $DOCKER_CERT_PATH or ~/.docker if empty
+	CertPath string
+}
+
+// certPath get the directory of the certificates in the same order as the docker CLI
+func (opts RemoteOptions) certPath() string {
+	if opts.CertPath != "" {
+		return opts.CertPath
+	}
+
+	if env := os.Getenv("DOCKER_CERT_PATH"); env != "" {
+		return env
+	}
+
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".docker")
+}
+
+// NewRemoteDockerApi connect to the docker daemon on the remote host,
+// the daemon of ssh:// is reached by `docker system dial-stdio` on the host
+func NewRemoteDockerApi(opts RemoteOptions) (*DockerApi, error) {
+	u, err := url.Parse(opts.Host)
+	if err != nil {
+		return nil, fmt.Errorf("invalid host %s: %v", opts.Host, err)
+	}
+
+	clientOpts := []client.Opt{client.WithAPIVersionNegotiation()}
+
+	switch u.Scheme {
+	case "ssh":
+		args, err := sshArgs(u)
+		if err != nil {
+			return nil, err
+		}
+
+		// Host of the requests is a placeholder, the connection is the stdio of ssh
+		clientOpts = append(clientOpts, client.WithHost("http://docker.example.com"),
+			client.WithDialContext(func(ctx context.Context, network, addr string) (net.Conn, error) {
+				return newCommandConn(ctx, "ssh", args...)
+			}))
+
+	case "tcp":
+		clientOpts = append(clientOpts, client.WithHost(opts.Host))
+
+		if opts.TLSVerify {
+			dir := opts.certPath()
+			clientOpts = append(clientOpts, client.WithTLSClientConfig(
+				filepath.Join(dir, "ca.pem"), filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")))
+		}
+
+	default:
+		return nil, fmt.Errorf("unsupported host %s, tcp://host:port or ssh://user@host is required", opts.Host)
+	}
+
+	cli, err := client.NewClientWithOpts(clientOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DockerApi{DCli: cli}, nil
+}
+
+// sshArgs build the arguments of ssh running `docker system dial-stdio` on the host,
+// the keys and known hosts are of the ssh configure of user
+func sshArgs(u *url.URL) ([]string, error) {
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("no host is given in %s", u.String())
+	}
+
+	if u.Path != "" && u.Path != "/" {
+		return nil, fmt.Errorf("path of the ssh host is not supported: %s", u.Path)
+	}
+
+	if _, ok := u.User.Password(); ok {
+		return nil, fmt.Errorf("password of the ssh host is not supported, use the ssh keys instead")
+	}
+
+	args := []string{}
+	if user := u.User.Username(); user != "" {
+		args = append(args, "-l", user)
+	}
+	if port := u.Port(); port != "" {
+		args = append(args, "-p", port)
+	}
+
+	return append(args, "--", u.Hostname(), "docker", "system", "dial-stdio"), nil
+}
+
+// commandConn is the connection over the stdin and stdout of a command
+type commandConn struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout io.ReadCloser
+	stderr bytes.Buffer
+}
+
+// newCommandConn start the command as the connection
+func newCommandConn(ctx context.Context, name string, args ...string) (net.Conn, error) {
+	c := &commandConn{cmd: exec.CommandContext(ctx, name, args...)}
+	c.cmd.Stderr = &c.stderr
+
+	var err error
+	c.stdin, err = c.cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	c.stdout, err = c.cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	if err = c.cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to run %s: %v", name, err)
+	}
+
+	return c, nil
+}
+
+func (c *commandConn) Read(p []byte) (int, error) {
+	n, err := c.stdout.Read(p)
+	if err == io.EOF && c.stderr.Len() > 0 {
+		// The errors of ssh and the remote command are only written on stderr
+		return n, fmt.Errorf("connection closed: %s", strings.TrimSpace(c.stderr.String()))
+	}
+
+	return n, err
+}
+
+func (c *commandConn) Write(p []byte) (int, error) {
+	return c.stdin.Write(p)
+}
+
+func (c *commandConn) Close() error {
+	c.stdin.Close()
+	if c.cmd.Process != nil {
+		c.cmd.Process.Kill()
+	}
+	c.cmd.Wait()
+
+	return nil
+}
+
+func (c *commandConn) LocalAddr() net.Addr {
+	return &net.UnixAddr{Name: "stdio", Net: "unix"}
+}
+
+func (c *commandConn) RemoteAddr() net.Addr {
+	return &net.UnixAddr{Name: "stdio", Net: "unix"}
+}
+
+// Deadlines are not supported by the pipes of command
+func (c *commandConn) SetDeadline(t time.Time) error      { return nil }
+func (c *commandConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *commandConn) SetWriteDeadline(t time.Time) error { return nil }
+
+// GetKernelVersion get the kernel version of the host running the daemon
+func (da DockerApi) GetKernelVersion(ctx context.Context) (string, error) {
+	info, err := da.DCli.Info(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return info.KernelVersion, nil
+}
@@ -0,0 +1,244 @@
+package inspector
+
+import (
+	"archive/tar"
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/kvesta/vesta/config"
+)
+
+// rootfsSkipped are the pseudo filesystems skipped when archiving the root filesystem of container
+var rootfsSkipped = []string{"proc", "sys", "dev"}
+
+// matchCRIContainer find the container by the ID, the prefix of ID, the name of container
+// or the name of `<namespace>_<pod>_<container>` for the containers of kubernetes
+func matchCRIContainer(containers []*criContainer, id string) (*criContainer, error) {
+	matched := []*criContainer{}
+	for _, ct := range containers {
+		name := ct.Name
+		if pod, ok := ct.Labels["io.kubernetes.pod.name"]; ok {
+			name = fmt.Sprintf("%s_%s_%s", ct.Labels["io.kubernetes.pod.namespace"], pod, ct.Name)
+		}
+
+		if ct.ID == id || name == id {
+			return ct, nil
+		}
+
+		if (len(id) >= 4 && strings.HasPrefix(ct.ID, id)) || ct.Name == id {
+			matched = append(matched, ct)
+		}
+	}
+
+	switch len(matched) {
+	case 0:
+		return nil, fmt.Errorf("no running container matches %s", id)
+	case 1:
+		return matched[0], nil
+	}
+
+	return nil, fmt.Errorf("%d running containers match %s, use the full ID or <namespace>_<pod>_<container>", len(matched), id)
+}
+
+// ContainerRootfs get the root filesystem of the running container on the host,
+// which is the root of its main process, and the mount points inside the container.
+// Root of the host is required for reading it
+func (c *CRIApi) ContainerRootfs(ctx context.Context, id string) (string, []string, error) {
+	containers, err := c.listContainers(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+
+	ct, err := matchCRIContainer(containers, id)
+	if err != nil {
+		return "", nil, err
+	}
+
+	_, info, err := c.containerStatus(ctx, ct.ID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var process struct {
+		Pid int `json:"pid"`
+	}
+	if err := json.Unmarshal([]byte(info["info"]), &process); err != nil || process.Pid < 1 {
+		return "", nil, fmt.Errorf("no process of container %s is found in the verbose status", ct.Name)
+	}
+
+	f, err := os.Open(fmt.Sprintf("/proc/%d/mountinfo", process.Pid))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read the mounts of container, root of the host is required: %v", err)
+	}
+	defer f.Close()
+
+	return fmt.Sprintf("/proc/%d/root", process.Pid), mountPoints(f), nil
+}
+
+// mountPoints get the mount points except the root from the mountinfo of process,
+// the volumes and the host paths are mounted on them
+func mountPoints(r io.Reader) []string {
+	mounts := []string{}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 5 {
+			continue
+		}
+
+		// Spaces and the special characters of mount point are escaped as octal, e.g. \040
+		point := fields[4]
+		if unquoted, err := strconv.Unquote(`"` + point + `"`); err == nil {
+			point = unquoted
+		}
+
+		if point = path.Clean(point); point != "/" {
+			mounts = append(mounts, point)
+		}
+	}
+
+	return mounts
+}
+
+// zeroReader pads the files shrinking while they are archived
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+
+	return len(p), nil
+}
+
+// archiveRootfs archive the directory as the tar stream of `docker export`,
+// the pseudo filesystems, the mounts, devices, sockets and the unreadable files are skipped
+func archiveRootfs(root string, mounts []string) io.ReadCloser {
+	pr, pw := io.Pipe()
+
+	mounted := map[string]bool{}
+	for _, m := range mounts {
+		mounted[strings.TrimPrefix(path.Clean(m), "/")] = true
+	}
+
+	go func() {
+		tw := tar.NewWriter(pw)
+
+		// Root of process is a symbolic link, which is not followed by Walk without the trailing slash
+		err := filepath.Walk(root+string(filepath.Separator), func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				if info != nil && info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			rel, err := filepath.Rel(root, path)
+			if err != nil || rel == "." {
+				return nil
+			}
+			rel = filepath.ToSlash(rel)
+
+			// Volumes and host paths such as `/` mounted at /host are not the filesystem of container
+			if mounted[rel] {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			if info.IsDir() {
+				for _, skipped := range rootfsSkipped {
+					if rel == skipped {
+						return filepath.SkipDir
+					}
+				}
+			}
+
+			link := ""
+			var file *os.File
+
+			switch mode := info.Mode(); {
+			case mode.IsDir():
+				rel += "/"
+			case mode&os.ModeSymlink != 0:
+				link, err = os.Readlink(path)
+				if err != nil {
+					return nil
+				}
+			case mode.IsRegular():
+				file, err = os.Open(path)
+				if err != nil {
+					return nil
+				}
+				defer file.Close()
+			default:
+				return nil
+			}
+
+			header, err := tar.FileInfoHeader(info, link)
+			if err != nil {
+				return nil
+			}
+			header.Name = rel
+
+			if err := tw.WriteHeader(header); err != nil {
+				return err
+			}
+
+			// The files of running container could grow or shrink when they are archived,
+			// the content is cut or padded to the size of header
+			if file != nil {
+				n, _ := io.Copy(tw, io.LimitReader(file, header.Size))
+				if n < header.Size {
+					_, err = io.CopyN(tw, zeroReader{}, header.Size-n)
+				}
+			}
+
+			return err
+		})
+
+		if err == nil {
+			err = tw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+
+	return pr
+}
+
+// GetTarFromCRI archive the root filesystem of the running container of containerd or CRI-O,
+// the images of CRI runtimes could not be exported
+func GetTarFromCRI(ctx context.Context, endpoint, id string) ([]io.ReadCloser, error) {
+	if ctx.Value("tarType") == "image" {
+		return nil, fmt.Errorf("the images of CRI runtime could not be exported, " +
+			"scan a running container of the image or a tar file saved by `ctr image export`")
+	}
+
+	c, err := NewCRIApi(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf(config.Green(fmt.Sprintf("Searching for container in %s", c.Endpoint)))
+	root, mounts, err := c.ContainerRootfs(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := os.Stat(root); err != nil {
+		return nil, fmt.Errorf("failed to read the root filesystem of container, root of the host is required: %v", err)
+	}
+
+	return []io.ReadCloser{archiveRootfs(root, mounts)}, nil
+}
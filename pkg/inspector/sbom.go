@@ -0,0 +1,268 @@
+package inspector
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/kvesta/vesta/config"
+	"github.com/kvesta/vesta/pkg/packages"
+)
+
+// SBOM is the document of the packages in an image or container
+type SBOM interface {
+	Write(w io.Writer) error
+}
+
+// SBOMFormats are the formats of `vesta sbom`, the first is the default
+var SBOMFormats = []string{"cyclonedx", "spdx"}
+
+// NewSBOM build the SBOM of target in the format
+func NewSBOM(format string, packs *packages.Packages, target string) (SBOM, error) {
+	switch strings.ToLower(format) {
+	case "", "cyclonedx":
+		return NewCycloneDX(packs, target), nil
+	case "spdx":
+		return NewSPDX(packs, target), nil
+	}
+
+	return nil, fmt.Errorf("unknown SBOM format '%s', available formats: %s", format, strings.Join(SBOMFormats, ", "))
+}
+
+// CycloneDX is the SBOM of the packages in an image or container, in the format of CycloneDX 1.4
+type CycloneDX struct {
+	BOMFormat    string           `json:"bomFormat"`
+	SpecVersion  string           `json:"specVersion"`
+	SerialNumber string           `json:"serialNumber"`
+	Version      int              `json:"version"`
+	Metadata     cdxMetadata      `json:"metadata"`
+	Components   []*cdxComponent  `json:"components"`
+	Dependencies []*cdxDependency `json:"dependencies,omitempty"`
+}
+
+type cdxMetadata struct {
+	Timestamp string        `json:"timestamp"`
+	Tools     []*cdxTool    `json:"tools"`
+	Component *cdxComponent `json:"component"`
+}
+
+type cdxTool struct {
+	Vendor  string `json:"vendor"`
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type cdxComponent struct {
+	BOMRef     string         `json:"bom-ref"`
+	Type       string         `json:"type"`
+	Name       string         `json:"name"`
+	Version    string         `json:"version,omitempty"`
+	PURL       string         `json:"purl,omitempty"`
+	Properties []*cdxProperty `json:"properties,omitempty"`
+}
+
+type cdxProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type cdxDependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn"`
+}
+
+// osPurlTypes map the IDs of OS to the types of package URL of their packages
+var osPurlTypes = map[string]string{
+	"debian": "deb",
+	"ubuntu": "deb",
+	"alpine": "apk",
+	"centos": "rpm",
+	"rhel":   "rpm",
+	"ol":     "rpm",
+	"fedora": "rpm",
+	"photon": "rpm",
+	"arch":   "alpm",
+}
+
+// packageURL format the package URL, the name is escaped except the slashes of namespace,
+// e.g. the scope of npm `@babel/core` is `%40babel`
+func packageURL(purlType, name, version string, qualifiers ...string) string {
+	segments := []string{}
+	for _, s := range strings.Split(name, "/") {
+		segments = append(segments, strings.ReplaceAll(url.PathEscape(s), "@", "%40"))
+	}
+
+	purl := fmt.Sprintf("pkg:%s/%s", purlType, strings.Join(segments, "/"))
+	if version != "" {
+		purl += "@" + url.PathEscape(version)
+	}
+
+	if len(qualifiers) > 0 {
+		purl += "?" + strings.Join(qualifiers, "&")
+	}
+
+	return purl
+}
+
+// newSerialNumber generate the random UUID of the BOM
+func newSerialNumber() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// sbomPackage is a package discovered in the image or container
+type sbomPackage struct {
+	Name    string
+	Version string
+	PURL    string
+
+	// os, python, node, go, java, php or rust, the OS itself is `os` with IsOS
+	Source string
+	IsOS   bool
+}
+
+// ref get the unique reference of the package, the package URL if it has one
+func (p *sbomPackage) ref() string {
+	if p.PURL != "" {
+		return p.PURL
+	}
+
+	return fmt.Sprintf("%s:%s@%s", p.Source, p.Name, p.Version)
+}
+
+// listPackages list the OS packages and the application dependencies with their package URLs,
+// the duplicated packages are listed once
+func listPackages(packs *packages.Packages) []*sbomPackage {
+	list := []*sbomPackage{}
+	seen := map[string]bool{}
+	add := func(p *sbomPackage) {
+		if !seen[p.ref()] {
+			seen[p.ref()] = true
+			list = append(list, p)
+		}
+	}
+
+	oid := strings.ToLower(packs.OsRelease.OID)
+	if oid != "" && oid != "linux" {
+		add(&sbomPackage{Name: oid, Version: packs.OsRelease.VERSION_ID, Source: "os", IsOS: true})
+	}
+
+	purlType := osPurlTypes[oid]
+	distro := fmt.Sprintf("distro=%s-%s", oid, packs.OsRelease.VERSION_ID)
+	for _, p := range packs.Packs {
+		pack := &sbomPackage{Name: p.Name, Version: p.Version, Source: "os"}
+		if purlType != "" {
+			qualifiers := []string{}
+			if p.Architecture != "" {
+				qualifiers = append(qualifiers, "arch="+url.QueryEscape(p.Architecture))
+			}
+			pack.PURL = packageURL(purlType, fmt.Sprintf("%s/%s", oid, p.Name), p.Version, append(qualifiers, distro)...)
+		}
+		add(pack)
+	}
+
+	for _, py := range packs.PythonPacks {
+		for _, p := range py.SitePacks {
+			add(&sbomPackage{Name: p.Name, Version: p.Version, Source: "python",
+				PURL: packageURL("pypi", strings.ToLower(p.Name), p.Version)})
+		}
+	}
+
+	for _, node := range packs.NodePacks {
+		for _, p := range node.NPMS {
+			add(&sbomPackage{Name: p.Name, Version: p.Version, Source: "node",
+				PURL: packageURL("npm", p.Name, p.Version)})
+		}
+	}
+
+	for _, bin := range packs.GOPacks {
+		for _, p := range bin.Deps {
+			name := p.Path
+			if name == "" {
+				name = p.Name
+			}
+			add(&sbomPackage{Name: name, Version: p.Version, Source: "go",
+				PURL: packageURL("golang", name, p.Version)})
+		}
+	}
+
+	for _, java := range packs.JavaPacks {
+		for _, jar := range java.Jars {
+			add(&sbomPackage{Name: jar.Name, Version: jar.Version, Source: "java"})
+		}
+	}
+
+	for _, php := range packs.PHPPacks {
+		for _, p := range php.Packs {
+			pack := &sbomPackage{Name: p.Component, Version: p.Version, Source: "php"}
+			if strings.Contains(p.Component, "/") {
+				pack.PURL = packageURL("composer", p.Component, p.Version)
+			}
+			add(pack)
+		}
+	}
+
+	for _, rust := range packs.RustPacks {
+		for _, p := range rust.Deps {
+			add(&sbomPackage{Name: p.Name, Version: p.Version, Source: "rust",
+				PURL: packageURL("cargo", p.Name, p.Version)})
+		}
+	}
+
+	return list
+}
+
+// NewCycloneDX build the SBOM from the packages discovered in the image or container,
+// the OS packages and the application dependencies are the components of target
+func NewCycloneDX(packs *packages.Packages, target string) *CycloneDX {
+	bom := &CycloneDX{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  "1.4",
+		SerialNumber: newSerialNumber(),
+		Version:      1,
+		Metadata: cdxMetadata{
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+			Tools:     []*cdxTool{{Vendor: "kvesta", Name: "vesta", Version: config.Version}},
+			Component: &cdxComponent{BOMRef: target, Type: "container", Name: target},
+		},
+		Components: []*cdxComponent{},
+	}
+
+	refs := []string{}
+	for _, p := range listPackages(packs) {
+		c := &cdxComponent{
+			BOMRef:     p.ref(),
+			Type:       "library",
+			Name:       p.Name,
+			Version:    p.Version,
+			PURL:       p.PURL,
+			Properties: []*cdxProperty{{Name: "vesta:package:type", Value: p.Source}},
+		}
+		if p.IsOS {
+			c.Type = "operating-system"
+		}
+
+		refs = append(refs, c.BOMRef)
+		bom.Components = append(bom.Components, c)
+	}
+
+	bom.Dependencies = []*cdxDependency{{Ref: target, DependsOn: refs}}
+
+	return bom
+}
+
+// Write encode the SBOM as JSON
+func (bom *CycloneDX) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(bom)
+}
@@ -0,0 +1,106 @@
+package inspector
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/kvesta/vesta/pkg/osrelease"
+	"github.com/kvesta/vesta/pkg/packages"
+)
+
+func TestNewCycloneDX(t *testing.T) {
+	packs := &packages.Packages{
+		OsRelease: osrelease.OsVersion{OID: "debian", VERSION_ID: "11"},
+		Packs: []*packages.Package{
+			{Name: "openssl", Version: "1.1.1n-0+deb11u3", Architecture: "amd64"},
+			{Name: "openssl", Version: "1.1.1n-0+deb11u3", Architecture: "amd64"},
+		},
+		PythonPacks: []*packages.Python{{SitePacks: []*packages.PIP{{Name: "Django", Version: "3.2.1"}}}},
+		NodePacks:   []*packages.Node{{NPMS: []*packages.NPM{{Name: "@babel/core", Version: "7.20.0"}}}},
+		GOPacks: []*packages.GOBIN{{Name: "app", Deps: []*packages.MOD{
+			{Name: "net", Path: "golang.org/x/net", Version: "v0.1.0"}}}},
+		JavaPacks: []*packages.JAVA{{Jars: []*packages.Jar{{Name: "log4j-core", Version: "2.14.1"}}}},
+	}
+
+	bom := NewCycloneDX(packs, "nginx:latest")
+
+	want := map[string]string{
+		"debian":           "",
+		"openssl":          "pkg:deb/debian/openssl@1.1.1n-0+deb11u3?arch=amd64&distro=debian-11",
+		"Django":           "pkg:pypi/django@3.2.1",
+		"@babel/core":      "pkg:npm/%40babel/core@7.20.0",
+		"golang.org/x/net": "pkg:golang/golang.org/x/net@v0.1.0",
+		"log4j-core":       "",
+	}
+
+	if len(bom.Components) != len(want) {
+		t.Fatalf("got %d components, want %d", len(bom.Components), len(want))
+	}
+
+	for _, c := range bom.Components {
+		purl, ok := want[c.Name]
+		if !ok || c.PURL != purl {
+			t.Errorf("component %s purl = %q, want %q", c.Name, c.PURL, purl)
+		}
+	}
+
+	if deps := bom.Dependencies[0]; deps.Ref != "nginx:latest" || len(deps.DependsOn) != len(want) {
+		t.Errorf("dependencies of target = %+v", deps)
+	}
+
+	var buf bytes.Buffer
+	if err := bom.Write(&buf); err != nil {
+		t.Fatalf("Write() error: %v", err)
+	}
+
+	out := map[string]interface{}{}
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if out["bomFormat"] != "CycloneDX" || out["specVersion"] != "1.4" {
+		t.Errorf("header = %v %v", out["bomFormat"], out["specVersion"])
+	}
+}
+
+func TestNewSPDX(t *testing.T) {
+	packs := &packages.Packages{
+		OsRelease: osrelease.OsVersion{OID: "alpine", VERSION_ID: "3.17.0"},
+		Packs:     []*packages.Package{{Name: "musl", Version: "1.2.3-r4", Architecture: "x86_64"}},
+		RustPacks: []*packages.Rust{{Name: "app", Deps: []*packages.Cargo{{Name: "serde", Version: "1.0.152"}}}},
+	}
+
+	bom, err := NewSBOM("spdx", packs, "alpine:3.17")
+	if err != nil {
+		t.Fatalf("NewSBOM() error: %v", err)
+	}
+	doc := bom.(*SPDX)
+
+	// The container and its 3 packages
+	if len(doc.Packages) != 4 || len(doc.Relationships) != 4 {
+		t.Fatalf("got %d packages and %d relationships, want 4 and 4", len(doc.Packages), len(doc.Relationships))
+	}
+
+	if rel := doc.Relationships[0]; rel.RelationshipType != "DESCRIBES" || rel.RelatedSPDXElement != "SPDXRef-alpine-3.17" {
+		t.Errorf("relationship of document = %+v", rel)
+	}
+
+	musl := doc.Packages[2]
+	if musl.Name != "musl" || len(musl.ExternalRefs) != 1 ||
+		musl.ExternalRefs[0].ReferenceLocator != "pkg:apk/alpine/musl@1.2.3-r4?arch=x86_64&distro=alpine-3.17.0" {
+		t.Errorf("package of musl = %+v", musl)
+	}
+
+	ids := map[string]bool{}
+	for _, p := range doc.Packages {
+		if ids[p.SPDXID] || spdxIDReg.MatchString(strings.TrimPrefix(p.SPDXID, "SPDXRef-")) {
+			t.Errorf("invalid or duplicated SPDXID %s", p.SPDXID)
+		}
+		ids[p.SPDXID] = true
+	}
+
+	if _, err := NewSBOM("swid", packs, "alpine:3.17"); err == nil {
+		t.Errorf("NewSBOM() of unknown format got no error")
+	}
+}
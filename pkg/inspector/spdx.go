@@ -0,0 +1,127 @@
+package inspector
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/kvesta/vesta/config"
+	"github.com/kvesta/vesta/pkg/packages"
+)
+
+// SPDX is the SBOM of the packages in an image or container, in the format of SPDX 2.3 JSON
+type SPDX struct {
+	SPDXVersion       string              `json:"spdxVersion"`
+	DataLicense       string              `json:"dataLicense"`
+	SPDXID            string              `json:"SPDXID"`
+	Name              string              `json:"name"`
+	DocumentNamespace string              `json:"documentNamespace"`
+	CreationInfo      spdxCreationInfo    `json:"creationInfo"`
+	Packages          []*spdxPackage      `json:"packages"`
+	Relationships     []*spdxRelationship `json:"relationships"`
+}
+
+type spdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type spdxPackage struct {
+	Name                  string             `json:"name"`
+	SPDXID                string             `json:"SPDXID"`
+	VersionInfo           string             `json:"versionInfo,omitempty"`
+	DownloadLocation      string             `json:"downloadLocation"`
+	FilesAnalyzed         bool               `json:"filesAnalyzed"`
+	LicenseConcluded      string             `json:"licenseConcluded"`
+	LicenseDeclared       string             `json:"licenseDeclared"`
+	CopyrightText         string             `json:"copyrightText"`
+	PrimaryPackagePurpose string             `json:"primaryPackagePurpose"`
+	Comment               string             `json:"comment,omitempty"`
+	ExternalRefs          []*spdxExternalRef `json:"externalRefs,omitempty"`
+}
+
+type spdxExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type spdxRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+// spdxIDReg match the characters not allowed in the SPDX identifiers
+var spdxIDReg = regexp.MustCompile(`[^A-Za-z0-9.-]+`)
+
+// newSPDXPackage build the package of SPDX, the licenses are not discovered by vesta
+func newSPDXPackage(id, name, version, purpose string) *spdxPackage {
+	return &spdxPackage{
+		Name:                  name,
+		SPDXID:                id,
+		VersionInfo:           version,
+		DownloadLocation:      "NOASSERTION",
+		LicenseConcluded:      "NOASSERTION",
+		LicenseDeclared:       "NOASSERTION",
+		CopyrightText:         "NOASSERTION",
+		PrimaryPackagePurpose: purpose,
+	}
+}
+
+// NewSPDX build the SBOM from the packages discovered in the image or container,
+// the target is the described package containing the OS packages and the application dependencies
+func NewSPDX(packs *packages.Packages, target string) *SPDX {
+	uuid := strings.TrimPrefix(newSerialNumber(), "urn:uuid:")
+
+	doc := &SPDX{
+		SPDXVersion: "SPDX-2.3",
+		DataLicense: "CC0-1.0",
+		SPDXID:      "SPDXRef-DOCUMENT",
+		Name:        target,
+		DocumentNamespace: fmt.Sprintf("https://github.com/kvesta/vesta/spdx/%s-%s",
+			url.PathEscape(target), uuid),
+		CreationInfo: spdxCreationInfo{
+			Created:  time.Now().UTC().Format(time.RFC3339),
+			Creators: []string{"Organization: kvesta", fmt.Sprintf("Tool: vesta-%s", config.Version)},
+		},
+	}
+
+	targetID := "SPDXRef-" + spdxIDReg.ReplaceAllString(target, "-")
+	doc.Packages = append(doc.Packages, newSPDXPackage(targetID, target, "", "CONTAINER"))
+	doc.Relationships = append(doc.Relationships, &spdxRelationship{
+		SPDXElementID: doc.SPDXID, RelationshipType: "DESCRIBES", RelatedSPDXElement: targetID})
+
+	for i, p := range listPackages(packs) {
+		purpose := "LIBRARY"
+		if p.IsOS {
+			purpose = "OPERATING-SYSTEM"
+		}
+
+		id := fmt.Sprintf("SPDXRef-Package-%s-%s-%d", p.Source, spdxIDReg.ReplaceAllString(p.Name, "-"), i+1)
+		pack := newSPDXPackage(id, p.Name, p.Version, purpose)
+		pack.Comment = fmt.Sprintf("package type: %s", p.Source)
+		if p.PURL != "" {
+			pack.ExternalRefs = []*spdxExternalRef{{
+				ReferenceCategory: "PACKAGE-MANAGER", ReferenceType: "purl", ReferenceLocator: p.PURL}}
+		}
+
+		doc.Packages = append(doc.Packages, pack)
+		doc.Relationships = append(doc.Relationships, &spdxRelationship{
+			SPDXElementID: targetID, RelationshipType: "CONTAINS", RelatedSPDXElement: id})
+	}
+
+	return doc
+}
+
+// Write encode the SBOM as JSON
+func (doc *SPDX) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(doc)
+}
@@ -3,18 +3,27 @@ package inspector
 import (
 	"context"
 	"io"
-	"log"
 
 	"github.com/docker/docker/client"
+	"github.com/kvesta/vesta/config"
 )
 
 func GetTarFromID(ctx context.Context, ID string) ([]io.ReadCloser, error) {
 	var err error
 
 	// Use the inspector id from containerd or crio
+	if endpoint, ok := ctx.Value("cri").(string); ok && endpoint != "" {
+		tarFile, err := GetTarFromCRI(ctx, endpoint, ID)
+		if err != nil {
+			config.ErrorLog.Printf("expose inspector file error: %v", err)
+		}
+
+		return tarFile, err
+	}
+
 	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
 	if err != nil {
-		log.Printf("init docker environment failed: %v", err)
+		config.ErrorLog.Printf("init docker environment failed: %v", err)
 		return nil, err
 	}
 	c := DockerApi{
@@ -30,7 +39,7 @@ func GetTarFromID(ctx context.Context, ID string) ([]io.ReadCloser, error) {
 	} else {
 		tarFile, err = c.GetContainerName(ID)
 		if err != nil {
-			log.Printf("expose inspector file error: %v", err)
+			config.ErrorLog.Printf("expose inspector file error: %v", err)
 			return nil, err
 		}
 
@@ -9,6 +9,7 @@ import (
 	"regexp"
 	"strings"
 
+	"github.com/kvesta/vesta/config"
 	"github.com/kvesta/vesta/pkg/layer"
 
 	"github.com/docker/docker/api/types"
@@ -96,7 +97,7 @@ func GetKernelVersion(ctx context.Context) (string, error) {
 		}
 
 		if err := cli.ContainerRemove(ctx, resp.ID, removeOptions); err != nil {
-			log.Printf("Unable to remove container %s: %s", resp.ID, err)
+			config.ErrorLog.Printf("Unable to remove container %s: %s", resp.ID, err)
 		}
 	}()
 
@@ -135,14 +136,14 @@ func DetectOs(ctx context.Context, m layer.Manifest) (*OsVersion, error) {
 	for _, n := range paths {
 		rd, err := m.File(n)
 		if err != nil {
-			log.Printf("detect os error: %v", err)
+			config.ErrorLog.Printf("detect os error: %v", err)
 			continue
 		}
-		config := rd.String()
-		if config != "" {
-			osv, err = getOs(config, n)
+		content := rd.String()
+		if content != "" {
+			osv, err = getOs(content, n)
 			if err != nil {
-				log.Printf("parse os error: %v", err)
+				config.ErrorLog.Printf("parse os error: %v", err)
 			}
 			break
 		}
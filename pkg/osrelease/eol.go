@@ -0,0 +1,65 @@
+package osrelease
+
+import (
+	"strings"
+	"time"
+)
+
+// eolDates maps the distribution to the end of life date of each version,
+// the end of the standard support is used for Ubuntu and the end of LTS for Debian
+var eolDates = map[string]map[string]string{
+	"debian": {
+		"6": "2016-02-29", "7": "2018-05-31", "8": "2020-06-30",
+		"9": "2022-06-30", "10": "2024-06-30", "11": "2026-08-31",
+		"12": "2028-06-30",
+	},
+	"ubuntu": {
+		"12.04": "2017-04-28", "14.04": "2019-04-25", "16.04": "2021-04-30",
+		"18.04": "2023-05-31", "20.04": "2025-05-29", "22.04": "2027-06-01",
+		"24.04": "2029-05-31", "19.10": "2020-07-17", "20.10": "2021-07-22",
+		"21.04": "2022-01-20", "21.10": "2022-07-14", "22.10": "2023-07-20",
+		"23.04": "2024-01-25", "23.10": "2024-07-11",
+	},
+	"centos": {
+		"5": "2017-03-31", "6": "2020-11-30", "7": "2024-06-30", "8": "2021-12-31",
+	},
+	"alpine": {
+		"3.7": "2019-11-01", "3.8": "2020-05-01", "3.9": "2021-01-01",
+		"3.10": "2021-05-01", "3.11": "2021-11-01", "3.12": "2022-05-01",
+		"3.13": "2022-11-01", "3.14": "2023-05-01", "3.15": "2023-11-01",
+		"3.16": "2024-05-23", "3.17": "2024-11-22", "3.18": "2025-05-09",
+		"3.19": "2025-11-01", "3.20": "2026-04-01",
+	},
+	"amzn": {
+		"2018.03": "2023-12-31",
+	},
+}
+
+// GetEOL get the end of life date of the distribution,
+// the version is matched by the full version, then major.minor and major
+func GetEOL(osv *OsVersion) (time.Time, bool) {
+	dates, ok := eolDates[strings.ToLower(osv.OID)]
+	if !ok {
+		return time.Time{}, false
+	}
+
+	parts := strings.Split(osv.VERSION_ID, ".")
+	candidates := []string{osv.VERSION_ID}
+	if len(parts) > 1 {
+		candidates = append(candidates, strings.Join(parts[:2], "."))
+	}
+	candidates = append(candidates, parts[0])
+
+	for _, v := range candidates {
+		if date, ok := dates[v]; ok {
+			eol, err := time.Parse("2006-01-02", date)
+			if err != nil {
+				return time.Time{}, false
+			}
+
+			return eol, true
+		}
+	}
+
+	return time.Time{}, false
+}
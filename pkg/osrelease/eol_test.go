@@ -0,0 +1,31 @@
+package osrelease
+
+import "testing"
+
+func TestGetEOL(t *testing.T) {
+	tests := []struct {
+		oid       string
+		versionID string
+		want      string
+	}{
+		{oid: "debian", versionID: "8", want: "2020-06-30"},
+		{oid: "ubuntu", versionID: "16.04", want: "2021-04-30"},
+		{oid: "CentOS", versionID: "6.10", want: "2020-11-30"},
+		{oid: "alpine", versionID: "3.12.0", want: "2022-05-01"},
+		{oid: "alpine", versionID: "3.99.0"},
+		{oid: "linux"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.oid+tt.versionID, func(t *testing.T) {
+			eol, ok := GetEOL(&OsVersion{OID: tt.oid, VERSION_ID: tt.versionID})
+			if ok != (tt.want != "") {
+				t.Fatalf("GetEOL() found = %v, want %v", ok, tt.want != "")
+			}
+
+			if ok && eol.Format("2006-01-02") != tt.want {
+				t.Errorf("GetEOL() = %s, want %s", eol.Format("2006-01-02"), tt.want)
+			}
+		})
+	}
+}
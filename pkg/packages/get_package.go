@@ -2,8 +2,9 @@ package packages
 
 import (
 	"context"
-	"log"
 	"strings"
+
+	"github.com/kvesta/vesta/config"
 )
 
 var (
@@ -17,7 +18,7 @@ func (s *Packages) GetApp(ctx context.Context) error {
 		if strings.ToLower(s.OsRelease.OID) == r {
 			err := s.getRpmPacks(ctx)
 			if err != nil {
-				log.Printf("Get rpm packages failed: %v", err)
+				config.ErrorLog.Printf("Get rpm packages failed: %v", err)
 				return err
 			}
 			return nil
@@ -26,7 +27,7 @@ func (s *Packages) GetApp(ctx context.Context) error {
 
 	rd, err := m.File("var/lib/dpkg/status")
 	if err != nil {
-		log.Printf("Dpkg get failed, error: %v", err)
+		config.ErrorLog.Printf("Dpkg get failed, error: %v", err)
 	}
 	dpkg := rd.String()
 	if dpkg != "" {
@@ -34,7 +35,7 @@ func (s *Packages) GetApp(ctx context.Context) error {
 	}
 	rd, err = m.File("lib/apk/db/installed")
 	if err != nil {
-		log.Printf("Apk get failed, error: %v", err)
+		config.ErrorLog.Printf("Apk get failed, error: %v", err)
 	}
 	apk := rd.String()
 	if apk != "" {
@@ -43,7 +44,7 @@ func (s *Packages) GetApp(ctx context.Context) error {
 
 	rd, err = m.File("var/log/pacman.log")
 	if err != nil {
-		log.Printf("Pacman get failed, error: %v", err)
+		config.ErrorLog.Printf("Pacman get failed, error: %v", err)
 	}
 	pacman := rd.String()
 	if pacman != "" {
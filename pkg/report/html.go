@@ -0,0 +1,223 @@
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kvesta/vesta/config"
+)
+
+// severities are the severities in the order of the summary
+var severities = []string{"critical", "high", "medium", "low", "warning"}
+
+// Finding is a finding rendered in the report
+type Finding struct {
+	Severity    string
+	Type        string
+	Param       string
+	Value       string
+	Describe    string
+	Reference   string
+	Remediation string
+}
+
+// Section is the findings of a container, an image or a workload,
+// it is collapsible in the report
+type Section struct {
+	Name     string
+	Findings []*Finding
+}
+
+// Document is the content of the report, the sections are rendered in order
+type Document struct {
+	Title       string
+	GeneratedAt time.Time
+
+	// compliance score of 0-100, nil if the scan is not scored
+	Score *int
+
+	Sections []*Section
+}
+
+// severityCount is the count of findings of a severity
+type severityCount struct {
+	Severity string
+	Count    int
+}
+
+// sectionView is a section with the counts of its findings by severity
+type sectionView struct {
+	*Section
+	ID     string
+	Counts []severityCount
+	Worst  string
+}
+
+// summary is the executive summary of the report
+type summary struct {
+	Findings int
+	Sections int
+	Affected int
+	Counts   []severityCount
+	Worst    []*sectionView
+}
+
+// countSeverities count the findings by severity in the order of severities,
+// the severity with the most weight is returned with the counts
+func countSeverities(findings []*Finding) ([]severityCount, string) {
+	counts := map[string]int{}
+	worst := ""
+
+	for _, f := range findings {
+		severity := strings.ToLower(f.Severity)
+		counts[severity]++
+
+		if config.SeverityMap[severity] > config.SeverityMap[worst] {
+			worst = severity
+		}
+	}
+
+	result := []severityCount{}
+	for _, severity := range severities {
+		result = append(result, severityCount{Severity: severity, Count: counts[severity]})
+	}
+
+	return result, worst
+}
+
+// newSummary count the findings of the sections,
+// the sections with the most severe findings are listed first for the summary
+func newSummary(views []*sectionView) *summary {
+	sum := &summary{Sections: len(views)}
+
+	all := []*Finding{}
+	affected := []*sectionView{}
+	for _, v := range views {
+		all = append(all, v.Findings...)
+		if len(v.Findings) > 0 {
+			affected = append(affected, v)
+		}
+	}
+
+	sum.Findings = len(all)
+	sum.Affected = len(affected)
+	sum.Counts, _ = countSeverities(all)
+
+	sort.SliceStable(affected, func(i, j int) bool {
+		a, b := affected[i], affected[j]
+		if config.SeverityMap[a.Worst] != config.SeverityMap[b.Worst] {
+			return config.SeverityMap[a.Worst] > config.SeverityMap[b.Worst]
+		}
+
+		return len(a.Findings) > len(b.Findings)
+	})
+
+	if len(affected) > 5 {
+		affected = affected[:5]
+	}
+	sum.Worst = affected
+
+	return sum
+}
+
+// WriteHTML render the document as a standalone HTML page with an executive summary,
+// the sections holding critical or high findings are expanded
+func WriteHTML(w io.Writer, doc *Document) error {
+	views := []*sectionView{}
+	for i, s := range doc.Sections {
+		counts, worst := countSeverities(s.Findings)
+		views = append(views, &sectionView{Section: s, ID: fmt.Sprintf("section-%d", i+1), Counts: counts, Worst: worst})
+	}
+
+	return htmlTemplate.Execute(w, struct {
+		*Document
+		Version  string
+		Summary  *summary
+		Sections []*sectionView
+	}{
+		Document: doc,
+		Version:  config.Version,
+		Summary:  newSummary(views),
+		Sections: views,
+	})
+}
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"lower":    strings.ToLower,
+	"expanded": func(severity string) bool { return config.SeverityMap[severity] >= config.SeverityMap["high"] },
+	"date":     func(t time.Time) string { return t.Format("2006-01-02 15:04:05 MST") },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #24292f; }
+table { border-collapse: collapse; width: 100%; margin: 0.5em 0 1em; }
+th, td { border: 1px solid #d0d7de; padding: 4px 8px; text-align: left; vertical-align: top; }
+th { background: #f6f8fa; }
+details { border: 1px solid #d0d7de; border-radius: 6px; margin: 0.5em 0; padding: 0.5em 1em; }
+summary { cursor: pointer; font-weight: 600; }
+.badge { display: inline-block; min-width: 1.5em; padding: 0 6px; border-radius: 10px; color: #fff; font-size: 0.85em; text-align: center; }
+.critical { background: #8b0000; }
+.high { background: #d1242f; }
+.medium { background: #bf8700; }
+.low { background: #0969da; }
+.warning { background: #6e7781; }
+.muted { color: #6e7781; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p class="muted">Generated by vesta {{.Version}} at {{date .GeneratedAt}}</p>
+
+<h2>Executive summary</h2>
+<table>
+<tr><th>Findings</th><td>{{.Summary.Findings}}</td></tr>
+<tr><th>Affected</th><td>{{.Summary.Affected}} of {{.Summary.Sections}}</td></tr>
+{{- if .Score}}
+<tr><th>Score</th><td>{{.Score}} / 100</td></tr>
+{{- end}}
+<tr><th>Severities</th><td>
+{{- range .Summary.Counts}}<span class="badge {{.Severity}}" title="{{.Severity}}">{{.Count}}</span> {{.Severity}} {{end -}}
+</td></tr>
+</table>
+{{- if .Summary.Worst}}
+<h3>Fix first</h3>
+<ol>
+{{- range .Summary.Worst}}
+<li><a href="#{{.ID}}">{{.Name}}</a> <span class="badge {{.Worst}}">{{.Worst}}</span> {{len .Findings}} findings</li>
+{{- end}}
+</ol>
+{{- end}}
+
+<h2>Findings</h2>
+{{- range .Sections}}
+<details id="{{.ID}}"{{if expanded .Worst}} open{{end}}>
+<summary>{{.Name}} {{range .Counts}}{{if .Count}}<span class="badge {{.Severity}}" title="{{.Severity}}">{{.Count}}</span> {{end}}{{end}}</summary>
+{{- if .Findings}}
+<table>
+<tr><th>Severity</th><th>Type</th><th>Param</th><th>Value</th><th>Description</th><th>Remediation</th></tr>
+{{- range .Findings}}
+<tr>
+<td><span class="badge {{lower .Severity}}">{{lower .Severity}}</span></td>
+<td>{{.Type}}</td>
+<td>{{.Param}}</td>
+<td>{{.Value}}</td>
+<td>{{.Describe}}{{if .Reference}}<br><span class="muted">{{.Reference}}</span>{{end}}</td>
+<td>{{.Remediation}}</td>
+</tr>
+{{- end}}
+</table>
+{{- else}}
+<p class="muted">No findings.</p>
+{{- end}}
+</details>
+{{- end}}
+</body>
+</html>
+`))
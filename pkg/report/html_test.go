@@ -0,0 +1,56 @@
+package report
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteHTML(t *testing.T) {
+	score := 72
+	doc := &Document{
+		Title:       "Kubernetes analysis",
+		GeneratedAt: time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC),
+		Score:       &score,
+		Sections: []*Section{
+			{Name: "pod: default/web", Findings: []*Finding{
+				{Severity: "low", Param: "sidecar name: web | Resource", Value: "memory"},
+			}},
+			{Name: "Deployment: prod/api", Findings: []*Finding{
+				{Severity: "critical", Type: "Sidecar Privileged", Param: "sidecar name: api | privileged",
+					Value: "true", Describe: "<script>alert(1)</script>"},
+				{Severity: "medium", Param: "sidecar name: api | capabilities", Value: "NET_ADMIN"},
+			}},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteHTML(&buf, doc); err != nil {
+		t.Fatalf("WriteHTML() error: %v", err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		"<title>Kubernetes analysis</title>",
+		"<td>3</td>",
+		"<td>72 / 100</td>",
+		`<details id="section-2" open>`,
+		`<details id="section-1">`,
+		"&lt;script&gt;",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("WriteHTML() output does not contain %q", want)
+		}
+	}
+
+	if strings.Contains(out, "<script>") {
+		t.Errorf("WriteHTML() output is not escaped")
+	}
+
+	// The section with the critical finding is the first to fix
+	fixFirst := out[strings.Index(out, "Fix first"):]
+	if strings.Index(fixFirst, "prod/api") > strings.Index(fixFirst, "default/web") {
+		t.Errorf("WriteHTML() sections to fix first are not ordered by severity")
+	}
+}
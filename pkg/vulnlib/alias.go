@@ -0,0 +1,56 @@
+package vulnlib
+
+import "strings"
+
+// productAliases maps the canonical product name in database to the names used by
+// the packages, the distributions and the version outputs
+var productAliases = map[string][]string{
+	"docker":     {"docker engine", "docker_engine", "docker-engine", "docker-ce", "docker-ee", "docker.io", "moby", "moby-engine"},
+	"containerd": {"containerd.io", "containerd-shim"},
+	"runc":       {"runc.io", "opencontainers-runc"},
+	"cri-o":      {"crio", "cri_o"},
+	"istio":      {"istiod", "istio-proxy", "istio-pilot"},
+	"cilium":     {"cilium-agent", "cilium-operator"},
+	"envoy":      {"envoy-proxy", "envoyproxy"},
+}
+
+// normalizeName lowercase the name and unify the separators
+func normalizeName(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
+
+// CanonicalName get the product name in database of the alias,
+// the normalized name is returned if it is not a known alias
+func CanonicalName(name string) string {
+	name = normalizeName(name)
+
+	for canonical, aliases := range productAliases {
+		if name == canonical {
+			return canonical
+		}
+
+		for _, alias := range aliases {
+			if name == alias {
+				return canonical
+			}
+		}
+	}
+
+	return name
+}
+
+// aliasNames get the names in database which could describe the product,
+// the canonical name comes first
+func aliasNames(name string) []string {
+	canonical := CanonicalName(name)
+
+	names := []string{canonical}
+	for _, alias := range productAliases[canonical] {
+		// Only the names without space could be the product of CPE
+		if !strings.Contains(alias, " ") {
+			names = append(names, alias)
+		}
+	}
+
+	return names
+}
@@ -0,0 +1,56 @@
+package vulnlib
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+func TestCanonicalName(t *testing.T) {
+	tests := map[string]string{
+		"Docker Engine": "docker",
+		"docker-ce":     "docker",
+		"containerd.io": "containerd",
+		"CRIO":          "cri-o",
+		"nginx":         "nginx",
+	}
+
+	for name, want := range tests {
+		if got := CanonicalName(name); got != want {
+			t.Errorf("CanonicalName(%s) = %s, want %s", name, got, want)
+		}
+	}
+}
+
+func TestQueryVulnByNameFuzzy(t *testing.T) {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "vesta.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`CREATE TABLE vulns ("ID" INTEGER PRIMARY KEY, "Hash" TEXT, "VulnName" TEXT,
+		"MaxVersion" TEXT, "MinVersion" TEXT, "Description" TEXT, "Level" TEXT, "CVEID" TEXT,
+		"PublishDate" TEXT, "Component" TEXT, "Score" REAL, "Source" TEXT);
+		INSERT INTO vulns ("Hash", "VulnName", "MaxVersion", "MinVersion", "Description", "Level",
+			"CVEID", "PublishDate", "Component", "Score", "Source") VALUES
+		('a', 'docker', '20.10.9', '0.0', '', 'high', 'CVE-2021-41089', '', '', 5.7, 'nvd'),
+		('b', 'moby', '20.10.11', '0.0', '', 'medium', 'CVE-2021-41190', '', '', 3.0, 'nvd'),
+		('c', 'containerd', '1.5.8', '0.0', '', 'high', 'CVE-2021-41103', '', '', 5.9, 'nvd');`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cli := &Client{DB: db}
+
+	rows, err := cli.QueryVulnByName("docker")
+	if err != nil || len(rows) != 1 {
+		t.Fatalf("exact QueryVulnByName() found %d, want 1, error: %v", len(rows), err)
+	}
+
+	cli.Fuzzy = true
+	rows, err = cli.QueryVulnByName("docker-ce")
+	if err != nil || len(rows) != 2 {
+		t.Fatalf("fuzzy QueryVulnByName() found %d, want 2, error: %v", len(rows), err)
+	}
+}
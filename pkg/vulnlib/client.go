@@ -10,6 +10,9 @@ type Client struct {
 	DB  *sql.DB
 
 	Store string
+
+	// Fuzzy matches the aliases of product name in QueryVulnByName, e.g. `docker-ce` for `docker`
+	Fuzzy bool
 }
 
 type DBRow struct {
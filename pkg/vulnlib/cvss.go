@@ -15,6 +15,7 @@ import (
 	"time"
 
 	version2 "github.com/hashicorp/go-version"
+	"github.com/kvesta/vesta/config"
 	"github.com/tidwall/gjson"
 )
 
@@ -43,12 +44,12 @@ func (c *Client) GetCvss(ctx context.Context) error {
 		url := fmt.Sprintf(cvssUrl, y)
 		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 		if err != nil {
-			log.Printf("failed to get url: %s", url)
+			config.ErrorLog.Printf("failed to get url: %s", url)
 			continue
 		}
 		res, err := c.Cli.Do(req)
 		if err != nil {
-			log.Printf("failed to request url: %s", url)
+			config.ErrorLog.Printf("failed to request url: %s", url)
 			continue
 		}
 
@@ -75,7 +76,7 @@ func (c *Client) GetCvss(ctx context.Context) error {
 	// Update cvss data to database
 	err := c.cvssToDB()
 	if err != nil {
-		log.Printf("failed to store cvss")
+		config.ErrorLog.Printf("failed to store cvss")
 		return err
 	}
 
@@ -118,7 +119,7 @@ type vuln struct {
 func (c *Client) cvssToDB() error {
 	cvssFiles, err := ioutil.ReadDir(c.Store)
 	if err != nil {
-		log.Printf("failed to list dir '%s'", c.Store)
+		config.ErrorLog.Printf("failed to list dir '%s'", c.Store)
 		return err
 	}
 
@@ -137,7 +138,7 @@ func (c *Client) cvssToDB() error {
 		cveFile := filepath.Join(c.Store, cf.Name())
 		err = readCVSS(cveFile, c.cvssParse)
 		if err != nil {
-			log.Printf("%s is stored failed", cf.Name())
+			config.ErrorLog.Printf("%s is stored failed", cf.Name())
 			continue
 		}
 		log.Printf("%s is stored successfully", cf.Name())
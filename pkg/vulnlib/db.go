@@ -5,12 +5,12 @@ import (
 	"database/sql"
 	"encoding/hex"
 	"fmt"
-	"log"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
 
+	"github.com/kvesta/vesta/config"
 	_ "github.com/mattn/go-sqlite3"
 )
 
@@ -19,7 +19,7 @@ func (cli *Client) Init() error {
 	// Re-get homedir here
 	dir, err := getHomeDir()
 	if err != nil {
-		log.Printf("failed to get home dir, error: %v", err)
+		config.ErrorLog.Printf("failed to get home dir, error: %v", err)
 		return err
 	}
 
@@ -34,13 +34,16 @@ func (cli *Client) Init() error {
 		err = mkFolder(homedir)
 
 		if err != nil {
-			log.Printf("failed to create folder, error: %v", err)
+			config.ErrorLog.Printf("failed to create folder, error: %v", err)
 			return err
 		}
 	}
 
-	dbPath := filepath.Join(homedir, "vesta.db")
+	return cli.initDB(filepath.Join(homedir, "vesta.db"))
+}
 
+// initDB open the database of path, the table of vulnerabilities is created in the new database
+func (cli *Client) initDB(dbPath string) error {
 	var db *sql.DB
 	if !exists(dbPath) {
 		file, err := os.Create(dbPath)
@@ -100,12 +103,24 @@ func (cli *Client) update(v *vuln) error {
 	return nil
 }
 
+// QueryVulnByName get the vulnerabilities of the product name,
+// the aliases of product are matched as well if the client is fuzzy
 func (cli *Client) QueryVulnByName(name string) ([]*DBRow, error) {
 
 	dbRows := []*DBRow{}
 
-	sqlRow := `SELECT * FROM vulns WHERE vulnname = ?`
-	rows, err := cli.DB.Query(sqlRow, name)
+	names := []string{name}
+	if cli.Fuzzy {
+		names = aliasNames(name)
+	}
+
+	args := []interface{}{}
+	for _, n := range names {
+		args = append(args, n)
+	}
+
+	sqlRow := `SELECT * FROM vulns WHERE vulnname IN (?` + strings.Repeat(", ?", len(names)-1) + `)`
+	rows, err := cli.DB.Query(sqlRow, args...)
 
 	if err != nil {
 		return dbRows, err
@@ -2,6 +2,7 @@ package vulnlib
 
 import (
 	"context"
+	"io"
 	"io/ioutil"
 	"log"
 	"net/http"
@@ -13,7 +14,8 @@ import (
 	"github.com/kvesta/vesta/config"
 )
 
-// Fetch get cvss data from Internet
+// Fetch get cvss data from Internet, the database is built into a temporary file
+// and swapped in after the validation, so the database in use is kept if any step fails
 func Fetch(ctx context.Context) error {
 	log.Printf(config.Green("Begin updating vulnerability database"))
 
@@ -28,33 +30,24 @@ func Fetch(ctx context.Context) error {
 		},
 	}
 
-	dir, err := getHomeDir()
+	store, err := StoreDir()
 	if err != nil {
-		log.Printf("failed to get home dir, error: %v", err)
+		config.ErrorLog.Printf("failed to get home dir, error: %v", err)
 		return err
 	}
 
-	var store string
-	if runtime.GOOS == "windows" {
-		store = filepath.Join(dir, "vestadata")
-
-	} else {
-		store = filepath.Join(dir, ".vesta")
-	}
-
-	if ctx.Value("reset") != nil && ctx.Value("reset").(bool) {
-		dataFile := filepath.Join(store, "date.txt")
-		dbFile := filepath.Join(store, "vesta.db")
-
-		_ = os.Remove(dataFile)
-		_ = os.Remove(dbFile)
+	// All the cvss files are stored again without the date log,
+	// the database in use is replaced only after the new one is built
+	reset := ctx.Value("reset") != nil && ctx.Value("reset").(bool)
+	if reset {
+		_ = os.Remove(filepath.Join(store, "date.txt"))
 	}
 
 	if !exists(store) {
 		err = mkFolder(store)
 
 		if err != nil {
-			log.Printf("failed to create folder, error: %v", err)
+			config.ErrorLog.Printf("failed to create folder, error: %v", err)
 			return err
 		}
 	}
@@ -66,30 +59,76 @@ func Fetch(ctx context.Context) error {
 		log.Printf("Vulnerability Data expired, updating database")
 	}
 
+	// The database is built in the same folder, so the rename is atomic
+	dbPath := filepath.Join(store, "vesta.db")
+	buildPath := dbPath + ".build"
+	_ = os.Remove(buildPath)
+	defer os.Remove(buildPath)
+
+	// The cvss of this year is added to the copy of database in use unless it is reset
+	if !reset && exists(dbPath) {
+		err = copyFile(dbPath, buildPath)
+		if err != nil {
+			config.ErrorLog.Printf("failed to copy database, error: %v", err)
+			return err
+		}
+	}
+
 	cli.Store = store
-	err = cli.Init()
+	err = cli.initDB(buildPath)
 	if err != nil {
-		log.Printf("failed to init database")
+		config.ErrorLog.Printf("failed to init database")
 		return err
 	}
 
-	defer cli.DB.Close()
-
 	// Get cvss data and store to database
 	err = cli.GetCvss(ctx)
+	cli.DB.Close()
+	if err != nil {
+		config.ErrorLog.Printf("failed to get cvss data, error: %v", err)
+		return err
+	}
+
+	err = validateDB(buildPath)
 	if err != nil {
-		log.Printf("failed to get cvss data, error: %v", err)
+		config.ErrorLog.Printf("invalid database, the database in use is kept, error: %v", err)
+		return err
+	}
+
+	err = os.Rename(buildPath, dbPath)
+	if err != nil {
+		return err
 	}
 
 	// Write log
 	err = writeLog(store)
 	if err != nil {
-		log.Printf("failed to write date log, error: %v", err)
+		config.ErrorLog.Printf("failed to write date log, error: %v", err)
+	}
+
+	now := time.Now().UTC()
+	err = writeMetadata(store, &Metadata{Version: now.Format("20060102"), UpdatedAt: now, Source: "nvd"})
+	if err != nil {
+		config.ErrorLog.Printf("failed to write metadata, error: %v", err)
 	}
 
 	return nil
 }
 
+// StoreDir get the folder of the vulnerability database
+func StoreDir() (string, error) {
+	dir, err := getHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	if runtime.GOOS == "windows" {
+		return filepath.Join(dir, "vestadata"), nil
+	}
+
+	return filepath.Join(dir, ".vesta"), nil
+}
+
 func getHomeDir() (string, error) {
 	if runtime.GOOS == "windows" {
 		dir, err := os.Getwd()
@@ -140,7 +179,7 @@ func checkExpired(path string) bool {
 	} else {
 		dateFile, err = os.Open(filename)
 		if err != nil {
-			log.Printf("failed to open date: %v", err)
+			config.ErrorLog.Printf("failed to open date: %v", err)
 			return true
 		}
 	}
@@ -163,7 +202,7 @@ func checkExpired(path string) bool {
 
 	// Check whether a time format
 	if err != nil {
-		log.Printf("Date format error, expired")
+		config.ErrorLog.Printf("Date format error, expired")
 		return true
 	}
 
@@ -174,6 +213,27 @@ func checkExpired(path string) bool {
 	return false
 }
 
+// copyFile copy the file of src to dst
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(out, in)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
+
 func writeLog(path string) error {
 
 	filename := filepath.Join(path, "date.txt")
@@ -181,7 +241,7 @@ func writeLog(path string) error {
 	if !exists(filename) {
 		f, err := os.Create(filename)
 		if err != nil {
-			log.Printf("failed to create log")
+			config.ErrorLog.Printf("failed to create log")
 			return err
 		}
 		f.Close()
@@ -191,7 +251,7 @@ func writeLog(path string) error {
 
 	dateFile, err := os.OpenFile(filename, os.O_WRONLY, 0644)
 	if err != nil {
-		log.Printf("failed to open log")
+		config.ErrorLog.Printf("failed to open log")
 		return err
 	}
 
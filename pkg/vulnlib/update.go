@@ -0,0 +1,277 @@
+package vulnlib
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kvesta/vesta/config"
+)
+
+// Metadata describe the vulnerability database in use
+type Metadata struct {
+	Version   string    `json:"version"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	SHA256    string    `json:"sha256,omitempty"`
+	Source    string    `json:"source"`
+}
+
+const metadataFile = "metadata.json"
+
+// Update replace the vulnerability database with the snapshot of the option `snapshot`,
+// the database is fetched from NVD if the snapshot is not given.
+// Either way the database in use is replaced only after the new one is validated
+func Update(ctx context.Context) error {
+	url, _ := ctx.Value("snapshot").(string)
+	if url == "" {
+		return Fetch(ctx)
+	}
+
+	store, err := StoreDir()
+	if err != nil {
+		config.ErrorLog.Printf("failed to get home dir, error: %v", err)
+		return err
+	}
+
+	err = mkFolder(store)
+	if err != nil {
+		config.ErrorLog.Printf("failed to create folder, error: %v", err)
+		return err
+	}
+
+	checksum, _ := ctx.Value("checksum").(string)
+
+	cli := Client{
+		Cli:   &http.Client{Timeout: 10 * time.Minute},
+		Store: store,
+	}
+
+	log.Printf(config.Green("Begin updating vulnerability database from snapshot"))
+
+	_, err = cli.UpdateSnapshot(url, checksum)
+
+	return err
+}
+
+// UpdateSnapshot download the database snapshot and swap it in after the validation,
+// the database in use is kept if any step fails.
+// The checksum is fetched from `<url>.sha256` if it is empty
+func (cli *Client) UpdateSnapshot(url, checksum string) (*Metadata, error) {
+	var err error
+
+	if checksum == "" {
+		checksum, err = cli.fetchChecksum(url + ".sha256")
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the checksum of snapshot: %v", err)
+		}
+	}
+	checksum = strings.ToLower(checksum)
+
+	// The snapshot is downloaded into the same folder, so the rename is atomic
+	tmp, err := ioutil.TempFile(cli.Store, "vesta.db.*.download")
+	if err != nil {
+		return nil, err
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	sum, modified, err := cli.download(url, tmp)
+	tmp.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to download the snapshot: %v", err)
+	}
+
+	if sum != checksum {
+		return nil, fmt.Errorf("checksum of snapshot mismatched, want %s, got %s", checksum, sum)
+	}
+
+	err = validateDB(tmpPath)
+	if err != nil {
+		return nil, fmt.Errorf("invalid snapshot: %v", err)
+	}
+
+	err = os.Rename(tmpPath, filepath.Join(cli.Store, "vesta.db"))
+	if err != nil {
+		return nil, err
+	}
+
+	meta := &Metadata{
+		Version:   fmt.Sprintf("%s-%s", modified.UTC().Format("20060102"), sum[:12]),
+		UpdatedAt: time.Now().UTC(),
+		SHA256:    sum,
+		Source:    url,
+	}
+
+	err = writeMetadata(cli.Store, meta)
+	if err != nil {
+		config.ErrorLog.Printf("failed to write metadata, error: %v", err)
+	}
+
+	// The snapshot is up to date, skip the fetching of NVD in scans
+	err = writeLog(cli.Store)
+	if err != nil {
+		config.ErrorLog.Printf("failed to write date log, error: %v", err)
+	}
+
+	return meta, nil
+}
+
+// fetchChecksum get the checksum in the format of `sha256sum`
+func (cli *Client) fetchChecksum(url string) (string, error) {
+	resp, err := cli.Cli.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("status: %s", resp.Status)
+	}
+
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+	if err != nil {
+		return "", err
+	}
+
+	fields := strings.Fields(string(data))
+	if len(fields) < 1 {
+		return "", fmt.Errorf("empty checksum")
+	}
+
+	return fields[0], nil
+}
+
+// download write the snapshot to the file, the sha256 and the modified time are returned
+func (cli *Client) download(url string, w io.Writer) (string, time.Time, error) {
+	modified := time.Now()
+
+	resp, err := cli.Cli.Get(url)
+	if err != nil {
+		return "", modified, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", modified, fmt.Errorf("status: %s", resp.Status)
+	}
+
+	if lm, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		modified = lm
+	}
+
+	h := sha256.New()
+	_, err = io.Copy(io.MultiWriter(w, h), resp.Body)
+	if err != nil {
+		return "", modified, err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), modified, nil
+}
+
+// validateDB check the integrity and the vulnerability table of the database
+func validateDB(path string) error {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var result string
+	err = db.QueryRow("PRAGMA integrity_check").Scan(&result)
+	if err != nil {
+		return err
+	}
+	if result != "ok" {
+		return fmt.Errorf("integrity check: %s", result)
+	}
+
+	var count int
+	err = db.QueryRow("SELECT COUNT(*) FROM vulns").Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count < 1 {
+		return fmt.Errorf("no vulnerability in database")
+	}
+
+	return nil
+}
+
+func writeMetadata(store string, meta *Metadata) error {
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := filepath.Join(store, metadataFile+".tmp")
+	err = ioutil.WriteFile(tmp, data, 0644)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, filepath.Join(store, metadataFile))
+}
+
+// LoadMetadata get the metadata of the database in use,
+// the database fetched from NVD before the metadata is tracked is described by `date.txt`
+func LoadMetadata() (*Metadata, error) {
+	store, err := StoreDir()
+	if err != nil {
+		return nil, err
+	}
+
+	return loadMetadata(store)
+}
+
+func loadMetadata(store string) (*Metadata, error) {
+	data, err := ioutil.ReadFile(filepath.Join(store, metadataFile))
+	if err == nil {
+		meta := &Metadata{}
+		err = json.Unmarshal(data, meta)
+		return meta, err
+	}
+
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	date, err := ioutil.ReadFile(filepath.Join(store, "date.txt"))
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := time.Parse("02/01/2006", strings.TrimSpace(string(date)))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Metadata{Version: updated.Format("20060102"), UpdatedAt: updated, Source: "nvd"}, nil
+}
+
+// Age get the duration since the database is updated
+func (m *Metadata) Age() time.Duration {
+	return time.Since(m.UpdatedAt)
+}
+
+// LogFreshness log the version and the age of the database in use
+func LogFreshness() {
+	meta, err := LoadMetadata()
+	if err != nil {
+		log.Printf(config.Yellow("Vulnerability database version is unknown, run `vesta update` to update it"))
+		return
+	}
+
+	days := int(meta.Age().Hours() / 24)
+	log.Printf("Vulnerability database version: %s, updated %d day(s) ago", meta.Version, days)
+}
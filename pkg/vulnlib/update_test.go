@@ -0,0 +1,97 @@
+package vulnlib
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// buildSnapshot create a database with a vulnerability and return the content
+func buildSnapshot(t *testing.T) []byte {
+	path := filepath.Join(t.TempDir(), "snapshot.db")
+
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = db.Exec(`CREATE TABLE vulns ("ID" INTEGER PRIMARY KEY, "VulnName" TEXT);
+		INSERT INTO vulns ("VulnName") VALUES ('docker');`)
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return data
+}
+
+func TestUpdateSnapshot(t *testing.T) {
+	snapshot := buildSnapshot(t)
+	sum := sha256.Sum256(snapshot)
+	checksum := hex.EncodeToString(sum[:])
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/vesta.db":
+			w.Write(snapshot)
+		case "/vesta.db.sha256":
+			w.Write([]byte(checksum + "  vesta.db\n"))
+		case "/broken.db":
+			w.Write([]byte("not a database"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	store := t.TempDir()
+	dbPath := filepath.Join(store, "vesta.db")
+	if err := os.WriteFile(dbPath, []byte("current"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cli := &Client{Cli: srv.Client(), Store: store}
+
+	// Failed updates keep the database in use
+	if _, err := cli.UpdateSnapshot(srv.URL+"/vesta.db", "0123"); err == nil {
+		t.Errorf("UpdateSnapshot() accepted the mismatched checksum")
+	}
+
+	brokenSum := sha256.Sum256([]byte("not a database"))
+	if _, err := cli.UpdateSnapshot(srv.URL+"/broken.db", hex.EncodeToString(brokenSum[:])); err == nil {
+		t.Errorf("UpdateSnapshot() accepted the invalid database")
+	}
+
+	if data, _ := os.ReadFile(dbPath); string(data) != "current" {
+		t.Fatalf("database is changed by the failed update")
+	}
+
+	meta, err := cli.UpdateSnapshot(srv.URL+"/vesta.db", "")
+	if err != nil {
+		t.Fatalf("UpdateSnapshot() error = %v", err)
+	}
+
+	if meta.SHA256 != checksum {
+		t.Errorf("UpdateSnapshot() sha256 = %s, want %s", meta.SHA256, checksum)
+	}
+
+	loaded, err := loadMetadata(store)
+	if err != nil || loaded.Version != meta.Version {
+		t.Errorf("loadMetadata() = %v, %v, want version %s", loaded, err, meta.Version)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(store, "*.download"))
+	if len(files) > 0 {
+		t.Errorf("temporary files are left: %v", files)
+	}
+}